@@ -0,0 +1,101 @@
+// Command seed creates the built-in reference data, the ISO countries and
+// the document type catalog, and writes it to stdout as JSON.
+//
+// It seeds into the memory repositories, as the tree has no other storage
+// backend yet. A backend should swap its repositories in for them: both
+// seeds skip the entries that already exist, so they can be rerun.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"payroll/internal/audit"
+	"payroll/internal/country"
+	"payroll/internal/doctype"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/uow"
+
+	"github.com/google/uuid"
+)
+
+// subject is recorded as the actor of the seeded data.
+const subject = "seed"
+
+// output is what the command writes: every country with its document types.
+type output struct {
+	Countries []countryOutput `json:"countries"`
+}
+
+type countryOutput struct {
+	ID         uuid.UUID       `json:"id"`
+	Code       string          `json:"code"`
+	Name       string          `json:"name"`
+	CoinCode   string          `json:"coinCode"`
+	CoinSymbol string          `json:"coinSymbol"`
+	DocTypes   []docTypeOutput `json:"docTypes,omitempty"`
+}
+
+type docTypeOutput struct {
+	ID   uuid.UUID `json:"id"`
+	Code string    `json:"code"`
+	Name string    `json:"name"`
+}
+
+// discardRecorder drops audit events: the memory repositories do not
+// outlive the command, so neither would their audit trail.
+type discardRecorder struct{}
+
+func (discardRecorder) Record(context.Context, audit.Event) error { return nil }
+
+func main() {
+	if err := run(context.Background(), os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		os.Exit(1)
+	}
+}
+
+// run seeds countries and then document types, which need the countries,
+// and writes the result to w. Counts go to log.
+func run(ctx context.Context, w, log io.Writer) error {
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: subject, Roles: []auth.Role{auth.RolePlatformAdmin}})
+
+	countries := country.NewMemoryRepository()
+	docTypes := doctype.NewMemoryRepository()
+	countryService := country.NewService(countries, uow.NewMemory(), discardRecorder{})
+	docTypeService := doctype.NewService(docTypes, countries, doctype.DefaultRules())
+
+	createdCountries, err := countryService.Seed(ctx)
+	if err != nil {
+		return fmt.Errorf("seeding countries: %w", err)
+	}
+	createdDocTypes, err := docTypeService.Seed(ctx)
+	if err != nil {
+		return fmt.Errorf("seeding document types: %w", err)
+	}
+	fmt.Fprintf(log, "created %d countries and %d document types\n", createdCountries, createdDocTypes)
+
+	list, err := countryService.ListAllCountries(ctx)
+	if err != nil {
+		return err
+	}
+	out := output{Countries: make([]countryOutput, 0, len(list))}
+	for _, c := range list {
+		types, err := docTypeService.ListDocTypesByCountry(ctx, c.ID)
+		if err != nil {
+			return err
+		}
+		co := countryOutput{ID: c.ID, Code: c.Code, Name: c.Name, CoinCode: c.CoinCode, CoinSymbol: c.CoinSymbol}
+		for _, d := range types {
+			co.DocTypes = append(co.DocTypes, docTypeOutput{ID: d.ID, Code: d.Code, Name: d.Name})
+		}
+		out.Countries = append(out.Countries, co)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
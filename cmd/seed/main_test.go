@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_WritesCountriesWithTheirDocTypes(t *testing.T) {
+	var stdout, log bytes.Buffer
+
+	require.NoError(t, run(context.Background(), &stdout, &log))
+
+	var out output
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &out))
+	require.NotEmpty(t, out.Countries)
+	for i := 1; i < len(out.Countries); i++ {
+		assert.Less(t, out.Countries[i-1].Code, out.Countries[i].Code)
+	}
+
+	var chile *countryOutput
+	for i := range out.Countries {
+		if out.Countries[i].Code == "CL" {
+			chile = &out.Countries[i]
+		}
+	}
+	require.NotNil(t, chile)
+	assert.Equal(t, "CLP", chile.CoinCode)
+	var codes []string
+	for _, d := range chile.DocTypes {
+		codes = append(codes, d.Code)
+	}
+	assert.Equal(t, []string{"PASSPORT", "RUT"}, codes)
+	assert.Contains(t, log.String(), "document types")
+}
@@ -2,6 +2,7 @@ package apperror
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
@@ -11,6 +12,12 @@ const (
 	TypeNotFound  Type = "NOT_FOUND"
 	TypeInvalid   Type = "INVALID_INPUT"
 	TypeDuplicate Type = "DUPLICATE_ENTRY"
+	// TypeConflict reports a write based on a stale version of an entity.
+	TypeConflict Type = "CONFLICT"
+	// TypeUnauthenticated reports a call made without a caller identity.
+	TypeUnauthenticated Type = "UNAUTHENTICATED"
+	// TypeForbidden reports a caller that may not perform the operation.
+	TypeForbidden Type = "FORBIDDEN"
 )
 
 type DomainError struct {
@@ -23,7 +30,7 @@ type DomainError struct {
 func (e *DomainError) Error() string {
 	if len(e.Details) > 0 {
 		detailBytes, err := json.Marshal(e.Details)
-		if err != nil {
+		if err == nil {
 			return fmt.Sprintf("[%s/%s]: %s. Details: %s", e.Origin, e.Type, e.Message, string(detailBytes))
 		}
 	}
@@ -47,3 +54,38 @@ func NewValidationError(origin string, details map[string]string) error {
 		Details: details,
 	}
 }
+
+// IsType reports whether err is a DomainError of the given type.
+func IsType(err error, errType Type) bool {
+	var domainErr *DomainError
+	return errors.As(err, &domainErr) && domainErr.Type == errType
+}
+
+// NewDuplicateError reports a uniqueness conflict. Details maps each
+// conflicting field to a description of the conflict.
+func NewDuplicateError(origin string, details map[string]string) error {
+	return &DomainError{
+		Type:    TypeDuplicate,
+		Origin:  origin,
+		Message: "Duplicate entry",
+		Details: details,
+	}
+}
+
+func NewWithDetails(errType Type, origin, msg string, details map[string]string) error {
+	return &DomainError{
+		Type:    errType,
+		Origin:  origin,
+		Message: msg,
+		Details: details,
+	}
+}
+
+// NewConflictError reports that an entity changed since the caller read it.
+func NewConflictError(origin, msg string) error {
+	return &DomainError{
+		Type:    TypeConflict,
+		Origin:  origin,
+		Message: msg,
+	}
+}
@@ -0,0 +1,131 @@
+// Package audit keeps an append-only log of every change to master data and
+// payroll: who changed what, when, from which request, and the fields that
+// changed.
+//
+// Entries of each tenant form a hash chain. Every entry's Hash covers its
+// content and the Hash of the entry before it, so editing, removing or
+// reordering entries in the store breaks the chain from that point on, which
+// Service.Verify detects. Platform-wide data such as countries is logged in
+// its own chain, under uuid.Nil.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"payroll/internal/platform/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Action string
+
+const (
+	ActionCreate  Action = "CREATE"
+	ActionUpdate  Action = "UPDATE"
+	ActionDelete  Action = "DELETE"
+	ActionRestore Action = "RESTORE"
+	// ActionErase records that personal data was erased. Its entry carries
+	// no changes, so that the erased values are not copied into the log.
+	ActionErase Action = "ERASE"
+	// ActionPurge records that a soft-deleted entity was removed
+	// permanently. Its entry carries no changes either.
+	ActionPurge Action = "PURGE"
+)
+
+// Change is one field that changed, with its JSON-encoded values. Before is
+// null for creations.
+type Change struct {
+	Field  string
+	Before json.RawMessage
+	After  json.RawMessage
+}
+
+type Entry struct {
+	ID         uuid.UUID
+	TenantID   uuid.UUID
+	Sequence   int64
+	OccurredAt time.Time
+	Actor      string
+	RequestID  string
+	EntityType string
+	EntityID   uuid.UUID
+	Action     Action
+	Changes    []Change
+	PrevHash   string
+	Hash       string
+}
+
+// ComputeHash returns the hash the entry must carry: SHA-256 over its JSON
+// encoding with Hash left empty.
+func (e *Entry) ComputeHash() string {
+	c := *e
+	c.Hash = ""
+	data, err := json.Marshal(c)
+	if err != nil {
+		// Entries only hold values that encode.
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Event describes a change to be recorded. Before and After are the entity
+// before and after the change; Before is nil for creations.
+type Event struct {
+	TenantID   uuid.UUID
+	EntityType string
+	EntityID   uuid.UUID
+	Action     Action
+	Before     any
+	After      any
+}
+
+// Recorder records changes. Services call it in the same unit of work as the
+// change itself, so that a change is never saved without its entry.
+type Recorder interface {
+	Record(ctx context.Context, ev Event) error
+}
+
+// Filter is what the repository receives from Service.List. Results must be
+// ordered by ID and start after Query.After.
+type Filter struct {
+	TenantID   uuid.UUID
+	EntityType string
+	EntityID   *uuid.UUID
+	Actor      string
+	From       *time.Time
+	To         *time.Time
+	pagination.Query
+}
+
+// Repository stores entries. It has no way to change or remove them.
+type Repository interface {
+	// Append stores e. It must fail if the tenant's chain already has an
+	// entry with e's Sequence (e.g. through a unique index), so that
+	// concurrent writers on other instances cannot fork the chain.
+	Append(ctx context.Context, e *Entry) error
+	// Last returns the latest entry of the tenant's chain, or nil if the
+	// chain is empty.
+	Last(ctx context.Context, tenantID uuid.UUID) (*Entry, error)
+	List(ctx context.Context, filter Filter) ([]*Entry, error)
+	// Iterate calls fn with every entry of the tenant's chain in sequence
+	// order, stopping at the first error.
+	Iterate(ctx context.Context, tenantID uuid.UUID, fn func(*Entry) error) error
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx whose changes are recorded with the
+// given request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of ctx, or "" if it has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// bookkeeping are the domain.BaseEntity fields that change on every write
+// and are already part of the entry itself.
+var bookkeeping = map[string]bool{
+	"ID":        true,
+	"Version":   true,
+	"CreatedAt": true,
+	"UpdatedAt": true,
+}
+
+// redacted stands in for the values of fields tagged `audit:"redact"`.
+var redacted = json.RawMessage(`"REDACTED"`)
+
+// Diff returns the exported fields whose values differ between before and
+// after, two values of the same struct type or pointers to one; either may
+// be nil. Fields of embedded structs are compared as if declared on the outer
+// struct. Fields tagged `audit:"-"` are never recorded. Fields tagged
+// `audit:"redact"`, such as personal data, are recorded as changed with
+// "REDACTED" in place of every value that is not null, so that the log, which
+// cannot be edited, never holds them.
+func Diff(before, after any) ([]Change, error) {
+	b, a := fields(before), fields(after)
+	names := b.names
+	if len(names) == 0 {
+		names = a.names
+	}
+
+	var changes []Change
+	for _, name := range names {
+		beforeJSON, err := encode(b.values, name)
+		if err != nil {
+			return nil, err
+		}
+		afterJSON, err := encode(a.values, name)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(beforeJSON, afterJSON) {
+			continue
+		}
+		if b.redact[name] || a.redact[name] {
+			beforeJSON, afterJSON = redact(beforeJSON), redact(afterJSON)
+		}
+		changes = append(changes, Change{Field: name, Before: beforeJSON, After: afterJSON})
+	}
+	return changes, nil
+}
+
+type fieldSet struct {
+	names  []string
+	values map[string]reflect.Value
+	redact map[string]bool
+}
+
+func fields(v any) fieldSet {
+	set := fieldSet{values: make(map[string]reflect.Value), redact: make(map[string]bool)}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return set
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return set
+	}
+	set.collect(rv)
+	return set
+}
+
+func (s *fieldSet) collect(rv reflect.Value) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("audit") == "-" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			s.collect(rv.Field(i))
+			continue
+		}
+		if bookkeeping[f.Name] {
+			continue
+		}
+		s.names = append(s.names, f.Name)
+		s.values[f.Name] = rv.Field(i)
+		s.redact[f.Name] = f.Tag.Get("audit") == "redact"
+	}
+}
+
+func redact(value json.RawMessage) json.RawMessage {
+	if bytes.Equal(value, []byte("null")) {
+		return value
+	}
+	return redacted
+}
+
+func encode(values map[string]reflect.Value, name string) (json.RawMessage, error) {
+	v, ok := values[name]
+	if !ok {
+		return json.RawMessage("null"), nil
+	}
+	return json.Marshal(v.Interface())
+}
@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/platform/auth"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRepo struct {
+	entries []*Entry
+}
+
+func (r *memoryRepo) Append(_ context.Context, e *Entry) error {
+	for _, existing := range r.entries {
+		if existing.TenantID == e.TenantID && existing.Sequence == e.Sequence {
+			return apperror.New(apperror.TypeConflict, "test", "sequence taken")
+		}
+	}
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+func (r *memoryRepo) Last(_ context.Context, tenantID uuid.UUID) (*Entry, error) {
+	var last *Entry
+	for _, e := range r.entries {
+		if e.TenantID == tenantID {
+			last = e
+		}
+	}
+	return last, nil
+}
+
+func (r *memoryRepo) List(_ context.Context, filter Filter) ([]*Entry, error) {
+	var out []*Entry
+	for _, e := range r.entries {
+		if e.TenantID != filter.TenantID ||
+			(filter.EntityType != "" && e.EntityType != filter.EntityType) ||
+			(filter.EntityID != nil && e.EntityID != *filter.EntityID) ||
+			(filter.Actor != "" && e.Actor != filter.Actor) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (r *memoryRepo) Iterate(_ context.Context, tenantID uuid.UUID, fn func(*Entry) error) error {
+	for _, e := range r.entries {
+		if e.TenantID == tenantID {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type person struct {
+	domain.BaseEntity
+	Name   string
+	Salary int64
+	Secret string  `audit:"-"`
+	Phone  *string `audit:"redact"`
+}
+
+func withRole(tenantID uuid.UUID, role auth.Role) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "alice", TenantID: tenantID, Roles: []auth.Role{role},
+	})
+}
+
+func TestDiff_ReportsChangedFieldsOnly(t *testing.T) {
+	before := &person{Name: "Ada", Salary: 1000, Secret: "a"}
+	before.Initialize()
+	after := *before
+	after.Salary = 1200
+	after.Secret = "b"
+	after.Touch()
+
+	changes, err := Diff(before, &after)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "Salary", changes[0].Field)
+	assert.JSONEq(t, "1000", string(changes[0].Before))
+	assert.JSONEq(t, "1200", string(changes[0].After))
+
+	created, err := Diff(nil, &after)
+	require.NoError(t, err)
+	fields := map[string]json.RawMessage{}
+	for _, c := range created {
+		fields[c.Field] = c.After
+		assert.JSONEq(t, "null", string(c.Before))
+	}
+	assert.Contains(t, fields, "Name")
+	assert.NotContains(t, fields, "DeletedAt", "nil on both sides")
+	assert.NotContains(t, fields, "Secret")
+	assert.NotContains(t, fields, "ID")
+}
+
+func TestDiff_RedactsTaggedFields(t *testing.T) {
+	phone := "555-0100"
+	before := &person{Name: "Ada"}
+	before.Initialize()
+	after := *before
+	after.Phone = &phone
+
+	changes, err := Diff(before, &after)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "Phone", changes[0].Field)
+	assert.JSONEq(t, "null", string(changes[0].Before))
+	assert.JSONEq(t, `"REDACTED"`, string(changes[0].After))
+
+	changed := "555-0199"
+	again := after
+	again.Phone = &changed
+	changes, err = Diff(&after, &again)
+	require.NoError(t, err)
+	require.Len(t, changes, 1, "a change between two values is still recorded")
+	assert.JSONEq(t, `"REDACTED"`, string(changes[0].Before))
+	assert.NotContains(t, string(changes[0].After), "555")
+
+	changes, err = Diff(&again, &again)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestRecord_ChainsEntriesAndDetectsTampering(t *testing.T) {
+	repo := &memoryRepo{}
+	svc := NewService(repo)
+	tenantID := uuid.New()
+	ctx := WithRequestID(withRole(tenantID, auth.RoleTenantAdmin), "req-1")
+
+	before := &person{Name: "Ada", Salary: 1000}
+	before.Initialize()
+	after := *before
+	after.Salary = 1200
+	require.NoError(t, svc.Record(ctx, Event{TenantID: tenantID, EntityType: "Person", EntityID: before.ID, Action: ActionCreate, After: before}))
+	require.NoError(t, svc.Record(ctx, Event{TenantID: tenantID, EntityType: "Person", EntityID: before.ID, Action: ActionUpdate, Before: before, After: &after}))
+	require.NoError(t, svc.Record(context.Background(), Event{EntityType: "Country", EntityID: uuid.New(), Action: ActionCreate}))
+
+	require.Len(t, repo.entries, 3)
+	first, second := repo.entries[0], repo.entries[1]
+	assert.Equal(t, int64(1), first.Sequence)
+	assert.Equal(t, int64(2), second.Sequence)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.Equal(t, "alice", second.Actor)
+	assert.Equal(t, "req-1", second.RequestID)
+	assert.Equal(t, int64(1), repo.entries[2].Sequence, "platform-wide data has its own chain")
+	assert.Equal(t, "system", repo.entries[2].Actor)
+
+	v, err := svc.Verify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Verification{Entries: 2, Valid: true}, v)
+
+	second.Changes[0].After = json.RawMessage("999999")
+	v, err = svc.Verify(ctx)
+	require.NoError(t, err)
+	assert.False(t, v.Valid)
+	assert.Equal(t, int64(2), v.BrokenAt)
+}
+
+func TestList_IsScopedToTheCallersTenantAndRole(t *testing.T) {
+	repo := &memoryRepo{}
+	svc := NewService(repo)
+	tenantID, otherID := uuid.New(), uuid.New()
+	entityID := uuid.New()
+	for _, id := range []uuid.UUID{tenantID, tenantID, otherID} {
+		require.NoError(t, svc.Record(context.Background(), Event{TenantID: id, EntityType: "Employee", EntityID: entityID, Action: ActionUpdate}))
+	}
+	require.NoError(t, svc.Record(context.Background(), Event{TenantID: tenantID, EntityType: "Workspace", EntityID: uuid.New(), Action: ActionCreate}))
+
+	page, err := svc.List(withRole(tenantID, auth.RoleAuditor), ListParams{EntityType: "Employee", EntityID: &entityID})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	for _, e := range page.Items {
+		assert.Equal(t, tenantID, e.TenantID)
+	}
+
+	_, err = svc.List(withRole(tenantID, auth.RolePayrollManager), ListParams{})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	from := time.Now()
+	to := from.Add(-time.Hour)
+	_, err = svc.List(withRole(tenantID, auth.RoleAuditor), ListParams{From: &from, To: &to})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+}
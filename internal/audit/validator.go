@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/validation"
+)
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+func (v *Validator) ValidatePage(params pagination.Params) {
+	for field, msg := range params.Validate() {
+		v.AddError(field, msg)
+	}
+}
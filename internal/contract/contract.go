@@ -0,0 +1,121 @@
+package contract
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/money"
+	"payroll/internal/payroll"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	modelOrigin = "Contract"
+	salaryCode  = "SALARY"
+)
+
+// Contract holds the pay agreed with an employee. Salary is denominated in
+// Currency and paid in PayCurrency, which is usually the currency of the
+// workspace's country.
+type Contract struct {
+	domain.BaseEntity
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	EmployeeID  uuid.UUID
+
+	Salary      money.Amount
+	Currency    string
+	PayCurrency string
+	StartDate   time.Time
+	EndDate     *time.Time
+}
+
+type CreateContractParams struct {
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	EmployeeID  uuid.UUID
+	Salary      money.Amount
+	Currency    string
+	// Defaults to the currency of the workspace's country.
+	PayCurrency *string
+	StartDate   time.Time
+	EndDate     *time.Time
+}
+
+func NewContract(params CreateContractParams, defaultPayCurrency string) (*Contract, error) {
+	validator := NewValidator()
+
+	if params.TenantID == uuid.Nil {
+		validator.AddError("TenantID", "is empty")
+	}
+	if params.WorkspaceID == uuid.Nil {
+		validator.AddError("WorkspaceID", "is empty")
+	}
+	if params.EmployeeID == uuid.Nil {
+		validator.AddError("EmployeeID", "is empty")
+	}
+
+	params.Currency = strings.ToUpper(strings.TrimSpace(params.Currency))
+	payCurrency := defaultPayCurrency
+	if params.PayCurrency != nil {
+		payCurrency = *params.PayCurrency
+	}
+	payCurrency = strings.ToUpper(strings.TrimSpace(payCurrency))
+
+	validator.ValidateSalary(params.Salary)
+	validator.ValidateCurrency("Currency", params.Currency)
+	validator.ValidateCurrency("PayCurrency", payCurrency)
+	validator.ValidateDates(params.StartDate, params.EndDate)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	contract := &Contract{
+		TenantID:    params.TenantID,
+		WorkspaceID: params.WorkspaceID,
+		EmployeeID:  params.EmployeeID,
+		Salary:      params.Salary,
+		Currency:    params.Currency,
+		PayCurrency: payCurrency,
+		StartDate:   params.StartDate,
+		EndDate:     params.EndDate,
+	}
+	contract.Initialize()
+
+	return contract, nil
+}
+
+func (c *Contract) IsActiveOn(date time.Time) bool {
+	if c.StartDate.After(date) {
+		return false
+	}
+	return c.EndDate == nil || !c.EndDate.Before(date)
+}
+
+// CalculateParams builds the payroll input for the contract. The salary is
+// passed in its own currency and converted by the payroll service if needed.
+func (c *Contract) CalculateParams() payroll.CalculateParams {
+	return payroll.CalculateParams{
+		EmployeeID: c.EmployeeID,
+		Currency:   c.PayCurrency,
+		Earnings: []payroll.Item{{
+			Code:     salaryCode,
+			Kind:     payroll.ItemKindEarning,
+			Amount:   c.Salary,
+			Currency: c.Currency,
+		}},
+	}
+}
+
+type Repository interface {
+	Create(ctx context.Context, contract *Contract) error
+	Get(ctx context.Context, id uuid.UUID) (*Contract, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, contract *Contract) error
+	ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*Contract, error)
+}
@@ -0,0 +1,123 @@
+package contract
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/country"
+	"payroll/internal/employee"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/tenant"
+	"payroll/internal/workspace"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const serviceOrigin = "ContractService"
+
+type Service struct {
+	repo          Repository
+	employeeRepo  employee.Repository
+	workspaceRepo workspace.Repository
+	countryRepo   country.Repository
+	logger        logger.Logger
+}
+
+func NewService(r Repository, er employee.Repository, wr workspace.Repository, cr country.Repository, l logger.Logger) *Service {
+	return &Service{
+		repo:          r,
+		employeeRepo:  er,
+		workspaceRepo: wr,
+		countryRepo:   cr,
+		logger:        l,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, params CreateContractParams) (*Contract, error) {
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	params.TenantID = tenantID
+	if err := auth.Authorize(ctx, auth.PermEmployeeWrite, auth.Resource{WorkspaceID: params.WorkspaceID, EmployeeID: params.EmployeeID}, serviceOrigin); err != nil {
+		return nil, err
+	}
+
+	emp, err := s.employeeRepo.GetByID(ctx, params.EmployeeID)
+	if err != nil || emp.IsDeleted() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Invalid EmployeeID")
+	}
+	if emp.TenantID != params.TenantID || emp.WorkspaceID != params.WorkspaceID {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Employee does not belong to the given workspace")
+	}
+
+	ws, err := s.workspaceRepo.Get(ctx, params.WorkspaceID)
+	if err != nil || ws.IsDeleted() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Invalid WorkspaceID")
+	}
+	c, err := s.countryRepo.GetByID(ctx, ws.CountryID)
+	if err != nil {
+		s.logger.Error(err, "Failed to get workspace country", "workspace_id", ws.ID)
+		return nil, err
+	}
+
+	contract, err := NewContract(params, c.CoinCode)
+	if err != nil {
+		s.logger.Warn("Failed to create contract due to validation errors", "errors", err)
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, contract); err != nil {
+		s.logger.Error(err, "Failed to save contract to repository")
+		return nil, err
+	}
+
+	s.logger.Info("Contract created successfully", "contract_id", contract.ID, "employee_id", contract.EmployeeID)
+	return contract, nil
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Contract, error) {
+	contract, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, contract.TenantID, serviceOrigin, "Contract not found"); err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermEmployeeRead, contract.resource(), serviceOrigin); err != nil {
+		return nil, err
+	}
+	return contract, nil
+}
+
+func (s *Service) ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*Contract, error) {
+	contracts, err := s.repo.ListByEmployeeID(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	contracts, err = tenant.Filter(ctx, contracts, func(c *Contract) uuid.UUID { return c.TenantID })
+	if err != nil {
+		return nil, err
+	}
+	return auth.Filter(ctx, auth.PermEmployeeRead, contracts, (*Contract).resource)
+}
+
+// GetActive returns the employee's contract in force on date.
+func (s *Service) GetActive(ctx context.Context, employeeID uuid.UUID, date time.Time) (*Contract, error) {
+	contracts, err := s.ListByEmployeeID(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	for _, contract := range contracts {
+		if contract.IsActiveOn(date) {
+			return contract, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, serviceOrigin, "Employee has no active contract on the given date")
+}
+
+// resource is what permissions on the contract are checked against.
+func (c *Contract) resource() auth.Resource {
+	return auth.Resource{WorkspaceID: c.WorkspaceID, EmployeeID: c.EmployeeID}
+}
@@ -0,0 +1,100 @@
+package contract
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/country"
+	"payroll/internal/employee"
+	"payroll/internal/platform/logger"
+	"payroll/internal/testkit"
+	"payroll/internal/workspace"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRepo struct {
+	Repository
+	contracts []*Contract
+}
+
+func (r *memoryRepo) Create(_ context.Context, c *Contract) error {
+	r.contracts = append(r.contracts, c)
+	return nil
+}
+
+func (r *memoryRepo) Get(_ context.Context, id uuid.UUID) (*Contract, error) {
+	for _, c := range r.contracts {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "contract not found")
+}
+
+func (r *memoryRepo) ListByEmployeeID(_ context.Context, employeeID uuid.UUID) ([]*Contract, error) {
+	var out []*Contract
+	for _, c := range r.contracts {
+		if c.EmployeeID == employeeID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+type fakeWorkspaceRepo struct {
+	workspace.Repository
+}
+
+func (fakeWorkspaceRepo) Get(_ context.Context, id uuid.UUID) (*workspace.Workspace, error) {
+	ws := &workspace.Workspace{CountryID: uuid.New()}
+	ws.ID = id
+	return ws, nil
+}
+
+type fakeCountryRepo struct {
+	country.Repository
+}
+
+func (fakeCountryRepo) GetByID(_ context.Context, id uuid.UUID) (*country.Country, error) {
+	return &country.Country{CoinCode: "USD"}, nil
+}
+
+func TestService_DoesNotLeakContractsAcrossTenants(t *testing.T) {
+	tenantID := uuid.New()
+	emp := &employee.Employee{TenantID: tenantID, WorkspaceID: uuid.New()}
+	emp.Initialize()
+	employees := employee.NewMemoryRepository()
+	require.NoError(t, employees.Create(context.Background(), emp))
+	svc := NewService(&memoryRepo{}, employees, fakeWorkspaceRepo{}, fakeCountryRepo{}, logger.Nop{})
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	params := CreateContractParams{
+		WorkspaceID: emp.WorkspaceID,
+		EmployeeID:  emp.ID,
+		Salary:      100000,
+		Currency:    "USD",
+		StartDate:   start,
+	}
+	contract, err := svc.Create(testkit.AdminContext(tenantID), params)
+	require.NoError(t, err)
+
+	other := testkit.AdminContext(uuid.New())
+	_, err = svc.Get(other, contract.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	list, err := svc.ListByEmployeeID(other, emp.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = svc.GetActive(other, emp.ID, start)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+
+	_, err = svc.Create(other, params)
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid), "another tenant's employee cannot be given a contract")
+	params.TenantID = tenantID
+	_, err = svc.Create(other, params)
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid), "nor can another tenant be claimed")
+}
@@ -0,0 +1,39 @@
+package contract
+
+import (
+	"payroll/internal/money"
+	"payroll/internal/platform/validation"
+	"time"
+)
+
+const currencyCodeLength = 3
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+func (v *Validator) ValidateSalary(salary money.Amount) {
+	if !salary.IsPositive() {
+		v.AddError("Salary", "must be greater than zero")
+	}
+}
+
+func (v *Validator) ValidateCurrency(field, code string) {
+	if code == "" {
+		v.AddError(field, "is empty")
+	} else if len(code) != currencyCodeLength {
+		v.AddError(field, "must be a 3-letter currency code")
+	}
+}
+
+func (v *Validator) ValidateDates(start time.Time, end *time.Time) {
+	if start.IsZero() {
+		v.AddError("StartDate", "is empty")
+	} else if end != nil && end.Before(start) {
+		v.AddError("EndDate", "cannot be before StartDate")
+	}
+}
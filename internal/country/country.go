@@ -5,13 +5,13 @@ import (
 	"payroll/internal/apperror"
 	"payroll/internal/domain"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Country struct {
 	domain.BaseEntity
-	ID         uuid.UUID
 	Code       string
 	Name       string
 	CoinCode   string
@@ -30,14 +30,17 @@ type UpdateCountryParams struct {
 	Name       *string
 	CoinCode   *string
 	CoinSymbol *string
+	// Version, when set, must equal the current version (e.g. from an
+	// If-Match header) or the update fails with a conflict.
+	Version *int64
 }
 
 func NewCountry(params CreateCountryParams) (*Country, error) {
 	validator := NewValidator()
 
-	params.Code = strings.TrimSpace(params.Code)
+	params.Code = strings.ToUpper(strings.TrimSpace(params.Code))
 	params.Name = strings.TrimSpace(params.Name)
-	params.CoinCode = strings.TrimSpace(params.CoinCode)
+	params.CoinCode = strings.ToUpper(strings.TrimSpace(params.CoinCode))
 	params.CoinSymbol = strings.TrimSpace(params.CoinSymbol)
 
 	validator.ValidateCode(params.Code)
@@ -60,10 +63,18 @@ func NewCountry(params CreateCountryParams) (*Country, error) {
 	return country, nil
 }
 
+// Repository persists countries. GetByID also returns soft-deleted countries
+// so that they can be restored; every other read excludes them.
 type Repository interface {
 	Create(ctx context.Context, country *Country) error
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
 	Update(ctx context.Context, country *Country) error
+	// Delete removes a country permanently.
 	Delete(ctx context.Context, id uuid.UUID) error
+	// PurgeDeleted permanently removes the countries soft-deleted before the
+	// given time and returns them.
+	PurgeDeleted(ctx context.Context, before time.Time) ([]*Country, error)
 	ExistsByCode(ctx context.Context, code string) (bool, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*Country, error)
 	GetByCode(ctx context.Context, code string) (*Country, error)
@@ -0,0 +1,178 @@
+package country
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/platform/uow"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const memoryOrigin = "MemoryCountryRepository"
+
+// MemoryRepository is a Repository that keeps countries in memory, for tests
+// and single-process deployments. It stores and returns copies, compares and
+// increments versions on Update, enforces the uniqueness of Code among live
+// countries, and undoes its writes when a uow.Memory unit of work rolls back,
+// including the increment of the Version it was given.
+type MemoryRepository struct {
+	mu        sync.Mutex
+	countries map[uuid.UUID]*Country
+}
+
+var _ Repository = (*MemoryRepository)(nil)
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{countries: make(map[uuid.UUID]*Country)}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, country *Country) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkUnique(country); err != nil {
+		return err
+	}
+	stored := *country
+	r.countries[country.ID] = &stored
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.countries, country.ID)
+	})
+	return nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, country *Country) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.countries[country.ID]
+	if !ok {
+		return apperror.New(apperror.TypeNotFound, memoryOrigin, "Country not found")
+	}
+	if previous.Version != country.Version {
+		return apperror.NewConflictError(memoryOrigin, "The country was modified since it was read")
+	}
+	if err := r.checkUnique(country); err != nil {
+		return err
+	}
+	country.Version++
+	stored := *country
+	r.countries[country.ID] = &stored
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.countries[country.ID] = previous
+		country.Version = previous.Version
+	})
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.countries[id]
+	if !ok {
+		return nil
+	}
+	delete(r.countries, id)
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.countries[id] = previous
+	})
+	return nil
+}
+
+// PurgeDeleted returns the purged countries ordered by code.
+func (r *MemoryRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]*Country, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var purged []*Country
+	for id, c := range r.countries {
+		if c.IsDeleted() && c.DeletedAt.Before(before) {
+			delete(r.countries, id)
+			purged = append(purged, c)
+		}
+	}
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, c := range purged {
+			r.countries[c.ID] = c
+		}
+	})
+	slices.SortFunc(purged, func(a, b *Country) int { return strings.Compare(a.Code, b.Code) })
+	list := make([]*Country, len(purged))
+	for i, c := range purged {
+		copied := *c
+		list[i] = &copied
+	}
+	return list, nil
+}
+
+func (r *MemoryRepository) ExistsByCode(_ context.Context, code string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.findByCode(code, uuid.Nil) != nil, nil
+}
+
+func (r *MemoryRepository) GetByID(_ context.Context, id uuid.UUID) (*Country, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.countries[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, memoryOrigin, "Country not found")
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (r *MemoryRepository) GetByCode(_ context.Context, code string) (*Country, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.findByCode(code, uuid.Nil)
+	if c == nil {
+		return nil, apperror.New(apperror.TypeNotFound, memoryOrigin, "Country not found")
+	}
+	copied := *c
+	return &copied, nil
+}
+
+// ListAll returns the live countries ordered by code.
+func (r *MemoryRepository) ListAll(_ context.Context) ([]*Country, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*Country
+	for _, c := range r.countries {
+		if !c.IsDeleted() {
+			copied := *c
+			list = append(list, &copied)
+		}
+	}
+	slices.SortFunc(list, func(a, b *Country) int { return strings.Compare(a.Code, b.Code) })
+	return list, nil
+}
+
+// checkUnique returns a TypeDuplicate error when another live country has
+// the code of country.
+func (r *MemoryRepository) checkUnique(country *Country) error {
+	if country.IsDeleted() || r.findByCode(country.Code, country.ID) == nil {
+		return nil
+	}
+	return apperror.NewDuplicateError(memoryOrigin, map[string]string{"Code": "already exists"})
+}
+
+// findByCode returns the live country other than except with the given
+// code, or nil.
+func (r *MemoryRepository) findByCode(code string, except uuid.UUID) *Country {
+	for _, c := range r.countries {
+		if c.ID != except && !c.IsDeleted() && strings.EqualFold(c.Code, code) {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package country
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/platform/uow"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	newCountry := func(code string) *Country {
+		c, err := NewCountry(CreateCountryParams{Code: code, Name: code, CoinCode: "EUR", CoinSymbol: "€"})
+		require.NoError(t, err)
+		return c
+	}
+
+	t.Run("Create rejects a code in use", func(t *testing.T) {
+		r := NewMemoryRepository()
+		require.NoError(t, r.Create(ctx, newCountry("ES")))
+
+		err := r.Create(ctx, newCountry("ES"))
+
+		assert.True(t, apperror.IsType(err, apperror.TypeDuplicate))
+	})
+
+	t.Run("Update rejects a stale version", func(t *testing.T) {
+		r := NewMemoryRepository()
+		c := newCountry("ES")
+		require.NoError(t, r.Create(ctx, c))
+		stale := *c
+		require.NoError(t, r.Update(ctx, c))
+
+		err := r.Update(ctx, &stale)
+
+		assert.True(t, apperror.IsType(err, apperror.TypeConflict))
+	})
+
+	t.Run("rolled back writes are undone", func(t *testing.T) {
+		r := NewMemoryRepository()
+		c := newCountry("ES")
+
+		_ = uow.NewMemory().Do(ctx, func(ctx context.Context) error {
+			require.NoError(t, r.Create(ctx, c))
+			return assert.AnError
+		})
+
+		exists, err := r.ExistsByCode(ctx, "ES")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("reads exclude deleted countries except GetByID", func(t *testing.T) {
+		r := NewMemoryRepository()
+		c := newCountry("ES")
+		c.SoftDelete()
+		require.NoError(t, r.Create(ctx, c))
+
+		_, err := r.GetByCode(ctx, "ES")
+		assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+		list, err := r.ListAll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+		got, err := r.GetByID(ctx, c.ID)
+		require.NoError(t, err)
+		assert.True(t, got.IsDeleted())
+	})
+
+	t.Run("rollback restores purged countries", func(t *testing.T) {
+		r := NewMemoryRepository()
+		c := newCountry("ES")
+		c.SoftDelete()
+		require.NoError(t, r.Create(ctx, c))
+
+		_ = uow.NewMemory().Do(ctx, func(ctx context.Context) error {
+			purged, err := r.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+			require.NoError(t, err)
+			require.Len(t, purged, 1)
+			assert.Equal(t, c.ID, purged[0].ID)
+			return assert.AnError
+		})
+
+		_, err := r.GetByID(ctx, c.ID)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,46 @@
+package country
+
+import (
+	"context"
+
+	"payroll/internal/iso"
+)
+
+// Seed creates a country for every ISO 3166-1 entry that has a currency,
+// using the embedded ISO data. Countries whose code already exists are left
+// untouched, so it can be run repeatedly. It returns the number of countries
+// created.
+//
+// cmd/seed runs it, followed by doctype.Service.Seed.
+func (s *Service) Seed(ctx context.Context) (int, error) {
+	created := 0
+	for _, c := range iso.Countries() {
+		if c.Currency == "" {
+			continue
+		}
+		currency, ok := iso.LookupCurrency(c.Currency)
+		if !ok {
+			continue
+		}
+
+		exists, err := s.countryRepository.ExistsByCode(ctx, c.Alpha2)
+		if err != nil {
+			return created, err
+		}
+		if exists {
+			continue
+		}
+
+		_, err = s.CreateCountry(ctx, CreateCountryParams{
+			Code:       c.Alpha2,
+			Name:       c.Name,
+			CoinCode:   currency.Code,
+			CoinSymbol: currency.Symbol,
+		})
+		if err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
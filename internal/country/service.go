@@ -3,28 +3,62 @@ package country
 import (
 	"context"
 	"strings"
+	"time"
 
 	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/uow"
 
 	"github.com/google/uuid"
 )
 
+const auditEntity = "Country"
+
 type Service struct {
 	countryRepository Repository
+	uow               uow.UnitOfWork
+	audit             audit.Recorder
 }
 
-func NewService(cr Repository) *Service {
+func NewService(cr Repository, u uow.UnitOfWork, a audit.Recorder) *Service {
 	return &Service{
 		countryRepository: cr,
+		uow:               u,
+		audit:             a,
 	}
 }
 
+// save runs write and records ev in the platform-wide audit chain, in one
+// unit of work.
+func (s *Service) save(ctx context.Context, ev audit.Event, write func(ctx context.Context) error) error {
+	ev.EntityType = auditEntity
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, ev)
+	})
+}
+
 func (s *Service) ListAllCountries(ctx context.Context) ([]*Country, error) {
 	return s.countryRepository.ListAll(ctx)
 }
 
 func (s *Service) GetCountryByID(ctx context.Context, id uuid.UUID) (*Country, error) {
-	return s.countryRepository.GetByID(ctx, id)
+	return s.getCountry(ctx, id)
+}
+
+// getCountry returns the country unless it has been soft-deleted.
+func (s *Service) getCountry(ctx context.Context, id uuid.UUID) (*Country, error) {
+	country, err := s.countryRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if country.IsDeleted() {
+		return nil, apperror.New(apperror.TypeNotFound, "CountryService", "Country not found")
+	}
+	return country, nil
 }
 
 func (s *Service) GetCountryByCode(ctx context.Context, code string) (*Country, error) {
@@ -32,6 +66,9 @@ func (s *Service) GetCountryByCode(ctx context.Context, code string) (*Country,
 }
 
 func (s *Service) CreateCountry(ctx context.Context, params CreateCountryParams) (*Country, error) {
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, "CountryService"); err != nil {
+		return nil, err
+	}
 	country, err := NewCountry(params)
 	if err != nil {
 		return nil, err
@@ -45,58 +82,147 @@ func (s *Service) CreateCountry(ctx context.Context, params CreateCountryParams)
 		return nil, apperror.New(apperror.TypeDuplicate, "CountryService", "A country with this code already exists")
 	}
 
-	if err := s.countryRepository.Create(ctx, country); err != nil {
+	err = s.save(ctx, audit.Event{EntityID: country.ID, Action: audit.ActionCreate, After: country}, func(ctx context.Context) error {
+		return s.countryRepository.Create(ctx, country)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return country, nil
 }
 
+// UpdateCountry checks params.Version and saves the country in one unit of
+// work, so that no other write can come in between.
 func (s *Service) UpdateCountry(ctx context.Context, id uuid.UUID, params UpdateCountryParams) (*Country, error) {
-	country, err := s.countryRepository.GetByID(ctx, id)
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, "CountryService"); err != nil {
+		return nil, err
+	}
+	var country *Country
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		country, err = s.getCountry(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !country.MatchesVersion(params.Version) {
+			return apperror.NewConflictError("CountryService", "The country was modified since it was read")
+		}
+		before := *country
+
+		validator := NewValidator()
+
+		if params.Code != nil {
+			trimmedCode := strings.ToUpper(strings.TrimSpace(*params.Code))
+			validator.ValidateCode(trimmedCode)
+			country.Code = trimmedCode
+		}
+		if params.Name != nil {
+			trimmedName := strings.TrimSpace(*params.Name)
+			validator.ValidateName(trimmedName)
+			country.Name = trimmedName
+		}
+		if params.CoinCode != nil {
+			trimmedCoinCode := strings.ToUpper(strings.TrimSpace(*params.CoinCode))
+			validator.ValidateCoinCode(trimmedCoinCode)
+			country.CoinCode = trimmedCoinCode
+		}
+		if params.CoinSymbol != nil {
+			trimmedCoinSymbol := strings.TrimSpace(*params.CoinSymbol)
+			validator.ValidateCoinSymbol(trimmedCoinSymbol)
+			country.CoinSymbol = trimmedCoinSymbol
+		}
+
+		if validator.HasErrors() {
+			return apperror.NewValidationError("CountryService", validator.Errors())
+		}
+
+		country.Touch()
+
+		return s.save(ctx, audit.Event{EntityID: id, Action: audit.ActionUpdate, Before: &before, After: country}, func(ctx context.Context) error {
+			return s.countryRepository.Update(ctx, country)
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	validator := NewValidator()
+	return country, nil
+}
 
-	if params.Code != nil {
-		trimmedCode := strings.TrimSpace(*params.Code)
-		validator.ValidateCode(trimmedCode)
-		country.Code = trimmedCode
+// DeleteCountry soft-deletes the country. It can be restored until it is
+// purged.
+func (s *Service) DeleteCountry(ctx context.Context, id uuid.UUID) error {
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, "CountryService"); err != nil {
+		return err
 	}
-	if params.Name != nil {
-		trimmedName := strings.TrimSpace(*params.Name)
-		validator.ValidateName(trimmedName)
-		country.Name = trimmedName
+	country, err := s.getCountry(ctx, id)
+	if err != nil {
+		return err
 	}
-	if params.CoinCode != nil {
-		trimmedCoinCode := strings.TrimSpace(*params.CoinCode)
-		validator.ValidateCoinCode(trimmedCoinCode)
-		country.CoinCode = trimmedCoinCode
+	before := *country
+	country.SoftDelete()
+	return s.save(ctx, audit.Event{EntityID: id, Action: audit.ActionDelete, Before: &before, After: country}, func(ctx context.Context) error {
+		return s.countryRepository.Update(ctx, country)
+	})
+}
+
+// RestoreCountry undoes DeleteCountry, provided no other country has taken
+// the code in the meantime.
+func (s *Service) RestoreCountry(ctx context.Context, id uuid.UUID) (*Country, error) {
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, "CountryService"); err != nil {
+		return nil, err
 	}
-	if params.CoinSymbol != nil {
-		trimmedCoinSymbol := strings.TrimSpace(*params.CoinSymbol)
-		validator.ValidateCoinSymbol(trimmedCoinSymbol)
-		country.CoinSymbol = trimmedCoinSymbol
+	country, err := s.countryRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-
-	if validator.HasErrors() {
-		return nil, apperror.NewValidationError("CountryService", validator.Errors())
+	if !country.IsDeleted() {
+		return country, nil
 	}
 
-	country.Touch()
-
-	if err := s.countryRepository.Update(ctx, country); err != nil {
+	exists, err := s.countryRepository.ExistsByCode(ctx, country.Code)
+	if err != nil {
 		return nil, err
 	}
+	if exists {
+		return nil, apperror.New(apperror.TypeDuplicate, "CountryService", "A country with this code already exists")
+	}
 
+	before := *country
+	country.Restore()
+	err = s.save(ctx, audit.Event{EntityID: id, Action: audit.ActionRestore, Before: &before, After: country}, func(ctx context.Context) error {
+		return s.countryRepository.Update(ctx, country)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return country, nil
 }
 
-func (s *Service) DeleteCountry(ctx context.Context, id uuid.UUID) error {
-	if _, err := s.countryRepository.GetByID(ctx, id); err != nil {
-		return err
+// PurgeCountries permanently removes the countries soft-deleted before the
+// given time and records each removal in the audit log. It requires
+// auth.PermDataPurge.
+func (s *Service) PurgeCountries(ctx context.Context, before time.Time) (int, error) {
+	if err := auth.Authorize(ctx, auth.PermDataPurge, auth.Resource{}, "CountryService"); err != nil {
+		return 0, err
+	}
+	var purged []*Country
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if purged, err = s.countryRepository.PurgeDeleted(ctx, before); err != nil {
+			return err
+		}
+		for _, c := range purged {
+			err := s.audit.Record(ctx, audit.Event{EntityType: auditEntity, EntityID: c.ID, Action: audit.ActionPurge})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
-	return s.countryRepository.Delete(ctx, id)
+	return len(purged), nil
 }
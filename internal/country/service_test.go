@@ -0,0 +1,102 @@
+package country
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/iso"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/uow"
+	"payroll/internal/testkit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryCountryRepo struct {
+	Repository
+	countries map[string]*Country
+}
+
+func (r *memoryCountryRepo) Create(_ context.Context, c *Country) error {
+	r.countries[c.Code] = c
+	return nil
+}
+
+func (r *memoryCountryRepo) ExistsByCode(_ context.Context, code string) (bool, error) {
+	_, ok := r.countries[code]
+	return ok, nil
+}
+
+func (r *memoryCountryRepo) GetByID(_ context.Context, id uuid.UUID) (*Country, error) {
+	for _, c := range r.countries {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "country not found")
+}
+
+func (r *memoryCountryRepo) Update(_ context.Context, c *Country) error {
+	r.countries[c.Code] = c
+	return nil
+}
+
+func TestCreateCountry_RequiresISOCodes(t *testing.T) {
+	svc := NewService(&memoryCountryRepo{countries: map[string]*Country{}}, uow.NewMemory(), &testkit.Audit{})
+
+	c, err := svc.CreateCountry(platformAdmin, CreateCountryParams{Code: "cl", Name: "Chile", CoinCode: "clp", CoinSymbol: "$"})
+	require.NoError(t, err)
+	assert.Equal(t, "CL", c.Code)
+	assert.Equal(t, "CLP", c.CoinCode)
+	assert.NotEqual(t, uuid.Nil, c.ID)
+
+	_, err = svc.CreateCountry(platformAdmin, CreateCountryParams{Code: "XX", Name: "Nowhere", CoinCode: "ABC", CoinSymbol: "?"})
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, "is not an ISO 3166-1 country code", domainErr.Details["Code"])
+	assert.Equal(t, "is not an ISO 4217 currency code", domainErr.Details["CoinCode"])
+}
+
+func TestSeed_IsIdempotent(t *testing.T) {
+	repo := &memoryCountryRepo{countries: map[string]*Country{}}
+	svc := NewService(repo, uow.NewMemory(), &testkit.Audit{})
+
+	created, err := svc.Seed(platformAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, len(iso.Countries())-1, created) // Antarctica has no currency
+	assert.Equal(t, "JPY", repo.countries["JP"].CoinCode)
+
+	created, err = svc.Seed(platformAdmin)
+	require.NoError(t, err)
+	assert.Zero(t, created)
+}
+
+func TestPurgeCountries_RequiresPurgePermissionAndAudits(t *testing.T) {
+	repo := NewMemoryRepository()
+	audits := &testkit.Audit{}
+	svc := NewService(repo, uow.NewMemory(), audits)
+	c, err := svc.CreateCountry(platformAdmin, CreateCountryParams{Code: "CL", Name: "Chile", CoinCode: "CLP", CoinSymbol: "$"})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteCountry(platformAdmin, c.ID))
+
+	_, err = svc.PurgeCountries(testkit.AdminContext(uuid.New()), time.Now().Add(time.Minute))
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	purged, err := svc.PurgeCountries(platformAdmin, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = repo.GetByID(platformAdmin, c.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	last := audits.Events[len(audits.Events)-1]
+	assert.Equal(t, audit.ActionPurge, last.Action)
+	assert.Equal(t, c.ID, last.EntityID)
+}
+
+var platformAdmin = auth.WithPrincipal(context.Background(), &auth.Principal{
+	Subject: "ops", Roles: []auth.Role{auth.RolePlatformAdmin},
+})
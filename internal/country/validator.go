@@ -2,13 +2,14 @@ package country
 
 import (
 	"fmt"
+	"payroll/internal/iso"
 	"payroll/internal/platform/validation"
 )
 
 const (
-	maxCodeLength       = 3
+	codeLength          = 2
 	maxNameLength       = 100
-	maxCoinCodeLength   = 3
+	coinCodeLength      = 3
 	maxCoinSymbolLength = 5
 )
 
@@ -23,8 +24,10 @@ func NewValidator() *Validator {
 func (v *Validator) ValidateCode(code string) {
 	if code == "" {
 		v.AddError("Code", "is empty")
-	} else if len(code) > maxCodeLength {
-		v.AddError("Code", fmt.Sprintf("must be less than %d characters", maxCodeLength))
+	} else if len(code) != codeLength {
+		v.AddError("Code", "must be an ISO 3166-1 alpha-2 code")
+	} else if _, ok := iso.LookupCountry(code); !ok {
+		v.AddError("Code", "is not an ISO 3166-1 country code")
 	}
 }
 
@@ -39,10 +42,13 @@ func (v *Validator) ValidateName(name string) {
 func (v *Validator) ValidateCoinCode(coinCode string) {
 	if coinCode == "" {
 		v.AddError("CoinCode", "is empty")
-	} else if len(coinCode) > maxCoinCodeLength {
-		v.AddError("CoinCode", fmt.Sprintf("must be less than %d characters", maxCoinCodeLength))
+	} else if len(coinCode) != coinCodeLength {
+		v.AddError("CoinCode", "must be an ISO 4217 currency code")
+	} else if _, ok := iso.LookupCurrency(coinCode); !ok {
+		v.AddError("CoinCode", "is not an ISO 4217 currency code")
 	}
 }
+
 func (v *Validator) ValidateCoinSymbol(coinSymbol string) {
 	if coinSymbol == "" {
 		v.AddError("CoinSymbol", "is empty")
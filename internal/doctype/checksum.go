@@ -0,0 +1,125 @@
+package doctype
+
+import "strings"
+
+// ChecksumFunc reports whether a normalised document number carries a valid
+// check digit.
+type ChecksumFunc func(number string) bool
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Mod11 validates numbers whose last character is a modulo 11 check digit
+// computed with weights 2..7 from the right, where 10 is written as K (as in
+// the Chilean RUT).
+func Mod11(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	body, check := number[:len(number)-1], number[len(number)-1]
+	if !isDigits(body) {
+		return false
+	}
+
+	sum, weight := 0, 2
+	for i := len(body) - 1; i >= 0; i-- {
+		sum += int(body[i]-'0') * weight
+		weight++
+		if weight > 7 {
+			weight = 2
+		}
+	}
+
+	var expected byte
+	switch r := 11 - sum%11; r {
+	case 11:
+		expected = '0'
+	case 10:
+		expected = 'K'
+	default:
+		expected = byte('0' + r)
+	}
+	return check == expected
+}
+
+// Luhn validates numbers with a Luhn (mod 10) check digit.
+func Luhn(number string) bool {
+	if len(number) < 2 || !isDigits(number) {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// CPF validates the two modulo 11 check digits of a Brazilian CPF.
+func CPF(number string) bool {
+	if len(number) != 11 || !isDigits(number) || strings.Count(number, number[:1]) == 11 {
+		return false
+	}
+
+	checkDigit := func(digits string) byte {
+		sum := 0
+		weight := len(digits) + 1
+		for i := 0; i < len(digits); i++ {
+			sum += int(digits[i]-'0') * weight
+			weight--
+		}
+		r := sum * 10 % 11
+		if r == 10 {
+			r = 0
+		}
+		return byte('0' + r)
+	}
+
+	return checkDigit(number[:9]) == number[9] && checkDigit(number[:10]) == number[10]
+}
+
+const spanishIDLetters = "TRWAGMYFPDXBNJZSQVHLCKE"
+
+// SpanishID validates the control letter of a Spanish DNI (8 digits and a
+// letter) or NIE (X, Y or Z, 7 digits and a letter).
+func SpanishID(number string) bool {
+	if len(number) != 9 {
+		return false
+	}
+	digits := number[:8]
+	switch number[0] {
+	case 'X':
+		digits = "0" + number[1:8]
+	case 'Y':
+		digits = "1" + number[1:8]
+	case 'Z':
+		digits = "2" + number[1:8]
+	}
+	if !isDigits(digits) {
+		return false
+	}
+
+	n := 0
+	for i := 0; i < len(digits); i++ {
+		n = n*10 + int(digits[i]-'0')
+	}
+	return number[8] == spanishIDLetters[n%23]
+}
@@ -2,18 +2,82 @@ package doctype
 
 import (
 	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
+const modelOrigin = "DocType"
+
 type DocType struct {
-	ID        uuid.UUID
+	domain.BaseEntity
+	CountryId uuid.UUID
+	// CountryCode is the ISO code of the country, set by the service. Together
+	// with Code it selects the rule numbers are checked against; see
+	// Service.NormalizeNumber for types stored without it.
+	CountryCode string
+	Code        string
+	Name        string
+	// Inactive document types are kept for existing employees but cannot be
+	// used for new ones.
+	Active bool
+}
+
+type CreateDocTypeParams struct {
 	CountryId uuid.UUID
 	Code      string
 	Name      string
 }
 
+type UpdateDocTypeParams struct {
+	Code *string
+	Name *string
+	// Version, when set, must equal the current version (e.g. from an
+	// If-Match header) or the update fails with a conflict.
+	Version *int64
+}
+
+func NewDocType(params CreateDocTypeParams) (*DocType, error) {
+	validator := NewValidator()
+
+	params.Code = normalizeCode(params.Code)
+	params.Name = strings.TrimSpace(params.Name)
+
+	validator.ValidateCountryID(params.CountryId)
+	validator.ValidateCode(params.Code)
+	validator.ValidateName(params.Name)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	docType := &DocType{
+		CountryId: params.CountryId,
+		Code:      params.Code,
+		Name:      params.Name,
+		Active:    true,
+	}
+	docType.Initialize()
+
+	return docType, nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Repository persists document types. IsValidForCountry must only accept
+// active document types.
 type Repository interface {
 	IsValidForCountry(ctx context.Context, docTypeID uuid.UUID, countryID uuid.UUID) (bool, error)
 	Get(ctx context.Context, id uuid.UUID) (*DocType, error)
+	GetByCountryIDAndCode(ctx context.Context, countryID uuid.UUID, code string) (*DocType, error)
+	ListByCountryID(ctx context.Context, countryID uuid.UUID) ([]*DocType, error)
+	ExistsByCountryIDAndCode(ctx context.Context, countryID uuid.UUID, code string) (bool, error)
+	Create(ctx context.Context, docType *DocType) error
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, docType *DocType) error
 }
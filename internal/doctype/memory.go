@@ -0,0 +1,141 @@
+package doctype
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/platform/uow"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const memoryOrigin = "MemoryDocTypeRepository"
+
+// MemoryRepository is a Repository that keeps document types in memory, for
+// tests and single-process deployments. It stores and returns copies,
+// compares and increments versions on Update, enforces the uniqueness of
+// Code within a country, and undoes its writes when a uow.Memory unit of
+// work rolls back, including the increment of the Version it was given.
+type MemoryRepository struct {
+	mu       sync.Mutex
+	docTypes map[uuid.UUID]*DocType
+}
+
+var _ Repository = (*MemoryRepository)(nil)
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{docTypes: make(map[uuid.UUID]*DocType)}
+}
+
+func (r *MemoryRepository) IsValidForCountry(_ context.Context, docTypeID uuid.UUID, countryID uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.docTypes[docTypeID]
+	return ok && d.Active && d.CountryId == countryID, nil
+}
+
+func (r *MemoryRepository) Get(_ context.Context, id uuid.UUID) (*DocType, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.docTypes[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, memoryOrigin, "Document type not found")
+	}
+	c := *d
+	return &c, nil
+}
+
+func (r *MemoryRepository) GetByCountryIDAndCode(_ context.Context, countryID uuid.UUID, code string) (*DocType, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.find(countryID, code, uuid.Nil)
+	if d == nil {
+		return nil, apperror.New(apperror.TypeNotFound, memoryOrigin, "Document type not found")
+	}
+	c := *d
+	return &c, nil
+}
+
+// ListByCountryID returns the country's document types ordered by code.
+func (r *MemoryRepository) ListByCountryID(_ context.Context, countryID uuid.UUID) ([]*DocType, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*DocType
+	for _, d := range r.docTypes {
+		if d.CountryId == countryID {
+			c := *d
+			list = append(list, &c)
+		}
+	}
+	slices.SortFunc(list, func(a, b *DocType) int { return strings.Compare(a.Code, b.Code) })
+	return list, nil
+}
+
+func (r *MemoryRepository) ExistsByCountryIDAndCode(_ context.Context, countryID uuid.UUID, code string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(countryID, code, uuid.Nil) != nil, nil
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, docType *DocType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkUnique(docType); err != nil {
+		return err
+	}
+	stored := *docType
+	r.docTypes[docType.ID] = &stored
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.docTypes, docType.ID)
+	})
+	return nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, docType *DocType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.docTypes[docType.ID]
+	if !ok {
+		return apperror.New(apperror.TypeNotFound, memoryOrigin, "Document type not found")
+	}
+	if previous.Version != docType.Version {
+		return apperror.NewConflictError(memoryOrigin, "The document type was modified since it was read")
+	}
+	if err := r.checkUnique(docType); err != nil {
+		return err
+	}
+	docType.Version++
+	stored := *docType
+	r.docTypes[docType.ID] = &stored
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.docTypes[docType.ID] = previous
+		docType.Version = previous.Version
+	})
+	return nil
+}
+
+// checkUnique returns a TypeDuplicate error when another document type of
+// the country has the code of docType.
+func (r *MemoryRepository) checkUnique(docType *DocType) error {
+	if r.find(docType.CountryId, docType.Code, docType.ID) == nil {
+		return nil
+	}
+	return apperror.NewDuplicateError(memoryOrigin, map[string]string{"Code": "already exists"})
+}
+
+// find returns the document type of the country other than except with the
+// given code, or nil.
+func (r *MemoryRepository) find(countryID uuid.UUID, code string, except uuid.UUID) *DocType {
+	for _, d := range r.docTypes {
+		if d.ID != except && d.CountryId == countryID && d.Code == code {
+			return d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package doctype
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+)
+
+// Rule describes the valid shape of the numbers of a document type. Numbers
+// are normalised before they are checked, so patterns never need to account
+// for separators.
+type Rule struct {
+	Pattern   *regexp.Regexp
+	MinLength int
+	MaxLength int
+	Checksum  ChecksumFunc
+}
+
+// ruleKey identifies the rule of a document type: the same code can mean
+// different documents in different countries.
+type ruleKey struct {
+	countryCode string
+	code        string
+}
+
+func newRuleKey(countryCode, code string) ruleKey {
+	return ruleKey{countryCode: strings.ToUpper(countryCode), code: strings.ToUpper(code)}
+}
+
+var passportRule = Rule{Pattern: regexp.MustCompile(`^[A-Z0-9]+$`), MinLength: 6, MaxLength: 9}
+
+// Rules holds the rule of each document type by country and code. It is
+// immutable: With returns a copy, so a value can be shared freely.
+type Rules struct {
+	rules map[ruleKey]Rule
+}
+
+// DefaultRules returns the rules of the document types in Catalog.
+func DefaultRules() Rules {
+	return Rules{rules: map[ruleKey]Rule{
+		{"AR", "DNI"}:      {Pattern: regexp.MustCompile(`^[0-9]+$`), MinLength: 7, MaxLength: 8},
+		{"AR", "PASSPORT"}: passportRule,
+		{"BR", "CPF"}:      {Pattern: regexp.MustCompile(`^[0-9]{11}$`), Checksum: CPF},
+		{"BR", "PASSPORT"}: passportRule,
+		{"CA", "SIN"}:      {Pattern: regexp.MustCompile(`^[0-9]{9}$`), Checksum: Luhn},
+		{"CA", "PASSPORT"}: passportRule,
+		{"CL", "RUT"}:      {Pattern: regexp.MustCompile(`^[0-9]{7,8}[0-9K]$`), Checksum: Mod11},
+		{"CL", "PASSPORT"}: passportRule,
+		{"CO", "CC"}:       {Pattern: regexp.MustCompile(`^[0-9]+$`), MinLength: 6, MaxLength: 10},
+		{"CO", "PASSPORT"}: passportRule,
+		{"ES", "NIF"}:      {Pattern: regexp.MustCompile(`^[0-9XYZ][0-9]{7}[A-Z]$`), Checksum: SpanishID},
+		{"ES", "NIE"}:      {Pattern: regexp.MustCompile(`^[XYZ][0-9]{7}[A-Z]$`), Checksum: SpanishID},
+		{"ES", "PASSPORT"}: passportRule,
+		{"MX", "CURP"}:     {Pattern: regexp.MustCompile(`^[A-Z]{4}[0-9]{6}[HM][A-Z]{5}[0-9A-Z][0-9]$`)},
+		{"MX", "PASSPORT"}: passportRule,
+		{"US", "SSN"}:      {Pattern: regexp.MustCompile(`^[0-9]{9}$`)},
+		{"US", "PASSPORT"}: passportRule,
+	}}
+}
+
+// With returns a copy of r in which numbers of document types with the given
+// code in the country with the given ISO code follow rule, replacing any
+// existing one.
+func (r Rules) With(countryCode, code string, rule Rule) Rules {
+	rules := maps.Clone(r.rules)
+	if rules == nil {
+		rules = make(map[ruleKey]Rule)
+	}
+	rules[newRuleKey(countryCode, code)] = rule
+	return Rules{rules: rules}
+}
+
+func (r Rules) Lookup(countryCode, code string) (Rule, bool) {
+	rule, ok := r.rules[newRuleKey(countryCode, code)]
+	return rule, ok
+}
+
+// NormalizeNumber strips dots, dashes and whitespace and upper-cases the
+// number, which is the form document numbers are stored and compared in.
+func NormalizeNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '-', ' ', '\t':
+			return -1
+		}
+		if 'a' <= r && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, number)
+}
+
+// Check validates an already normalised number against the rule.
+func (r Rule) Check(number string) error {
+	if r.MinLength > 0 && len(number) < r.MinLength {
+		return fmt.Errorf("must be at least %d characters", r.MinLength)
+	}
+	if r.MaxLength > 0 && len(number) > r.MaxLength {
+		return fmt.Errorf("must be at most %d characters", r.MaxLength)
+	}
+	if r.Pattern != nil && !r.Pattern.MatchString(number) {
+		return errors.New("has an invalid format")
+	}
+	if r.Checksum != nil && !r.Checksum(number) {
+		return errors.New("has an invalid check digit")
+	}
+	return nil
+}
+
+// NormalizeNumber returns number in normalised form, or an error describing
+// why it is not valid for the document type. Types without a rule for their
+// country and code accept any number; types without a country code accept
+// none, since their rule cannot be told.
+func (r Rules) NormalizeNumber(docType *DocType, number string) (string, error) {
+	if docType.CountryCode == "" {
+		return "", errors.New("cannot be checked: the document type has no country code")
+	}
+	normalized := NormalizeNumber(number)
+	rule, ok := r.Lookup(docType.CountryCode, docType.Code)
+	if !ok {
+		return normalized, nil
+	}
+	if err := rule.Check(normalized); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
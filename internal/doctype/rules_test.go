@@ -0,0 +1,75 @@
+package doctype
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksums(t *testing.T) {
+	assert.True(t, Mod11("123456785"))
+	assert.True(t, Mod11("10000013K"))
+	assert.False(t, Mod11("123456789"))
+
+	assert.True(t, Luhn("046454286"))
+	assert.False(t, Luhn("046454287"))
+
+	assert.True(t, CPF("52998224725"))
+	assert.False(t, CPF("52998224726"))
+	assert.False(t, CPF("11111111111"))
+
+	assert.True(t, SpanishID("12345678Z"))
+	assert.True(t, SpanishID("X1234567L"))
+	assert.False(t, SpanishID("12345678A"))
+}
+
+func TestNormalizeNumber(t *testing.T) {
+	assert.Equal(t, "12345678K", NormalizeNumber(" 12.345.678-k "))
+}
+
+func TestRules_NormalizeNumber(t *testing.T) {
+	rules := DefaultRules()
+	rut := &DocType{CountryCode: "CL", Code: "RUT"}
+
+	normalized, err := rules.NormalizeNumber(rut, "12.345.678-5")
+	assert.NoError(t, err)
+	assert.Equal(t, "123456785", normalized)
+
+	_, err = rules.NormalizeNumber(rut, "12.345.678-9")
+	assert.EqualError(t, err, "has an invalid check digit")
+
+	_, err = rules.NormalizeNumber(&DocType{CountryCode: "cl", Code: "passport"}, "AB12")
+	assert.EqualError(t, err, "must be at least 6 characters")
+
+	normalized, err = rules.NormalizeNumber(&DocType{CountryCode: "CL", Code: "UNKNOWN"}, "a-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "A1", normalized)
+
+	_, err = rules.NormalizeNumber(&DocType{Code: "RUT"}, "123456785")
+	assert.Error(t, err, "a document type without a country code fails closed")
+}
+
+func TestRules_NormalizeNumberUsesTheRuleOfItsCountry(t *testing.T) {
+	rules := DefaultRules()
+	_, err := rules.NormalizeNumber(&DocType{CountryCode: "AR", Code: "DNI"}, "A1")
+	assert.Error(t, err)
+
+	normalized, err := rules.NormalizeNumber(&DocType{CountryCode: "PE", Code: "DNI"}, "a-1")
+	assert.NoError(t, err, "a code is only checked in the countries it has a rule for")
+	assert.Equal(t, "A1", normalized)
+}
+
+func TestRules_With(t *testing.T) {
+	defaults := DefaultRules()
+	rules := defaults.With("cl", "test-code", Rule{Pattern: regexp.MustCompile(`^[0-9]{4}$`)})
+
+	_, err := rules.NormalizeNumber(&DocType{CountryCode: "CL", Code: "TEST-CODE"}, "12-345")
+	assert.EqualError(t, err, "has an invalid format")
+	_, err = rules.NormalizeNumber(&DocType{CountryCode: "AR", Code: "TEST-CODE"}, "12-345")
+	assert.NoError(t, err)
+	_, ok := defaults.Lookup("CL", "TEST-CODE")
+	assert.False(t, ok, "the original is not changed")
+	_, ok = rules.Lookup("CL", "RUT")
+	assert.True(t, ok)
+}
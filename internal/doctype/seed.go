@@ -0,0 +1,62 @@
+package doctype
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"payroll/internal/apperror"
+)
+
+// SeedDocType is an entry of the built-in catalog. Country and code match
+// the rules of DefaultRules.
+type SeedDocType struct {
+	Code string
+	Name string
+}
+
+// Catalog lists common document types by ISO 3166-1 alpha-2 country code.
+var Catalog = map[string][]SeedDocType{
+	"AR": {{"DNI", "Documento Nacional de Identidad"}, {"PASSPORT", "Pasaporte"}},
+	"BR": {{"CPF", "Cadastro de Pessoas Físicas"}, {"PASSPORT", "Passaporte"}},
+	"CA": {{"SIN", "Social Insurance Number"}, {"PASSPORT", "Passport"}},
+	"CL": {{"RUT", "Rol Único Tributario"}, {"PASSPORT", "Pasaporte"}},
+	"CO": {{"CC", "Cédula de Ciudadanía"}, {"PASSPORT", "Pasaporte"}},
+	"ES": {{"NIF", "Número de Identificación Fiscal"}, {"NIE", "Número de Identidad de Extranjero"}, {"PASSPORT", "Pasaporte"}},
+	"MX": {{"CURP", "Clave Única de Registro de Población"}, {"PASSPORT", "Pasaporte"}},
+	"US": {{"SSN", "Social Security Number"}, {"PASSPORT", "Passport"}},
+}
+
+// Seed creates the catalog entries of every country present in the country
+// repository, country by country in code order. Entries that already exist
+// are left untouched, so it can be run repeatedly. It returns the number of
+// document types created.
+func (s *Service) Seed(ctx context.Context) (int, error) {
+	created := 0
+	for _, countryCode := range slices.Sorted(maps.Keys(Catalog)) {
+		entries := Catalog[countryCode]
+		c, err := s.countryRepository.GetByCode(ctx, countryCode)
+		if err != nil {
+			if apperror.IsType(err, apperror.TypeNotFound) {
+				continue
+			}
+			return created, err
+		}
+
+		for _, entry := range entries {
+			exists, err := s.docTypeRepository.ExistsByCountryIDAndCode(ctx, c.ID, entry.Code)
+			if err != nil {
+				return created, err
+			}
+			if exists {
+				continue
+			}
+
+			if _, err := s.CreateDocType(ctx, CreateDocTypeParams{CountryId: c.ID, Code: entry.Code, Name: entry.Name}); err != nil {
+				return created, err
+			}
+			created++
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,169 @@
+package doctype
+
+import (
+	"context"
+	"strings"
+
+	"payroll/internal/apperror"
+	"payroll/internal/country"
+	"payroll/internal/platform/auth"
+
+	"github.com/google/uuid"
+)
+
+const serviceOrigin = "DocTypeService"
+
+type Service struct {
+	docTypeRepository Repository
+	countryRepository country.Repository
+	rules             Rules
+}
+
+func NewService(dtr Repository, cr country.Repository, r Rules) *Service {
+	return &Service{
+		docTypeRepository: dtr,
+		countryRepository: cr,
+		rules:             r,
+	}
+}
+
+// NormalizeNumber checks number against the rule of the document type and
+// returns it in normalised form. The country code is read from the country
+// when the document type does not carry it, e.g. when it was stored before
+// the field existed.
+func (s *Service) NormalizeNumber(ctx context.Context, docType *DocType, number string) (string, error) {
+	if docType.CountryCode == "" {
+		c, err := s.countryRepository.GetByID(ctx, docType.CountryId)
+		if err != nil {
+			return "", err
+		}
+		withCode := *docType
+		withCode.CountryCode = c.Code
+		docType = &withCode
+	}
+	normalized, err := s.rules.NormalizeNumber(docType, number)
+	if err != nil {
+		return "", apperror.NewValidationError(serviceOrigin, map[string]string{"DocNumber": err.Error()})
+	}
+	return normalized, nil
+}
+
+func (s *Service) ListDocTypesByCountry(ctx context.Context, countryID uuid.UUID) ([]*DocType, error) {
+	return s.docTypeRepository.ListByCountryID(ctx, countryID)
+}
+
+func (s *Service) GetDocTypeByID(ctx context.Context, id uuid.UUID) (*DocType, error) {
+	return s.docTypeRepository.Get(ctx, id)
+}
+
+func (s *Service) CreateDocType(ctx context.Context, params CreateDocTypeParams) (*DocType, error) {
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	docType, err := NewDocType(params)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := s.countryRepository.GetByID(ctx, docType.CountryId)
+	if err != nil {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Invalid CountryId")
+	}
+	docType.CountryCode = c.Code
+
+	exists, err := s.docTypeRepository.ExistsByCountryIDAndCode(ctx, docType.CountryId, docType.Code)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, apperror.New(apperror.TypeDuplicate, serviceOrigin, "A document type with this code already exists for the country")
+	}
+
+	if err := s.docTypeRepository.Create(ctx, docType); err != nil {
+		return nil, err
+	}
+
+	return docType, nil
+}
+
+func (s *Service) UpdateDocType(ctx context.Context, id uuid.UUID, params UpdateDocTypeParams) (*DocType, error) {
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	docType, err := s.docTypeRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !docType.MatchesVersion(params.Version) {
+		return nil, apperror.NewConflictError(serviceOrigin, "The document type was modified since it was read")
+	}
+
+	validator := NewValidator()
+	codeChanged := false
+
+	if params.Code != nil {
+		code := normalizeCode(*params.Code)
+		validator.ValidateCode(code)
+		codeChanged = code != docType.Code
+		docType.Code = code
+	}
+	if params.Name != nil {
+		trimmedName := strings.TrimSpace(*params.Name)
+		validator.ValidateName(trimmedName)
+		docType.Name = trimmedName
+	}
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	if codeChanged {
+		exists, err := s.docTypeRepository.ExistsByCountryIDAndCode(ctx, docType.CountryId, docType.Code)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, apperror.New(apperror.TypeDuplicate, serviceOrigin, "A document type with this code already exists for the country")
+		}
+	}
+
+	docType.Touch()
+
+	if err := s.docTypeRepository.Update(ctx, docType); err != nil {
+		return nil, err
+	}
+
+	return docType, nil
+}
+
+// DeactivateDocType stops a document type from being used for new
+// employees. Employees that already use it are not affected.
+func (s *Service) DeactivateDocType(ctx context.Context, id uuid.UUID) (*DocType, error) {
+	return s.setActive(ctx, id, false)
+}
+
+func (s *Service) ActivateDocType(ctx context.Context, id uuid.UUID) (*DocType, error) {
+	return s.setActive(ctx, id, true)
+}
+
+func (s *Service) setActive(ctx context.Context, id uuid.UUID, active bool) (*DocType, error) {
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	docType, err := s.docTypeRepository.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if docType.Active == active {
+		return docType, nil
+	}
+
+	docType.Active = active
+	docType.Touch()
+
+	if err := s.docTypeRepository.Update(ctx, docType); err != nil {
+		return nil, err
+	}
+
+	return docType, nil
+}
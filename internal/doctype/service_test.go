@@ -0,0 +1,164 @@
+package doctype
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"payroll/internal/apperror"
+	"payroll/internal/country"
+	"payroll/internal/platform/auth"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryDocTypeRepo struct {
+	Repository
+	docTypes map[uuid.UUID]*DocType
+}
+
+func (r *memoryDocTypeRepo) Get(_ context.Context, id uuid.UUID) (*DocType, error) {
+	if dt, ok := r.docTypes[id]; ok {
+		return dt, nil
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "doc type not found")
+}
+
+func (r *memoryDocTypeRepo) ExistsByCountryIDAndCode(_ context.Context, countryID uuid.UUID, code string) (bool, error) {
+	for _, dt := range r.docTypes {
+		if dt.CountryId == countryID && dt.Code == code {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryDocTypeRepo) Create(_ context.Context, dt *DocType) error {
+	r.docTypes[dt.ID] = dt
+	return nil
+}
+
+func (r *memoryDocTypeRepo) Update(_ context.Context, dt *DocType) error {
+	r.docTypes[dt.ID] = dt
+	return nil
+}
+
+type stubCountryRepo struct {
+	country.Repository
+	countries []*country.Country
+	lookups   []string
+}
+
+func (r *stubCountryRepo) GetByID(_ context.Context, id uuid.UUID) (*country.Country, error) {
+	for _, c := range r.countries {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "country not found")
+}
+
+func (r *stubCountryRepo) GetByCode(_ context.Context, code string) (*country.Country, error) {
+	r.lookups = append(r.lookups, code)
+	for _, c := range r.countries {
+		if c.Code == code {
+			return c, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "country not found")
+}
+
+func newTestService(t *testing.T) (*Service, *memoryDocTypeRepo, *country.Country) {
+	t.Helper()
+	chile, err := country.NewCountry(country.CreateCountryParams{Code: "CL", Name: "Chile", CoinCode: "CLP", CoinSymbol: "$"})
+	require.NoError(t, err)
+
+	repo := &memoryDocTypeRepo{docTypes: make(map[uuid.UUID]*DocType)}
+	return NewService(repo, &stubCountryRepo{countries: []*country.Country{chile}}, DefaultRules()), repo, chile
+}
+
+func TestCreateDocType_RejectsDuplicateCodePerCountry(t *testing.T) {
+	svc, _, chile := newTestService(t)
+
+	dt, err := svc.CreateDocType(platformAdmin, CreateDocTypeParams{CountryId: chile.ID, Code: " rut ", Name: "RUT"})
+	require.NoError(t, err)
+	assert.Equal(t, "RUT", dt.Code)
+	assert.Equal(t, chile.Code, dt.CountryCode)
+	assert.True(t, dt.Active)
+
+	_, err = svc.CreateDocType(platformAdmin, CreateDocTypeParams{CountryId: chile.ID, Code: "RUT", Name: "Other"})
+	assert.True(t, apperror.IsType(err, apperror.TypeDuplicate))
+
+	_, err = svc.CreateDocType(platformAdmin, CreateDocTypeParams{CountryId: uuid.New(), Code: "RUT", Name: "RUT"})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+}
+
+func TestUpdateDocType_RejectsCodeTakenByAnotherType(t *testing.T) {
+	svc, _, chile := newTestService(t)
+	_, err := svc.CreateDocType(platformAdmin, CreateDocTypeParams{CountryId: chile.ID, Code: "RUT", Name: "RUT"})
+	require.NoError(t, err)
+	passport, err := svc.CreateDocType(platformAdmin, CreateDocTypeParams{CountryId: chile.ID, Code: "PASSPORT", Name: "Pasaporte"})
+	require.NoError(t, err)
+
+	sameCode, newName := "passport", "Pasaporte extranjero"
+	_, err = svc.UpdateDocType(platformAdmin, passport.ID, UpdateDocTypeParams{Code: &sameCode, Name: &newName})
+	require.NoError(t, err)
+
+	taken := "RUT"
+	_, err = svc.UpdateDocType(platformAdmin, passport.ID, UpdateDocTypeParams{Code: &taken})
+	assert.True(t, apperror.IsType(err, apperror.TypeDuplicate))
+}
+
+func TestDeactivateDocType(t *testing.T) {
+	svc, _, chile := newTestService(t)
+	dt, err := svc.CreateDocType(platformAdmin, CreateDocTypeParams{CountryId: chile.ID, Code: "RUT", Name: "RUT"})
+	require.NoError(t, err)
+
+	dt, err = svc.DeactivateDocType(platformAdmin, dt.ID)
+	require.NoError(t, err)
+	assert.False(t, dt.Active)
+}
+
+func TestSeed_IsIdempotent(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+
+	created, err := svc.Seed(platformAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, len(Catalog["CL"]), created)
+
+	created, err = svc.Seed(platformAdmin)
+	require.NoError(t, err)
+	assert.Zero(t, created)
+	assert.Len(t, repo.docTypes, len(Catalog["CL"]))
+}
+
+func TestSeed_GoesThroughCountriesInCodeOrder(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	countries := svc.countryRepository.(*stubCountryRepo)
+
+	_, err := svc.Seed(platformAdmin)
+	require.NoError(t, err)
+	assert.True(t, slices.IsSorted(countries.lookups))
+	assert.Len(t, countries.lookups, len(Catalog))
+}
+
+var platformAdmin = auth.WithPrincipal(context.Background(), &auth.Principal{
+	Subject: "ops", Roles: []auth.Role{auth.RolePlatformAdmin},
+})
+
+func TestNormalizeNumber_ReadsTheCountryCodeWhenMissing(t *testing.T) {
+	svc, _, chile := newTestService(t)
+	rut := &DocType{CountryId: chile.ID, Code: "RUT"}
+
+	_, err := svc.NormalizeNumber(context.Background(), rut, "12.345.678-9")
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid), "the rule of the country still applies")
+	normalized, err := svc.NormalizeNumber(context.Background(), rut, "12.345.678-5")
+	require.NoError(t, err)
+	assert.Equal(t, "123456785", normalized)
+	assert.Empty(t, rut.CountryCode, "the document type is not changed")
+
+	_, err = svc.NormalizeNumber(context.Background(), &DocType{CountryId: uuid.New(), Code: "RUT"}, "123456785")
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+}
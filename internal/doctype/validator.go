@@ -0,0 +1,43 @@
+package doctype
+
+import (
+	"fmt"
+	"payroll/internal/platform/validation"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxCodeLength = 20
+	maxNameLength = 100
+)
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+func (v *Validator) ValidateCountryID(countryID uuid.UUID) {
+	if countryID == uuid.Nil {
+		v.AddError("CountryId", "is empty")
+	}
+}
+
+func (v *Validator) ValidateCode(code string) {
+	if code == "" {
+		v.AddError("Code", "is empty")
+	} else if len(code) > maxCodeLength {
+		v.AddError("Code", fmt.Sprintf("must be less than %d characters", maxCodeLength))
+	}
+}
+
+func (v *Validator) ValidateName(name string) {
+	if name == "" {
+		v.AddError("Name", "is empty")
+	} else if len(name) > maxNameLength {
+		v.AddError("Name", fmt.Sprintf("must be less than %d characters", maxNameLength))
+	}
+}
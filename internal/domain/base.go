@@ -7,7 +7,12 @@ import (
 )
 
 type BaseEntity struct {
-	ID        uuid.UUID
+	ID uuid.UUID
+	// Version counts the writes of the entity, starting at 1. Repositories
+	// must only apply an update while the stored version still equals
+	// Version, and increment Version when they do; otherwise they return an
+	// apperror TypeConflict and leave the entity unchanged.
+	Version   int64
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt *time.Time
@@ -15,6 +20,7 @@ type BaseEntity struct {
 
 func (b *BaseEntity) Initialize() {
 	b.ID = uuid.Must(uuid.NewV7())
+	b.Version = 1
 	now := time.Now().UTC()
 	b.CreatedAt = now
 	b.UpdatedAt = now
@@ -23,3 +29,27 @@ func (b *BaseEntity) Initialize() {
 func (b *BaseEntity) Touch() {
 	b.UpdatedAt = time.Now().UTC()
 }
+
+// SoftDelete marks the entity as deleted. Repositories keep soft-deleted rows
+// until they are purged, but exclude them from lists, searches and
+// uniqueness checks.
+func (b *BaseEntity) SoftDelete() {
+	now := time.Now().UTC()
+	b.DeletedAt = &now
+	b.UpdatedAt = now
+}
+
+func (b *BaseEntity) Restore() {
+	b.DeletedAt = nil
+	b.Touch()
+}
+
+func (b *BaseEntity) IsDeleted() bool {
+	return b.DeletedAt != nil
+}
+
+// MatchesVersion reports whether expected is nil or the current version. It
+// lets a caller that read the entity earlier detect that it has changed.
+func (b *BaseEntity) MatchesVersion(expected *int64) bool {
+	return expected == nil || *expected == b.Version
+}
@@ -13,6 +13,18 @@ import (
 
 const employeeOrigin = "Employee"
 
+// Events the service publishes. The payload is the Employee without its
+// personal data; see Employee.Redacted.
+const (
+	EventCreated  = "EmployeeCreated"
+	EventUpdated  = "EmployeeUpdated"
+	EventDeleted  = "EmployeeDeleted"
+	EventRestored = "EmployeeRestored"
+	// EventErased tells subscribers to erase their copies of the
+	// employee's personal data.
+	EventErased = "EmployeeErased"
+)
+
 type EmployeeGender string
 
 const (
@@ -29,21 +41,48 @@ func (g EmployeeGender) IsValid() bool {
 	return false
 }
 
+// EmployeeStatus is where an employee is in their employment lifecycle.
+type EmployeeStatus string
+
+const (
+	StatusActive     EmployeeStatus = "ACTIVE"
+	StatusOnLeave    EmployeeStatus = "ON_LEAVE"
+	StatusTerminated EmployeeStatus = "TERMINATED"
+)
+
+func (s EmployeeStatus) IsValid() bool {
+	switch s {
+	case StatusActive, StatusOnLeave, StatusTerminated:
+		return true
+	}
+	return false
+}
+
+// Employee is a person employed in a workspace. The fields tagged
+// `audit:"redact"` are the personal data that Pseudonymise replaces; they are
+// kept out of the audit log and, see Redacted, out of event payloads.
 type Employee struct {
 	domain.BaseEntity
 	TenantID    uuid.UUID
 	WorkspaceID uuid.UUID
 
-	FirstName string
-	LastName  string
-	Email     string
-	Address   string
+	FirstName string `audit:"redact"`
+	LastName  string `audit:"redact"`
+	Email     string `audit:"redact"`
+	Address   string `audit:"redact"`
 	DocTypeID uuid.UUID
-	DocNumber string
+	DocNumber string `audit:"redact"`
+	Status    EmployeeStatus
 	//Not obligatory
-	BirthDate *time.Time
-	Gender    *EmployeeGender
-	Phone     *string
+	HireDate   *time.Time
+	BirthDate  *time.Time `audit:"redact"`
+	Gender     *EmployeeGender
+	Phone      *string `audit:"redact"`
+	Department *string
+	// BankAccount is where the employee's net pay is sent.
+	BankAccount *string `audit:"redact"`
+	// ErasedAt is when the personal data was pseudonymised; see Pseudonymise.
+	ErasedAt *time.Time
 }
 
 type CreateEmployeeParams struct {
@@ -55,21 +94,32 @@ type CreateEmployeeParams struct {
 	Address     string
 	DocTypeID   uuid.UUID
 	DocNumber   string
+	Status      *string // defaults to ACTIVE
+	HireDate    *time.Time
 	BirthDate   *time.Time
 	Gender      *string
 	Phone       *string
+	Department  *string
+	BankAccount *string
 }
 
 type UpdateEmployeeParams struct {
-	FirstName *string
-	LastName  *string
-	Email     *string
-	Address   *string
-	DocTypeID *uuid.UUID
-	DocNumber *string
-	BirthDate *time.Time
-	Gender    *string
-	Phone     *string
+	FirstName   *string
+	LastName    *string
+	Email       *string
+	Address     *string
+	DocTypeID   *uuid.UUID
+	DocNumber   *string
+	Status      *string
+	HireDate    *time.Time
+	BirthDate   *time.Time
+	Gender      *string
+	Phone       *string
+	Department  *string
+	BankAccount *string
+	// Version, when set, must equal the current version (e.g. from an
+	// If-Match header) or the update fails with a conflict.
+	Version *int64
 }
 
 func NewEmployee(params CreateEmployeeParams) (*Employee, error) {
@@ -90,21 +140,34 @@ func NewEmployee(params CreateEmployeeParams) (*Employee, error) {
 	if params.Phone != nil {
 		*params.Phone = strings.TrimSpace(*params.Phone)
 	}
+	if params.Department != nil {
+		*params.Department = strings.TrimSpace(*params.Department)
+	}
+	if params.BankAccount != nil {
+		*params.BankAccount = strings.TrimSpace(*params.BankAccount)
+	}
 
 	validator.ValidateFirstName(params.FirstName)
 	validator.ValidateLastName(params.LastName)
 	validator.ValidateEmail(params.Email)
+	validator.ValidateStatus(params.Status)
 	validator.ValidateBirthDate(params.BirthDate)
 	validator.ValidateDocTypeID(params.DocTypeID)
 	validator.ValidateDocNumber(params.DocNumber)
 	validator.ValidateGender(params.Gender)
 	validator.ValidatePhone(params.Phone)
+	validator.ValidateDepartment(params.Department)
+	validator.ValidateBankAccount(params.BankAccount)
 
 	var empGender *EmployeeGender
 	if params.Gender != nil {
 		gender := EmployeeGender(*params.Gender)
 		empGender = &gender
 	}
+	status := StatusActive
+	if params.Status != nil {
+		status = EmployeeStatus(*params.Status)
+	}
 	if validator.HasErrors() {
 		return nil, apperror.NewValidationError(employeeOrigin, validator.Errors())
 	}
@@ -118,21 +181,81 @@ func NewEmployee(params CreateEmployeeParams) (*Employee, error) {
 		Address:     params.Address,
 		DocTypeID:   params.DocTypeID,
 		DocNumber:   params.DocNumber,
+		Status:      status,
+		HireDate:    params.HireDate,
 		BirthDate:   params.BirthDate,
 		Gender:      empGender,
 		Phone:       params.Phone,
+		Department:  params.Department,
+		BankAccount: params.BankAccount,
 	}
 	emp.Initialize()
 
 	return emp, nil
 }
 
+// Pseudonymise replaces the employee's personal data with values derived
+// from its ID, for a data subject's erasure request. Email and DocNumber stay
+// unique within the tenant. The ID, workspace, status, dates of employment
+// and everything that refers to the employee (contracts, payroll results) are
+// kept, so payroll totals remain reportable for their retention period.
+func (e *Employee) Pseudonymise(at time.Time) {
+	pseudonym := "erased-" + e.ID.String()
+	e.FirstName = "Erased"
+	e.LastName = "Employee"
+	e.Email = pseudonym + "@erased.invalid"
+	e.Phone = nil
+	e.Address = ""
+	e.BirthDate = nil
+	e.DocNumber = pseudonym
+	e.BankAccount = nil
+	e.ErasedAt = &at
+	e.Touch()
+}
+
+// Redacted returns a copy of the employee without its personal data, for
+// event payloads, which are copied to the outbox and to webhook subscribers.
+// Subscribers that need the data read it through the API.
+func (e *Employee) Redacted() *Employee {
+	c := *e
+	c.FirstName, c.LastName, c.Email, c.Address, c.DocNumber = "", "", "", "", ""
+	c.BirthDate, c.Phone, c.BankAccount = nil, nil, nil
+	return &c
+}
+
+func (e *Employee) IsErased() bool {
+	return e.ErasedAt != nil
+}
+
+// Repository persists employees. Email and DocNumber are unique per tenant:
+// the service checks them before writing, and implementations backed by a
+// shared store must also enforce them (e.g. with unique indexes) and report a
+// violation from Create or Update as an apperror TypeDuplicate, so that
+// concurrent writers on other instances cannot both succeed.
+//
+// GetByID also returns soft-deleted employees so that they can be restored;
+// every other read, including the uniqueness checks, excludes them.
 type Repository interface {
 	Create(ctx context.Context, employee *Employee) error
 	ListByWorkspaceIDAndTenantID(ctx context.Context, workspaceID uuid.UUID, tenantID uuid.UUID) ([]*Employee, error)
+	// Search returns the employees matching filter, in the order and from
+	// the position it describes. Implementations must follow the semantics
+	// of SearchFilter.Matches and SearchFilter.Compare.
+	Search(ctx context.Context, filter SearchFilter) ([]*Employee, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*Employee, error)
+	// ListByIDs returns the employees with the given IDs, soft-deleted ones
+	// included, ordered by ID. IDs that do not exist are skipped.
+	ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*Employee, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
 	Update(ctx context.Context, employee *Employee) error
+	// Delete removes an employee permanently.
 	Delete(ctx context.Context, id uuid.UUID) error
+	// PurgeDeleted permanently removes the employees soft-deleted before the
+	// given time and returns them. Only their BaseEntity, TenantID and
+	// WorkspaceID need to be set.
+	PurgeDeleted(ctx context.Context, before time.Time) ([]*Employee, error)
 	ExistsByTenantIDAndDocNumber(ctx context.Context, tenantID uuid.UUID, docNumber string) (bool, error)
 	ExistsByTenantIDAndEmail(ctx context.Context, tenantID uuid.UUID, email string) (bool, error)
+	CountByTenantID(ctx context.Context, tenantID uuid.UUID) (int, error)
 }
@@ -0,0 +1,362 @@
+package employee
+
+import (
+	"context"
+	"payroll/internal/platform/fieldcrypt"
+	"payroll/internal/platform/uow"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Blind index purposes, which keep the indexes of different fields apart.
+const (
+	emailIndexPurpose     = "employee.email"
+	docNumberIndexPurpose = "employee.doc_number"
+)
+
+// SealedEmployee is an employee as EncryptedRepository hands it to storage.
+// The sensitive fields are cleared on Employee and held encrypted in the
+// fields of the same name, "" when unset.
+type SealedEmployee struct {
+	Employee    Employee
+	Email       string
+	Address     string
+	DocNumber   string
+	BirthDate   string
+	BankAccount string
+	// KeyID names the key-encryption key the fields are wrapped with.
+	KeyID string
+	// EmailIndex and DocNumberIndex are blind indexes of the lower-cased
+	// email and the document number.
+	EmailIndex     string
+	DocNumberIndex string
+}
+
+// SealedRepository stores sealed employees for EncryptedRepository. It has
+// the semantics of Repository, except that email and document number are
+// looked up by blind index: ExistsByTenantIDAndEmail and
+// ExistsByTenantIDAndDocNumber receive indexes, SearchFilter.Email and
+// SearchFilter.DocNumber hold indexes matching EmailIndex and DocNumberIndex
+// exactly, and the per-tenant uniqueness is enforced on the indexes. An
+// in-memory implementation can apply SearchFilter to a copy of Employee with
+// Email and DocNumber set to the indexes.
+type SealedRepository interface {
+	Create(ctx context.Context, employee *SealedEmployee) error
+	ListByWorkspaceIDAndTenantID(ctx context.Context, workspaceID uuid.UUID, tenantID uuid.UUID) ([]*SealedEmployee, error)
+	Search(ctx context.Context, filter SearchFilter) ([]*SealedEmployee, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*SealedEmployee, error)
+	ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*SealedEmployee, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, employee *SealedEmployee) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	PurgeDeleted(ctx context.Context, before time.Time) ([]*SealedEmployee, error)
+	ExistsByTenantIDAndDocNumber(ctx context.Context, tenantID uuid.UUID, docNumberIndex string) (bool, error)
+	ExistsByTenantIDAndEmail(ctx context.Context, tenantID uuid.UUID, emailIndex string) (bool, error)
+	CountByTenantID(ctx context.Context, tenantID uuid.UUID) (int, error)
+	// ListNotSealedWith returns up to limit employees, soft-deleted ones
+	// included, whose KeyID is not keyID.
+	ListNotSealedWith(ctx context.Context, keyID string, limit int) ([]*SealedEmployee, error)
+	// UpdateSealing stores the sealed fields and KeyID of employee, unless
+	// the stored employee's version is no longer employee.Employee.Version,
+	// and reports whether it did. Nothing else is changed.
+	UpdateSealing(ctx context.Context, employee *SealedEmployee) (bool, error)
+}
+
+// EncryptedRepository is a Repository that encrypts Email, Address,
+// DocNumber, BirthDate and BankAccount before they reach the store, and keeps
+// blind indexes so that email and document number lookups still work.
+type EncryptedRepository struct {
+	store SealedRepository
+	crypt *fieldcrypt.Encrypter
+}
+
+var _ Repository = (*EncryptedRepository)(nil)
+
+func NewEncryptedRepository(store SealedRepository, e *fieldcrypt.Encrypter) *EncryptedRepository {
+	return &EncryptedRepository{store: store, crypt: e}
+}
+
+func (r *EncryptedRepository) Create(ctx context.Context, employee *Employee) error {
+	sealed, err := r.seal(ctx, employee)
+	if err != nil {
+		return err
+	}
+	return r.store.Create(ctx, sealed)
+}
+
+func (r *EncryptedRepository) ListByWorkspaceIDAndTenantID(ctx context.Context, workspaceID uuid.UUID, tenantID uuid.UUID) ([]*Employee, error) {
+	sealed, err := r.store.ListByWorkspaceIDAndTenantID(ctx, workspaceID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, sealed)
+}
+
+func (r *EncryptedRepository) Search(ctx context.Context, filter SearchFilter) ([]*Employee, error) {
+	var err error
+	if filter.Email != "" {
+		if filter.Email, err = r.emailIndex(ctx, filter.Email); err != nil {
+			return nil, err
+		}
+	}
+	if filter.DocNumber != "" {
+		if filter.DocNumber, err = r.crypt.BlindIndex(ctx, docNumberIndexPurpose, filter.DocNumber); err != nil {
+			return nil, err
+		}
+	}
+	sealed, err := r.store.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, sealed)
+}
+
+func (r *EncryptedRepository) GetByID(ctx context.Context, id uuid.UUID) (*Employee, error) {
+	sealed, err := r.store.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.open(ctx, sealed)
+}
+
+func (r *EncryptedRepository) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*Employee, error) {
+	sealed, err := r.store.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, sealed)
+}
+
+func (r *EncryptedRepository) Update(ctx context.Context, employee *Employee) error {
+	sealed, err := r.seal(ctx, employee)
+	if err != nil {
+		return err
+	}
+	if err := r.store.Update(ctx, sealed); err != nil {
+		return err
+	}
+	// The store increments the version of the copy it was given.
+	read := employee.Version
+	employee.Version = sealed.Employee.Version
+	uow.OnRollback(ctx, func() { employee.Version = read })
+	return nil
+}
+
+func (r *EncryptedRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.store.Delete(ctx, id)
+}
+
+// PurgeDeleted returns the purged employees without opening their sealed
+// fields, which are left empty.
+func (r *EncryptedRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]*Employee, error) {
+	sealed, err := r.store.PurgeDeleted(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]*Employee, 0, len(sealed))
+	for _, s := range sealed {
+		purged = append(purged, &s.Employee)
+	}
+	return purged, nil
+}
+
+func (r *EncryptedRepository) ExistsByTenantIDAndDocNumber(ctx context.Context, tenantID uuid.UUID, docNumber string) (bool, error) {
+	index, err := r.crypt.BlindIndex(ctx, docNumberIndexPurpose, docNumber)
+	if err != nil {
+		return false, err
+	}
+	return r.store.ExistsByTenantIDAndDocNumber(ctx, tenantID, index)
+}
+
+func (r *EncryptedRepository) ExistsByTenantIDAndEmail(ctx context.Context, tenantID uuid.UUID, email string) (bool, error) {
+	index, err := r.emailIndex(ctx, email)
+	if err != nil {
+		return false, err
+	}
+	return r.store.ExistsByTenantIDAndEmail(ctx, tenantID, index)
+}
+
+func (r *EncryptedRepository) CountByTenantID(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	return r.store.CountByTenantID(ctx, tenantID)
+}
+
+// RotateKeys rewraps the fields of every employee sealed with an older key
+// with the provider's current key, batchSize employees at a time, and
+// returns how many were rewrapped. Only the wrapped data keys change. An
+// employee updated while its batch is processed is skipped: the update
+// sealed it with the current key already. Run it after making a new key
+// current, and retire the old key once it and the RotateKeys of
+// selfservice.EncryptedRepository, which shares the keys, have returned.
+func (r *EncryptedRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	rotated := 0
+	for {
+		keyID, err := r.crypt.CurrentKeyID(ctx)
+		if err != nil {
+			return rotated, err
+		}
+		batch, err := r.store.ListNotSealedWith(ctx, keyID, batchSize)
+		if err != nil || len(batch) == 0 {
+			return rotated, err
+		}
+		progress := false
+		for _, sealed := range batch {
+			if err := r.rewrap(ctx, sealed, keyID); err != nil {
+				return rotated, err
+			}
+			ok, err := r.store.UpdateSealing(ctx, sealed)
+			if err != nil {
+				return rotated, err
+			}
+			if ok {
+				rotated++
+				progress = true
+			}
+		}
+		if !progress {
+			return rotated, nil
+		}
+	}
+}
+
+func (r *EncryptedRepository) rewrap(ctx context.Context, sealed *SealedEmployee, keyID string) error {
+	for _, field := range []*string{&sealed.Email, &sealed.Address, &sealed.DocNumber, &sealed.BirthDate, &sealed.BankAccount} {
+		if *field == "" {
+			continue
+		}
+		rewrapped, err := r.crypt.Rewrap(ctx, *field)
+		if err != nil {
+			return err
+		}
+		*field = rewrapped
+	}
+	sealed.KeyID = keyID
+	return nil
+}
+
+func (r *EncryptedRepository) emailIndex(ctx context.Context, email string) (string, error) {
+	return r.crypt.BlindIndex(ctx, emailIndexPurpose, strings.ToLower(email))
+}
+
+func (r *EncryptedRepository) seal(ctx context.Context, employee *Employee) (*SealedEmployee, error) {
+	sealed := &SealedEmployee{Employee: *employee}
+	sealed.Employee.Email = ""
+	sealed.Employee.Address = ""
+	sealed.Employee.DocNumber = ""
+	sealed.Employee.BirthDate = nil
+	sealed.Employee.BankAccount = nil
+
+	var birthDate string
+	if employee.BirthDate != nil {
+		b, err := employee.BirthDate.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		birthDate = string(b)
+	}
+
+	var err error
+	if sealed.KeyID, err = r.crypt.CurrentKeyID(ctx); err != nil {
+		return nil, err
+	}
+	fields := []struct {
+		sealed *string
+		name   string
+		value  string
+	}{
+		{&sealed.Email, "Email", employee.Email},
+		{&sealed.Address, "Address", employee.Address},
+		{&sealed.DocNumber, "DocNumber", employee.DocNumber},
+		{&sealed.BirthDate, "BirthDate", birthDate},
+		{&sealed.BankAccount, "BankAccount", valueOf(employee.BankAccount)},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if *f.sealed, err = r.crypt.Seal(ctx, []byte(f.value), additionalData(employee.ID, f.name)); err != nil {
+			return nil, err
+		}
+	}
+
+	if sealed.EmailIndex, err = r.emailIndex(ctx, employee.Email); err != nil {
+		return nil, err
+	}
+	if sealed.DocNumberIndex, err = r.crypt.BlindIndex(ctx, docNumberIndexPurpose, employee.DocNumber); err != nil {
+		return nil, err
+	}
+	return sealed, nil
+}
+
+func (r *EncryptedRepository) open(ctx context.Context, sealed *SealedEmployee) (*Employee, error) {
+	employee := sealed.Employee
+	id := employee.ID
+
+	var err error
+	if employee.Email, err = r.openField(ctx, sealed.Email, id, "Email"); err != nil {
+		return nil, err
+	}
+	if employee.Address, err = r.openField(ctx, sealed.Address, id, "Address"); err != nil {
+		return nil, err
+	}
+	if employee.DocNumber, err = r.openField(ctx, sealed.DocNumber, id, "DocNumber"); err != nil {
+		return nil, err
+	}
+	birthDate, err := r.openField(ctx, sealed.BirthDate, id, "BirthDate")
+	if err != nil {
+		return nil, err
+	}
+	if birthDate != "" {
+		var t time.Time
+		if err := t.UnmarshalText([]byte(birthDate)); err != nil {
+			return nil, err
+		}
+		employee.BirthDate = &t
+	}
+	bankAccount, err := r.openField(ctx, sealed.BankAccount, id, "BankAccount")
+	if err != nil {
+		return nil, err
+	}
+	if bankAccount != "" {
+		employee.BankAccount = &bankAccount
+	}
+	return &employee, nil
+}
+
+func (r *EncryptedRepository) openField(ctx context.Context, sealed string, id uuid.UUID, name string) (string, error) {
+	if sealed == "" {
+		return "", nil
+	}
+	plaintext, err := r.crypt.Open(ctx, sealed, additionalData(id, name))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func (r *EncryptedRepository) openAll(ctx context.Context, sealed []*SealedEmployee) ([]*Employee, error) {
+	employees := make([]*Employee, 0, len(sealed))
+	for _, s := range sealed {
+		employee, err := r.open(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		employees = append(employees, employee)
+	}
+	return employees, nil
+}
+
+// additionalData binds a sealed value to its employee and field, so that it
+// cannot be copied to another.
+func additionalData(id uuid.UUID, field string) []byte {
+	return []byte(id.String() + "/" + field)
+}
+
+func valueOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
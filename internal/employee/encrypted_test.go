@@ -0,0 +1,220 @@
+package employee
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/platform/fieldcrypt"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/uow"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySealedRepo keeps sealed employees and, for lookups, a
+// MemoryRepository of copies whose Email and DocNumber are the indexes.
+type memorySealedRepo struct {
+	SealedRepository
+	index  *MemoryRepository
+	sealed map[uuid.UUID]SealedEmployee
+}
+
+func newMemorySealedRepo() *memorySealedRepo {
+	return &memorySealedRepo{index: NewMemoryRepository(), sealed: make(map[uuid.UUID]SealedEmployee)}
+}
+
+func indexView(s *SealedEmployee) *Employee {
+	view := s.Employee
+	view.Email = s.EmailIndex
+	view.DocNumber = s.DocNumberIndex
+	return &view
+}
+
+func (r *memorySealedRepo) Create(ctx context.Context, s *SealedEmployee) error {
+	if err := r.index.Create(ctx, indexView(s)); err != nil {
+		return err
+	}
+	r.sealed[s.Employee.ID] = *s
+	return nil
+}
+
+func (r *memorySealedRepo) ListByWorkspaceIDAndTenantID(ctx context.Context, workspaceID, tenantID uuid.UUID) ([]*SealedEmployee, error) {
+	views, err := r.index.ListByWorkspaceIDAndTenantID(ctx, workspaceID, tenantID)
+	return r.lookup(views), err
+}
+
+func (r *memorySealedRepo) GetByID(_ context.Context, id uuid.UUID) (*SealedEmployee, error) {
+	s, ok := r.sealed[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "employee not found")
+	}
+	return &s, nil
+}
+
+func (r *memorySealedRepo) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*SealedEmployee, error) {
+	views, err := r.index.ListByIDs(ctx, ids)
+	return r.lookup(views), err
+}
+
+func (r *memorySealedRepo) Update(ctx context.Context, s *SealedEmployee) error {
+	view := indexView(s)
+	if err := r.index.Update(ctx, view); err != nil {
+		return err
+	}
+	s.Employee.Version = view.Version
+	r.sealed[s.Employee.ID] = *s
+	return nil
+}
+
+func (r *memorySealedRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(r.sealed, id)
+	return r.index.Delete(ctx, id)
+}
+
+func (r *memorySealedRepo) PurgeDeleted(ctx context.Context, before time.Time) ([]*SealedEmployee, error) {
+	views, err := r.index.PurgeDeleted(ctx, before)
+	purged := r.lookup(views)
+	for _, v := range views {
+		delete(r.sealed, v.ID)
+	}
+	return purged, err
+}
+
+func (r *memorySealedRepo) Search(ctx context.Context, filter SearchFilter) ([]*SealedEmployee, error) {
+	views, err := r.index.Search(ctx, filter)
+	return r.lookup(views), err
+}
+
+// lookup returns the sealed employees of the index views.
+func (r *memorySealedRepo) lookup(views []*Employee) []*SealedEmployee {
+	list := make([]*SealedEmployee, 0, len(views))
+	for _, v := range views {
+		s := r.sealed[v.ID]
+		list = append(list, &s)
+	}
+	return list
+}
+
+func (r *memorySealedRepo) ExistsByTenantIDAndDocNumber(ctx context.Context, tenantID uuid.UUID, index string) (bool, error) {
+	return r.index.ExistsByTenantIDAndDocNumber(ctx, tenantID, index)
+}
+
+func (r *memorySealedRepo) ExistsByTenantIDAndEmail(ctx context.Context, tenantID uuid.UUID, index string) (bool, error) {
+	return r.index.ExistsByTenantIDAndEmail(ctx, tenantID, index)
+}
+
+func (r *memorySealedRepo) CountByTenantID(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	return r.index.CountByTenantID(ctx, tenantID)
+}
+
+func (r *memorySealedRepo) ListNotSealedWith(_ context.Context, keyID string, limit int) ([]*SealedEmployee, error) {
+	var list []*SealedEmployee
+	for _, s := range r.sealed {
+		if s.KeyID != keyID && len(list) < limit {
+			list = append(list, &s)
+		}
+	}
+	return list, nil
+}
+
+func (r *memorySealedRepo) UpdateSealing(_ context.Context, s *SealedEmployee) (bool, error) {
+	stored := r.sealed[s.Employee.ID]
+	if stored.Employee.Version != s.Employee.Version {
+		return false, nil
+	}
+	stored.Email, stored.Address, stored.DocNumber, stored.BirthDate, stored.BankAccount = s.Email, s.Address, s.DocNumber, s.BirthDate, s.BankAccount
+	stored.KeyID = s.KeyID
+	r.sealed[s.Employee.ID] = stored
+	return true, nil
+}
+
+func testKeys(t *testing.T, current string, ids ...string) *fieldcrypt.Encrypter {
+	var keys []fieldcrypt.Key
+	for _, id := range ids {
+		keys = append(keys, fieldcrypt.Key{ID: id, Secret: bytes.Repeat([]byte(id[len(id)-1:]), fieldcrypt.KeySize)})
+	}
+	p, err := fieldcrypt.NewLocalKeyProvider(current, keys, bytes.Repeat([]byte{9}, fieldcrypt.KeySize))
+	require.NoError(t, err)
+	return fieldcrypt.New(p)
+}
+
+func TestEncryptedRepository_StoresCiphertextAndLooksUpByBlindIndex(t *testing.T) {
+	f := newServiceFixture()
+	store := newMemorySealedRepo()
+	repo := NewEncryptedRepository(store, testKeys(t, "k1", "k1"))
+	f.service = NewService(repo, &stubWorkspaceRepo{ws: f.workspace}, f.docTypes, f.docNumbers, f.tenants, uow.NewMemory(), f.audits, f.events, logger.Nop{})
+
+	params := f.createParams("Ada@Example.com", "1001")
+	birthDate := time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC)
+	account := "DE89 3704 0044"
+	params.BirthDate, params.BankAccount = &birthDate, &account
+	ada, err := f.service.Create(f.ctx, params)
+	require.NoError(t, err)
+
+	stored := store.sealed[ada.ID]
+	assert.Empty(t, stored.Employee.Email)
+	assert.Nil(t, stored.Employee.BirthDate)
+	assert.Nil(t, stored.Employee.BankAccount)
+	assert.Equal(t, "Ada", stored.Employee.FirstName, "fields that are not designated stay in the clear")
+	for _, sealed := range []string{stored.Email, stored.Address, stored.DocNumber, stored.BirthDate, stored.BankAccount} {
+		assert.NotEmpty(t, sealed)
+		assert.NotContains(t, sealed, "1001")
+	}
+
+	_, err = f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, apperror.TypeDuplicate, domainErr.Type)
+	assert.Contains(t, domainErr.Details, "Email", "email lookups ignore case")
+	assert.Contains(t, domainErr.Details, "DocNumber")
+
+	got, err := f.service.GetByID(f.ctx, ada.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ada.Email, got.Email)
+	assert.True(t, birthDate.Equal(*got.BirthDate))
+	assert.Equal(t, account, *got.BankAccount)
+
+	page, err := f.service.Search(f.ctx, SearchParams{WorkspaceID: f.workspace.ID, DocNumber: "1001"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, ada.ID, page.Items[0].ID)
+
+	address := "1 Main St"
+	updated, err := f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{Address: &address})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+	assert.Equal(t, int64(2), store.sealed[ada.ID].Employee.Version)
+}
+
+func TestEncryptedRepository_RotateKeysRewrapsOldValues(t *testing.T) {
+	ctx := context.Background()
+	store := newMemorySealedRepo()
+	emp := &Employee{TenantID: uuid.New(), FirstName: "Ada", Email: "ada@example.com", DocNumber: "1001", Address: "12 St James's Square"}
+	emp.Initialize()
+	require.NoError(t, NewEncryptedRepository(store, testKeys(t, "k1", "k1")).Create(ctx, emp))
+	before := store.sealed[emp.ID]
+
+	repo := NewEncryptedRepository(store, testKeys(t, "k2", "k1", "k2"))
+	n, err := repo.RotateKeys(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	after := store.sealed[emp.ID]
+	assert.Equal(t, "k2", after.KeyID)
+	assert.Equal(t, before.EmailIndex, after.EmailIndex)
+	assert.NotEqual(t, before.Email, after.Email)
+
+	retired := NewEncryptedRepository(store, testKeys(t, "k2", "k2"))
+	got, err := retired.GetByID(ctx, emp.ID)
+	require.NoError(t, err, "rotated values open without the retired key")
+	assert.Equal(t, "12 St James's Square", got.Address)
+
+	n, err = repo.RotateKeys(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
@@ -0,0 +1,460 @@
+package employee
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/doctype"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/tenant"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	importOrigin     = "EmployeeImport"
+	importDateLayout = "2006-01-02"
+	// rowErrorKey reports problems that do not belong to a single field.
+	rowErrorKey = "Row"
+)
+
+type ImportMode string
+
+const (
+	// ImportAllOrNothing commits only when every row is valid.
+	ImportAllOrNothing ImportMode = "ALL_OR_NOTHING"
+	// ImportValidRowsOnly commits the valid rows and reports the rest.
+	ImportValidRowsOnly ImportMode = "VALID_ROWS_ONLY"
+)
+
+func (m ImportMode) IsValid() bool {
+	switch m {
+	case ImportAllOrNothing, ImportValidRowsOnly:
+		return true
+	}
+	return false
+}
+
+// Fields that can be mapped to CSV columns. DocTypeCode is resolved against
+// the document types of the workspace's country and can be used instead of
+// DocTypeID.
+var importFields = []string{
+	"FirstName", "LastName", "Email", "Address", "DocTypeID", "DocTypeCode",
+	"DocNumber", "Status", "HireDate", "BirthDate", "Gender", "Phone", "Department",
+}
+
+type ImportParams struct {
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	// Mapping maps field names to CSV column headers. Fields that are not
+	// mapped are read from a column named after the field, if present.
+	Mapping map[string]string
+	Mode    ImportMode
+	// DryRun validates every row without saving anything.
+	DryRun bool
+}
+
+// RowError holds the problems found in one CSV row, keyed by the same field
+// names the Validator uses. Row is the line number in the file, the header
+// being line 1.
+type RowError struct {
+	Row    int
+	Errors map[string]string
+}
+
+type ImportReport struct {
+	TotalRows int
+	ValidRows int
+	Imported  int
+	Committed bool
+	Errors    []RowError
+}
+
+type importRow struct {
+	line     int
+	employee *Employee
+}
+
+// Import creates employees in bulk from CSV. Every row goes through the same
+// checks as Create plus email and document number uniqueness, both against
+// the tenant's existing employees and within the file.
+func (s *Service) Import(ctx context.Context, r io.Reader, params ImportParams) (*ImportReport, error) {
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	params.TenantID = tenantID
+
+	validator := NewValidator()
+	if params.WorkspaceID == uuid.Nil {
+		validator.AddError("WorkspaceID", "is empty")
+	}
+	if !params.Mode.IsValid() {
+		validator.AddError("Mode", "is invalid")
+	}
+	for field := range params.Mapping {
+		if !isImportField(field) {
+			validator.AddError("Mapping", fmt.Sprintf("unknown field %q", field))
+		}
+	}
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(importOrigin, validator.Errors())
+	}
+	if err := auth.Authorize(ctx, auth.PermEmployeeWrite, auth.Resource{WorkspaceID: params.WorkspaceID}, importOrigin); err != nil {
+		return nil, err
+	}
+
+	ws, err := s.workspaceRepo.Get(ctx, params.WorkspaceID)
+	if err != nil || ws.IsDeleted() || ws.TenantID != params.TenantID {
+		return nil, apperror.New(apperror.TypeInvalid, importOrigin, "Invalid WorkspaceID")
+	}
+
+	unlock := s.tenantLocks.Lock(params.TenantID.String())
+	defer unlock()
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, apperror.New(apperror.TypeInvalid, importOrigin, "Missing CSV header")
+	}
+	columns, err := resolveColumns(header, params.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{}
+	checker := newRowChecker(s, ws.CountryID, params.TenantID)
+	var valid []importRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		report.TotalRows++
+		if err != nil {
+			report.Errors = append(report.Errors, RowError{Row: line, Errors: map[string]string{rowErrorKey: err.Error()}})
+			continue
+		}
+
+		emp, rowErrs, err := checker.check(ctx, line, func(field string) string {
+			if idx, ok := columns[field]; ok && idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}, params)
+		if err != nil {
+			return nil, err
+		}
+		if len(rowErrs) > 0 {
+			report.Errors = append(report.Errors, RowError{Row: line, Errors: rowErrs})
+			continue
+		}
+		valid = append(valid, importRow{line: line, employee: emp})
+	}
+	report.ValidRows = len(valid)
+
+	if params.DryRun || len(valid) == 0 {
+		return report, nil
+	}
+	if params.Mode == ImportAllOrNothing && len(report.Errors) > 0 {
+		s.logger.Warn("Employee import rejected", "workspace_id", params.WorkspaceID, "invalid_rows", len(report.Errors))
+		return report, nil
+	}
+	if err := s.checkLimit(ctx, params.TenantID, len(valid)); err != nil {
+		return nil, err
+	}
+
+	if err := s.commitImport(ctx, valid, params.Mode, report); err != nil {
+		return nil, err
+	}
+	report.Committed = report.Imported > 0
+
+	s.logger.Info("Employee import completed", "workspace_id", params.WorkspaceID, "imported", report.Imported)
+	return report, nil
+}
+
+// commitImport saves the valid rows. In all-or-nothing mode they are saved
+// in one unit of work, so a failed save leaves nothing behind; otherwise each
+// row is saved in its own.
+func (s *Service) commitImport(ctx context.Context, rows []importRow, mode ImportMode, report *ImportReport) error {
+	if mode == ImportValidRowsOnly {
+		for _, row := range rows {
+			if err := s.uow.Do(ctx, func(ctx context.Context) error { return s.createImported(ctx, row) }); err != nil {
+				report.Errors = append(report.Errors, RowError{Row: row.line, Errors: map[string]string{rowErrorKey: err.Error()}})
+				continue
+			}
+			report.Imported++
+		}
+		return nil
+	}
+
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		for _, row := range rows {
+			if err := s.createImported(ctx, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.Imported = len(rows)
+	return nil
+}
+
+func (s *Service) createImported(ctx context.Context, row importRow) error {
+	if err := s.employeeRepo.Create(ctx, row.employee); err != nil {
+		s.logger.Error(err, "Failed to save imported employee", "row", row.line)
+		return err
+	}
+	return s.record(ctx, audit.ActionCreate, nil, row.employee)
+}
+
+func isImportField(field string) bool {
+	for _, f := range importFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveColumns returns the column index of every field present in the file.
+func resolveColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.TrimSpace(name)] = i
+	}
+
+	columns := make(map[string]int)
+	for _, field := range importFields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+		if idx, ok := positions[column]; ok {
+			columns[field] = idx
+		} else if mapped {
+			return nil, apperror.NewValidationError(importOrigin, map[string]string{
+				"Mapping": fmt.Sprintf("column %q for field %s not found in header", column, field),
+			})
+		}
+	}
+	return columns, nil
+}
+
+// rowChecker validates rows against the repositories, caching lookups and
+// remembering the emails and document numbers already seen in the file.
+type rowChecker struct {
+	service    *Service
+	countryID  uuid.UUID
+	tenantID   uuid.UUID
+	validIDs   map[uuid.UUID]bool
+	docTypes   map[uuid.UUID]*doctype.DocType
+	docCodes   map[string]*doctype.DocType
+	emails     map[string]int
+	docNumbers map[string]int
+}
+
+func newRowChecker(s *Service, countryID, tenantID uuid.UUID) *rowChecker {
+	return &rowChecker{
+		service:    s,
+		countryID:  countryID,
+		tenantID:   tenantID,
+		validIDs:   make(map[uuid.UUID]bool),
+		docTypes:   make(map[uuid.UUID]*doctype.DocType),
+		docCodes:   make(map[string]*doctype.DocType),
+		emails:     make(map[string]int),
+		docNumbers: make(map[string]int),
+	}
+}
+
+// check builds and validates the employee of one row. It only returns an
+// error when a repository fails; row problems are returned as field errors.
+func (c *rowChecker) check(ctx context.Context, line int, get func(string) string, params ImportParams) (*Employee, map[string]string, error) {
+	errs := make(map[string]string)
+	optional := func(field string) *string {
+		if value := get(field); value != "" {
+			return &value
+		}
+		return nil
+	}
+
+	create := CreateEmployeeParams{
+		TenantID:    params.TenantID,
+		WorkspaceID: params.WorkspaceID,
+		FirstName:   get("FirstName"),
+		LastName:    get("LastName"),
+		Email:       get("Email"),
+		Address:     get("Address"),
+		DocNumber:   get("DocNumber"),
+		Status:      optional("Status"),
+		Gender:      optional("Gender"),
+		Phone:       optional("Phone"),
+		Department:  optional("Department"),
+	}
+
+	if raw := get("HireDate"); raw != "" {
+		hireDate, err := time.Parse(importDateLayout, raw)
+		if err != nil {
+			errs["HireDate"] = "must be formatted as YYYY-MM-DD"
+		} else {
+			create.HireDate = &hireDate
+		}
+	}
+	if raw := get("BirthDate"); raw != "" {
+		birthDate, err := time.Parse(importDateLayout, raw)
+		if err != nil {
+			errs["BirthDate"] = "must be formatted as YYYY-MM-DD"
+		} else {
+			create.BirthDate = &birthDate
+		}
+	}
+
+	if err := c.resolveDocType(ctx, get, &create, errs); err != nil {
+		return nil, nil, err
+	}
+
+	emp, err := NewEmployee(create)
+	if err != nil {
+		var domainErr *apperror.DomainError
+		if !errors.As(err, &domainErr) {
+			return nil, nil, err
+		}
+		for field, msg := range domainErr.Details {
+			if _, exists := errs[field]; exists {
+				continue
+			}
+			if _, unresolved := errs["DocTypeCode"]; unresolved && field == "DocTypeID" {
+				continue
+			}
+			errs[field] = msg
+		}
+		return nil, errs, nil
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	dt, err := c.docType(ctx, emp.DocTypeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	normalized, err := c.service.docNumbers.NormalizeNumber(ctx, dt, emp.DocNumber)
+	if err != nil {
+		var domainErr *apperror.DomainError
+		if errors.As(err, &domainErr) && domainErr.Type == apperror.TypeInvalid {
+			return nil, domainErr.Details, nil
+		}
+		return nil, nil, err
+	}
+	emp.DocNumber = normalized
+
+	if err := c.checkUniqueness(ctx, emp, errs); err != nil {
+		return nil, nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	c.emails[normalizeEmail(emp.Email)] = line
+	c.docNumbers[emp.DocNumber] = line
+	return emp, nil, nil
+}
+
+func (c *rowChecker) docType(ctx context.Context, id uuid.UUID) (*doctype.DocType, error) {
+	if dt, ok := c.docTypes[id]; ok {
+		return dt, nil
+	}
+	dt, err := c.service.docTypeRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.docTypes[id] = dt
+	return dt, nil
+}
+
+func (c *rowChecker) resolveDocType(ctx context.Context, get func(string) string, create *CreateEmployeeParams, errs map[string]string) error {
+	if raw := get("DocTypeID"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			errs["DocTypeID"] = "is not a valid UUID"
+			return nil
+		}
+		valid, cached := c.validIDs[id]
+		if !cached {
+			valid, err = c.service.docTypeRepo.IsValidForCountry(ctx, id, c.countryID)
+			if err != nil {
+				return err
+			}
+			c.validIDs[id] = valid
+		}
+		if !valid {
+			errs["DocTypeID"] = "is not valid for the workspace's country"
+		}
+		create.DocTypeID = id
+		return nil
+	}
+
+	code := get("DocTypeCode")
+	if code == "" {
+		return nil
+	}
+	dt, cached := c.docCodes[code]
+	if !cached {
+		var err error
+		dt, err = c.service.docTypeRepo.GetByCountryIDAndCode(ctx, c.countryID, code)
+		if err != nil && !apperror.IsType(err, apperror.TypeNotFound) {
+			return err
+		}
+		c.docCodes[code] = dt
+	}
+	if dt == nil || !dt.Active {
+		errs["DocTypeCode"] = "is not a document type of the workspace's country"
+		return nil
+	}
+	create.DocTypeID = dt.ID
+	return nil
+}
+
+// normalizeEmail returns the form emails are compared in, both within the
+// file and against the repository.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (c *rowChecker) checkUniqueness(ctx context.Context, emp *Employee, errs map[string]string) error {
+	email := normalizeEmail(emp.Email)
+	if line, ok := c.emails[email]; ok {
+		errs["Email"] = fmt.Sprintf("is duplicated in row %d", line)
+	} else {
+		exists, err := c.service.employeeRepo.ExistsByTenantIDAndEmail(ctx, c.tenantID, email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			errs["Email"] = "already exists"
+		}
+	}
+
+	if line, ok := c.docNumbers[emp.DocNumber]; ok {
+		errs["DocNumber"] = fmt.Sprintf("is duplicated in row %d", line)
+	} else {
+		exists, err := c.service.employeeRepo.ExistsByTenantIDAndDocNumber(ctx, c.tenantID, emp.DocNumber)
+		if err != nil {
+			return err
+		}
+		if exists {
+			errs["DocNumber"] = "already exists"
+		}
+	}
+	return nil
+}
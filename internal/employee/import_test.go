@@ -0,0 +1,130 @@
+package employee
+
+import (
+	"strings"
+	"testing"
+
+	"payroll/internal/apperror"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const importCSV = `Nombre,Apellido,Correo,Direccion,Tipo,Documento,Nacimiento
+Ada,Lovelace,ada@example.com,London,NID,1001,1815-12-10
+Alan,Turing,alan@example.com,Wilmslow,NID,1002,1912-06-23
+Grace,Hopper,ada@example.com,Arlington,NID,1003,1906-12-09
+,Nameless,bad-email,Nowhere,PASSPORT,1004,10/10/1990
+`
+
+var importMapping = map[string]string{
+	"FirstName":   "Nombre",
+	"LastName":    "Apellido",
+	"Email":       "Correo",
+	"Address":     "Direccion",
+	"DocTypeCode": "Tipo",
+	"DocNumber":   "Documento",
+	"BirthDate":   "Nacimiento",
+}
+
+func (f *serviceFixture) importParams(mode ImportMode, dryRun bool) ImportParams {
+	return ImportParams{
+		TenantID:    f.workspace.TenantID,
+		WorkspaceID: f.workspace.ID,
+		Mapping:     importMapping,
+		Mode:        mode,
+		DryRun:      dryRun,
+	}
+}
+
+func TestImport_ReportsRowErrorsByField(t *testing.T) {
+	f := newServiceFixture()
+
+	report, err := f.service.Import(f.ctx, strings.NewReader(importCSV), f.importParams(ImportValidRowsOnly, true))
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.TotalRows)
+	assert.Equal(t, 2, report.ValidRows)
+	assert.False(t, report.Committed)
+	require.Len(t, report.Errors, 2)
+
+	assert.Equal(t, 4, report.Errors[0].Row)
+	assert.Equal(t, "is duplicated in row 2", report.Errors[0].Errors["Email"])
+
+	assert.Equal(t, 5, report.Errors[1].Row)
+	for _, field := range []string{"FirstName", "Email", "DocTypeCode", "BirthDate"} {
+		assert.Contains(t, report.Errors[1].Errors, field)
+	}
+	assert.NotContains(t, report.Errors[1].Errors, "DocTypeID")
+	assert.Empty(t, f.employees.employees)
+}
+
+func TestImport_ValidRowsOnlyCommitsValidRows(t *testing.T) {
+	f := newServiceFixture()
+
+	report, err := f.service.Import(f.ctx, strings.NewReader(importCSV), f.importParams(ImportValidRowsOnly, false))
+	require.NoError(t, err)
+
+	assert.True(t, report.Committed)
+	assert.Equal(t, 2, report.Imported)
+	assert.Len(t, f.employees.employees, 2)
+}
+
+func TestImport_RejectedOverEmployeeLimit(t *testing.T) {
+	f := newServiceFixture()
+	f.tenants.maxEmployees = 1
+
+	_, err := f.service.Import(f.ctx, strings.NewReader(importCSV), f.importParams(ImportValidRowsOnly, false))
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	assert.Empty(t, f.employees.employees)
+}
+
+func TestImport_AllOrNothingCommitsNothingOnErrors(t *testing.T) {
+	f := newServiceFixture()
+
+	report, err := f.service.Import(f.ctx, strings.NewReader(importCSV), f.importParams(ImportAllOrNothing, false))
+	require.NoError(t, err)
+
+	assert.False(t, report.Committed)
+	assert.Equal(t, 0, report.Imported)
+	assert.Empty(t, f.employees.employees)
+}
+
+func TestImport_ChecksExistingEmployees(t *testing.T) {
+	f := newServiceFixture()
+	_, err := f.service.Create(f.ctx, f.createParams("alan@example.com", "9999"))
+	require.NoError(t, err)
+
+	report, err := f.service.Import(f.ctx, strings.NewReader(importCSV), f.importParams(ImportValidRowsOnly, true))
+	require.NoError(t, err)
+
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, 3, report.Errors[0].Row)
+	assert.Equal(t, "already exists", report.Errors[0].Errors["Email"])
+}
+
+func TestImport_UnknownMappingColumn(t *testing.T) {
+	f := newServiceFixture()
+	params := f.importParams(ImportValidRowsOnly, true)
+	params.Mapping = map[string]string{"FirstName": "Missing"}
+
+	_, err := f.service.Import(f.ctx, strings.NewReader(importCSV), params)
+	assert.Error(t, err)
+}
+
+func TestImport_ComparesEmailsIgnoringCase(t *testing.T) {
+	f := newServiceFixture()
+	_, err := f.service.Create(f.ctx, f.createParams("alan@example.com", "9999"))
+	require.NoError(t, err)
+
+	csv := "Nombre,Apellido,Correo,Direccion,Tipo,Documento,Nacimiento\n" +
+		"Ada,Lovelace,Ada@Example.com,London,NID,1001,1815-12-10\n" +
+		"Ada,King,ada@example.COM,London,NID,1002,1815-12-10\n" +
+		"Alan,Turing, ALAN@example.com,Wilmslow,NID,1003,1912-06-23\n"
+	report, err := f.service.Import(f.ctx, strings.NewReader(csv), f.importParams(ImportValidRowsOnly, true))
+	require.NoError(t, err)
+
+	require.Len(t, report.Errors, 2)
+	assert.Equal(t, "is duplicated in row 2", report.Errors[0].Errors["Email"])
+	assert.Equal(t, "already exists", report.Errors[1].Errors["Email"])
+}
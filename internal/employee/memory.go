@@ -0,0 +1,223 @@
+package employee
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/platform/uow"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const memoryOrigin = "MemoryEmployeeRepository"
+
+// MemoryRepository is a Repository that keeps employees in memory, for tests
+// and single-process deployments. It stores and returns copies, compares and
+// increments versions on Update, enforces the per-tenant uniqueness of Email
+// and DocNumber, and undoes its writes when a uow.Memory unit of work rolls
+// back, including the increment of the Version it was given.
+type MemoryRepository struct {
+	mu        sync.Mutex
+	employees map[uuid.UUID]*Employee
+}
+
+var _ Repository = (*MemoryRepository)(nil)
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{employees: make(map[uuid.UUID]*Employee)}
+}
+
+func (r *MemoryRepository) Create(ctx context.Context, employee *Employee) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkUnique(employee); err != nil {
+		return err
+	}
+	stored := *employee
+	r.employees[employee.ID] = &stored
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.employees, employee.ID)
+	})
+	return nil
+}
+
+func (r *MemoryRepository) ListByWorkspaceIDAndTenantID(_ context.Context, workspaceID uuid.UUID, tenantID uuid.UUID) ([]*Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*Employee
+	for _, e := range r.employees {
+		if !e.IsDeleted() && e.WorkspaceID == workspaceID && e.TenantID == tenantID {
+			c := *e
+			list = append(list, &c)
+		}
+	}
+	slices.SortFunc(list, func(a, b *Employee) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+	return list, nil
+}
+
+func (r *MemoryRepository) Search(_ context.Context, filter SearchFilter) ([]*Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*Employee
+	for _, e := range r.employees {
+		if filter.Matches(e) && filter.IsAfterCursor(e) {
+			c := *e
+			list = append(list, &c)
+		}
+	}
+	slices.SortFunc(list, filter.Compare)
+	if filter.Limit > 0 && len(list) > filter.Limit {
+		list = list[:filter.Limit]
+	}
+	return list, nil
+}
+
+func (r *MemoryRepository) GetByID(_ context.Context, id uuid.UUID) (*Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.employees[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, memoryOrigin, "Employee not found")
+	}
+	c := *e
+	return &c, nil
+}
+
+func (r *MemoryRepository) ListByIDs(_ context.Context, ids []uuid.UUID) ([]*Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*Employee
+	for _, id := range ids {
+		if e, ok := r.employees[id]; ok {
+			c := *e
+			list = append(list, &c)
+		}
+	}
+	slices.SortFunc(list, func(a, b *Employee) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+	return slices.CompactFunc(list, func(a, b *Employee) bool { return a.ID == b.ID }), nil
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, employee *Employee) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.employees[employee.ID]
+	if !ok {
+		return apperror.New(apperror.TypeNotFound, memoryOrigin, "Employee not found")
+	}
+	if previous.Version != employee.Version {
+		return apperror.NewConflictError(memoryOrigin, "The employee was modified since it was read")
+	}
+	if err := r.checkUnique(employee); err != nil {
+		return err
+	}
+	employee.Version++
+	stored := *employee
+	r.employees[employee.ID] = &stored
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.employees[employee.ID] = previous
+		employee.Version = previous.Version
+	})
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.employees[id]
+	if !ok {
+		return nil
+	}
+	delete(r.employees, id)
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.employees[id] = previous
+	})
+	return nil
+}
+
+func (r *MemoryRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]*Employee, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var removed, purged []*Employee
+	for id, e := range r.employees {
+		if e.IsDeleted() && e.DeletedAt.Before(before) {
+			delete(r.employees, id)
+			removed = append(removed, e)
+			c := *e
+			purged = append(purged, &c)
+		}
+	}
+	uow.OnRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, e := range removed {
+			r.employees[e.ID] = e
+		}
+	})
+	slices.SortFunc(purged, func(a, b *Employee) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+	return purged, nil
+}
+
+func (r *MemoryRepository) ExistsByTenantIDAndDocNumber(_ context.Context, tenantID uuid.UUID, docNumber string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(tenantID, uuid.Nil, func(e *Employee) bool { return e.DocNumber == docNumber }), nil
+}
+
+func (r *MemoryRepository) ExistsByTenantIDAndEmail(_ context.Context, tenantID uuid.UUID, email string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(tenantID, uuid.Nil, func(e *Employee) bool { return strings.EqualFold(e.Email, email) }), nil
+}
+
+func (r *MemoryRepository) CountByTenantID(_ context.Context, tenantID uuid.UUID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, e := range r.employees {
+		if !e.IsDeleted() && e.TenantID == tenantID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// checkUnique returns a TypeDuplicate error when another live employee of
+// the tenant has the email or document number of employee. Soft-deleted
+// employees do not conflict, and neither do employees that are themselves
+// soft-deleted.
+func (r *MemoryRepository) checkUnique(employee *Employee) error {
+	if employee.IsDeleted() {
+		return nil
+	}
+	details := make(map[string]string)
+	if r.find(employee.TenantID, employee.ID, func(e *Employee) bool { return strings.EqualFold(e.Email, employee.Email) }) {
+		details["Email"] = "already exists"
+	}
+	if r.find(employee.TenantID, employee.ID, func(e *Employee) bool { return e.DocNumber == employee.DocNumber }) {
+		details["DocNumber"] = "already exists"
+	}
+	if len(details) > 0 {
+		return apperror.NewDuplicateError(memoryOrigin, details)
+	}
+	return nil
+}
+
+// find reports whether a live employee of the tenant other than except
+// satisfies match.
+func (r *MemoryRepository) find(tenantID, except uuid.UUID, match func(*Employee) bool) bool {
+	for _, e := range r.employees {
+		if e.ID != except && !e.IsDeleted() && e.TenantID == tenantID && match(e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,349 @@
+package employee
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/uow"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRepositoryConformance runs the Repository contract against every
+// backend in the tree. A new backend adds itself here.
+func TestRepositoryConformance(t *testing.T) {
+	backends := map[string]func(t *testing.T) Repository{
+		"Memory": func(*testing.T) Repository {
+			return NewMemoryRepository()
+		},
+		"Encrypted": func(t *testing.T) Repository {
+			return NewEncryptedRepository(newMemorySealedRepo(), testKeys(t, "k1", "k1"))
+		},
+		"EncryptedSQL": func(t *testing.T) Repository {
+			return NewEncryptedRepository(newSQLiteRepository(t), testKeys(t, "k1", "k1"))
+		},
+	}
+	for name, newRepo := range backends {
+		t.Run(name, func(t *testing.T) {
+			testRepository(t, newRepo)
+		})
+	}
+}
+
+// TestUpdate_RollbackRestoresVersion checks that an Update undone by its
+// unit of work leaves the caller's entity at the version it read.
+func TestUpdate_RollbackRestoresVersion(t *testing.T) {
+	backends := map[string]func(t *testing.T) (Repository, uow.UnitOfWork){
+		"Memory": func(*testing.T) (Repository, uow.UnitOfWork) {
+			return NewMemoryRepository(), uow.NewMemory()
+		},
+		"EncryptedSQL": func(t *testing.T) (Repository, uow.UnitOfWork) {
+			store := newSQLiteRepository(t)
+			return NewEncryptedRepository(store, testKeys(t, "k1", "k1")), uow.NewSQL(store.db, nil)
+		},
+	}
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, u := newBackend(t)
+			e := conformanceEmployee(uuid.New(), uuid.New(), "Ada", "Lovelace", "1000")
+			require.NoError(t, repo.Create(ctx, e))
+
+			err := u.Do(ctx, func(ctx context.Context) error {
+				e.LastName = "King"
+				require.NoError(t, repo.Update(ctx, e))
+				return assert.AnError
+			})
+			require.ErrorIs(t, err, assert.AnError)
+			assert.Equal(t, int64(1), e.Version)
+
+			require.NoError(t, repo.Update(ctx, e), "the caller can retry with the version it read")
+		})
+	}
+}
+
+// conformanceSet is what testRepository stores: five employees of one
+// workspace, plus one of another workspace, one of another tenant and one
+// soft-deleted, none of which any search of the workspace may return.
+type conformanceSet struct {
+	tenantID, workspaceID uuid.UUID
+	byName                map[string]*Employee
+}
+
+func seedConformanceSet(t *testing.T, repo Repository) *conformanceSet {
+	t.Helper()
+	set := &conformanceSet{tenantID: uuid.New(), workspaceID: uuid.New(), byName: make(map[string]*Employee)}
+	people := []struct {
+		first, last string
+		status      EmployeeStatus
+		gender      EmployeeGender
+		hired       string
+	}{
+		{"Ada", "Lovelace", StatusActive, GenderFemale, "2021-03-01"},
+		{"Alan", "Turing", StatusActive, GenderMale, "2019-06-15"},
+		{"Grace", "Hopper", StatusOnLeave, GenderFemale, "2020-01-10"},
+		{"Edsger", "Dijkstra", StatusTerminated, GenderMale, ""},
+		{"Barbara", "Liskov", StatusActive, GenderFemale, "2021-03-01"},
+	}
+	for i, p := range people {
+		e := conformanceEmployee(set.tenantID, set.workspaceID, p.first, p.last, fmt.Sprintf("%d", 1000+i))
+		e.Status = p.status
+		gender := p.gender
+		e.Gender = &gender
+		if p.hired != "" {
+			hired, err := time.Parse(searchDateLayout, p.hired)
+			require.NoError(t, err)
+			e.HireDate = &hired
+		}
+		require.NoError(t, repo.Create(context.Background(), e))
+		set.byName[p.last] = e
+	}
+
+	others := []*Employee{
+		conformanceEmployee(set.tenantID, uuid.New(), "Ada", "Byron", "2000"),
+		conformanceEmployee(uuid.New(), set.workspaceID, "Ada", "Lovelace", "1000"),
+	}
+	deleted := conformanceEmployee(set.tenantID, set.workspaceID, "Alonzo", "Church", "3000")
+	deleted.SoftDelete()
+	for _, e := range append(others, deleted) {
+		require.NoError(t, repo.Create(context.Background(), e))
+	}
+	set.byName["Church"] = deleted
+	return set
+}
+
+func conformanceEmployee(tenantID, workspaceID uuid.UUID, first, last, docNumber string) *Employee {
+	e := &Employee{
+		TenantID:    tenantID,
+		WorkspaceID: workspaceID,
+		FirstName:   first,
+		LastName:    last,
+		Email:       first + "." + last + "@example.com",
+		DocTypeID:   uuid.New(),
+		DocNumber:   docNumber,
+		Status:      StatusActive,
+	}
+	e.Initialize()
+	return e
+}
+
+// searchAll pages through filter two at a time and returns the last names.
+func searchAll(t *testing.T, repo Repository, filter SearchFilter) []string {
+	t.Helper()
+	if filter.SortBy == "" {
+		filter.SortBy = SortByCreatedAt
+	}
+	if filter.Order == "" {
+		filter.Order = SortAsc
+	}
+	filter.Query = pagination.Query{Limit: 2}
+
+	var names []string
+	for {
+		page, err := repo.Search(context.Background(), filter)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page), filter.Limit)
+		for _, e := range page {
+			names = append(names, e.LastName)
+		}
+		if len(page) < filter.Limit {
+			return names
+		}
+		last := page[len(page)-1]
+		filter.After, filter.AfterKey = last.ID, filter.SortKey(last)
+	}
+}
+
+func testRepository(t *testing.T, newRepo func(t *testing.T) Repository) {
+	ctx := context.Background()
+
+	t.Run("Search sorts and pages", func(t *testing.T) {
+		repo := newRepo(t)
+		set := seedConformanceSet(t, repo)
+		filter := SearchFilter{TenantID: set.tenantID, WorkspaceID: set.workspaceID}
+
+		assert.Equal(t, []string{"Lovelace", "Turing", "Hopper", "Dijkstra", "Liskov"}, searchAll(t, repo, filter))
+
+		filter.SortBy = SortByLastName
+		assert.Equal(t, []string{"Dijkstra", "Hopper", "Liskov", "Lovelace", "Turing"}, searchAll(t, repo, filter))
+
+		// Lovelace and Liskov were hired on the same day, so their IDs
+		// decide, reversed like the dates.
+		filter.SortBy, filter.Order = SortByHireDate, SortDesc
+		assert.Equal(t, []string{"Liskov", "Lovelace", "Hopper", "Turing", "Dijkstra"}, searchAll(t, repo, filter))
+	})
+
+	t.Run("Search filters", func(t *testing.T) {
+		repo := newRepo(t)
+		set := seedConformanceSet(t, repo)
+		filter := func() SearchFilter {
+			return SearchFilter{TenantID: set.tenantID, WorkspaceID: set.workspaceID}
+		}
+
+		f := filter()
+		f.NamePrefix = "l"
+		assert.Equal(t, []string{"Lovelace", "Liskov"}, searchAll(t, repo, f))
+
+		f = filter()
+		f.NamePrefix = "A"
+		assert.Equal(t, []string{"Lovelace", "Turing"}, searchAll(t, repo, f), "the first name matches too")
+
+		f = filter()
+		f.Email = "ALAN.Turing@example.com"
+		assert.Equal(t, []string{"Turing"}, searchAll(t, repo, f))
+
+		f = filter()
+		f.DocNumber = "1002"
+		assert.Equal(t, []string{"Hopper"}, searchAll(t, repo, f))
+
+		f = filter()
+		gender, status := GenderFemale, StatusActive
+		f.Gender, f.Status = &gender, &status
+		assert.Equal(t, []string{"Lovelace", "Liskov"}, searchAll(t, repo, f))
+
+		f = filter()
+		from := time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2021, 3, 1, 23, 0, 0, 0, time.UTC)
+		f.HiredFrom, f.HiredTo = &from, &to
+		assert.Equal(t, []string{"Lovelace", "Hopper", "Liskov"}, searchAll(t, repo, f), "both bounds are inclusive days")
+
+		f = filter()
+		f.SortBy = SortByHireDate
+		f.HiredTo = &to
+		assert.NotContains(t, searchAll(t, repo, f), "Dijkstra", "employees without a hire date never match a bounded search")
+	})
+
+	t.Run("soft-deleted employees are only found by ID", func(t *testing.T) {
+		repo := newRepo(t)
+		set := seedConformanceSet(t, repo)
+		church := set.byName["Church"]
+
+		got, err := repo.GetByID(ctx, church.ID)
+		require.NoError(t, err)
+		assert.True(t, got.IsDeleted())
+
+		list, err := repo.ListByWorkspaceIDAndTenantID(ctx, set.workspaceID, set.tenantID)
+		require.NoError(t, err)
+		assert.Len(t, list, 5)
+
+		exists, err := repo.ExistsByTenantIDAndEmail(ctx, set.tenantID, church.Email)
+		require.NoError(t, err)
+		assert.False(t, exists)
+		count, err := repo.CountByTenantID(ctx, set.tenantID)
+		require.NoError(t, err)
+		assert.Equal(t, 6, count)
+	})
+
+	t.Run("ListByIDs includes soft-deleted employees", func(t *testing.T) {
+		repo := newRepo(t)
+		set := seedConformanceSet(t, repo)
+		ids := []uuid.UUID{set.byName["Turing"].ID, uuid.New(), set.byName["Church"].ID, set.byName["Turing"].ID}
+
+		list, err := repo.ListByIDs(ctx, ids)
+		require.NoError(t, err)
+		require.Len(t, list, 2, "unknown and repeated IDs are skipped")
+		assert.Less(t, list[0].ID.String(), list[1].ID.String())
+		for _, e := range list {
+			assert.Equal(t, set.byName[e.LastName].Email, e.Email)
+		}
+		assert.ElementsMatch(t, []string{"Turing", "Church"}, []string{list[0].LastName, list[1].LastName})
+	})
+
+	t.Run("email and document number are unique per tenant", func(t *testing.T) {
+		repo := newRepo(t)
+		set := seedConformanceSet(t, repo)
+		ada := set.byName["Lovelace"]
+
+		exists, err := repo.ExistsByTenantIDAndEmail(ctx, set.tenantID, "ADA.LOVELACE@example.com")
+		require.NoError(t, err)
+		assert.True(t, exists, "emails compare ignoring case")
+		exists, err = repo.ExistsByTenantIDAndDocNumber(ctx, set.tenantID, ada.DocNumber)
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		twin := conformanceEmployee(set.tenantID, set.workspaceID, "Ada", "Lovelace", "9999")
+		twin.Email = "ADA.LOVELACE@example.com"
+		assert.True(t, apperror.IsType(repo.Create(ctx, twin), apperror.TypeDuplicate))
+
+		alan, err := repo.GetByID(ctx, set.byName["Turing"].ID)
+		require.NoError(t, err)
+		alan.DocNumber = ada.DocNumber
+		assert.True(t, apperror.IsType(repo.Update(ctx, alan), apperror.TypeDuplicate))
+	})
+
+	t.Run("Update stores a new version", func(t *testing.T) {
+		repo := newRepo(t)
+		set := seedConformanceSet(t, repo)
+
+		ada, err := repo.GetByID(ctx, set.byName["Lovelace"].ID)
+		require.NoError(t, err)
+		ada.LastName = "King"
+		require.NoError(t, repo.Update(ctx, ada))
+		assert.Equal(t, int64(2), ada.Version)
+
+		got, err := repo.GetByID(ctx, ada.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "King", got.LastName)
+		assert.Equal(t, int64(2), got.Version)
+		got.LastName = "Byron"
+		again, err := repo.GetByID(ctx, ada.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "King", again.LastName, "reads return copies")
+	})
+
+	t.Run("Update rejects a stale version", func(t *testing.T) {
+		repo := newRepo(t)
+		set := seedConformanceSet(t, repo)
+		id := set.byName["Lovelace"].ID
+
+		// Both writers read version 1; exactly one of them may win.
+		errs := make(chan error, 2)
+		var start sync.WaitGroup
+		start.Add(1)
+		for _, name := range []string{"King", "Byron"} {
+			read, err := repo.GetByID(ctx, id)
+			require.NoError(t, err)
+			go func() {
+				start.Wait()
+				read.LastName = name
+				errs <- repo.Update(ctx, read)
+			}()
+		}
+		start.Done()
+
+		first, second := <-errs, <-errs
+		if first != nil {
+			first, second = second, first
+		}
+		require.NoError(t, first)
+		assert.True(t, apperror.IsType(second, apperror.TypeConflict))
+
+		got, err := repo.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), got.Version)
+	})
+
+	t.Run("Delete and PurgeDeleted remove employees", func(t *testing.T) {
+		repo := newRepo(t)
+		set := seedConformanceSet(t, repo)
+
+		require.NoError(t, repo.Delete(ctx, set.byName["Turing"].ID))
+		_, err := repo.GetByID(ctx, set.byName["Turing"].ID)
+		assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+
+		purged, err := repo.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, purged, 1)
+		assert.Equal(t, set.byName["Church"].ID, purged[0].ID)
+		assert.Equal(t, set.tenantID, purged[0].TenantID)
+		_, err = repo.GetByID(ctx, set.byName["Church"].ID)
+		assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	})
+}
@@ -0,0 +1,249 @@
+package employee
+
+import (
+	"bytes"
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/doctype"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/tenant"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const searchDateLayout = "2006-01-02"
+
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "CREATED_AT"
+	SortByLastName  SortField = "LAST_NAME"
+	SortByHireDate  SortField = "HIRE_DATE"
+)
+
+func (f SortField) IsValid() bool {
+	switch f {
+	case SortByCreatedAt, SortByLastName, SortByHireDate:
+		return true
+	}
+	return false
+}
+
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "ASC"
+	SortDesc SortOrder = "DESC"
+)
+
+func (o SortOrder) IsValid() bool {
+	return o == SortAsc || o == SortDesc
+}
+
+// SearchParams selects a page of a workspace's employees. Every filter is
+// optional. A cursor is only valid with the sort it was returned for.
+type SearchParams struct {
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	// NamePrefix matches the start of the first or last name, ignoring case.
+	NamePrefix string
+	// Email matches the whole address, ignoring case.
+	Email string
+	// DocNumber matches the normalised document number.
+	DocNumber string
+	Gender    *string
+	Status    *string
+	// HiredFrom and HiredTo bound the hire date, both inclusive. Employees
+	// without a hire date never match a bounded search.
+	HiredFrom *time.Time
+	HiredTo   *time.Time
+	// Defaults to CREATED_AT ASC.
+	SortBy SortField
+	Order  SortOrder
+	pagination.Params
+}
+
+// SearchFilter is what the repository receives from Service.Search. Matches
+// and Compare define its semantics, so that every backend returns the same
+// employees in the same order: an in-memory repository can use them
+// directly, and a SQL one translates them to
+//
+//	WHERE <filters> AND (sort_key, id) > (AfterKey, After)
+//	ORDER BY sort_key, id
+//	LIMIT Limit
+//
+// with both comparisons reversed for DESC, where sort_key is the value
+// returned by SortKey.
+type SearchFilter struct {
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	NamePrefix  string
+	Email       string
+	DocNumber   string
+	Gender      *EmployeeGender
+	Status      *EmployeeStatus
+	HiredFrom   *time.Time
+	HiredTo     *time.Time
+	SortBy      SortField
+	Order       SortOrder
+	pagination.Query
+}
+
+// Matches reports whether e satisfies every filter, ignoring the cursor.
+// Soft-deleted employees never match.
+func (f SearchFilter) Matches(e *Employee) bool {
+	if e.IsDeleted() || e.TenantID != f.TenantID || e.WorkspaceID != f.WorkspaceID {
+		return false
+	}
+	if f.NamePrefix != "" {
+		prefix := strings.ToLower(f.NamePrefix)
+		if !strings.HasPrefix(strings.ToLower(e.FirstName), prefix) &&
+			!strings.HasPrefix(strings.ToLower(e.LastName), prefix) {
+			return false
+		}
+	}
+	if f.Email != "" && !strings.EqualFold(e.Email, f.Email) {
+		return false
+	}
+	if f.DocNumber != "" && e.DocNumber != f.DocNumber {
+		return false
+	}
+	if f.Gender != nil && (e.Gender == nil || *e.Gender != *f.Gender) {
+		return false
+	}
+	if f.Status != nil && e.Status != *f.Status {
+		return false
+	}
+	if f.HiredFrom != nil || f.HiredTo != nil {
+		if e.HireDate == nil {
+			return false
+		}
+		hired := e.HireDate.Format(searchDateLayout)
+		if f.HiredFrom != nil && hired < f.HiredFrom.Format(searchDateLayout) {
+			return false
+		}
+		if f.HiredTo != nil && hired > f.HiredTo.Format(searchDateLayout) {
+			return false
+		}
+	}
+	return true
+}
+
+// SortKey returns the value e is sorted by before its ID: the lower-cased
+// last name, or the hire date as YYYY-MM-DD with employees that have none
+// sorting first. Sorting by CREATED_AT uses the ID alone, which is a
+// UUIDv7 and therefore ordered by creation time.
+func (f SearchFilter) SortKey(e *Employee) string {
+	switch f.SortBy {
+	case SortByLastName:
+		return strings.ToLower(e.LastName)
+	case SortByHireDate:
+		if e.HireDate == nil {
+			return ""
+		}
+		return e.HireDate.Format(searchDateLayout)
+	}
+	return ""
+}
+
+// Compare orders a before b (negative) or after b (positive).
+func (f SearchFilter) Compare(a, b *Employee) int {
+	return f.compare(f.SortKey(a), a.ID, f.SortKey(b), b.ID)
+}
+
+// IsAfterCursor reports whether e belongs after the position of the query.
+func (f SearchFilter) IsAfterCursor(e *Employee) bool {
+	if f.After == uuid.Nil {
+		return true
+	}
+	return f.compare(f.SortKey(e), e.ID, f.AfterKey, f.After) > 0
+}
+
+func (f SearchFilter) compare(keyA string, idA uuid.UUID, keyB string, idB uuid.UUID) int {
+	c := strings.Compare(keyA, keyB)
+	if c == 0 {
+		c = bytes.Compare(idA[:], idB[:])
+	}
+	if f.Order == SortDesc {
+		return -c
+	}
+	return c
+}
+
+// Search returns a page of the workspace's employees.
+func (s *Service) Search(ctx context.Context, params SearchParams) (*pagination.Page[*Employee], error) {
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	params.TenantID = tenantID
+	params.NamePrefix = strings.TrimSpace(params.NamePrefix)
+	params.Email = strings.TrimSpace(params.Email)
+	params.DocNumber = doctype.NormalizeNumber(strings.TrimSpace(params.DocNumber))
+	if params.SortBy == "" {
+		params.SortBy = SortByCreatedAt
+	}
+	if params.Order == "" {
+		params.Order = SortAsc
+	}
+
+	validator := NewValidator()
+	if params.WorkspaceID == uuid.Nil {
+		validator.AddError("WorkspaceID", "is empty")
+	}
+	validator.ValidateGender(params.Gender)
+	validator.ValidateStatus(params.Status)
+	if params.HiredFrom != nil && params.HiredTo != nil && params.HiredTo.Before(*params.HiredFrom) {
+		validator.AddError("HiredTo", "must not be before HiredFrom")
+	}
+	if !params.SortBy.IsValid() {
+		validator.AddError("SortBy", "is invalid")
+	}
+	if !params.Order.IsValid() {
+		validator.AddError("Order", "is invalid")
+	}
+	for field, msg := range params.Params.Validate() {
+		validator.AddError(field, msg)
+	}
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+	if err := auth.Authorize(ctx, auth.PermEmployeeRead, auth.Resource{WorkspaceID: params.WorkspaceID}, serviceOrigin); err != nil {
+		return nil, err
+	}
+
+	filter := SearchFilter{
+		TenantID:    params.TenantID,
+		WorkspaceID: params.WorkspaceID,
+		NamePrefix:  params.NamePrefix,
+		Email:       params.Email,
+		DocNumber:   params.DocNumber,
+		HiredFrom:   params.HiredFrom,
+		HiredTo:     params.HiredTo,
+		SortBy:      params.SortBy,
+		Order:       params.Order,
+		Query:       params.Params.Query(),
+	}
+	if params.Gender != nil {
+		gender := EmployeeGender(*params.Gender)
+		filter.Gender = &gender
+	}
+	if params.Status != nil {
+		status := EmployeeStatus(*params.Status)
+		filter.Status = &status
+	}
+
+	employees, err := s.employeeRepo.Search(ctx, filter)
+	if err != nil {
+		s.logger.Error(err, "Failed to search employees", "workspace_id", params.WorkspaceID)
+		return nil, err
+	}
+
+	return pagination.NewPage(employees, filter.Query, func(e *Employee) string {
+		return pagination.EncodeKeyCursor(filter.SortKey(e), e.ID)
+	}), nil
+}
@@ -0,0 +1,96 @@
+package employee
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *serviceFixture) seedForSearch(t *testing.T) {
+	t.Helper()
+	people := []struct {
+		first, last, status, hired string
+	}{
+		{"Ada", "Lovelace", "ACTIVE", "2021-03-01"},
+		{"Alan", "Turing", "ACTIVE", "2019-06-15"},
+		{"Grace", "Hopper", "ON_LEAVE", "2020-01-10"},
+		{"Edsger", "Dijkstra", "TERMINATED", ""},
+		{"Barbara", "Liskov", "ACTIVE", "2022-09-30"},
+	}
+	for i, p := range people {
+		params := f.createParams(fmt.Sprintf("%s@example.com", p.first), fmt.Sprintf("%d", 1000+i))
+		params.FirstName, params.LastName = p.first, p.last
+		status := p.status
+		params.Status = &status
+		if p.hired != "" {
+			hired, err := time.Parse("2006-01-02", p.hired)
+			require.NoError(t, err)
+			params.HireDate = &hired
+		}
+		_, err := f.service.Create(f.ctx, params)
+		require.NoError(t, err)
+	}
+}
+
+func (f *serviceFixture) searchAll(t *testing.T, params SearchParams) []string {
+	t.Helper()
+	params.TenantID, params.WorkspaceID = f.workspace.TenantID, f.workspace.ID
+	params.Limit = 2
+
+	var names []string
+	for {
+		page, err := f.service.Search(f.ctx, params)
+		require.NoError(t, err)
+		for _, e := range page.Items {
+			names = append(names, e.LastName)
+		}
+		if page.NextCursor == "" {
+			return names
+		}
+		params.Cursor = page.NextCursor
+	}
+}
+
+func TestSearch_SortsAndPaginates(t *testing.T) {
+	f := newServiceFixture()
+	f.seedForSearch(t)
+
+	assert.Equal(t, []string{"Lovelace", "Turing", "Hopper", "Dijkstra", "Liskov"}, f.searchAll(t, SearchParams{}))
+	assert.Equal(t, []string{"Dijkstra", "Hopper", "Liskov", "Lovelace", "Turing"}, f.searchAll(t, SearchParams{SortBy: SortByLastName}))
+	assert.Equal(t, []string{"Liskov", "Lovelace", "Hopper", "Turing", "Dijkstra"},
+		f.searchAll(t, SearchParams{SortBy: SortByHireDate, Order: SortDesc}))
+}
+
+func TestSearch_Filters(t *testing.T) {
+	f := newServiceFixture()
+	f.seedForSearch(t)
+
+	active := "ACTIVE"
+	assert.Equal(t, []string{"Lovelace", "Liskov"}, f.searchAll(t, SearchParams{NamePrefix: "l", Status: &active}))
+	assert.Equal(t, []string{"Turing"}, f.searchAll(t, SearchParams{Email: "ALAN@example.com"}))
+	assert.Equal(t, []string{"Hopper"}, f.searchAll(t, SearchParams{DocNumber: "1.002"}))
+
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"Lovelace", "Hopper"}, f.searchAll(t, SearchParams{HiredFrom: &from, HiredTo: &to}))
+}
+
+func TestSearch_RejectsInvalidParams(t *testing.T) {
+	f := newServiceFixture()
+	_, err := f.service.Search(f.ctx, SearchParams{
+		TenantID:    f.workspace.TenantID,
+		WorkspaceID: uuid.Nil,
+		SortBy:      "SALARY",
+	})
+
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Contains(t, domainErr.Details, "WorkspaceID")
+	assert.Contains(t, domainErr.Details, "SortBy")
+}
@@ -3,73 +3,310 @@ package employee
 import (
 	"context"
 	"payroll/internal/apperror"
+	"payroll/internal/audit"
 	"payroll/internal/doctype"
+	"payroll/internal/event"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/keylock"
 	"payroll/internal/platform/logger"
+	"payroll/internal/platform/tenant"
+	"payroll/internal/platform/uow"
 	"payroll/internal/workspace"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	serviceOrigin = "EmployeeService"
+	auditEntity   = "Employee"
+)
+
+// TenantPolicy enforces the tenant's status and plan. Reads are allowed for
+// suspended tenants; every write checks the policy first.
+type TenantPolicy interface {
+	// CheckActive fails if the tenant is suspended.
+	CheckActive(ctx context.Context, tenantID uuid.UUID) error
+	// CheckEmployeeLimit fails if the tenant is suspended or its plan does
+	// not allow count employees.
+	CheckEmployeeLimit(ctx context.Context, tenantID uuid.UUID, count int) error
+}
+
+// DocNumberNormalizer checks document numbers against the rules of their
+// type and normalises them, e.g. doctype.Service.
+type DocNumberNormalizer interface {
+	NormalizeNumber(ctx context.Context, docType *doctype.DocType, number string) (string, error)
+}
+
 type Service struct {
 	employeeRepo  Repository
 	workspaceRepo workspace.Repository
 	docTypeRepo   doctype.Repository
+	docNumbers    DocNumberNormalizer
+	tenants       TenantPolicy
+	uow           uow.UnitOfWork
+	audit         audit.Recorder
+	events        event.Publisher
 	logger        logger.Logger
+	// tenantLocks serializes uniqueness checks and writes per tenant. It is
+	// always taken before a unit of work is started, never inside one.
+	tenantLocks *keylock.KeyLock
 }
 
-func NewService(er Repository, wr workspace.Repository, dtr doctype.Repository, l logger.Logger) *Service {
+func NewService(er Repository, wr workspace.Repository, dtr doctype.Repository, dn DocNumberNormalizer, tp TenantPolicy, u uow.UnitOfWork, a audit.Recorder, p event.Publisher, l logger.Logger) *Service {
 	return &Service{
 		employeeRepo:  er,
 		workspaceRepo: wr,
 		docTypeRepo:   dtr,
+		docNumbers:    dn,
+		tenants:       tp,
+		uow:           u,
+		audit:         a,
+		events:        p,
 		logger:        l,
+		tenantLocks:   keylock.New(),
+	}
+}
+
+// checkUniqueness returns a TypeDuplicate error naming every field of emp
+// that conflicts with another employee of the tenant. Only the fields in
+// fields are checked, so an update does not collide with itself.
+func (s *Service) checkUniqueness(ctx context.Context, emp *Employee, fields uniqueFields) error {
+	details := make(map[string]string)
+
+	if fields.email {
+		exists, err := s.employeeRepo.ExistsByTenantIDAndEmail(ctx, emp.TenantID, emp.Email)
+		if err != nil {
+			s.logger.Error(err, "Failed to check email uniqueness")
+			return err
+		}
+		if exists {
+			details["Email"] = "already exists"
+		}
+	}
+	if fields.docNumber {
+		exists, err := s.employeeRepo.ExistsByTenantIDAndDocNumber(ctx, emp.TenantID, emp.DocNumber)
+		if err != nil {
+			s.logger.Error(err, "Failed to check document number uniqueness")
+			return err
+		}
+		if exists {
+			details["DocNumber"] = "already exists"
+		}
+	}
+
+	if len(details) > 0 {
+		err := apperror.NewDuplicateError(serviceOrigin, details)
+		s.logger.Warn("Employee uniqueness check failed", "errors", err)
+		return err
+	}
+	return nil
+}
+
+// normalizeDocNumber checks emp.DocNumber against the rules of its document
+// type and stores it in normalised form.
+func (s *Service) normalizeDocNumber(ctx context.Context, emp *Employee) error {
+	dt, err := s.docTypeRepo.Get(ctx, emp.DocTypeID)
+	if err != nil {
+		s.logger.Error(err, "Failed to get document type", "doc_type_id", emp.DocTypeID)
+		return err
+	}
+
+	normalized, err := s.docNumbers.NormalizeNumber(ctx, dt, emp.DocNumber)
+	if apperror.IsType(err, apperror.TypeInvalid) {
+		s.logger.Warn("Document number is not valid for its type", "doc_type", dt.Code, "errors", err)
+		return err
+	}
+	if err != nil {
+		s.logger.Error(err, "Failed to check document number", "doc_type_id", dt.ID)
+		return err
+	}
+	emp.DocNumber = normalized
+	return nil
+}
+
+// checkLimit fails unless the tenant can have n more employees. The caller
+// must hold the tenant lock so that the count stays accurate.
+func (s *Service) checkLimit(ctx context.Context, tenantID uuid.UUID, n int) error {
+	count, err := s.employeeRepo.CountByTenantID(ctx, tenantID)
+	if err != nil {
+		s.logger.Error(err, "Failed to count employees", "tenant_id", tenantID)
+		return err
+	}
+	if err := s.tenants.CheckEmployeeLimit(ctx, tenantID, count+n); err != nil {
+		s.logger.Warn("Employee creation rejected by tenant policy", "tenant_id", tenantID, "errors", err)
+		return err
 	}
+	return nil
+}
+
+// eventTypes maps audited actions to the events published for them.
+var eventTypes = map[audit.Action]string{
+	audit.ActionCreate:  EventCreated,
+	audit.ActionUpdate:  EventUpdated,
+	audit.ActionDelete:  EventDeleted,
+	audit.ActionRestore: EventRestored,
+}
+
+// record adds the change of emp to the audit log and publishes its event.
+// Call it in the unit of work that saves emp; before is nil for creations.
+func (s *Service) record(ctx context.Context, action audit.Action, before, emp *Employee) error {
+	err := s.audit.Record(ctx, audit.Event{
+		TenantID:   emp.TenantID,
+		EntityType: auditEntity,
+		EntityID:   emp.ID,
+		Action:     action,
+		Before:     before,
+		After:      emp,
+	})
+	if err == nil {
+		err = s.events.Publish(ctx, event.Event{Type: eventTypes[action], TenantID: emp.TenantID, EntityID: emp.ID, Payload: emp.Redacted()})
+	}
+	if err != nil {
+		s.logger.Error(err, "Failed to record employee change", "employee_id", emp.ID)
+	}
+	return err
+}
+
+type uniqueFields struct {
+	email     bool
+	docNumber bool
 }
 
 func (s *Service) Create(ctx context.Context, params CreateEmployeeParams) (*Employee, error) {
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermEmployeeWrite, auth.Resource{WorkspaceID: params.WorkspaceID}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	params.TenantID = tenantID
+
 	employee, err := NewEmployee(params)
 	if err != nil {
 		s.logger.Warn("Failed to create new employee due to validation errors", "errors", err)
 		return nil, err
 	}
 
-	ws, err := s.workspaceRepo.Get(ctx, params.WorkspaceID)
+	unlock := s.tenantLocks.Lock(employee.TenantID.String())
+	defer unlock()
+
+	if err := s.checkLimit(ctx, employee.TenantID, 1); err != nil {
+		return nil, err
+	}
+
+	// The workspace, document type and uniqueness checks are read in the
+	// same unit of work as the write, so they still hold when it commits.
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		ws, err := s.workspaceRepo.Get(ctx, params.WorkspaceID)
+		if err != nil || ws.IsDeleted() || ws.TenantID != tenantID {
+			return apperror.New(apperror.TypeInvalid, serviceOrigin, "Invalid WorkspaceID")
+		}
+		s.logger.Debug("Workspace validation successful", "workspace_id", params.WorkspaceID)
+
+		isValid, err := s.docTypeRepo.IsValidForCountry(ctx, params.DocTypeID, ws.CountryID)
+		if err != nil {
+			s.logger.Error(err, "Failed to validate document type for country")
+			return err
+		}
+		if !isValid {
+			err := apperror.New(apperror.TypeInvalid, serviceOrigin, "DocType is not valid for the employee's country")
+			s.logger.Warn(err.Error(), "doc_type_id", params.DocTypeID, "country", ws.CountryID)
+			return err
+		}
+
+		if err := s.normalizeDocNumber(ctx, employee); err != nil {
+			return err
+		}
+		if err := s.checkUniqueness(ctx, employee, uniqueFields{email: true, docNumber: true}); err != nil {
+			return err
+		}
+
+		if err := s.employeeRepo.Create(ctx, employee); err != nil {
+			s.logger.Error(err, "Failed to save employee to repository")
+			return err
+		}
+		return s.record(ctx, audit.ActionCreate, nil, employee)
+	})
 	if err != nil {
-		return nil, apperror.New(apperror.TypeInvalid, "EmployeeService", "Invalid WorkspaceID")
+		return nil, err
 	}
-	s.logger.Debug("Workspace validation successful", "workspace_id", params.WorkspaceID)
 
-	isValid, err := s.docTypeRepo.IsValidForCountry(ctx, params.DocTypeID, ws.CountryID)
+	s.logger.Info("Employee created successfully", "employee_id", employee.ID)
+	return employee, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*Employee, error) {
+	employee, err := s.get(ctx, id)
 	if err != nil {
-		s.logger.Error(err, "Failed to validate document type for country")
 		return nil, err
 	}
-	if !isValid {
-		err := apperror.New(apperror.TypeInvalid, "EmployeeService", "DocType is not valid for the employee's country")
-		s.logger.Warn(err.Error(), "doc_type_id", params.DocTypeID, "country", ws.CountryID)
+	if err := authorize(ctx, auth.PermEmployeeRead, employee); err != nil {
 		return nil, err
 	}
+	return employee, nil
+}
 
-	if err := s.employeeRepo.Create(ctx, employee); err != nil {
-		s.logger.Error(err, "Failed to save employee to repository")
+// authorize checks that the caller holds perm on the employee's data.
+func authorize(ctx context.Context, perm auth.Permission, employee *Employee) error {
+	return auth.Authorize(ctx, perm, auth.Resource{WorkspaceID: employee.WorkspaceID, EmployeeID: employee.ID}, serviceOrigin)
+}
+
+// get returns the employee unless it has been soft-deleted or belongs to
+// another tenant.
+func (s *Service) get(ctx context.Context, id uuid.UUID) (*Employee, error) {
+	employee, err := s.getAnyState(ctx, id)
+	if err != nil {
 		return nil, err
 	}
-
-	s.logger.Info("Employee created successfully", "employee_id", employee.ID)
+	if employee.IsDeleted() {
+		return nil, apperror.New(apperror.TypeNotFound, serviceOrigin, "Employee not found")
+	}
 	return employee, nil
 }
 
-func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*Employee, error) {
-	return s.employeeRepo.GetByID(ctx, id)
+// getAnyState is get without the soft-delete check.
+func (s *Service) getAnyState(ctx context.Context, id uuid.UUID) (*Employee, error) {
+	employee, err := s.employeeRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, employee.TenantID, serviceOrigin, "Employee not found"); err != nil {
+		return nil, err
+	}
+	return employee, nil
 }
 
 func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateEmployeeParams) (*Employee, error) {
-	employee, err := s.employeeRepo.GetByID(ctx, id)
+	employee, err := s.get(ctx, id)
 	if err != nil {
 		s.logger.Error(err, "Failed to get employee for update", "employee_id", id)
 		return nil, err
 	}
+	if err := authorize(ctx, auth.PermEmployeeWrite, employee); err != nil {
+		return nil, err
+	}
+	if employee.IsErased() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Employee has been erased")
+	}
+	before := *employee
+	if !employee.MatchesVersion(params.Version) {
+		err := apperror.NewConflictError(serviceOrigin, "Employee was modified since it was read")
+		s.logger.Warn(err.Error(), "employee_id", id, "version", employee.Version)
+		return nil, err
+	}
+	if err := s.tenants.CheckActive(ctx, employee.TenantID); err != nil {
+		return nil, err
+	}
+
+	unlock := s.tenantLocks.Lock(employee.TenantID.String())
+	defer unlock()
+
+	previousEmail := employee.Email
+	previousDocNumber := employee.DocNumber
+	previousDocTypeID := employee.DocTypeID
 
 	validator := NewValidator()
 
@@ -88,6 +325,11 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateEmploye
 		validator.ValidateEmail(*params.Email)
 		employee.Email = *params.Email
 	}
+	if params.Address != nil {
+		*params.Address = strings.TrimSpace(*params.Address)
+		validator.ValidateAddress(params.Address)
+		employee.Address = *params.Address
+	}
 	if params.DocTypeID != nil {
 		validator.ValidateDocTypeID(*params.DocTypeID)
 		employee.DocTypeID = *params.DocTypeID
@@ -97,6 +339,18 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateEmploye
 		validator.ValidateDocNumber(*params.DocNumber)
 		employee.DocNumber = *params.DocNumber
 	}
+	if params.Status != nil {
+		*params.Status = strings.TrimSpace(*params.Status)
+		validator.ValidateStatus(params.Status)
+		employee.Status = EmployeeStatus(*params.Status)
+	}
+	if params.HireDate != nil {
+		if params.HireDate.IsZero() {
+			employee.HireDate = nil
+		} else {
+			employee.HireDate = params.HireDate
+		}
+	}
 	if params.BirthDate != nil {
 		if params.BirthDate.IsZero() {
 			employee.BirthDate = nil
@@ -125,16 +379,76 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateEmploye
 		}
 	}
 
+	if params.Department != nil {
+		*params.Department = strings.TrimSpace(*params.Department)
+		if *params.Department == "" {
+			employee.Department = nil
+		} else {
+			validator.ValidateDepartment(params.Department)
+			employee.Department = params.Department
+		}
+	}
+	if params.BankAccount != nil {
+		*params.BankAccount = strings.TrimSpace(*params.BankAccount)
+		if *params.BankAccount == "" {
+			employee.BankAccount = nil
+		} else {
+			validator.ValidateBankAccount(params.BankAccount)
+			employee.BankAccount = params.BankAccount
+		}
+	}
+
 	if validator.HasErrors() {
 		err := apperror.NewValidationError("UpdateEmployee", validator.Errors())
 		s.logger.Warn("Failed to update employee due to validation errors", "errors", err)
 		return nil, err
 	}
 
-	employee.Touch()
+	// As in Create, the document type and uniqueness checks are read in the
+	// same unit of work as the write.
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if employee.DocTypeID != previousDocTypeID {
+			ws, err := s.workspaceRepo.Get(ctx, employee.WorkspaceID)
+			if err != nil {
+				s.logger.Error(err, "Failed to get workspace for update", "workspace_id", employee.WorkspaceID)
+				return err
+			}
+			isValid, err := s.docTypeRepo.IsValidForCountry(ctx, employee.DocTypeID, ws.CountryID)
+			if err != nil {
+				s.logger.Error(err, "Failed to validate document type for country")
+				return err
+			}
+			if !isValid {
+				err := apperror.New(apperror.TypeInvalid, serviceOrigin, "DocType is not valid for the employee's country")
+				s.logger.Warn(err.Error(), "doc_type_id", employee.DocTypeID, "country", ws.CountryID)
+				return err
+			}
+		}
+
+		if employee.DocTypeID != previousDocTypeID || employee.DocNumber != previousDocNumber {
+			if err := s.normalizeDocNumber(ctx, employee); err != nil {
+				return err
+			}
+		}
+
+		// Document numbers are unique per tenant regardless of type, so a
+		// change of DocTypeID alone cannot introduce a conflict.
+		changed := uniqueFields{
+			email:     !strings.EqualFold(employee.Email, previousEmail),
+			docNumber: employee.DocNumber != previousDocNumber,
+		}
+		if err := s.checkUniqueness(ctx, employee, changed); err != nil {
+			return err
+		}
 
-	if err := s.employeeRepo.Update(ctx, employee); err != nil {
-		s.logger.Error(err, "Failed to save updated employee to repository", "employee_id", id)
+		employee.Touch()
+		if err := s.employeeRepo.Update(ctx, employee); err != nil {
+			s.logger.Error(err, "Failed to save updated employee to repository", "employee_id", id)
+			return err
+		}
+		return s.record(ctx, audit.ActionUpdate, &before, employee)
+	})
+	if err != nil {
 		return nil, err
 	}
 	s.logger.Info("Employee updated successfully", "employee_id", employee.ID)
@@ -142,12 +456,158 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateEmploye
 }
 
 func (s *Service) ListByWorkspaceIDAndTenantID(ctx context.Context, workspaceID uuid.UUID, tenantID uuid.UUID) ([]*Employee, error) {
+	tenantID, err := tenant.Resolve(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermEmployeeRead, auth.Resource{WorkspaceID: workspaceID}, serviceOrigin); err != nil {
+		return nil, err
+	}
 	return s.employeeRepo.ListByWorkspaceIDAndTenantID(ctx, workspaceID, tenantID)
 }
 
+// Delete soft-deletes the employee. It can be restored until it is purged.
 func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
-	if _, err := s.employeeRepo.GetByID(ctx, id); err != nil {
+	employee, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := authorize(ctx, auth.PermEmployeeWrite, employee); err != nil {
 		return err
 	}
-	return s.employeeRepo.Delete(ctx, id)
+	if err := s.tenants.CheckActive(ctx, employee.TenantID); err != nil {
+		return err
+	}
+	before := *employee
+	employee.SoftDelete()
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.employeeRepo.Update(ctx, employee); err != nil {
+			s.logger.Error(err, "Failed to delete employee", "employee_id", id)
+			return err
+		}
+		return s.record(ctx, audit.ActionDelete, &before, employee)
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Employee deleted", "employee_id", id)
+	return nil
+}
+
+// Restore undoes Delete, provided no other employee of the tenant has taken
+// the email or document number in the meantime.
+func (s *Service) Restore(ctx context.Context, id uuid.UUID) (*Employee, error) {
+	employee, err := s.getAnyState(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, auth.PermEmployeeWrite, employee); err != nil {
+		return nil, err
+	}
+	if !employee.IsDeleted() {
+		return employee, nil
+	}
+
+	unlock := s.tenantLocks.Lock(employee.TenantID.String())
+	defer unlock()
+
+	if err := s.checkLimit(ctx, employee.TenantID, 1); err != nil {
+		return nil, err
+	}
+
+	before := *employee
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.checkUniqueness(ctx, employee, uniqueFields{email: true, docNumber: true}); err != nil {
+			return err
+		}
+		employee.Restore()
+		if err := s.employeeRepo.Update(ctx, employee); err != nil {
+			s.logger.Error(err, "Failed to restore employee", "employee_id", id)
+			return err
+		}
+		return s.record(ctx, audit.ActionRestore, &before, employee)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Employee restored", "employee_id", id)
+	return employee, nil
+}
+
+// Erase pseudonymises the employee's personal data (see
+// Employee.Pseudonymise) for a data subject's erasure request. Only
+// terminated or deleted employees can be erased, and erasing one twice is a
+// no-op. The audit entry records that the employee was erased but not the
+// values it replaced. Erasure is allowed for suspended tenants: the request
+// must be honoured regardless of the tenant's plan.
+func (s *Service) Erase(ctx context.Context, id uuid.UUID) (*Employee, error) {
+	employee, err := s.getAnyState(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, auth.PermPrivacyManage, employee); err != nil {
+		return nil, err
+	}
+	if employee.IsErased() {
+		return employee, nil
+	}
+	if !employee.IsDeleted() && employee.Status != StatusTerminated {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Only terminated or deleted employees can be erased")
+	}
+
+	unlock := s.tenantLocks.Lock(employee.TenantID.String())
+	defer unlock()
+
+	employee.Pseudonymise(time.Now().UTC())
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.employeeRepo.Update(ctx, employee); err != nil {
+			s.logger.Error(err, "Failed to erase employee", "employee_id", id)
+			return err
+		}
+		err := s.audit.Record(ctx, audit.Event{
+			TenantID:   employee.TenantID,
+			EntityType: auditEntity,
+			EntityID:   employee.ID,
+			Action:     audit.ActionErase,
+		})
+		if err == nil {
+			err = s.events.Publish(ctx, event.Event{Type: EventErased, TenantID: employee.TenantID, EntityID: employee.ID, Payload: employee.Redacted()})
+		}
+		if err != nil {
+			s.logger.Error(err, "Failed to record employee erasure", "employee_id", id)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Employee erased", "employee_id", id)
+	return employee, nil
+}
+
+// Purge permanently removes the employees soft-deleted before the given time
+// and records each removal in its tenant's audit log. It is a maintenance
+// job that runs across tenants, so it requires auth.PermDataPurge.
+func (s *Service) Purge(ctx context.Context, before time.Time) (int, error) {
+	if err := auth.Authorize(ctx, auth.PermDataPurge, auth.Resource{}, serviceOrigin); err != nil {
+		return 0, err
+	}
+	var purged []*Employee
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if purged, err = s.employeeRepo.PurgeDeleted(ctx, before); err != nil {
+			return err
+		}
+		for _, e := range purged {
+			err := s.audit.Record(ctx, audit.Event{TenantID: e.TenantID, EntityType: auditEntity, EntityID: e.ID, Action: audit.ActionPurge})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(purged), nil
 }
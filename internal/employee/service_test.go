@@ -1 +1,461 @@
 package employee
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/country"
+	"payroll/internal/doctype"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/uow"
+	"payroll/internal/testkit"
+	"payroll/internal/workspace"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubTenants struct {
+	suspended    bool
+	maxEmployees int
+}
+
+func (s *stubTenants) CheckActive(context.Context, uuid.UUID) error {
+	if s.suspended {
+		return apperror.New(apperror.TypeForbidden, "test", "tenant is suspended")
+	}
+	return nil
+}
+
+func (s *stubTenants) CheckEmployeeLimit(ctx context.Context, tenantID uuid.UUID, count int) error {
+	if err := s.CheckActive(ctx, tenantID); err != nil {
+		return err
+	}
+	if s.maxEmployees > 0 && count > s.maxEmployees {
+		return apperror.New(apperror.TypeForbidden, "test", "plan limit exceeded")
+	}
+	return nil
+}
+
+type stubWorkspaceRepo struct {
+	workspace.Repository
+	ws *workspace.Workspace
+}
+
+func (r *stubWorkspaceRepo) Get(_ context.Context, id uuid.UUID) (*workspace.Workspace, error) {
+	if r.ws == nil || r.ws.ID != id {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "workspace not found")
+	}
+	return r.ws, nil
+}
+
+type stubDocTypeRepo struct {
+	doctype.Repository
+	docTypes []*doctype.DocType
+}
+
+func (r *stubDocTypeRepo) IsValidForCountry(_ context.Context, docTypeID, countryID uuid.UUID) (bool, error) {
+	for _, dt := range r.docTypes {
+		if dt.ID == docTypeID {
+			return dt.CountryId == countryID && dt.Active, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *stubDocTypeRepo) Get(_ context.Context, id uuid.UUID) (*doctype.DocType, error) {
+	for _, dt := range r.docTypes {
+		if dt.ID == id {
+			return dt, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "doc type not found")
+}
+
+func (r *stubDocTypeRepo) GetByCountryIDAndCode(_ context.Context, countryID uuid.UUID, code string) (*doctype.DocType, error) {
+	for _, dt := range r.docTypes {
+		if dt.CountryId == countryID && dt.Code == code {
+			return dt, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "doc type not found")
+}
+
+type stubCountryRepo struct {
+	country.Repository
+	country *country.Country
+}
+
+func (r *stubCountryRepo) GetByID(_ context.Context, id uuid.UUID) (*country.Country, error) {
+	if r.country.ID != id {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "country not found")
+	}
+	return r.country, nil
+}
+
+type serviceFixture struct {
+	ctx       context.Context
+	service   *Service
+	employees *MemoryRepository
+	tenants   *stubTenants
+	audits    *testkit.Audit
+	events    *testkit.Publisher
+	workspace *workspace.Workspace
+	docType   *doctype.DocType
+	docTypes  *stubDocTypeRepo
+	// docNumbers applies the default rules; the workspace's country has
+	// none.
+	docNumbers *doctype.Service
+}
+
+func newServiceFixture() *serviceFixture {
+	ws := &workspace.Workspace{TenantID: uuid.New(), CountryID: uuid.New(), Code: "WS"}
+	ws.Initialize()
+	dt := &doctype.DocType{CountryId: ws.CountryID, Code: "NID", Name: "National ID", Active: true}
+	dt.Initialize()
+	c := &country.Country{Code: "ZZ"}
+	c.ID = ws.CountryID
+	docTypes := &stubDocTypeRepo{docTypes: []*doctype.DocType{dt}}
+	docNumbers := doctype.NewService(docTypes, &stubCountryRepo{country: c}, doctype.DefaultRules())
+
+	employees := NewMemoryRepository()
+	tenants := &stubTenants{}
+	audits := &testkit.Audit{}
+	events := &testkit.Publisher{}
+	return &serviceFixture{
+		ctx:        testkit.AdminContext(ws.TenantID),
+		service:    NewService(employees, &stubWorkspaceRepo{ws: ws}, docTypes, docNumbers, tenants, uow.NewMemory(), audits, events, logger.Nop{}),
+		employees:  employees,
+		tenants:    tenants,
+		audits:     audits,
+		events:     events,
+		workspace:  ws,
+		docType:    dt,
+		docTypes:   docTypes,
+		docNumbers: docNumbers,
+	}
+}
+
+func (f *serviceFixture) createParams(email, docNumber string) CreateEmployeeParams {
+	return CreateEmployeeParams{
+		TenantID:    f.workspace.TenantID,
+		WorkspaceID: f.workspace.ID,
+		FirstName:   "Ada",
+		LastName:    "Lovelace",
+		Email:       email,
+		Address:     "12 St James's Square",
+		DocTypeID:   f.docType.ID,
+		DocNumber:   docNumber,
+	}
+}
+
+func TestCreate_RejectsDuplicateEmailAndDocNumber(t *testing.T) {
+	f := newServiceFixture()
+	_, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+
+	_, err = f.service.Create(f.ctx, f.createParams("ADA@example.com", "1001"))
+
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, apperror.TypeDuplicate, domainErr.Type)
+	assert.Contains(t, domainErr.Details, "Email")
+	assert.Contains(t, domainErr.Details, "DocNumber")
+}
+
+func TestCreate_ConcurrentDuplicatesOnlyOneSucceeds(t *testing.T) {
+	f := newServiceFixture()
+	const attempts = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.True(t, apperror.IsType(err, apperror.TypeDuplicate))
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+	assert.Len(t, f.employees.employees, 1)
+}
+
+func TestUpdate_ChecksUniquenessOfChangedFields(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+	_, err = f.service.Create(f.ctx, f.createParams("alan@example.com", "1002"))
+	require.NoError(t, err)
+
+	sameEmail, firstName := "ada@example.com", "Augusta"
+	_, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{Email: &sameEmail, FirstName: &firstName})
+	require.NoError(t, err)
+
+	takenDocNumber := "1002"
+	_, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{DocNumber: &takenDocNumber})
+
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, apperror.TypeDuplicate, domainErr.Type)
+	assert.Equal(t, map[string]string{"DocNumber": "already exists"}, domainErr.Details)
+}
+
+func TestUpdate_RejectsDocTypeOfAnotherCountry(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+
+	otherDocType := uuid.New()
+	_, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{DocTypeID: &otherDocType})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+}
+
+func TestCreate_NormalizesAndValidatesDocNumber(t *testing.T) {
+	f := newServiceFixture()
+	f.docType.Code = "RUT"
+	chile := &country.Country{Code: "CL"}
+	chile.ID = f.workspace.CountryID
+	f.service.docNumbers = doctype.NewService(f.docTypes, &stubCountryRepo{country: chile}, doctype.DefaultRules())
+
+	emp, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "12.345.678-5"))
+	require.NoError(t, err)
+	assert.Equal(t, "123456785", emp.DocNumber)
+
+	_, err = f.service.Create(f.ctx, f.createParams("alan@example.com", "12345678-5"))
+	assert.True(t, apperror.IsType(err, apperror.TypeDuplicate))
+
+	_, err = f.service.Create(f.ctx, f.createParams("grace@example.com", "12.345.678-9"))
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, apperror.TypeInvalid, domainErr.Type)
+	assert.Equal(t, "has an invalid check digit", domainErr.Details["DocNumber"])
+}
+
+func TestDelete_SoftDeletesRestoresAndPurges(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+
+	require.NoError(t, f.service.Delete(f.ctx, ada.ID))
+	_, err = f.service.GetByID(f.ctx, ada.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+
+	// The deleted employee no longer blocks its email, so restoring it
+	// conflicts with the employee that took it.
+	augusta, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1002"))
+	require.NoError(t, err)
+	_, err = f.service.Restore(f.ctx, ada.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeDuplicate))
+
+	require.NoError(t, f.service.Delete(f.ctx, augusta.ID))
+	restored, err := f.service.Restore(f.ctx, ada.ID)
+	require.NoError(t, err)
+	assert.False(t, restored.IsDeleted())
+
+	_, err = f.service.Purge(f.ctx, time.Now().Add(time.Minute))
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden), "tenant admins cannot purge")
+
+	purged, err := f.service.Purge(testkit.PlatformAdminContext(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Len(t, f.employees.employees, 1)
+	last := f.audits.Events[len(f.audits.Events)-1]
+	assert.Equal(t, audit.ActionPurge, last.Action)
+	assert.Equal(t, augusta.ID, last.EntityID)
+	assert.Equal(t, f.workspace.TenantID, last.TenantID)
+}
+
+func TestUpdate_RejectsStaleVersion(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+	read := ada.Version
+
+	firstName := "Augusta"
+	updated, err := f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{FirstName: &firstName, Version: &read})
+	require.NoError(t, err)
+	assert.Equal(t, read+1, updated.Version)
+
+	lastName := "King"
+	_, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{LastName: &lastName, Version: &read})
+	assert.True(t, apperror.IsType(err, apperror.TypeConflict))
+	assert.Equal(t, "Lovelace", f.employees.employees[ada.ID].LastName)
+}
+
+func TestService_DoesNotLeakAcrossTenants(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+
+	other := testkit.AdminContext(uuid.New())
+	firstName := "Mallory"
+
+	_, err = f.service.GetByID(other, ada.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	_, err = f.service.Update(other, ada.ID, UpdateEmployeeParams{FirstName: &firstName})
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	assert.True(t, apperror.IsType(f.service.Delete(other, ada.ID), apperror.TypeNotFound))
+	_, err = f.service.Restore(other, ada.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+
+	page, err := f.service.Search(other, SearchParams{WorkspaceID: f.workspace.ID})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	// Claiming the workspace's tenant does not help, and neither does
+	// omitting it: the tenant comes from the context.
+	_, err = f.service.Create(other, f.createParams("mallory@example.com", "2001"))
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+	params := f.createParams("mallory@example.com", "2001")
+	params.TenantID = uuid.Nil
+	_, err = f.service.Create(other, params)
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+
+	_, err = f.service.GetByID(context.Background(), ada.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+	assert.Equal(t, "Ada", f.employees.employees[ada.ID].FirstName)
+}
+
+func TestService_EnforcesTenantPolicy(t *testing.T) {
+	f := newServiceFixture()
+	f.tenants.maxEmployees = 1
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+
+	_, err = f.service.Create(f.ctx, f.createParams("grace@example.com", "1002"))
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	assert.Len(t, f.employees.employees, 1)
+
+	f.tenants.suspended = true
+	name := "Augusta"
+	_, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{FirstName: &name})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	assert.True(t, apperror.IsType(f.service.Delete(f.ctx, ada.ID), apperror.TypeForbidden))
+
+	got, err := f.service.GetByID(f.ctx, ada.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", got.FirstName)
+}
+
+func TestService_SelfServiceSeesOnlyOwnRecord(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+	grace, err := f.service.Create(f.ctx, f.createParams("grace@example.com", "1002"))
+	require.NoError(t, err)
+
+	self := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "ada", TenantID: f.workspace.TenantID, Roles: []auth.Role{auth.RoleSelfService}, EmployeeID: ada.ID,
+	})
+	_, err = f.service.GetByID(self, ada.ID)
+	assert.NoError(t, err)
+	_, err = f.service.GetByID(self, grace.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	name := "Augusta"
+	_, err = f.service.Update(self, ada.ID, UpdateEmployeeParams{FirstName: &name})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = f.service.ListByWorkspaceIDAndTenantID(self, f.workspace.ID, uuid.Nil)
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+}
+
+func TestUpdate_RecordsFieldChangesAndPublishesEvent(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+
+	docNumber := "2002"
+	ada, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{DocNumber: &docNumber})
+	require.NoError(t, err)
+
+	require.Len(t, f.audits.Events, 2)
+	assert.Equal(t, audit.ActionCreate, f.audits.Events[0].Action)
+	ev := f.audits.Events[1]
+	assert.Equal(t, audit.ActionUpdate, ev.Action)
+	assert.Equal(t, f.workspace.TenantID, ev.TenantID)
+	changes, err := audit.Diff(ev.Before, ev.After)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "DocNumber", changes[0].Field)
+	assert.JSONEq(t, `"REDACTED"`, string(changes[0].Before), "personal data is not logged")
+	assert.JSONEq(t, `"REDACTED"`, string(changes[0].After))
+
+	require.Len(t, f.events.Events, 2)
+	assert.Equal(t, EventCreated, f.events.Events[0].Type)
+	assert.Equal(t, EventUpdated, f.events.Events[1].Type)
+	assert.Equal(t, ada.ID, f.events.Events[1].EntityID)
+	payload := f.events.Events[1].Payload.(*Employee)
+	assert.Empty(t, payload.DocNumber, "personal data is not published")
+	assert.Empty(t, payload.Email)
+	assert.Equal(t, "2002", ada.DocNumber, "only the payload is redacted")
+	assert.Equal(t, f.workspace.ID, payload.WorkspaceID)
+}
+
+func TestUpdate_AppliesAddressAndBankAccount(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+
+	address, account := " 1 Main St ", "DE89 3704 0044"
+	ada, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{Address: &address, BankAccount: &account})
+	require.NoError(t, err)
+	assert.Equal(t, "1 Main St", ada.Address)
+	assert.Equal(t, "DE89 3704 0044", *ada.BankAccount)
+
+	cleared := ""
+	ada, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{BankAccount: &cleared})
+	require.NoError(t, err)
+	assert.Nil(t, ada.BankAccount)
+}
+
+func TestErase_PseudonymisesTerminatedEmployeesWithoutAuditingValues(t *testing.T) {
+	f := newServiceFixture()
+	ada, err := f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	require.NoError(t, err)
+
+	_, err = f.service.Erase(f.ctx, ada.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid), "active employees cannot be erased")
+
+	terminated := string(StatusTerminated)
+	_, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{Status: &terminated})
+	require.NoError(t, err)
+	ada, err = f.service.Erase(f.ctx, ada.ID)
+	require.NoError(t, err)
+
+	assert.True(t, ada.IsErased())
+	assert.Equal(t, "Erased", ada.FirstName)
+	assert.NotContains(t, ada.Email, "ada@example.com")
+	assert.NotEqual(t, "1001", ada.DocNumber)
+	assert.Empty(t, ada.Address)
+	assert.Equal(t, StatusTerminated, ada.Status)
+
+	ev := f.audits.Events[len(f.audits.Events)-1]
+	assert.Equal(t, audit.ActionErase, ev.Action)
+	assert.Nil(t, ev.Before)
+	assert.Nil(t, ev.After)
+	assert.Equal(t, EventErased, f.events.Events[len(f.events.Events)-1].Type)
+
+	address := "1 Main St"
+	_, err = f.service.Update(f.ctx, ada.ID, UpdateEmployeeParams{Address: &address})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+	_, err = f.service.Create(f.ctx, f.createParams("ada@example.com", "1001"))
+	assert.NoError(t, err, "the erased email and document number are free again")
+}
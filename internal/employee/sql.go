@@ -0,0 +1,387 @@
+package employee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"payroll/internal/apperror"
+	"payroll/internal/platform/uow"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const sqlOrigin = "SQLEmployeeRepository"
+
+// SQLSchema creates the table SQLRepository stores employees in. The sealed
+// fields hold ciphertext, and the *_key columns hold the sort keys of
+// SearchFilter.SortKey, computed when the row is written. The partial
+// unique indexes enforce the per-tenant uniqueness of live employees.
+//
+// It is valid for PostgreSQL and SQLite. On PostgreSQL the key columns and
+// id must compare bytewise, as with the "C" collation, for searches to
+// follow SearchFilter.Compare.
+const SQLSchema = `
+CREATE TABLE employees (
+	id               TEXT PRIMARY KEY,
+	version          BIGINT NOT NULL,
+	created_at       TIMESTAMP NOT NULL,
+	updated_at       TIMESTAMP NOT NULL,
+	deleted_at       TIMESTAMP,
+	tenant_id        TEXT NOT NULL,
+	workspace_id     TEXT NOT NULL,
+	first_name       TEXT NOT NULL,
+	last_name        TEXT NOT NULL,
+	doc_type_id      TEXT NOT NULL,
+	status           TEXT NOT NULL,
+	hire_date        TIMESTAMP,
+	gender           TEXT,
+	phone            TEXT,
+	department       TEXT,
+	erased_at        TIMESTAMP,
+	email            TEXT NOT NULL,
+	address          TEXT NOT NULL,
+	doc_number       TEXT NOT NULL,
+	birth_date       TEXT NOT NULL,
+	bank_account     TEXT NOT NULL,
+	key_id           TEXT NOT NULL,
+	email_index      TEXT NOT NULL,
+	doc_number_index TEXT NOT NULL,
+	first_name_key   TEXT NOT NULL,
+	last_name_key    TEXT NOT NULL,
+	hire_date_key    TEXT NOT NULL
+);
+CREATE UNIQUE INDEX employees_email_index ON employees (tenant_id, email_index) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX employees_doc_number_index ON employees (tenant_id, doc_number_index) WHERE deleted_at IS NULL;
+CREATE INDEX employees_workspace ON employees (tenant_id, workspace_id, id);
+`
+
+const sqlColumns = `id, version, created_at, updated_at, deleted_at, tenant_id, workspace_id,
+	first_name, last_name, doc_type_id, status, hire_date, gender, phone, department, erased_at,
+	email, address, doc_number, birth_date, bank_account, key_id, email_index, doc_number_index,
+	first_name_key, last_name_key, hire_date_key`
+
+// sortColumns are the columns holding SearchFilter.SortKey for each sort.
+var sortColumns = map[SortField]string{
+	SortByLastName: "last_name_key",
+	SortByHireDate: "hire_date_key",
+}
+
+// SQLRepository is a SealedRepository backed by database/sql, to be wrapped
+// in an EncryptedRepository. It runs its statements on uow.GetConn, so it
+// takes part in uow.SQL units of work. Statements use $n placeholders.
+type SQLRepository struct {
+	db *sql.DB
+}
+
+var _ SealedRepository = (*SQLRepository)(nil)
+
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+func (r *SQLRepository) Create(ctx context.Context, employee *SealedEmployee) error {
+	_, err := uow.GetConn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO employees (`+sqlColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27)`,
+		sqlValues(employee)...)
+	return r.writeError(err)
+}
+
+func (r *SQLRepository) ListByWorkspaceIDAndTenantID(ctx context.Context, workspaceID uuid.UUID, tenantID uuid.UUID) ([]*SealedEmployee, error) {
+	return r.query(ctx, `SELECT `+sqlColumns+` FROM employees
+		WHERE workspace_id = $1 AND tenant_id = $2 AND deleted_at IS NULL ORDER BY id`, workspaceID, tenantID)
+}
+
+// Search translates filter as described on SearchFilter.
+func (r *SQLRepository) Search(ctx context.Context, filter SearchFilter) ([]*SealedEmployee, error) {
+	where := []string{"tenant_id = $1", "workspace_id = $2", "deleted_at IS NULL"}
+	args := []any{filter.TenantID, filter.WorkspaceID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.NamePrefix != "" {
+		prefix := arg(escapeLike(strings.ToLower(filter.NamePrefix)) + "%")
+		where = append(where, fmt.Sprintf(`(first_name_key LIKE %[1]s ESCAPE '\' OR last_name_key LIKE %[1]s ESCAPE '\')`, prefix))
+	}
+	if filter.Email != "" {
+		where = append(where, "email_index = "+arg(filter.Email))
+	}
+	if filter.DocNumber != "" {
+		where = append(where, "doc_number_index = "+arg(filter.DocNumber))
+	}
+	if filter.Gender != nil {
+		where = append(where, "gender = "+arg(string(*filter.Gender)))
+	}
+	if filter.Status != nil {
+		where = append(where, "status = "+arg(string(*filter.Status)))
+	}
+	if filter.HiredFrom != nil || filter.HiredTo != nil {
+		where = append(where, "hire_date_key <> ''")
+		if filter.HiredFrom != nil {
+			where = append(where, "hire_date_key >= "+arg(filter.HiredFrom.Format(searchDateLayout)))
+		}
+		if filter.HiredTo != nil {
+			where = append(where, "hire_date_key <= "+arg(filter.HiredTo.Format(searchDateLayout)))
+		}
+	}
+
+	cmp, dir := ">", "ASC"
+	if filter.Order == SortDesc {
+		cmp, dir = "<", "DESC"
+	}
+	order := "id " + dir
+	key, sorted := sortColumns[filter.SortBy]
+	if sorted {
+		order = key + " " + dir + ", " + order
+	}
+	if filter.After != uuid.Nil {
+		if sorted {
+			where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", key, cmp, arg(filter.AfterKey), arg(filter.After)))
+		} else {
+			where = append(where, fmt.Sprintf("id %s %s", cmp, arg(filter.After)))
+		}
+	}
+
+	query := `SELECT ` + sqlColumns + ` FROM employees WHERE ` + strings.Join(where, " AND ") + ` ORDER BY ` + order
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	return r.query(ctx, query, args...)
+}
+
+func (r *SQLRepository) GetByID(ctx context.Context, id uuid.UUID) (*SealedEmployee, error) {
+	row := uow.GetConn(ctx, r.db).QueryRowContext(ctx, `SELECT `+sqlColumns+` FROM employees WHERE id = $1`, id)
+	employee, err := scanSealed(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.New(apperror.TypeNotFound, sqlOrigin, "Employee not found")
+	}
+	return employee, err
+}
+
+func (r *SQLRepository) ListByIDs(ctx context.Context, ids []uuid.UUID) ([]*SealedEmployee, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	return r.query(ctx, `SELECT `+sqlColumns+` FROM employees WHERE id IN (`+strings.Join(placeholders, ", ")+`) ORDER BY id`, args...)
+}
+
+// Update stores employee if the stored version is still its Version, and
+// increments Version.
+func (r *SQLRepository) Update(ctx context.Context, employee *SealedEmployee) error {
+	conn := uow.GetConn(ctx, r.db)
+	values := sqlValues(employee)
+	res, err := conn.ExecContext(ctx,
+		`UPDATE employees SET version = version + 1, created_at = $3, updated_at = $4, deleted_at = $5,
+			tenant_id = $6, workspace_id = $7, first_name = $8, last_name = $9, doc_type_id = $10,
+			status = $11, hire_date = $12, gender = $13, phone = $14, department = $15, erased_at = $16,
+			email = $17, address = $18, doc_number = $19, birth_date = $20, bank_account = $21,
+			key_id = $22, email_index = $23, doc_number_index = $24,
+			first_name_key = $25, last_name_key = $26, hire_date_key = $27
+		WHERE id = $1 AND version = $2`,
+		values...)
+	if err != nil {
+		return r.writeError(err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		if _, err := r.GetByID(ctx, employee.Employee.ID); err != nil {
+			return err
+		}
+		return apperror.NewConflictError(sqlOrigin, "The employee was modified since it was read")
+	}
+	read := employee.Employee.Version
+	employee.Employee.Version++
+	uow.OnRollback(ctx, func() { employee.Employee.Version = read })
+	return nil
+}
+
+func (r *SQLRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := uow.GetConn(ctx, r.db).ExecContext(ctx, `DELETE FROM employees WHERE id = $1`, id)
+	return err
+}
+
+func (r *SQLRepository) PurgeDeleted(ctx context.Context, before time.Time) ([]*SealedEmployee, error) {
+	return r.query(ctx, `DELETE FROM employees WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING `+sqlColumns, before.UTC())
+}
+
+func (r *SQLRepository) ExistsByTenantIDAndDocNumber(ctx context.Context, tenantID uuid.UUID, docNumberIndex string) (bool, error) {
+	return r.exists(ctx, `tenant_id = $1 AND doc_number_index = $2 AND deleted_at IS NULL`, tenantID, docNumberIndex)
+}
+
+func (r *SQLRepository) ExistsByTenantIDAndEmail(ctx context.Context, tenantID uuid.UUID, emailIndex string) (bool, error) {
+	return r.exists(ctx, `tenant_id = $1 AND email_index = $2 AND deleted_at IS NULL`, tenantID, emailIndex)
+}
+
+func (r *SQLRepository) CountByTenantID(ctx context.Context, tenantID uuid.UUID) (int, error) {
+	var count int
+	err := uow.GetConn(ctx, r.db).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM employees WHERE tenant_id = $1 AND deleted_at IS NULL`, tenantID).Scan(&count)
+	return count, err
+}
+
+func (r *SQLRepository) ListNotSealedWith(ctx context.Context, keyID string, limit int) ([]*SealedEmployee, error) {
+	return r.query(ctx, `SELECT `+sqlColumns+` FROM employees WHERE key_id <> $1 ORDER BY id LIMIT $2`, keyID, limit)
+}
+
+func (r *SQLRepository) UpdateSealing(ctx context.Context, employee *SealedEmployee) (bool, error) {
+	res, err := uow.GetConn(ctx, r.db).ExecContext(ctx,
+		`UPDATE employees SET email = $3, address = $4, doc_number = $5, birth_date = $6, bank_account = $7, key_id = $8
+		WHERE id = $1 AND version = $2`,
+		employee.Employee.ID, employee.Employee.Version,
+		employee.Email, employee.Address, employee.DocNumber, employee.BirthDate, employee.BankAccount, employee.KeyID)
+	if err != nil {
+		return false, err
+	}
+	updated, err := res.RowsAffected()
+	return updated == 1, err
+}
+
+func (r *SQLRepository) exists(ctx context.Context, where string, args ...any) (bool, error) {
+	var exists bool
+	err := uow.GetConn(ctx, r.db).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM employees WHERE `+where+`)`, args...).Scan(&exists)
+	return exists, err
+}
+
+func (r *SQLRepository) query(ctx context.Context, query string, args ...any) ([]*SealedEmployee, error) {
+	rows, err := uow.GetConn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*SealedEmployee
+	for rows.Next() {
+		employee, err := scanSealed(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, employee)
+	}
+	return list, rows.Err()
+}
+
+// writeError reports a violation of the unique indexes of SQLSchema as a
+// TypeDuplicate error. Drivers do not share an error type for it, so it is
+// recognised by the message, which names the violated index or its columns
+// on both PostgreSQL and SQLite.
+func (r *SQLRepository) writeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	if !strings.Contains(msg, "unique constraint") {
+		return err
+	}
+	details := make(map[string]string)
+	if strings.Contains(msg, "email_index") {
+		details["Email"] = "already exists"
+	}
+	if strings.Contains(msg, "doc_number_index") {
+		details["DocNumber"] = "already exists"
+	}
+	if len(details) == 0 {
+		details["ID"] = "already exists"
+	}
+	return apperror.NewDuplicateError(sqlOrigin, details)
+}
+
+// sqlValues returns the values of sqlColumns for employee, in order.
+func sqlValues(s *SealedEmployee) []any {
+	e := &s.Employee
+	return []any{
+		e.ID, e.Version, e.CreatedAt.UTC(), e.UpdatedAt.UTC(), utcOrNil(e.DeletedAt), e.TenantID, e.WorkspaceID,
+		e.FirstName, e.LastName, e.DocTypeID, string(e.Status), hireDate(e.HireDate), nullString((*string)(e.Gender)), nullString(e.Phone), nullString(e.Department), utcOrNil(e.ErasedAt),
+		s.Email, s.Address, s.DocNumber, s.BirthDate, s.BankAccount, s.KeyID, s.EmailIndex, s.DocNumberIndex,
+		strings.ToLower(e.FirstName), SearchFilter{SortBy: SortByLastName}.SortKey(e), SearchFilter{SortBy: SortByHireDate}.SortKey(e),
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSealed(row scanner) (*SealedEmployee, error) {
+	var (
+		s                               SealedEmployee
+		e                               = &s.Employee
+		status                          string
+		deletedAt, hireDate, erasedAt   sql.NullTime
+		gender, phone, department       sql.NullString
+		firstNameKey, lastNameKey, hire string
+	)
+	err := row.Scan(
+		&e.ID, &e.Version, &e.CreatedAt, &e.UpdatedAt, &deletedAt, &e.TenantID, &e.WorkspaceID,
+		&e.FirstName, &e.LastName, &e.DocTypeID, &status, &hireDate, &gender, &phone, &department, &erasedAt,
+		&s.Email, &s.Address, &s.DocNumber, &s.BirthDate, &s.BankAccount, &s.KeyID, &s.EmailIndex, &s.DocNumberIndex,
+		&firstNameKey, &lastNameKey, &hire,
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.Status = EmployeeStatus(status)
+	e.DeletedAt = timeOrNil(deletedAt)
+	e.HireDate = timeOrNil(hireDate)
+	e.ErasedAt = timeOrNil(erasedAt)
+	if gender.Valid {
+		g := EmployeeGender(gender.String)
+		e.Gender = &g
+	}
+	e.Phone = stringOrNil(phone)
+	e.Department = stringOrNil(department)
+	return &s, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern with a backslash.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// hireDate keeps the wall clock of the hire date, which SortKey and the
+// filters read the date from.
+func hireDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func timeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func stringOrNil(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
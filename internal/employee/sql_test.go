@@ -0,0 +1,59 @@
+package employee
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteRepository returns a SQLRepository on a fresh in-memory SQLite
+// database created from SQLSchema.
+func newSQLiteRepository(t *testing.T) *SQLRepository {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	// Every connection would open a database of its own.
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	_, err = db.Exec(SQLSchema)
+	require.NoError(t, err)
+	return NewSQLRepository(db)
+}
+
+func TestSQLRepository_RotationSkipsUpdatedEmployees(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteRepository(t)
+	repo := NewEncryptedRepository(store, testKeys(t, "k1", "k1"))
+	e := conformanceEmployee(uuid.New(), uuid.New(), "Ada", "Lovelace", "1000")
+	require.NoError(t, repo.Create(ctx, e))
+
+	batch, err := store.ListNotSealedWith(ctx, "k2", 10)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, "k1", batch[0].KeyID)
+	none, err := store.ListNotSealedWith(ctx, "k1", 10)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	stale := *batch[0]
+	require.NoError(t, repo.Update(ctx, e))
+	stale.KeyID = "k2"
+	ok, err := store.UpdateSealing(ctx, &stale)
+	require.NoError(t, err)
+	assert.False(t, ok, "the employee was updated since the batch was read")
+
+	fresh, err := store.GetByID(ctx, e.ID)
+	require.NoError(t, err)
+	fresh.KeyID = "k2"
+	ok, err = store.UpdateSealing(ctx, fresh)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	got, err := repo.GetByID(ctx, e.ID)
+	require.NoError(t, err)
+	assert.Equal(t, e.Email, got.Email)
+}
@@ -10,12 +10,14 @@ import (
 )
 
 const (
-	maxFirstNameLength = 50
-	maxLastNameLength  = 50
-	maxEmailLength     = 100
-	maxAddressLength   = 200
-	maxDocNumberLength = 50
-	maxPhoneLength     = 20
+	maxFirstNameLength   = 50
+	maxLastNameLength    = 50
+	maxEmailLength       = 100
+	maxAddressLength     = 200
+	maxDocNumberLength   = 50
+	maxPhoneLength       = 20
+	maxDepartmentLength  = 50
+	maxBankAccountLength = 50
 )
 
 type Validator struct {
@@ -75,6 +77,12 @@ func (v *Validator) ValidateBirthDate(birthDate *time.Time) {
 	}
 }
 
+func (v *Validator) ValidateStatus(status *string) {
+	if status != nil && !EmployeeStatus(*status).IsValid() {
+		v.AddError("Status", "is invalid")
+	}
+}
+
 func (v *Validator) ValidateGender(gender *string) {
 	if gender != nil {
 		g := EmployeeGender(*gender)
@@ -95,3 +103,15 @@ func (v *Validator) ValidateAddress(address *string) {
 		v.AddError("Address", fmt.Sprintf("must be less than %d characters", maxAddressLength))
 	}
 }
+
+func (v *Validator) ValidateBankAccount(account *string) {
+	if account != nil && len(*account) > maxBankAccountLength {
+		v.AddError("BankAccount", fmt.Sprintf("must be less than %d characters", maxBankAccountLength))
+	}
+}
+
+func (v *Validator) ValidateDepartment(department *string) {
+	if department != nil && len(*department) > maxDepartmentLength {
+		v.AddError("Department", fmt.Sprintf("must be less than %d characters", maxDepartmentLength))
+	}
+}
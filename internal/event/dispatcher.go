@@ -0,0 +1,182 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"payroll/internal/platform/logger"
+	"time"
+)
+
+// Handler handles a message. An error means the message was not handled
+// and is delivered again later.
+type Handler interface {
+	Handle(ctx context.Context, m *Message) error
+}
+
+type HandlerFunc func(ctx context.Context, m *Message) error
+
+func (f HandlerFunc) Handle(ctx context.Context, m *Message) error {
+	return f(ctx, m)
+}
+
+// Subscription routes messages to a handler. Name identifies it in the
+// outbox's delivery state, so it must be unique and should not change
+// between deployments.
+type Subscription struct {
+	Name    string
+	Handler Handler
+	// Types limits the subscription to these event types. Empty means all.
+	Types []string
+}
+
+func (s Subscription) wants(eventType string) bool {
+	if len(s.Types) == 0 {
+		return true
+	}
+	for _, t := range s.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type DispatcherConfig struct {
+	// BatchSize is how many entries one DispatchDue call takes at most.
+	// Defaults to 100.
+	BatchSize int
+	// MaxAttempts is how many times delivery is tried before the entry is
+	// marked failed. Defaults to 10.
+	MaxAttempts int
+	// Backoff returns the delay after the given failed attempt (1-based).
+	// Defaults to ExponentialBackoff(30s, 1h).
+	Backoff func(attempt int) time.Duration
+}
+
+// ExponentialBackoff doubles the delay after every attempt, starting at base
+// and capped at max.
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		return min(d, max)
+	}
+}
+
+type Dispatcher struct {
+	store         Store
+	config        DispatcherConfig
+	subscriptions []Subscription
+	logger        logger.Logger
+	now           func() time.Time
+}
+
+func NewDispatcher(s Store, config DispatcherConfig, l logger.Logger, subscriptions ...Subscription) (*Dispatcher, error) {
+	names := make(map[string]bool, len(subscriptions))
+	for _, sub := range subscriptions {
+		if sub.Name == "" || sub.Handler == nil {
+			return nil, fmt.Errorf("event: subscription %q needs a name and a handler", sub.Name)
+		}
+		if names[sub.Name] {
+			return nil, fmt.Errorf("event: duplicate subscription %q", sub.Name)
+		}
+		names[sub.Name] = true
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 10
+	}
+	if config.Backoff == nil {
+		config.Backoff = ExponentialBackoff(30*time.Second, time.Hour)
+	}
+	return &Dispatcher{
+		store:         s,
+		config:        config,
+		subscriptions: subscriptions,
+		logger:        l,
+		now:           time.Now,
+	}, nil
+}
+
+// DispatchDue delivers one batch of due entries and returns how many were
+// fully dispatched. A failing subscription only delays its own delivery:
+// the others are not retried, and other entries are not held back.
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	entries, err := d.store.Due(ctx, d.now().UTC(), d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	var errs []error
+	for _, e := range entries {
+		d.deliver(ctx, e)
+		if err := d.store.Update(ctx, e); err != nil {
+			d.logger.Error(err, "Failed to save outbox entry", "message_id", e.ID)
+			errs = append(errs, err)
+			continue
+		}
+		if e.DispatchedAt != nil {
+			dispatched++
+		}
+	}
+	return dispatched, errors.Join(errs...)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, e *Entry) {
+	var errs []error
+	for _, sub := range d.subscriptions {
+		if !sub.wants(e.Type) || e.IsDelivered(sub.Name) {
+			continue
+		}
+		if err := sub.Handler.Handle(ctx, &e.Message); err != nil {
+			d.logger.Warn("Event delivery failed", "message_id", e.ID, "type", e.Type, "subscription", sub.Name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", sub.Name, err))
+			continue
+		}
+		e.Delivered = append(e.Delivered, sub.Name)
+	}
+
+	now := d.now().UTC()
+	e.Attempts++
+	if len(errs) == 0 {
+		e.LastError = ""
+		e.DispatchedAt = &now
+		return
+	}
+	e.LastError = errors.Join(errs...).Error()
+	if e.Attempts >= d.config.MaxAttempts {
+		e.FailedAt = &now
+		d.logger.Error(errors.Join(errs...), "Giving up on event delivery", "message_id", e.ID, "type", e.Type, "attempts", e.Attempts)
+		return
+	}
+	e.NextAttemptAt = now.Add(d.config.Backoff(e.Attempts))
+}
+
+// Run dispatches due entries every interval until ctx is done. Batches are
+// taken back to back while they come back full.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := d.DispatchDue(ctx)
+			if err != nil {
+				d.logger.Error(err, "Event dispatch failed")
+			}
+			if err != nil || n < d.config.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
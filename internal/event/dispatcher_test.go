@@ -0,0 +1,148 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/uow"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	entries []*Entry
+}
+
+func (s *memoryStore) Add(ctx context.Context, entries []*Entry) error {
+	n := len(s.entries)
+	s.entries = append(s.entries, entries...)
+	uow.OnRollback(ctx, func() { s.entries = s.entries[:n] })
+	return nil
+}
+
+func (s *memoryStore) Due(_ context.Context, now time.Time, limit int) ([]*Entry, error) {
+	var due []*Entry
+	for _, e := range s.entries {
+		if e.DispatchedAt == nil && e.FailedAt == nil && !e.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, e)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) Update(context.Context, *Entry) error {
+	return nil
+}
+
+func (s *memoryStore) ScrubByEntityID(_ context.Context, tenantID, entityID uuid.UUID) (int, error) {
+	n := 0
+	for _, e := range s.entries {
+		if e.TenantID == tenantID && e.EntityID == entityID {
+			e.Payload = json.RawMessage("null")
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestOutbox_PublishesOnlyWhenTheUnitOfWorkCommits(t *testing.T) {
+	store := &memoryStore{}
+	u := uow.NewMemory()
+	outbox := NewOutbox(store, u)
+	ev := Event{Type: "EmployeeCreated", TenantID: uuid.New(), EntityID: uuid.New(), Payload: map[string]string{"name": "Ada"}}
+
+	err := u.Do(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, outbox.Publish(ctx, ev))
+		return errors.New("write failed")
+	})
+	require.Error(t, err)
+	assert.Empty(t, store.entries)
+
+	require.NoError(t, u.Do(context.Background(), func(ctx context.Context) error {
+		return outbox.Publish(ctx, ev)
+	}))
+	require.Len(t, store.entries, 1)
+	assert.Equal(t, "EmployeeCreated", store.entries[0].Type)
+	assert.JSONEq(t, `{"name":"Ada"}`, string(store.entries[0].Payload))
+}
+
+func TestDispatcher_RetriesOnlyFailedSubscriptions(t *testing.T) {
+	store := &memoryStore{}
+	require.NoError(t, NewOutbox(store, uow.NewMemory()).Publish(context.Background(),
+		Event{Type: "EmployeeCreated"}, Event{Type: "WorkspaceCreated"}))
+
+	var audited, notified []string
+	failing := true
+	d, err := NewDispatcher(store, DispatcherConfig{MaxAttempts: 3, Backoff: func(int) time.Duration { return time.Minute }}, logger.Nop{},
+		Subscription{Name: "audit", Handler: HandlerFunc(func(_ context.Context, m *Message) error {
+			audited = append(audited, m.Type)
+			return nil
+		})},
+		Subscription{Name: "notify", Types: []string{"EmployeeCreated"}, Handler: HandlerFunc(func(_ context.Context, m *Message) error {
+			if failing {
+				return errors.New("unavailable")
+			}
+			notified = append(notified, m.Type)
+			return nil
+		})},
+	)
+	require.NoError(t, err)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	n, err := d.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	employee := store.entries[0]
+	assert.Equal(t, []string{"audit"}, employee.Delivered)
+	assert.Equal(t, "notify: unavailable", employee.LastError)
+	assert.Equal(t, now.UTC().Add(time.Minute), employee.NextAttemptAt)
+
+	n, err = d.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "not due before the backoff")
+
+	failing = false
+	now = now.Add(time.Minute)
+	n, err = d.DispatchDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"EmployeeCreated", "WorkspaceCreated"}, audited)
+	assert.Equal(t, []string{"EmployeeCreated"}, notified)
+	assert.Equal(t, 2, employee.Attempts)
+	assert.NotNil(t, employee.DispatchedAt)
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	store := &memoryStore{}
+	require.NoError(t, NewOutbox(store, uow.NewMemory()).Publish(context.Background(), Event{Type: "PayrollRunFinalized"}))
+	d, err := NewDispatcher(store, DispatcherConfig{MaxAttempts: 2, Backoff: func(int) time.Duration { return 0 }}, logger.Nop{},
+		Subscription{Name: "sink", Handler: HandlerFunc(func(context.Context, *Message) error { return errors.New("down") })})
+	require.NoError(t, err)
+
+	for range 3 {
+		_, err := d.DispatchDue(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, store.entries[0].Attempts)
+	assert.NotNil(t, store.entries[0].FailedAt)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(30*time.Second, 5*time.Minute)
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, 2*time.Minute, backoff(3))
+	assert.Equal(t, 5*time.Minute, backoff(10))
+}
+
+func TestNewDispatcher_RejectsDuplicateSubscriptions(t *testing.T) {
+	h := HandlerFunc(func(context.Context, *Message) error { return nil })
+	_, err := NewDispatcher(&memoryStore{}, DispatcherConfig{}, logger.Nop{}, Subscription{Name: "a", Handler: h}, Subscription{Name: "a", Handler: h})
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+// Package event publishes domain events through a transactional outbox.
+//
+// Services publish events in the unit of work of the state change they
+// describe, so an event is stored if and only if the change is committed. A
+// Dispatcher later delivers the stored events to subscribers, which may be
+// in-process handlers or adapters to external sinks. Delivery is at least
+// once: a subscriber can see the same message again after a failure or a
+// crash, and should use Message.ID to ignore duplicates.
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"payroll/internal/audit"
+	"payroll/internal/platform/uow"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a change that services publish. Payload is encoded as JSON when
+// the event is published, so later changes to it are not seen.
+type Event struct {
+	Type     string
+	TenantID uuid.UUID
+	// EntityID identifies what changed, e.g. the employee.
+	EntityID uuid.UUID
+	Payload  any
+}
+
+// Message is a published event as subscribers receive it.
+type Message struct {
+	ID         uuid.UUID
+	Type       string
+	TenantID   uuid.UUID
+	EntityID   uuid.UUID
+	OccurredAt time.Time
+	RequestID  string
+	Payload    json.RawMessage
+}
+
+// Entry is a message in the outbox along with the state of its delivery.
+type Entry struct {
+	Message
+	Attempts      int
+	NextAttemptAt time.Time
+	// Delivered lists the subscriptions that have handled the message, so a
+	// retry only goes to the others.
+	Delivered []string
+	LastError string
+	// DispatchedAt is set once every subscription has handled the message.
+	DispatchedAt *time.Time
+	// FailedAt is set when delivery is given up after the last attempt.
+	FailedAt *time.Time
+}
+
+func (e *Entry) IsDelivered(subscription string) bool {
+	for _, name := range e.Delivered {
+		if name == subscription {
+			return true
+		}
+	}
+	return false
+}
+
+// Publisher stores events for delivery. Call it inside the unit of work of
+// the change the events describe.
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// Store is the outbox table. Its writes must take part in the unit of work
+// of the context they are given.
+type Store interface {
+	Add(ctx context.Context, entries []*Entry) error
+	// Due returns up to limit entries that are neither dispatched nor failed
+	// and whose NextAttemptAt is not after now, oldest first. With several
+	// dispatchers, implementations should claim the entries they return
+	// (e.g. with SELECT ... FOR UPDATE SKIP LOCKED and a lease) to limit
+	// duplicate deliveries.
+	Due(ctx context.Context, now time.Time, limit int) ([]*Entry, error)
+	// Update stores the delivery state of e. The message does not change
+	// after Add, except through ScrubByEntityID.
+	Update(ctx context.Context, e *Entry) error
+	// ScrubByEntityID replaces the payload of the tenant's entries about the
+	// entity with JSON null, e.g. when its personal data is erased, and
+	// returns how many there were. The entries are still delivered.
+	ScrubByEntityID(ctx context.Context, tenantID, entityID uuid.UUID) (int, error)
+}
+
+// Outbox is the Publisher that stores events in a Store.
+type Outbox struct {
+	store Store
+	uow   uow.UnitOfWork
+	now   func() time.Time
+}
+
+func NewOutbox(s Store, u uow.UnitOfWork) *Outbox {
+	return &Outbox{store: s, uow: u, now: time.Now}
+}
+
+func (o *Outbox) Publish(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	now := o.now().UTC()
+	entries := make([]*Entry, 0, len(events))
+	for _, ev := range events {
+		payload, err := json.Marshal(ev.Payload)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, &Entry{
+			Message: Message{
+				ID:         uuid.Must(uuid.NewV7()),
+				Type:       ev.Type,
+				TenantID:   ev.TenantID,
+				EntityID:   ev.EntityID,
+				OccurredAt: now,
+				RequestID:  audit.RequestID(ctx),
+				Payload:    payload,
+			},
+			NextAttemptAt: now,
+		})
+	}
+	// Joins the caller's unit of work; starts one only for callers that
+	// publish on their own.
+	return o.uow.Do(ctx, func(ctx context.Context) error {
+		return o.store.Add(ctx, entries)
+	})
+}
@@ -0,0 +1,124 @@
+package exchange
+
+import (
+	"context"
+	"math/big"
+	"payroll/internal/apperror"
+	"payroll/internal/money"
+	"strings"
+	"time"
+)
+
+const (
+	modelOrigin = "ExchangeRate"
+	// rateDecimals is the precision used when a rate is rendered as text.
+	rateDecimals = 10
+)
+
+// Rate is the price of one unit of Base expressed in Quote, effective from
+// Date until a newer rate for the same pair is published.
+type Rate struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Value *big.Rat
+}
+
+type CreateRateParams struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Value string
+}
+
+func NewRate(params CreateRateParams) (*Rate, error) {
+	validator := NewValidator()
+
+	params.Base = strings.ToUpper(strings.TrimSpace(params.Base))
+	params.Quote = strings.ToUpper(strings.TrimSpace(params.Quote))
+	params.Value = strings.TrimSpace(params.Value)
+
+	validator.ValidateDate(params.Date)
+	validator.ValidateCurrency("Base", params.Base)
+	validator.ValidateCurrency("Quote", params.Quote)
+	if params.Base != "" && params.Base == params.Quote {
+		validator.AddError("Quote", "must differ from Base")
+	}
+	value := validator.ValidateValue(params.Value)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	return &Rate{
+		Date:  truncateToDay(params.Date),
+		Base:  params.Base,
+		Quote: params.Quote,
+		Value: value,
+	}, nil
+}
+
+func truncateToDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// String renders the rate as a plain decimal without trailing zeros.
+func (r *Rate) String() string {
+	s := r.Value.FloatString(rateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Inverse returns the rate of the opposite pair on the same date.
+func (r *Rate) Inverse() *Rate {
+	return &Rate{
+		Date:  r.Date,
+		Base:  r.Quote,
+		Quote: r.Base,
+		Value: new(big.Rat).Inv(r.Value),
+	}
+}
+
+// Apply converts an amount in Base minor units into Quote minor units,
+// taking the minor-unit digits of both currencies into account and rounding
+// half away from zero.
+func (r *Rate) Apply(amount money.Amount) money.Amount {
+	value := new(big.Rat).Mul(big.NewRat(int64(amount), 1), r.Value)
+
+	shift := money.MinorUnits(r.Quote) - money.MinorUnits(r.Base)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil)
+	if shift >= 0 {
+		value.Mul(value, new(big.Rat).SetInt(scale))
+	} else {
+		value.Quo(value, new(big.Rat).SetInt(scale))
+	}
+
+	return money.Amount(roundHalfAwayFromZero(value))
+}
+
+func roundHalfAwayFromZero(r *big.Rat) int64 {
+	num := new(big.Int).Abs(r.Num())
+	den := r.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(rem, big.NewInt(2)).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return quo.Int64()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+type Repository interface {
+	Save(ctx context.Context, rates []*Rate) error
+	// GetLatest returns the newest rate for the pair dated on or before date.
+	GetLatest(ctx context.Context, base, quote string, date time.Time) (*Rate, error)
+}
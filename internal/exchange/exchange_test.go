@@ -0,0 +1,81 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"payroll/internal/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRate(t *testing.T, base, quote, value string) *Rate {
+	t.Helper()
+	rate, err := NewRate(CreateRateParams{
+		Date:  time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC),
+		Base:  base,
+		Quote: quote,
+		Value: value,
+	})
+	require.NoError(t, err)
+	return rate
+}
+
+func TestRate_ApplyHonoursMinorUnits(t *testing.T) {
+	usdJpy := newTestRate(t, "usd", "jpy", "151.235")
+	// 1,000.00 USD -> 151,235 JPY
+	assert.Equal(t, money.Amount(151_235), usdJpy.Apply(100_000))
+
+	eurUsd := newTestRate(t, "EUR", "USD", "1.0834")
+	// 10.05 EUR -> 10.888... USD, rounded to 10.89
+	assert.Equal(t, money.Amount(1089), eurUsd.Apply(1005))
+
+	usdKwd := newTestRate(t, "USD", "KWD", "0.3071")
+	assert.Equal(t, money.Amount(307_100), usdKwd.Apply(100_000))
+}
+
+func TestRate_InverseAndString(t *testing.T) {
+	rate := newTestRate(t, "USD", "EUR", "0.8")
+
+	inverse := rate.Inverse()
+	assert.Equal(t, "EUR", inverse.Base)
+	assert.Equal(t, "1.25", inverse.String())
+	assert.Equal(t, "0.8", rate.String())
+}
+
+func TestNewRate_Validation(t *testing.T) {
+	_, err := NewRate(CreateRateParams{Base: "USD", Quote: "USD", Value: "-1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Quote")
+	assert.Contains(t, err.Error(), "Value")
+	assert.Contains(t, err.Error(), "Date")
+}
+
+func TestParseCSV(t *testing.T) {
+	input := "date,base,quote,rate\n2026-01-31,USD,EUR,0.92\n2026-02-28,USD,EUR,0.93\n"
+
+	rates, err := ParseCSV(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	assert.Equal(t, "0.93", rates[1].String())
+	assert.Equal(t, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), rates[1].Date)
+}
+
+func TestParseCSV_ReportsLine(t *testing.T) {
+	input := "date,base,quote,rate\n2026-01-31,USD,EUR,abc\n"
+
+	_, err := ParseCSV(strings.NewReader(input))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Line 2")
+}
+
+func TestParseJSON(t *testing.T) {
+	input := `[{"date": "2026-01-31", "base": "GBP", "quote": "USD", "rate": "1.27"}]`
+
+	rates, err := ParseJSON(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, rates, 1)
+	assert.Equal(t, "GBP", rates[0].Base)
+}
@@ -0,0 +1,90 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"payroll/internal/apperror"
+	"strings"
+	"time"
+)
+
+const (
+	parseOrigin = "ExchangeRateParser"
+	dateLayout  = "2006-01-02"
+)
+
+var csvHeader = []string{"date", "base", "quote", "rate"}
+
+type rateRecord struct {
+	Date  string `json:"date"`
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+	Rate  string `json:"rate"`
+}
+
+func (r rateRecord) toRate() (*Rate, error) {
+	date, err := time.Parse(dateLayout, strings.TrimSpace(r.Date))
+	if err != nil {
+		return nil, apperror.NewValidationError(modelOrigin, map[string]string{"Date": "must be formatted as YYYY-MM-DD"})
+	}
+	return NewRate(CreateRateParams{Date: date, Base: r.Base, Quote: r.Quote, Value: r.Rate})
+}
+
+// ParseCSV reads rates from CSV with a "date,base,quote,rate" header.
+func ParseCSV(r io.Reader) ([]*Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, apperror.New(apperror.TypeInvalid, parseOrigin, "Missing CSV header")
+	}
+	for i, column := range csvHeader {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), column) {
+			return nil, apperror.New(apperror.TypeInvalid, parseOrigin,
+				fmt.Sprintf("CSV header must be %q", strings.Join(csvHeader, ",")))
+		}
+	}
+
+	var rates []*Rate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, apperror.New(apperror.TypeInvalid, parseOrigin, fmt.Sprintf("Line %d: %v", line, err))
+		}
+		rate, err := rateRecord{Date: record[0], Base: record[1], Quote: record[2], Rate: record[3]}.toRate()
+		if err != nil {
+			return nil, apperror.New(apperror.TypeInvalid, parseOrigin, fmt.Sprintf("Line %d: %v", line, err))
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+// ParseJSON reads rates from a JSON array of
+// {"date": "YYYY-MM-DD", "base": "USD", "quote": "EUR", "rate": "0.92"}.
+func ParseJSON(r io.Reader) ([]*Rate, error) {
+	var records []rateRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, apperror.New(apperror.TypeInvalid, parseOrigin, "Malformed JSON: "+err.Error())
+	}
+
+	rates := make([]*Rate, 0, len(records))
+	for i, record := range records {
+		rate, err := record.toRate()
+		if err != nil {
+			return nil, apperror.New(apperror.TypeInvalid, parseOrigin, fmt.Sprintf("Entry %d: %v", i, err))
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,92 @@
+package exchange
+
+import (
+	"context"
+	"io"
+	"payroll/internal/apperror"
+	"payroll/internal/money"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"strings"
+	"time"
+)
+
+const serviceOrigin = "ExchangeRateService"
+
+type Service struct {
+	repo   Repository
+	logger logger.Logger
+}
+
+func NewService(r Repository, l logger.Logger) *Service {
+	return &Service{
+		repo:   r,
+		logger: l,
+	}
+}
+
+func (s *Service) ImportCSV(ctx context.Context, r io.Reader) (int, error) {
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, serviceOrigin); err != nil {
+		return 0, err
+	}
+	rates, err := ParseCSV(r)
+	if err != nil {
+		return 0, err
+	}
+	return s.save(ctx, rates)
+}
+
+func (s *Service) ImportJSON(ctx context.Context, r io.Reader) (int, error) {
+	if err := auth.Authorize(ctx, auth.PermReferenceDataManage, auth.Resource{}, serviceOrigin); err != nil {
+		return 0, err
+	}
+	rates, err := ParseJSON(r)
+	if err != nil {
+		return 0, err
+	}
+	return s.save(ctx, rates)
+}
+
+func (s *Service) save(ctx context.Context, rates []*Rate) (int, error) {
+	if err := s.repo.Save(ctx, rates); err != nil {
+		s.logger.Error(err, "Failed to save exchange rates")
+		return 0, err
+	}
+	s.logger.Info("Exchange rates imported", "count", len(rates))
+	return len(rates), nil
+}
+
+// Lookup returns the rate effective on date for base→quote. When only the
+// opposite pair is published, its inverse is used.
+func (s *Service) Lookup(ctx context.Context, base, quote string, date time.Time) (*Rate, error) {
+	base = strings.ToUpper(base)
+	quote = strings.ToUpper(quote)
+	date = truncateToDay(date)
+
+	rate, err := s.repo.GetLatest(ctx, base, quote, date)
+	if err == nil {
+		return rate, nil
+	}
+	if !apperror.IsType(err, apperror.TypeNotFound) {
+		return nil, err
+	}
+
+	inverse, err := s.repo.GetLatest(ctx, quote, base, date)
+	if err != nil {
+		if apperror.IsType(err, apperror.TypeNotFound) {
+			return nil, apperror.New(apperror.TypeNotFound, serviceOrigin,
+				"No exchange rate from "+base+" to "+quote+" on or before "+date.Format(dateLayout))
+		}
+		return nil, err
+	}
+	return inverse.Inverse(), nil
+}
+
+// Convert implements payroll.Converter.
+func (s *Service) Convert(ctx context.Context, amount money.Amount, from, to string, date time.Time) (money.Amount, string, error) {
+	rate, err := s.Lookup(ctx, from, to, date)
+	if err != nil {
+		return 0, "", err
+	}
+	return rate.Apply(amount), rate.String(), nil
+}
@@ -0,0 +1,49 @@
+package exchange
+
+import (
+	"math/big"
+	"payroll/internal/platform/validation"
+	"time"
+)
+
+const currencyCodeLength = 3
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+func (v *Validator) ValidateDate(date time.Time) {
+	if date.IsZero() {
+		v.AddError("Date", "is empty")
+	}
+}
+
+func (v *Validator) ValidateCurrency(field, code string) {
+	if code == "" {
+		v.AddError(field, "is empty")
+	} else if len(code) != currencyCodeLength {
+		v.AddError(field, "must be a 3-letter currency code")
+	}
+}
+
+// ValidateValue parses a decimal rate and returns it when it is valid.
+func (v *Validator) ValidateValue(value string) *big.Rat {
+	if value == "" {
+		v.AddError("Value", "is empty")
+		return nil
+	}
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		v.AddError("Value", "is not a valid decimal number")
+		return nil
+	}
+	if rat.Sign() <= 0 {
+		v.AddError("Value", "must be greater than zero")
+		return nil
+	}
+	return rat
+}
@@ -0,0 +1,285 @@
+package garnishment
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/money"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const modelOrigin = "Garnishment"
+
+type OrderKind string
+
+const (
+	KindChildSupport OrderKind = "CHILD_SUPPORT"
+	KindTaxLevy      OrderKind = "TAX_LEVY"
+	KindCreditor     OrderKind = "CREDITOR"
+)
+
+func (k OrderKind) IsValid() bool {
+	switch k {
+	case KindChildSupport, KindTaxLevy, KindCreditor:
+		return true
+	}
+	return false
+}
+
+// DefaultPriority is the legal precedence of the kind when the order does not
+// state one: support obligations first, then tax levies, then creditors.
+func (k OrderKind) DefaultPriority() int {
+	switch k {
+	case KindChildSupport:
+		return 1
+	case KindTaxLevy:
+		return 2
+	}
+	return 3
+}
+
+type Method string
+
+const (
+	MethodFixed      Method = "FIXED"
+	MethodPercentage Method = "PERCENTAGE"
+)
+
+func (m Method) IsValid() bool {
+	switch m {
+	case MethodFixed, MethodPercentage:
+		return true
+	}
+	return false
+}
+
+type OrderStatus string
+
+const (
+	StatusActive    OrderStatus = "ACTIVE"
+	StatusCompleted OrderStatus = "COMPLETED"
+)
+
+// Payee is who the withheld money is remitted to.
+type Payee struct {
+	Name        string
+	Reference   string
+	BankAccount string
+}
+
+func (p Payee) key() string {
+	return p.Name + "|" + p.BankAccount
+}
+
+type Withholding struct {
+	RunID  uuid.UUID
+	Date   time.Time
+	Amount money.Amount
+}
+
+type Order struct {
+	domain.BaseEntity
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	EmployeeID  uuid.UUID
+
+	Kind     OrderKind
+	Priority int
+	// CaseNumber is the court or agency reference of the order.
+	CaseNumber string
+	Method     Method
+	// Amount is used by FIXED orders, Percentage (basis points of disposable
+	// net pay) by PERCENTAGE orders.
+	Amount     money.Amount
+	Percentage int64
+	// Optional caps: per payroll run and over the life of the order.
+	MaxPerRun *money.Amount
+	TotalCap  *money.Amount
+
+	Payee     Payee
+	StartDate time.Time
+	EndDate   *time.Time
+	Status    OrderStatus
+
+	Withheld     money.Amount
+	Withholdings []Withholding
+}
+
+type CreateOrderParams struct {
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	EmployeeID  uuid.UUID
+	Kind        OrderKind
+	Priority    *int
+	CaseNumber  string
+	Method      Method
+	Amount      money.Amount
+	Percentage  int64
+	MaxPerRun   *money.Amount
+	TotalCap    *money.Amount
+	Payee       Payee
+	StartDate   time.Time
+	EndDate     *time.Time
+}
+
+func NewOrder(params CreateOrderParams) (*Order, error) {
+	validator := NewValidator()
+
+	if params.TenantID == uuid.Nil {
+		validator.AddError("TenantID", "is empty")
+	}
+	if params.WorkspaceID == uuid.Nil {
+		validator.AddError("WorkspaceID", "is empty")
+	}
+	if params.EmployeeID == uuid.Nil {
+		validator.AddError("EmployeeID", "is empty")
+	}
+
+	params.CaseNumber = strings.TrimSpace(params.CaseNumber)
+	params.Payee.Name = strings.TrimSpace(params.Payee.Name)
+	params.Payee.Reference = strings.TrimSpace(params.Payee.Reference)
+	params.Payee.BankAccount = strings.TrimSpace(params.Payee.BankAccount)
+
+	validator.ValidateKind(params.Kind)
+	validator.ValidatePriority(params.Priority)
+	validator.ValidateCaseNumber(params.CaseNumber)
+	validator.ValidateMethod(params.Method, params.Amount, params.Percentage)
+	validator.ValidateCap("MaxPerRun", params.MaxPerRun)
+	validator.ValidateCap("TotalCap", params.TotalCap)
+	validator.ValidatePayee(params.Payee)
+	validator.ValidateDates(params.StartDate, params.EndDate)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	priority := params.Kind.DefaultPriority()
+	if params.Priority != nil {
+		priority = *params.Priority
+	}
+
+	order := &Order{
+		TenantID:    params.TenantID,
+		WorkspaceID: params.WorkspaceID,
+		EmployeeID:  params.EmployeeID,
+		Kind:        params.Kind,
+		Priority:    priority,
+		CaseNumber:  params.CaseNumber,
+		Method:      params.Method,
+		Amount:      params.Amount,
+		Percentage:  params.Percentage,
+		MaxPerRun:   params.MaxPerRun,
+		TotalCap:    params.TotalCap,
+		Payee:       params.Payee,
+		StartDate:   params.StartDate,
+		EndDate:     params.EndDate,
+		Status:      StatusActive,
+	}
+	order.Initialize()
+
+	return order, nil
+}
+
+func (o *Order) IsActiveOn(date time.Time) bool {
+	if o.Status != StatusActive || o.StartDate.After(date) {
+		return false
+	}
+	return o.EndDate == nil || !o.EndDate.Before(date)
+}
+
+// Requested is what the order asks for in a run, before the protected
+// minimum is considered.
+func (o *Order) Requested(disposable money.Amount) money.Amount {
+	requested := o.Amount
+	if o.Method == MethodPercentage {
+		requested = disposable.MulDiv(o.Percentage, 10000)
+	}
+	if o.MaxPerRun != nil {
+		requested = money.Min(requested, *o.MaxPerRun)
+	}
+	if o.TotalCap != nil {
+		requested = money.Min(requested, *o.TotalCap-o.Withheld)
+	}
+	return money.Max(requested, 0)
+}
+
+func (o *Order) withholdingForRun(runID uuid.UUID) int {
+	for i, w := range o.Withholdings {
+		if w.RunID == runID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (o *Order) HasWithholdingForRun(runID uuid.UUID) bool {
+	return o.withholdingForRun(runID) >= 0
+}
+
+// WithholdingForRun returns what the order withheld in runID, if anything.
+func (o *Order) WithholdingForRun(runID uuid.UUID) money.Amount {
+	if idx := o.withholdingForRun(runID); idx >= 0 {
+		return o.Withholdings[idx].Amount
+	}
+	return 0
+}
+
+// reverseRun undoes the withholding of runID so the run can be recalculated.
+func (o *Order) reverseRun(runID uuid.UUID) {
+	idx := o.withholdingForRun(runID)
+	if idx < 0 {
+		return
+	}
+	o.Withheld -= o.Withholdings[idx].Amount
+	o.Withholdings = append(o.Withholdings[:idx], o.Withholdings[idx+1:]...)
+	o.Status = StatusActive
+}
+
+func (o *Order) withhold(runID uuid.UUID, date time.Time, amount money.Amount) {
+	if amount > 0 {
+		o.Withheld += amount
+		o.Withholdings = append(o.Withholdings, Withholding{RunID: runID, Date: date, Amount: amount})
+	}
+	if o.TotalCap != nil && o.Withheld >= *o.TotalCap {
+		o.Status = StatusCompleted
+	}
+	o.Touch()
+}
+
+// SortByPriority orders garnishments in the sequence they must be honoured:
+// by priority, then by the oldest order.
+func SortByPriority(orders []*Order) {
+	sort.SliceStable(orders, func(i, j int) bool {
+		if orders[i].Priority != orders[j].Priority {
+			return orders[i].Priority < orders[j].Priority
+		}
+		return orders[i].StartDate.Before(orders[j].StartDate)
+	})
+}
+
+// ProtectedMinimumRule describes the part of net pay a country shields from
+// garnishment: the greater of a fixed amount and a percentage of net pay.
+type ProtectedMinimumRule struct {
+	CountryID  uuid.UUID
+	Amount     money.Amount
+	Percentage int64
+}
+
+func (r ProtectedMinimumRule) ProtectedAmount(net money.Amount) money.Amount {
+	return money.Max(r.Amount, net.MulDiv(r.Percentage, 10000))
+}
+
+type Repository interface {
+	Create(ctx context.Context, order *Order) error
+	Get(ctx context.Context, id uuid.UUID) (*Order, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, order *Order) error
+	ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*Order, error)
+	ListByWorkspaceID(ctx context.Context, workspaceID uuid.UUID) ([]*Order, error)
+	GetProtectedMinimumRule(ctx context.Context, countryID uuid.UUID) (*ProtectedMinimumRule, error)
+}
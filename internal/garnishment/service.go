@@ -0,0 +1,240 @@
+package garnishment
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/money"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/tenant"
+	"payroll/internal/workspace"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+const (
+	serviceOrigin = "GarnishmentService"
+	deductionCode = "GARNISHMENT"
+)
+
+type Service struct {
+	repo          Repository
+	employeeRepo  employee.Repository
+	workspaceRepo workspace.Repository
+	logger        logger.Logger
+}
+
+func NewService(r Repository, er employee.Repository, wr workspace.Repository, l logger.Logger) *Service {
+	return &Service{
+		repo:          r,
+		employeeRepo:  er,
+		workspaceRepo: wr,
+		logger:        l,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, params CreateOrderParams) (*Order, error) {
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	params.TenantID = tenantID
+	if err := auth.Authorize(ctx, auth.PermEmployeeWrite, auth.Resource{WorkspaceID: params.WorkspaceID, EmployeeID: params.EmployeeID}, serviceOrigin); err != nil {
+		return nil, err
+	}
+
+	order, err := NewOrder(params)
+	if err != nil {
+		s.logger.Warn("Failed to create garnishment order due to validation errors", "errors", err)
+		return nil, err
+	}
+
+	emp, err := s.employeeRepo.GetByID(ctx, params.EmployeeID)
+	if err != nil || emp.IsDeleted() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Invalid EmployeeID")
+	}
+	if emp.TenantID != order.TenantID || emp.WorkspaceID != order.WorkspaceID {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Employee does not belong to the given workspace")
+	}
+
+	if err := s.repo.Create(ctx, order); err != nil {
+		s.logger.Error(err, "Failed to save garnishment order to repository")
+		return nil, err
+	}
+
+	s.logger.Info("Garnishment order created successfully", "order_id", order.ID, "employee_id", order.EmployeeID)
+	return order, nil
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Order, error) {
+	order, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, order.TenantID, serviceOrigin, "Garnishment order not found"); err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermEmployeeRead, order.resource(), serviceOrigin); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *Service) ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*Order, error) {
+	orders, err := s.repo.ListByEmployeeID(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	orders, err = tenant.Filter(ctx, orders, func(o *Order) uuid.UUID { return o.TenantID })
+	if err != nil {
+		return nil, err
+	}
+	return auth.Filter(ctx, auth.PermEmployeeRead, orders, (*Order).resource)
+}
+
+// Deduct implements payroll.Deductor. Orders are honoured in priority order
+// and only out of the net pay above the protected minimum of the workspace's
+// country. Register it before voluntary deductions such as loans.
+func (s *Service) Deduct(ctx context.Context, run *payroll.Run, result *payroll.Result) ([]payroll.Item, error) {
+	orders, err := s.repo.ListByEmployeeID(ctx, result.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var applicable []*Order
+	for _, order := range orders {
+		if order.TenantID != run.TenantID {
+			continue
+		}
+		if order.HasWithholdingForRun(run.ID) {
+			order.reverseRun(run.ID)
+		} else if !order.IsActiveOn(run.PeriodEnd) {
+			continue
+		}
+		applicable = append(applicable, order)
+	}
+	if len(applicable) == 0 {
+		return nil, nil
+	}
+	SortByPriority(applicable)
+
+	protected, err := s.protectedMinimum(ctx, run.WorkspaceID, result.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	disposable := result.Net
+	available := money.Max(disposable-protected, 0)
+	var items []payroll.Item
+	for _, order := range applicable {
+		var amount money.Amount
+		if order.IsActiveOn(run.PeriodEnd) {
+			amount = money.Min(order.Requested(disposable), available)
+		}
+		order.withhold(run.ID, run.PeriodEnd, amount)
+		if err := s.repo.Update(ctx, order); err != nil {
+			s.logger.Error(err, "Failed to save garnishment withholding", "order_id", order.ID, "run_id", run.ID)
+			return nil, err
+		}
+
+		if amount == 0 {
+			continue
+		}
+		available -= amount
+		orderID := order.ID
+		items = append(items, payroll.Item{
+			Code:        deductionCode,
+			Description: string(order.Kind) + " " + order.CaseNumber,
+			Kind:        payroll.ItemKindDeduction,
+			Amount:      amount,
+			SourceID:    &orderID,
+		})
+	}
+
+	return items, nil
+}
+
+func (s *Service) protectedMinimum(ctx context.Context, workspaceID uuid.UUID, net money.Amount) (money.Amount, error) {
+	ws, err := s.workspaceRepo.Get(ctx, workspaceID)
+	if err != nil {
+		return 0, err
+	}
+
+	rule, err := s.repo.GetProtectedMinimumRule(ctx, ws.CountryID)
+	if err != nil {
+		if apperror.IsType(err, apperror.TypeNotFound) {
+			s.logger.Warn("No protected minimum rule for country, garnishing full net pay", "country_id", ws.CountryID)
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return rule.ProtectedAmount(net), nil
+}
+
+type RemittanceLine struct {
+	OrderID    uuid.UUID
+	EmployeeID uuid.UUID
+	CaseNumber string
+	Amount     money.Amount
+}
+
+// Remittance is what has to be paid to one payee for a payroll run.
+type Remittance struct {
+	Payee Payee
+	Total money.Amount
+	Lines []RemittanceLine
+}
+
+// RemittanceReport groups the amounts withheld in a run by payee.
+func (s *Service) RemittanceReport(ctx context.Context, run *payroll.Run) ([]*Remittance, error) {
+	if err := tenant.Check(ctx, run.TenantID, serviceOrigin, "Payroll run not found"); err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermReportRead, auth.Resource{WorkspaceID: run.WorkspaceID}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	orders, err := s.repo.ListByWorkspaceID(ctx, run.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	orders, err = tenant.Filter(ctx, orders, func(o *Order) uuid.UUID { return o.TenantID })
+	if err != nil {
+		return nil, err
+	}
+
+	byPayee := make(map[string]*Remittance)
+	var report []*Remittance
+	for _, order := range orders {
+		amount := order.WithholdingForRun(run.ID)
+		if amount == 0 {
+			continue
+		}
+		remittance, ok := byPayee[order.Payee.key()]
+		if !ok {
+			remittance = &Remittance{Payee: order.Payee}
+			byPayee[order.Payee.key()] = remittance
+			report = append(report, remittance)
+		}
+		remittance.Total += amount
+		remittance.Lines = append(remittance.Lines, RemittanceLine{
+			OrderID:    order.ID,
+			EmployeeID: order.EmployeeID,
+			CaseNumber: order.CaseNumber,
+			Amount:     amount,
+		})
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Payee.Name < report[j].Payee.Name
+	})
+	return report, nil
+}
+
+// resource is what permissions on the garnishment order are checked against.
+func (o *Order) resource() auth.Resource {
+	return auth.Resource{WorkspaceID: o.WorkspaceID, EmployeeID: o.EmployeeID}
+}
@@ -0,0 +1,179 @@
+package garnishment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/money"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/logger"
+	"payroll/internal/testkit"
+	"payroll/internal/workspace"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepo struct {
+	Repository
+	orders []*Order
+	rule   *ProtectedMinimumRule
+}
+
+func (r *fakeRepo) Get(_ context.Context, id uuid.UUID) (*Order, error) {
+	for _, order := range r.orders {
+		if order.ID == id {
+			return order, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "order not found")
+}
+
+func (r *fakeRepo) Update(_ context.Context, _ *Order) error { return nil }
+
+func (r *fakeRepo) ListByEmployeeID(_ context.Context, _ uuid.UUID) ([]*Order, error) {
+	return r.orders, nil
+}
+
+func (r *fakeRepo) ListByWorkspaceID(_ context.Context, _ uuid.UUID) ([]*Order, error) {
+	return r.orders, nil
+}
+
+func (r *fakeRepo) GetProtectedMinimumRule(_ context.Context, _ uuid.UUID) (*ProtectedMinimumRule, error) {
+	if r.rule == nil {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "not found")
+	}
+	return r.rule, nil
+}
+
+type fakeWorkspaceRepo struct {
+	workspace.Repository
+}
+
+func (fakeWorkspaceRepo) Get(_ context.Context, id uuid.UUID) (*workspace.Workspace, error) {
+	return &workspace.Workspace{CountryID: uuid.New()}, nil
+}
+
+var (
+	periodEnd = time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	tenantID  = uuid.New()
+	ctx       = testkit.AdminContext(tenantID)
+)
+
+func newTestOrder(t *testing.T, kind OrderKind, method Method, amount money.Amount, pct int64, payee string) *Order {
+	t.Helper()
+	order, err := NewOrder(CreateOrderParams{
+		TenantID:    tenantID,
+		WorkspaceID: uuid.New(),
+		EmployeeID:  uuid.New(),
+		Kind:        kind,
+		CaseNumber:  "CASE-" + string(kind),
+		Method:      method,
+		Amount:      amount,
+		Percentage:  pct,
+		Payee:       Payee{Name: payee},
+		StartDate:   periodEnd.AddDate(0, -1, 0),
+	})
+	require.NoError(t, err)
+	return order
+}
+
+func newTestRun() (*payroll.Run, *payroll.Result) {
+	run := &payroll.Run{TenantID: tenantID, PeriodEnd: periodEnd, Status: payroll.RunStatusOpen}
+	run.Initialize()
+	result := &payroll.Result{EmployeeID: uuid.New()}
+	result.AddItem(payroll.Item{Code: "SALARY", Kind: payroll.ItemKindEarning, Amount: 1000})
+	return run, result
+}
+
+func TestDeduct_HonoursPriorityAndProtectedMinimum(t *testing.T) {
+	creditor := newTestOrder(t, KindCreditor, MethodFixed, 500, 0, "Bank")
+	support := newTestOrder(t, KindChildSupport, MethodPercentage, 0, 2500, "Agency")
+	repo := &fakeRepo{
+		orders: []*Order{creditor, support},
+		rule:   &ProtectedMinimumRule{Amount: 400, Percentage: 5000},
+	}
+	svc := NewService(repo, nil, fakeWorkspaceRepo{}, logger.Nop{})
+	run, result := newTestRun()
+
+	items, err := svc.Deduct(ctx, run, result)
+	require.NoError(t, err)
+
+	require.Len(t, items, 2)
+	assert.Equal(t, support.ID, *items[0].SourceID)
+	assert.Equal(t, money.Amount(250), items[0].Amount)
+	assert.Equal(t, creditor.ID, *items[1].SourceID)
+	assert.Equal(t, money.Amount(250), items[1].Amount)
+}
+
+func TestDeduct_RecalculationReplacesPreviousWithholding(t *testing.T) {
+	order := newTestOrder(t, KindTaxLevy, MethodFixed, 300, 0, "Tax Office")
+	totalCap := money.Amount(300)
+	order.TotalCap = &totalCap
+	repo := &fakeRepo{orders: []*Order{order}}
+	svc := NewService(repo, nil, fakeWorkspaceRepo{}, logger.Nop{})
+	run, result := newTestRun()
+
+	_, err := svc.Deduct(ctx, run, result)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, order.Status)
+
+	items, err := svc.Deduct(ctx, run, result)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, money.Amount(300), order.Withheld)
+	assert.Len(t, order.Withholdings, 1)
+}
+
+func TestRemittanceReport_GroupsByPayee(t *testing.T) {
+	a := newTestOrder(t, KindCreditor, MethodFixed, 100, 0, "Bank")
+	b := newTestOrder(t, KindCreditor, MethodFixed, 50, 0, "Bank")
+	c := newTestOrder(t, KindTaxLevy, MethodFixed, 70, 0, "Agency")
+	repo := &fakeRepo{orders: []*Order{a, b, c}}
+	svc := NewService(repo, nil, fakeWorkspaceRepo{}, logger.Nop{})
+	run, result := newTestRun()
+
+	_, err := svc.Deduct(ctx, run, result)
+	require.NoError(t, err)
+
+	report, err := svc.RemittanceReport(ctx, run)
+	require.NoError(t, err)
+	require.Len(t, report, 2)
+	assert.Equal(t, "Agency", report[0].Payee.Name)
+	assert.Equal(t, money.Amount(70), report[0].Total)
+	assert.Equal(t, "Bank", report[1].Payee.Name)
+	assert.Equal(t, money.Amount(150), report[1].Total)
+	assert.Len(t, report[1].Lines, 2)
+}
+
+func TestService_DoesNotLeakOrdersAcrossTenants(t *testing.T) {
+	order := newTestOrder(t, KindCreditor, MethodFixed, 100, 0, "Bank")
+	svc := NewService(&fakeRepo{orders: []*Order{order}}, nil, fakeWorkspaceRepo{}, logger.Nop{})
+	run, result := newTestRun()
+	run.WorkspaceID = order.WorkspaceID
+	_, err := svc.Deduct(ctx, run, result)
+	require.NoError(t, err)
+
+	otherTenant := uuid.New()
+	other := testkit.AdminContext(otherTenant)
+	_, err = svc.Get(other, order.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	list, err := svc.ListByEmployeeID(other, order.EmployeeID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = svc.RemittanceReport(other, run)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+
+	otherRun := &payroll.Run{TenantID: otherTenant, WorkspaceID: order.WorkspaceID, PeriodEnd: periodEnd, Status: payroll.RunStatusOpen}
+	otherRun.Initialize()
+	items, err := svc.Deduct(other, otherRun, result)
+	require.NoError(t, err)
+	assert.Empty(t, items, "another tenant's run withholds nothing")
+	report, err := svc.RemittanceReport(other, otherRun)
+	require.NoError(t, err)
+	assert.Empty(t, report)
+	assert.Equal(t, money.Amount(100), order.Withheld)
+}
@@ -0,0 +1,86 @@
+package garnishment
+
+import (
+	"fmt"
+	"payroll/internal/money"
+	"payroll/internal/platform/validation"
+	"time"
+)
+
+const (
+	maxCaseNumberLength  = 50
+	maxPayeeNameLength   = 100
+	maxPayeeRefLength    = 50
+	maxBankAccountLength = 50
+)
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+func (v *Validator) ValidateKind(kind OrderKind) {
+	if !kind.IsValid() {
+		v.AddError("Kind", "is invalid")
+	}
+}
+
+func (v *Validator) ValidatePriority(priority *int) {
+	if priority != nil && *priority < 1 {
+		v.AddError("Priority", "must be greater than zero")
+	}
+}
+
+func (v *Validator) ValidateCaseNumber(caseNumber string) {
+	if caseNumber == "" {
+		v.AddError("CaseNumber", "is empty")
+	} else if len(caseNumber) > maxCaseNumberLength {
+		v.AddError("CaseNumber", fmt.Sprintf("must be less than %d characters", maxCaseNumberLength))
+	}
+}
+
+func (v *Validator) ValidateMethod(method Method, amount money.Amount, percentage int64) {
+	switch method {
+	case MethodFixed:
+		if !amount.IsPositive() {
+			v.AddError("Amount", "must be greater than zero")
+		}
+	case MethodPercentage:
+		if percentage <= 0 || percentage > 10000 {
+			v.AddError("Percentage", "must be between 1 and 10000 basis points")
+		}
+	default:
+		v.AddError("Method", "is invalid")
+	}
+}
+
+func (v *Validator) ValidateCap(field string, limit *money.Amount) {
+	if limit != nil && !limit.IsPositive() {
+		v.AddError(field, "must be greater than zero")
+	}
+}
+
+func (v *Validator) ValidatePayee(payee Payee) {
+	if payee.Name == "" {
+		v.AddError("PayeeName", "is empty")
+	} else if len(payee.Name) > maxPayeeNameLength {
+		v.AddError("PayeeName", fmt.Sprintf("must be less than %d characters", maxPayeeNameLength))
+	}
+	if len(payee.Reference) > maxPayeeRefLength {
+		v.AddError("PayeeReference", fmt.Sprintf("must be less than %d characters", maxPayeeRefLength))
+	}
+	if len(payee.BankAccount) > maxBankAccountLength {
+		v.AddError("PayeeBankAccount", fmt.Sprintf("must be less than %d characters", maxBankAccountLength))
+	}
+}
+
+func (v *Validator) ValidateDates(start time.Time, end *time.Time) {
+	if start.IsZero() {
+		v.AddError("StartDate", "is empty")
+	} else if end != nil && end.Before(start) {
+		v.AddError("EndDate", "cannot be before StartDate")
+	}
+}
@@ -0,0 +1,189 @@
+// Package httpapi exposes the services over HTTP with JSON bodies.
+//
+// Handler authenticates every request with auth.Authenticator and hands the
+// resulting context to the services, which authorize the caller themselves.
+// Service errors are mapped to status codes by their apperror type and
+// written as the DomainError itself.
+//
+// Responses that carry one entity have an ETag header with its version.
+// Update endpoints honour If-Match: an update of an entity that has changed
+// since is refused with 412 Precondition Failed.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/etag"
+	"payroll/internal/platform/logger"
+
+	"github.com/google/uuid"
+)
+
+const origin = "HTTPAPI"
+
+// maxBodySize is the largest request body decodeJSON accepts.
+const maxBodySize = 1 << 20
+
+// Services are the services the API exposes.
+type Services struct {
+	Workspaces Workspaces
+	Webhooks   Webhooks
+}
+
+type Handler struct {
+	mux           *http.ServeMux
+	authenticator *auth.Authenticator
+	logger        logger.Logger
+}
+
+func New(s Services, a *auth.Authenticator, l logger.Logger) *Handler {
+	h := &Handler{mux: http.NewServeMux(), authenticator: a, logger: l}
+	h.routeWorkspaces(s.Workspaces)
+	h.routeWebhooks(s.Webhooks)
+	return h
+}
+
+// ServeHTTP authenticates the request from its Authorization header and
+// routes it. The X-Request-ID header, when present, is recorded with the
+// audit entries of the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, err := h.authenticator.Authenticate(r.Context(), r.Header.Get("Authorization"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		ctx = audit.WithRequestID(ctx, id)
+	}
+	h.mux.ServeHTTP(w, r.WithContext(ctx))
+}
+
+var statusCodes = map[apperror.Type]int{
+	apperror.TypeInvalid:         http.StatusBadRequest,
+	apperror.TypeNotFound:        http.StatusNotFound,
+	apperror.TypeDuplicate:       http.StatusConflict,
+	apperror.TypeConflict:        http.StatusConflict,
+	apperror.TypeUnauthenticated: http.StatusUnauthorized,
+	apperror.TypeForbidden:       http.StatusForbidden,
+}
+
+// writeError writes a DomainError with the status of its type. Any other
+// error is logged and reported as an internal error without its message.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var domainErr *apperror.DomainError
+	if !errors.As(err, &domainErr) {
+		h.logger.Error(err, "Request failed", "method", r.Method, "path", r.URL.Path)
+		writeJSON(w, http.StatusInternalServerError, &apperror.DomainError{Origin: origin, Message: "Internal error"})
+		return
+	}
+	status, ok := statusCodes[domainErr.Type]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	switch {
+	case status == http.StatusUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Bearer, ApiKey`)
+	case domainErr.Type == apperror.TypeConflict && r.Header.Get("If-Match") != "":
+		status = http.StatusPreconditionFailed
+	}
+	writeJSON(w, status, domainErr)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeEntity writes body, the representation of an entity at version, with
+// its ETag.
+func writeEntity(w http.ResponseWriter, status int, version int64, body any) {
+	w.Header().Set("ETag", etag.Format(version))
+	writeJSON(w, status, body)
+}
+
+// decodeJSON decodes the request body into v, rejecting unknown fields and
+// bodies larger than maxBodySize.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return apperror.New(apperror.TypeInvalid, origin, "Malformed JSON body: "+err.Error())
+	}
+	return nil
+}
+
+// ifMatch returns the version required by the If-Match header, or nil when
+// there is none.
+func ifMatch(r *http.Request) (*int64, error) {
+	version, err := etag.ParseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		return nil, apperror.NewValidationError(origin, map[string]string{"If-Match": "is not a strong entity tag"})
+	}
+	return version, nil
+}
+
+// pathID parses the {id} wildcard of the route.
+func pathID(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return uuid.Nil, apperror.New(apperror.TypeNotFound, origin, "Not found")
+	}
+	return id, nil
+}
+
+// query reads the optional parameters of a URL query, collecting the errors
+// of those that do not parse.
+type query struct {
+	values map[string][]string
+	errs   map[string]string
+}
+
+func newQuery(r *http.Request) *query {
+	return &query{values: r.URL.Query(), errs: make(map[string]string)}
+}
+
+func (q *query) string(name string) string {
+	if v := q.values[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (q *query) int(name string) int {
+	s := q.string(name)
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		q.errs[name] = "is not a number"
+	}
+	return n
+}
+
+func (q *query) uuid(name string) *uuid.UUID {
+	s := q.string(name)
+	if s == "" {
+		return nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		q.errs[name] = "is not a valid ID"
+		return nil
+	}
+	return &id
+}
+
+func (q *query) err() error {
+	if len(q.errs) > 0 {
+		return apperror.NewValidationError(origin, q.errs)
+	}
+	return nil
+}
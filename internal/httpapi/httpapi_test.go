@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"payroll/internal/apperror"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiKey = "pk_test"
+
+// stubKeys authenticates apiKey as a tenant admin of tenantID.
+type stubKeys struct {
+	tenantID uuid.UUID
+}
+
+func (k stubKeys) ResolveAPIKey(_ context.Context, key string) (*auth.Principal, error) {
+	if key != apiKey {
+		return nil, apperror.New(apperror.TypeUnauthenticated, "test", "Invalid API key")
+	}
+	return &auth.Principal{Subject: "api-key", TenantID: k.tenantID, Roles: []auth.Role{auth.RoleTenantAdmin}}, nil
+}
+
+type apiFixture struct {
+	tenantID   uuid.UUID
+	workspaces *stubWorkspaces
+	webhooks   *stubWebhooks
+	handler    *Handler
+}
+
+func newAPIFixture() *apiFixture {
+	f := &apiFixture{tenantID: uuid.New(), workspaces: &stubWorkspaces{}, webhooks: &stubWebhooks{}}
+	services := Services{Workspaces: f.workspaces, Webhooks: f.webhooks}
+	f.handler = New(services, auth.NewAuthenticator(nil, stubKeys{tenantID: f.tenantID}), logger.Nop{})
+	return f
+}
+
+// do serves a request authenticated with apiKey and returns its response.
+func (f *apiFixture) do(method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "ApiKey "+apiKey)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) *apperror.DomainError {
+	t.Helper()
+	var domainErr apperror.DomainError
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &domainErr))
+	return &domainErr
+}
+
+func TestHandler_RequiresCredentials(t *testing.T) {
+	f := newAPIFixture()
+
+	for _, authorization := range []string{"", "ApiKey pk_wrong", "Basic abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/workspaces", nil)
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		f.handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, authorization)
+		assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, apperror.TypeUnauthenticated, decodeError(t, rec).Type)
+	}
+	assert.Nil(t, f.workspaces.listed)
+}
+
+func TestHandler_MapsErrorsToStatusCodes(t *testing.T) {
+	f := newAPIFixture()
+	for err, status := range map[error]int{
+		apperror.New(apperror.TypeNotFound, "test", "workspace not found"): http.StatusNotFound,
+		apperror.New(apperror.TypeForbidden, "test", "forbidden"):          http.StatusForbidden,
+		apperror.NewConflictError("test", "modified"):                      http.StatusConflict,
+		errors.New("connection refused"):                                   http.StatusInternalServerError,
+	} {
+		f.workspaces.err = err
+
+		rec := f.do(http.MethodGet, "/workspaces/"+uuid.NewString(), "")
+
+		assert.Equal(t, status, rec.Code, err.Error())
+		assert.NotContains(t, rec.Body.String(), "connection refused")
+	}
+}
@@ -0,0 +1,67 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"payroll/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+// Webhooks is the part of webhook.Service the API exposes.
+type Webhooks interface {
+	Replay(ctx context.Context, deliveryID uuid.UUID) (*webhook.Delivery, error)
+}
+
+var _ Webhooks = (*webhook.Service)(nil)
+
+// deliveryResponse leaves out the body, which may hold personal data.
+type deliveryResponse struct {
+	ID             uuid.UUID              `json:"id"`
+	SubscriptionID uuid.UUID              `json:"subscriptionId"`
+	MessageID      uuid.UUID              `json:"messageId"`
+	EventType      string                 `json:"eventType"`
+	EntityID       uuid.UUID              `json:"entityId"`
+	Status         webhook.DeliveryStatus `json:"status"`
+	NextAttemptAt  time.Time              `json:"nextAttemptAt"`
+	CreatedAt      time.Time              `json:"createdAt"`
+	ReplayOf       *uuid.UUID             `json:"replayOf,omitempty"`
+}
+
+func newDeliveryResponse(d *webhook.Delivery) deliveryResponse {
+	return deliveryResponse{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		MessageID:      d.MessageID,
+		EventType:      d.EventType,
+		EntityID:       d.EntityID,
+		Status:         d.Status,
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+		ReplayOf:       d.ReplayOf,
+	}
+}
+
+func (h *Handler) routeWebhooks(s Webhooks) {
+	h.mux.HandleFunc("POST /webhooks/deliveries/{id}/replay", func(w http.ResponseWriter, r *http.Request) {
+		h.replayDelivery(s, w, r)
+	})
+}
+
+// replayDelivery queues a finished delivery to be sent again and returns the
+// new delivery.
+func (h *Handler) replayDelivery(s Webhooks, w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	replay, err := s.Replay(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newDeliveryResponse(replay))
+}
@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/webhook"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubWebhooks replays the deliveries in delivered.
+type stubWebhooks struct {
+	Webhooks
+	delivered map[uuid.UUID]*webhook.Delivery
+}
+
+func (s *stubWebhooks) Replay(_ context.Context, deliveryID uuid.UUID) (*webhook.Delivery, error) {
+	original, ok := s.delivered[deliveryID]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "delivery not found")
+	}
+	replay := *original
+	replay.ID = uuid.New()
+	replay.Status = webhook.DeliveryStatusPending
+	replay.ReplayOf = &original.ID
+	return &replay, nil
+}
+
+func TestReplayDelivery(t *testing.T) {
+	f := newAPIFixture()
+	original := &webhook.Delivery{
+		ID: uuid.New(), SubscriptionID: uuid.New(), MessageID: uuid.New(), EventType: "EmployeeCreated",
+		Body: json.RawMessage(`{"data":{"email":"ada@example.com"}}`), CreatedAt: time.Now(),
+	}
+	f.webhooks.delivered = map[uuid.UUID]*webhook.Delivery{original.ID: original}
+
+	rec := f.do(http.MethodPost, "/webhooks/deliveries/"+original.ID.String()+"/replay", "")
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var got deliveryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.NotEqual(t, original.ID, got.ID)
+	assert.Equal(t, original.ID, *got.ReplayOf)
+	assert.Equal(t, original.MessageID, got.MessageID)
+	assert.Equal(t, webhook.DeliveryStatusPending, got.Status)
+	assert.NotContains(t, rec.Body.String(), "ada@example.com")
+
+	assert.Equal(t, http.StatusNotFound, f.do(http.MethodPost, "/webhooks/deliveries/"+uuid.NewString()+"/replay", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, f.do(http.MethodGet, "/webhooks/deliveries/"+original.ID.String()+"/replay", "").Code)
+}
@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"payroll/internal/platform/pagination"
+	"payroll/internal/workspace"
+
+	"github.com/google/uuid"
+)
+
+// Workspaces is the part of workspace.Service the API exposes.
+type Workspaces interface {
+	List(ctx context.Context, params workspace.ListWorkspacesParams) (*pagination.Page[*workspace.Workspace], error)
+	Get(ctx context.Context, id uuid.UUID) (*workspace.Workspace, error)
+	Update(ctx context.Context, id uuid.UUID, params workspace.UpdateWorkspaceParams) (*workspace.Workspace, error)
+}
+
+var _ Workspaces = (*workspace.Service)(nil)
+
+type workspaceResponse struct {
+	ID        uuid.UUID                 `json:"id"`
+	TenantID  uuid.UUID                 `json:"tenantId"`
+	CountryID uuid.UUID                 `json:"countryId"`
+	Code      string                    `json:"code"`
+	Name      string                    `json:"name"`
+	Status    workspace.WorkspaceStatus `json:"status"`
+	Version   int64                     `json:"version"`
+	CreatedAt time.Time                 `json:"createdAt"`
+	UpdatedAt time.Time                 `json:"updatedAt"`
+}
+
+func newWorkspaceResponse(ws *workspace.Workspace) workspaceResponse {
+	return workspaceResponse{
+		ID:        ws.ID,
+		TenantID:  ws.TenantID,
+		CountryID: ws.CountryID,
+		Code:      ws.Code,
+		Name:      ws.Name,
+		Status:    ws.Status,
+		Version:   ws.Version,
+		CreatedAt: ws.CreatedAt,
+		UpdatedAt: ws.UpdatedAt,
+	}
+}
+
+type updateWorkspaceRequest struct {
+	Code *string `json:"code"`
+	Name *string `json:"name"`
+}
+
+type pageResponse[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor is passed as the cursor parameter to get the next page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+func (h *Handler) routeWorkspaces(s Workspaces) {
+	h.mux.HandleFunc("GET /workspaces", func(w http.ResponseWriter, r *http.Request) {
+		h.listWorkspaces(s, w, r)
+	})
+	h.mux.HandleFunc("GET /workspaces/{id}", func(w http.ResponseWriter, r *http.Request) {
+		h.getWorkspace(s, w, r)
+	})
+	h.mux.HandleFunc("PATCH /workspaces/{id}", func(w http.ResponseWriter, r *http.Request) {
+		h.updateWorkspace(s, w, r)
+	})
+}
+
+// listWorkspaces serves a page of the caller's workspaces. It takes the
+// optional query parameters tenantId (for platform admins), status,
+// countryId, search, cursor and limit.
+func (h *Handler) listWorkspaces(s Workspaces, w http.ResponseWriter, r *http.Request) {
+	q := newQuery(r)
+	params := workspace.ListWorkspacesParams{
+		CountryID: q.uuid("countryId"),
+		Search:    q.string("search"),
+		Params:    pagination.Params{Cursor: q.string("cursor"), Limit: q.int("limit")},
+	}
+	if tenantID := q.uuid("tenantId"); tenantID != nil {
+		params.TenantID = *tenantID
+	}
+	if status := q.string("status"); status != "" {
+		st := workspace.WorkspaceStatus(status)
+		params.Status = &st
+	}
+	if err := q.err(); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	page, err := s.List(r.Context(), params)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp := pageResponse[workspaceResponse]{Items: make([]workspaceResponse, 0, len(page.Items)), NextCursor: page.NextCursor}
+	for _, ws := range page.Items {
+		resp.Items = append(resp.Items, newWorkspaceResponse(ws))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) getWorkspace(s Workspaces, w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	ws, err := s.Get(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	writeEntity(w, http.StatusOK, ws.Version, newWorkspaceResponse(ws))
+}
+
+// updateWorkspace changes the fields present in the body. With an If-Match
+// header it only applies to the version named there.
+func (h *Handler) updateWorkspace(s Workspaces, w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	version, err := ifMatch(r)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	var req updateWorkspaceRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	ws, err := s.Update(r.Context(), id, workspace.UpdateWorkspaceParams{Code: req.Code, Name: req.Name, Version: version})
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	writeEntity(w, http.StatusOK, ws.Version, newWorkspaceResponse(ws))
+}
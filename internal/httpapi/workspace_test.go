@@ -0,0 +1,160 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"payroll/internal/apperror"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/workspace"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubWorkspaces struct {
+	Workspaces
+	ws     *workspace.Workspace
+	page   *pagination.Page[*workspace.Workspace]
+	err    error
+	listed *workspace.ListWorkspacesParams
+	// updated is what Update was last called with.
+	updated *workspace.UpdateWorkspaceParams
+}
+
+func (s *stubWorkspaces) List(_ context.Context, params workspace.ListWorkspacesParams) (*pagination.Page[*workspace.Workspace], error) {
+	s.listed = &params
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.page, nil
+}
+
+func (s *stubWorkspaces) Get(_ context.Context, id uuid.UUID) (*workspace.Workspace, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.ws == nil || s.ws.ID != id {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "workspace not found")
+	}
+	return s.ws, nil
+}
+
+// Update applies params to ws like the service does, comparing the version.
+func (s *stubWorkspaces) Update(ctx context.Context, id uuid.UUID, params workspace.UpdateWorkspaceParams) (*workspace.Workspace, error) {
+	s.updated = &params
+	ws, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ws.MatchesVersion(params.Version) {
+		return nil, apperror.NewConflictError("test", "workspace was modified since it was read")
+	}
+	if params.Name != nil {
+		ws.Name = *params.Name
+	}
+	ws.Version++
+	return ws, nil
+}
+
+func newTestWorkspace(t *testing.T, tenantID uuid.UUID) *workspace.Workspace {
+	t.Helper()
+	ws, err := workspace.NewWorkspace(workspace.CreateWorkspaceParams{TenantID: tenantID, CountryID: uuid.New(), Code: "HQ", Name: "Headquarters"})
+	require.NoError(t, err)
+	return ws
+}
+
+func TestListWorkspaces_PassesFiltersAndReturnsThePage(t *testing.T) {
+	f := newAPIFixture()
+	ws := newTestWorkspace(t, f.tenantID)
+	f.workspaces.page = &pagination.Page[*workspace.Workspace]{Items: []*workspace.Workspace{ws}, NextCursor: "next"}
+	countryID := uuid.New()
+
+	rec := f.do(http.MethodGet, "/workspaces?status=ACTIVE&countryId="+countryID.String()+"&search=head&cursor=abc&limit=10", "")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	params := f.workspaces.listed
+	require.NotNil(t, params)
+	assert.Equal(t, workspace.WorkspaceStatusActive, *params.Status)
+	assert.Equal(t, countryID, *params.CountryID)
+	assert.Equal(t, "head", params.Search)
+	assert.Equal(t, pagination.Params{Cursor: "abc", Limit: 10}, params.Params)
+	assert.Equal(t, uuid.Nil, params.TenantID)
+
+	var page pageResponse[workspaceResponse]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, ws.ID, page.Items[0].ID)
+	assert.Equal(t, "HQ", page.Items[0].Code)
+	assert.Equal(t, "next", page.NextCursor)
+}
+
+func TestListWorkspaces_RejectsMalformedParameters(t *testing.T) {
+	f := newAPIFixture()
+
+	rec := f.do(http.MethodGet, "/workspaces?limit=ten&countryId=spain", "")
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	details := decodeError(t, rec).Details
+	assert.Contains(t, details, "limit")
+	assert.Contains(t, details, "countryId")
+	assert.Nil(t, f.workspaces.listed)
+}
+
+func TestGetWorkspace(t *testing.T) {
+	f := newAPIFixture()
+	f.workspaces.ws = newTestWorkspace(t, f.tenantID)
+
+	rec := f.do(http.MethodGet, "/workspaces/"+f.workspaces.ws.ID.String(), "")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got workspaceResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, f.workspaces.ws.ID, got.ID)
+	assert.Equal(t, workspace.WorkspaceStatusPending, got.Status)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusNotFound, f.do(http.MethodGet, "/workspaces/not-an-id", "").Code)
+}
+
+func TestUpdateWorkspace_HonoursIfMatch(t *testing.T) {
+	f := newAPIFixture()
+	f.workspaces.ws = newTestWorkspace(t, f.tenantID)
+	target := "/workspaces/" + f.workspaces.ws.ID.String()
+
+	rec := f.do(http.MethodPatch, target, `{"name":"Head office"}`, "If-Match", `"1"`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	assert.Equal(t, int64(1), *f.workspaces.updated.Version)
+	assert.Equal(t, "Head office", *f.workspaces.updated.Name)
+	assert.Nil(t, f.workspaces.updated.Code)
+
+	rec = f.do(http.MethodPatch, target, `{"name":"Main office"}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, apperror.TypeConflict, decodeError(t, rec).Type)
+
+	rec = f.do(http.MethodPatch, target, `{"name":"Main office"}`)
+	require.Equal(t, http.StatusOK, rec.Code, "without If-Match the update is unconditional")
+	assert.Nil(t, f.workspaces.updated.Version)
+}
+
+func TestUpdateWorkspace_RejectsMalformedRequests(t *testing.T) {
+	f := newAPIFixture()
+	f.workspaces.ws = newTestWorkspace(t, f.tenantID)
+	target := "/workspaces/" + f.workspaces.ws.ID.String()
+
+	for _, tc := range []struct{ body, ifMatch string }{
+		{`{"name":"Head office"}`, `W/"1"`},
+		{`{"name":`, `"1"`},
+		{`{"status":"ACTIVE"}`, `"1"`},
+	} {
+		rec := f.do(http.MethodPatch, target, tc.body, "If-Match", tc.ifMatch)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, tc.body)
+	}
+	assert.Nil(t, f.workspaces.updated)
+}
@@ -0,0 +1,127 @@
+// Package iso exposes the ISO 3166-1 country and ISO 4217 currency lists
+// embedded in the binary.
+//
+// The country list also records the currency in use in each country, and the
+// currency list carries a display symbol; neither is part of the standards
+// themselves. Precious metals, funds and testing codes (XAU, XDR, XTS, ...)
+// are left out because payroll is never paid in them.
+package iso
+
+import (
+	"embed"
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed data/*.csv
+var data embed.FS
+
+type Country struct {
+	Alpha2  string
+	Alpha3  string
+	Numeric string
+	Name    string
+	// Currency is empty for territories without a currency of their own.
+	Currency string
+}
+
+type Currency struct {
+	Code       string
+	Numeric    string
+	MinorUnits int
+	Name       string
+	Symbol     string
+}
+
+var (
+	countries     []Country
+	countryIndex  = make(map[string]*Country)
+	currencies    []Currency
+	currencyIndex = make(map[string]*Currency)
+)
+
+func init() {
+	for _, record := range readCSV("data/countries.csv") {
+		countries = append(countries, Country{
+			Alpha2:   record[0],
+			Alpha3:   record[1],
+			Numeric:  record[2],
+			Name:     record[3],
+			Currency: record[4],
+		})
+	}
+	for i := range countries {
+		countryIndex[countries[i].Alpha2] = &countries[i]
+		countryIndex[countries[i].Alpha3] = &countries[i]
+	}
+
+	for _, record := range readCSV("data/currencies.csv") {
+		minorUnits, err := strconv.Atoi(record[2])
+		if err != nil {
+			panic("iso: invalid minor units for " + record[0])
+		}
+		currencies = append(currencies, Currency{
+			Code:       record[0],
+			Numeric:    record[1],
+			MinorUnits: minorUnits,
+			Name:       record[3],
+			Symbol:     record[4],
+		})
+	}
+	for i := range currencies {
+		currencyIndex[currencies[i].Code] = &currencies[i]
+	}
+}
+
+// readCSV returns the records of an embedded file without its header. The
+// data ships with the binary, so a malformed file is a programming error.
+func readCSV(name string) [][]string {
+	f, err := data.Open(name)
+	if err != nil {
+		panic("iso: " + err.Error())
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		panic("iso: " + name + ": " + err.Error())
+	}
+	return records[1:]
+}
+
+// LookupCountry finds a country by its alpha-2 or alpha-3 code, ignoring
+// case.
+func LookupCountry(code string) (Country, bool) {
+	c, ok := countryIndex[strings.ToUpper(code)]
+	if !ok {
+		return Country{}, false
+	}
+	return *c, true
+}
+
+// LookupCurrency finds a currency by its alphabetic code, ignoring case.
+func LookupCurrency(code string) (Currency, bool) {
+	c, ok := currencyIndex[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, false
+	}
+	return *c, true
+}
+
+// Countries returns every country sorted by alpha-2 code.
+func Countries() []Country {
+	list := make([]Country, len(countries))
+	copy(list, countries)
+	sort.Slice(list, func(i, j int) bool { return list[i].Alpha2 < list[j].Alpha2 })
+	return list
+}
+
+// Currencies returns every currency sorted by code.
+func Currencies() []Currency {
+	list := make([]Currency, len(currencies))
+	copy(list, currencies)
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
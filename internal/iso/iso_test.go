@@ -0,0 +1,44 @@
+package iso
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCountry(t *testing.T) {
+	byAlpha2, ok := LookupCountry("cl")
+	require.True(t, ok)
+	byAlpha3, ok := LookupCountry("CHL")
+	require.True(t, ok)
+
+	assert.Equal(t, byAlpha2, byAlpha3)
+	assert.Equal(t, "CLP", byAlpha2.Currency)
+
+	_, ok = LookupCountry("ZZ")
+	assert.False(t, ok)
+}
+
+func TestLookupCurrency(t *testing.T) {
+	for code, digits := range map[string]int{"USD": 2, "JPY": 0, "KWD": 3, "CLF": 4} {
+		c, ok := LookupCurrency(code)
+		require.True(t, ok, code)
+		assert.Equal(t, digits, c.MinorUnits, code)
+	}
+
+	_, ok := LookupCurrency("XAU")
+	assert.False(t, ok)
+}
+
+func TestCountryCurrenciesExist(t *testing.T) {
+	countries := Countries()
+	assert.Len(t, countries, 249)
+	for _, c := range countries {
+		if c.Currency == "" {
+			continue
+		}
+		_, ok := LookupCurrency(c.Currency)
+		assert.True(t, ok, "%s uses unknown currency %s", c.Alpha2, c.Currency)
+	}
+}
@@ -0,0 +1,341 @@
+package loan
+
+import (
+	"context"
+	"math"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const modelOrigin = "Loan"
+
+// maxInstallments bounds schedules generated from an installment amount, so a
+// tiny installment cannot produce an endless schedule.
+const maxInstallments = 600
+
+type LoanKind string
+
+const (
+	KindLoan    LoanKind = "LOAN"
+	KindAdvance LoanKind = "ADVANCE"
+)
+
+func (k LoanKind) IsValid() bool {
+	switch k {
+	case KindLoan, KindAdvance:
+		return true
+	}
+	return false
+}
+
+type LoanStatus string
+
+const (
+	StatusActive  LoanStatus = "ACTIVE"
+	StatusPaidOff LoanStatus = "PAID_OFF"
+)
+
+// Installment is one entry of the amortisation schedule. Installments are
+// recovered oldest first; whatever is not covered by a payroll run stays
+// outstanding and is picked up again by the next one.
+type Installment struct {
+	Number    int
+	DueDate   time.Time
+	Principal money.Amount
+	Interest  money.Amount
+	Paid      money.Amount
+}
+
+func (i Installment) Amount() money.Amount {
+	return i.Principal + i.Interest
+}
+
+func (i Installment) Outstanding() money.Amount {
+	return i.Amount() - i.Paid
+}
+
+type Allocation struct {
+	Installment int
+	Amount      money.Amount
+}
+
+// Repayment records money recovered on a loan. RunID is nil for payments
+// made outside payroll, such as an early payoff.
+type Repayment struct {
+	RunID       *uuid.UUID
+	Date        time.Time
+	Amount      money.Amount
+	Allocations []Allocation
+}
+
+type Loan struct {
+	domain.BaseEntity
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	EmployeeID  uuid.UUID
+
+	Kind      LoanKind
+	Status    LoanStatus
+	Principal money.Amount
+	// Annual interest rate in basis points (1250 = 12.5%).
+	InterestRate int64
+	StartDate    time.Time
+	Schedule     []Installment
+	Repayments   []Repayment
+}
+
+type CreateLoanParams struct {
+	TenantID     uuid.UUID
+	WorkspaceID  uuid.UUID
+	EmployeeID   uuid.UUID
+	Kind         LoanKind
+	Principal    money.Amount
+	InterestRate int64
+	StartDate    time.Time
+	// Exactly one of InstallmentAmount and InstallmentCount must be set.
+	InstallmentAmount *money.Amount
+	InstallmentCount  *int
+}
+
+func NewLoan(params CreateLoanParams) (*Loan, error) {
+	validator := NewValidator()
+
+	if params.TenantID == uuid.Nil {
+		validator.AddError("TenantID", "is empty")
+	}
+	if params.WorkspaceID == uuid.Nil {
+		validator.AddError("WorkspaceID", "is empty")
+	}
+	if params.EmployeeID == uuid.Nil {
+		validator.AddError("EmployeeID", "is empty")
+	}
+	validator.ValidateKind(params.Kind)
+	validator.ValidatePrincipal(params.Principal)
+	validator.ValidateInterestRate(params.Kind, params.InterestRate)
+	validator.ValidateStartDate(params.StartDate)
+	validator.ValidateInstallments(params.InstallmentAmount, params.InstallmentCount)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	var schedule []Installment
+	if params.InstallmentCount != nil {
+		schedule = scheduleByCount(params.Principal, params.InterestRate, params.StartDate, *params.InstallmentCount)
+	} else {
+		var ok bool
+		schedule, ok = scheduleByAmount(params.Principal, params.InterestRate, params.StartDate, *params.InstallmentAmount)
+		if !ok {
+			validator.AddError("InstallmentAmount", "is too small to repay the loan")
+			return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+		}
+	}
+
+	loan := &Loan{
+		TenantID:     params.TenantID,
+		WorkspaceID:  params.WorkspaceID,
+		EmployeeID:   params.EmployeeID,
+		Kind:         params.Kind,
+		Status:       StatusActive,
+		Principal:    params.Principal,
+		InterestRate: params.InterestRate,
+		StartDate:    params.StartDate,
+		Schedule:     schedule,
+	}
+	loan.Initialize()
+
+	return loan, nil
+}
+
+// periodInterest returns the monthly interest on balance, rounded to the
+// minor unit.
+func periodInterest(balance money.Amount, rate int64) money.Amount {
+	return balance.MulDiv(rate, 12*10000)
+}
+
+// annuityPayment is the fixed installment that repays principal in count
+// monthly payments, rounded up so the last installment is never the largest.
+func annuityPayment(principal money.Amount, rate int64, count int) money.Amount {
+	if rate == 0 {
+		return (principal + money.Amount(count) - 1) / money.Amount(count)
+	}
+	r := float64(rate) / 12 / 10000
+	payment := float64(principal) * r / (1 - math.Pow(1+r, -float64(count)))
+	return money.Amount(math.Ceil(payment))
+}
+
+func scheduleByCount(principal money.Amount, rate int64, start time.Time, count int) []Installment {
+	payment := annuityPayment(principal, rate, count)
+	schedule := make([]Installment, 0, count)
+	balance := principal
+	for n := 1; n <= count && balance > 0; n++ {
+		interest := periodInterest(balance, rate)
+		part := payment - interest
+		if n == count || part > balance {
+			part = balance
+		}
+		schedule = append(schedule, Installment{
+			Number:    n,
+			DueDate:   start.AddDate(0, n-1, 0),
+			Principal: part,
+			Interest:  interest,
+		})
+		balance -= part
+	}
+	return schedule
+}
+
+func scheduleByAmount(principal money.Amount, rate int64, start time.Time, payment money.Amount) ([]Installment, bool) {
+	var schedule []Installment
+	balance := principal
+	for n := 1; balance > 0; n++ {
+		interest := periodInterest(balance, rate)
+		if n > maxInstallments || payment <= interest {
+			return nil, false
+		}
+		part := money.Min(payment-interest, balance)
+		schedule = append(schedule, Installment{
+			Number:    n,
+			DueDate:   start.AddDate(0, n-1, 0),
+			Principal: part,
+			Interest:  interest,
+		})
+		balance -= part
+	}
+	return schedule, true
+}
+
+func (l *Loan) IsActive() bool {
+	return l.Status == StatusActive
+}
+
+// Outstanding is everything still owed under the current schedule,
+// including interest not yet due.
+func (l *Loan) Outstanding() money.Amount {
+	var total money.Amount
+	for _, inst := range l.Schedule {
+		total += inst.Outstanding()
+	}
+	return total
+}
+
+// DueAsOf is the amount due on or before date that has not been recovered,
+// including arrears carried forward from earlier runs.
+func (l *Loan) DueAsOf(date time.Time) money.Amount {
+	var total money.Amount
+	for _, inst := range l.Schedule {
+		if !inst.DueDate.After(date) {
+			total += inst.Outstanding()
+		}
+	}
+	return total
+}
+
+// PayoffAmount is what settles the loan on date: everything already due plus
+// the remaining principal. Interest of installments not yet due is waived.
+func (l *Loan) PayoffAmount(date time.Time) money.Amount {
+	var total money.Amount
+	for _, inst := range l.Schedule {
+		if inst.DueDate.After(date) {
+			total += inst.Principal - inst.Paid
+		} else {
+			total += inst.Outstanding()
+		}
+	}
+	return total
+}
+
+func (l *Loan) repaymentForRun(runID uuid.UUID) int {
+	for i, rep := range l.Repayments {
+		if rep.RunID != nil && *rep.RunID == runID {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasRepaymentForRun reports whether runID already recovered money from the
+// loan.
+func (l *Loan) HasRepaymentForRun(runID uuid.UUID) bool {
+	return l.repaymentForRun(runID) >= 0
+}
+
+// RecoverInRun recovers up to available from the installments due by
+// date. Recalculating the same run first reverses what it recovered before,
+// so the operation is idempotent per run. It returns the amount recovered.
+func (l *Loan) RecoverInRun(runID uuid.UUID, date time.Time, available money.Amount) money.Amount {
+	if idx := l.repaymentForRun(runID); idx >= 0 {
+		for _, alloc := range l.Repayments[idx].Allocations {
+			l.Schedule[alloc.Installment-1].Paid -= alloc.Amount
+		}
+		l.Repayments = append(l.Repayments[:idx], l.Repayments[idx+1:]...)
+		l.Status = StatusActive
+	}
+
+	repayment := Repayment{RunID: &runID, Date: date}
+	for i := range l.Schedule {
+		inst := &l.Schedule[i]
+		if available <= 0 || inst.DueDate.After(date) {
+			break
+		}
+		amount := money.Min(inst.Outstanding(), available)
+		if amount <= 0 {
+			continue
+		}
+		inst.Paid += amount
+		available -= amount
+		repayment.Amount += amount
+		repayment.Allocations = append(repayment.Allocations, Allocation{Installment: inst.Number, Amount: amount})
+	}
+
+	if repayment.Amount > 0 {
+		l.Repayments = append(l.Repayments, repayment)
+	}
+	l.settleIfRepaid()
+	l.Touch()
+
+	return repayment.Amount
+}
+
+// Payoff settles the loan on date and returns the amount paid.
+func (l *Loan) Payoff(date time.Time) money.Amount {
+	repayment := Repayment{Date: date}
+	for i := range l.Schedule {
+		inst := &l.Schedule[i]
+		if inst.DueDate.After(date) {
+			inst.Interest = 0
+		}
+		amount := inst.Outstanding()
+		if amount <= 0 {
+			continue
+		}
+		inst.Paid += amount
+		repayment.Amount += amount
+		repayment.Allocations = append(repayment.Allocations, Allocation{Installment: inst.Number, Amount: amount})
+	}
+
+	l.Repayments = append(l.Repayments, repayment)
+	l.settleIfRepaid()
+	l.Touch()
+
+	return repayment.Amount
+}
+
+func (l *Loan) settleIfRepaid() {
+	if l.Outstanding() == 0 {
+		l.Status = StatusPaidOff
+	}
+}
+
+type Repository interface {
+	Create(ctx context.Context, loan *Loan) error
+	Get(ctx context.Context, id uuid.UUID) (*Loan, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, loan *Loan) error
+	ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*Loan, error)
+}
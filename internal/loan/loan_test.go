@@ -0,0 +1,153 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/money"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+func newTestLoan(t *testing.T, principal money.Amount, rate int64, count int) *Loan {
+	t.Helper()
+	loan, err := NewLoan(CreateLoanParams{
+		TenantID:         uuid.New(),
+		WorkspaceID:      uuid.New(),
+		EmployeeID:       uuid.New(),
+		Kind:             KindLoan,
+		Principal:        principal,
+		InterestRate:     rate,
+		StartDate:        start,
+		InstallmentCount: &count,
+	})
+	require.NoError(t, err)
+	return loan
+}
+
+func sumPrincipal(schedule []Installment) money.Amount {
+	var total money.Amount
+	for _, inst := range schedule {
+		total += inst.Principal
+	}
+	return total
+}
+
+func TestNewLoan_ScheduleByCountRepaysPrincipal(t *testing.T) {
+	loan := newTestLoan(t, 1_000_000, 1200, 12)
+
+	require.Len(t, loan.Schedule, 12)
+	assert.Equal(t, money.Amount(1_000_000), sumPrincipal(loan.Schedule))
+	assert.Equal(t, money.Amount(10_000), loan.Schedule[0].Interest)
+	assert.Equal(t, start.AddDate(0, 11, 0), loan.Schedule[11].DueDate)
+	for _, inst := range loan.Schedule[:11] {
+		assert.Equal(t, money.Amount(88_849), inst.Amount())
+	}
+}
+
+func TestNewLoan_ZeroInterestSplitsEvenly(t *testing.T) {
+	loan := newTestLoan(t, 100, 0, 3)
+
+	require.Len(t, loan.Schedule, 3)
+	assert.Equal(t, money.Amount(34), loan.Schedule[0].Amount())
+	assert.Equal(t, money.Amount(32), loan.Schedule[2].Amount())
+}
+
+func TestNewLoan_ScheduleByAmount(t *testing.T) {
+	amount := money.Amount(300)
+	loan, err := NewLoan(CreateLoanParams{
+		TenantID:          uuid.New(),
+		WorkspaceID:       uuid.New(),
+		EmployeeID:        uuid.New(),
+		Kind:              KindAdvance,
+		Principal:         1000,
+		StartDate:         start,
+		InstallmentAmount: &amount,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, loan.Schedule, 4)
+	assert.Equal(t, money.Amount(100), loan.Schedule[3].Amount())
+}
+
+func TestNewLoan_ValidationErrors(t *testing.T) {
+	amount := money.Amount(1)
+	count := 2
+	_, err := NewLoan(CreateLoanParams{
+		Kind:              KindAdvance,
+		Principal:         0,
+		InterestRate:      500,
+		InstallmentAmount: &amount,
+		InstallmentCount:  &count,
+	})
+
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, apperror.TypeInvalid, domainErr.Type)
+	for _, field := range []string{"TenantID", "WorkspaceID", "EmployeeID", "Principal", "InterestRate", "StartDate", "Installments"} {
+		assert.Contains(t, domainErr.Details, field)
+	}
+}
+
+func TestNewLoan_InstallmentTooSmall(t *testing.T) {
+	amount := money.Amount(10)
+	_, err := NewLoan(CreateLoanParams{
+		TenantID:          uuid.New(),
+		WorkspaceID:       uuid.New(),
+		EmployeeID:        uuid.New(),
+		Kind:              KindLoan,
+		Principal:         1_000_000,
+		InterestRate:      1200,
+		StartDate:         start,
+		InstallmentAmount: &amount,
+	})
+
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Contains(t, domainErr.Details, "InstallmentAmount")
+}
+
+func TestRecoverInRun_CarriesForwardWhenNetInsufficient(t *testing.T) {
+	loan := newTestLoan(t, 300, 0, 3)
+	first, second := uuid.New(), uuid.New()
+
+	recovered := loan.RecoverInRun(first, start, 60)
+	assert.Equal(t, money.Amount(60), recovered)
+	assert.Equal(t, money.Amount(40), loan.DueAsOf(start))
+
+	recovered = loan.RecoverInRun(second, start.AddDate(0, 1, 0), 1000)
+	assert.Equal(t, money.Amount(140), recovered)
+	assert.Equal(t, money.Amount(0), loan.DueAsOf(start.AddDate(0, 1, 0)))
+	assert.Equal(t, money.Amount(100), loan.Outstanding())
+	assert.True(t, loan.IsActive())
+}
+
+func TestRecoverInRun_RecalculationIsIdempotent(t *testing.T) {
+	loan := newTestLoan(t, 300, 0, 3)
+	run := uuid.New()
+
+	loan.RecoverInRun(run, start, 100)
+	loan.RecoverInRun(run, start, 30)
+
+	assert.Equal(t, money.Amount(270), loan.Outstanding())
+	require.Len(t, loan.Repayments, 1)
+	assert.Equal(t, money.Amount(30), loan.Repayments[0].Amount)
+}
+
+func TestPayoff_WaivesFutureInterest(t *testing.T) {
+	loan := newTestLoan(t, 1_000_000, 1200, 12)
+	loan.RecoverInRun(uuid.New(), start, 1_000_000)
+
+	expected := loan.PayoffAmount(start)
+	paid := loan.Payoff(start)
+
+	assert.Equal(t, expected, paid)
+	assert.Equal(t, 1_000_000-loan.Schedule[0].Principal, paid)
+	assert.Equal(t, StatusPaidOff, loan.Status)
+	assert.Equal(t, money.Amount(0), loan.Outstanding())
+}
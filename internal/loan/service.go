@@ -0,0 +1,181 @@
+package loan
+
+import (
+	"bytes"
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/tenant"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	serviceOrigin = "LoanService"
+	deductionCode = "LOAN"
+)
+
+type Service struct {
+	repo         Repository
+	employeeRepo employee.Repository
+	logger       logger.Logger
+}
+
+func NewService(r Repository, er employee.Repository, l logger.Logger) *Service {
+	return &Service{
+		repo:         r,
+		employeeRepo: er,
+		logger:       l,
+	}
+}
+
+func (s *Service) Create(ctx context.Context, params CreateLoanParams) (*Loan, error) {
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	params.TenantID = tenantID
+	if err := auth.Authorize(ctx, auth.PermEmployeeWrite, auth.Resource{WorkspaceID: params.WorkspaceID, EmployeeID: params.EmployeeID}, serviceOrigin); err != nil {
+		return nil, err
+	}
+
+	loan, err := NewLoan(params)
+	if err != nil {
+		s.logger.Warn("Failed to create loan due to validation errors", "errors", err)
+		return nil, err
+	}
+
+	emp, err := s.employeeRepo.GetByID(ctx, params.EmployeeID)
+	if err != nil || emp.IsDeleted() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Invalid EmployeeID")
+	}
+	if emp.TenantID != loan.TenantID || emp.WorkspaceID != loan.WorkspaceID {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Employee does not belong to the given workspace")
+	}
+
+	if err := s.repo.Create(ctx, loan); err != nil {
+		s.logger.Error(err, "Failed to save loan to repository")
+		return nil, err
+	}
+
+	s.logger.Info("Loan created successfully", "loan_id", loan.ID, "employee_id", loan.EmployeeID)
+	return loan, nil
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Loan, error) {
+	return s.get(ctx, id, auth.PermEmployeeRead)
+}
+
+// get returns the loan if it belongs to the caller's tenant and the caller
+// holds perm on it.
+func (s *Service) get(ctx context.Context, id uuid.UUID, perm auth.Permission) (*Loan, error) {
+	loan, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, loan.TenantID, serviceOrigin, "Loan not found"); err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, perm, loan.resource(), serviceOrigin); err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+func (s *Service) ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*Loan, error) {
+	loans, err := s.repo.ListByEmployeeID(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	loans, err = tenant.Filter(ctx, loans, func(l *Loan) uuid.UUID { return l.TenantID })
+	if err != nil {
+		return nil, err
+	}
+	return auth.Filter(ctx, auth.PermEmployeeRead, loans, (*Loan).resource)
+}
+
+// Payoff settles an active loan early. Interest of installments not yet due
+// is waived.
+func (s *Service) Payoff(ctx context.Context, id uuid.UUID, date time.Time) (*Loan, error) {
+	loan, err := s.get(ctx, id, auth.PermEmployeeWrite)
+	if err != nil {
+		return nil, err
+	}
+	if !loan.IsActive() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Loan is not active")
+	}
+
+	amount := loan.Payoff(date)
+
+	if err := s.repo.Update(ctx, loan); err != nil {
+		s.logger.Error(err, "Failed to save loan payoff", "loan_id", id)
+		return nil, err
+	}
+
+	s.logger.Info("Loan paid off", "loan_id", id, "amount", amount)
+	return loan, nil
+}
+
+// Deduct implements payroll.Deductor. It recovers the installments due by
+// the end of the run period from the employee's loans, oldest loan first,
+// without taking net pay below zero. Anything left unpaid is carried forward
+// to the next run.
+func (s *Service) Deduct(ctx context.Context, run *payroll.Run, result *payroll.Result) ([]payroll.Item, error) {
+	loans, err := s.repo.ListByEmployeeID(ctx, result.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(loans, func(i, j int) bool {
+		if !loans[i].CreatedAt.Equal(loans[j].CreatedAt) {
+			return loans[i].CreatedAt.Before(loans[j].CreatedAt)
+		}
+		return bytes.Compare(loans[i].ID[:], loans[j].ID[:]) < 0
+	})
+
+	available := result.Net
+	var items []payroll.Item
+	for _, loan := range loans {
+		if loan.TenantID != run.TenantID {
+			continue
+		}
+		if !loan.IsActive() && !loan.HasRepaymentForRun(run.ID) {
+			continue
+		}
+
+		recovered := loan.RecoverInRun(run.ID, run.PeriodEnd, available)
+		if err := s.repo.Update(ctx, loan); err != nil {
+			s.logger.Error(err, "Failed to save loan repayment", "loan_id", loan.ID, "run_id", run.ID)
+			return nil, err
+		}
+
+		if due := loan.DueAsOf(run.PeriodEnd); due > 0 {
+			s.logger.Warn("Net pay insufficient for loan installment, carrying forward",
+				"loan_id", loan.ID, "run_id", run.ID, "carried_forward", due)
+		}
+		if recovered == 0 {
+			continue
+		}
+
+		available -= recovered
+		loanID := loan.ID
+		items = append(items, payroll.Item{
+			Code:        deductionCode,
+			Description: string(loan.Kind),
+			Kind:        payroll.ItemKindDeduction,
+			Amount:      recovered,
+			SourceID:    &loanID,
+		})
+	}
+
+	return items, nil
+}
+
+// resource is what permissions on the loan are checked against.
+func (l *Loan) resource() auth.Resource {
+	return auth.Resource{WorkspaceID: l.WorkspaceID, EmployeeID: l.EmployeeID}
+}
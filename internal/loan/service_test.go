@@ -0,0 +1,218 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/money"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/uow"
+	"payroll/internal/testkit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepo stores copies of loans, undoes updates when a uow.Memory unit
+// of work rolls back, and lists an employee's loans newest first, so that
+// tests notice when the service relies on the store's order.
+type memoryRepo struct {
+	Repository
+	loans []*Loan
+}
+
+func clone(l *Loan) *Loan {
+	c := *l
+	c.Schedule = slices.Clone(l.Schedule)
+	c.Repayments = slices.Clone(l.Repayments)
+	for i := range c.Repayments {
+		c.Repayments[i].Allocations = slices.Clone(c.Repayments[i].Allocations)
+	}
+	return &c
+}
+
+func (r *memoryRepo) ListByEmployeeID(_ context.Context, employeeID uuid.UUID) ([]*Loan, error) {
+	var out []*Loan
+	for i := len(r.loans) - 1; i >= 0; i-- {
+		if r.loans[i].EmployeeID == employeeID {
+			out = append(out, clone(r.loans[i]))
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryRepo) Get(_ context.Context, id uuid.UUID) (*Loan, error) {
+	if l := r.get(id); l != nil {
+		return clone(l), nil
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "loan not found")
+}
+
+func (r *memoryRepo) Update(ctx context.Context, l *Loan) error {
+	for i, stored := range r.loans {
+		if stored.ID == l.ID {
+			r.loans[i] = clone(l)
+			uow.OnRollback(ctx, func() { r.loans[i] = stored })
+			return nil
+		}
+	}
+	return apperror.New(apperror.TypeNotFound, "test", "loan not found")
+}
+
+// get returns the stored loan.
+func (r *memoryRepo) get(id uuid.UUID) *Loan {
+	for _, l := range r.loans {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
+// deductFixture has two single-installment loans of 100 due on start, the
+// first taken out a day before the second.
+type deductFixture struct {
+	service      *Service
+	repo         *memoryRepo
+	employees    *employee.MemoryRepository
+	older, newer uuid.UUID
+	run          *payroll.Run
+}
+
+func newDeductFixture(t *testing.T) *deductFixture {
+	older := newTestLoan(t, 100, 0, 1)
+	newer := newTestLoan(t, 100, 0, 1)
+	newer.TenantID, newer.EmployeeID = older.TenantID, older.EmployeeID
+	older.CreatedAt = newer.CreatedAt.AddDate(0, 0, -1)
+
+	run := &payroll.Run{TenantID: older.TenantID, WorkspaceID: uuid.New(), PeriodEnd: start, Status: payroll.RunStatusOpen}
+	run.Initialize()
+	repo := &memoryRepo{loans: []*Loan{older, newer}}
+	employees := employee.NewMemoryRepository()
+	borrower := &employee.Employee{TenantID: run.TenantID, WorkspaceID: run.WorkspaceID}
+	borrower.Initialize()
+	borrower.ID = older.EmployeeID
+	require.NoError(t, employees.Create(context.Background(), borrower))
+	return &deductFixture{
+		service:   NewService(repo, nil, logger.Nop{}),
+		repo:      repo,
+		employees: employees,
+		older:     older.ID,
+		newer:     newer.ID,
+		run:       run,
+	}
+}
+
+func (f *deductFixture) employeeID() uuid.UUID {
+	return f.repo.get(f.older).EmployeeID
+}
+
+func (f *deductFixture) deduct(t *testing.T, run *payroll.Run, net money.Amount) []payroll.Item {
+	items, err := f.service.Deduct(context.Background(), run, &payroll.Result{EmployeeID: f.employeeID(), Net: net})
+	require.NoError(t, err)
+	return items
+}
+
+func TestDeduct_RecoversOldestLoanFirstAndCarriesForward(t *testing.T) {
+	f := newDeductFixture(t)
+
+	items := f.deduct(t, f.run, 150)
+	require.Len(t, items, 2)
+	assert.Equal(t, f.older, *items[0].SourceID)
+	assert.Equal(t, money.Amount(100), items[0].Amount)
+	assert.Equal(t, f.newer, *items[1].SourceID)
+	assert.Equal(t, money.Amount(50), items[1].Amount)
+	assert.False(t, f.repo.get(f.older).IsActive())
+	assert.Equal(t, money.Amount(50), f.repo.get(f.newer).DueAsOf(start), "the shortfall is carried forward")
+
+	next := &payroll.Run{TenantID: f.run.TenantID, PeriodEnd: start.AddDate(0, 1, 0)}
+	next.Initialize()
+	items = f.deduct(t, next, 1000)
+	require.Len(t, items, 1)
+	assert.Equal(t, money.Amount(50), items[0].Amount)
+	assert.False(t, f.repo.get(f.newer).IsActive())
+}
+
+func TestDeduct_InsufficientNetDeductsNothing(t *testing.T) {
+	f := newDeductFixture(t)
+
+	assert.Empty(t, f.deduct(t, f.run, 0))
+	assert.Equal(t, money.Amount(100), f.repo.get(f.older).DueAsOf(start))
+	assert.Equal(t, money.Amount(100), f.repo.get(f.newer).DueAsOf(start))
+	assert.True(t, f.repo.get(f.older).IsActive())
+
+	items := f.deduct(t, f.run, 100)
+	require.Len(t, items, 1, "recalculating the run replaces its earlier recovery")
+	assert.Equal(t, f.older, *items[0].SourceID)
+}
+
+// failingResults is a payroll store whose results cannot be saved.
+type failingResults struct {
+	payroll.Repository
+	run *payroll.Run
+}
+
+var errStoreDown = errors.New("result store unavailable")
+
+func (r *failingResults) GetRun(context.Context, uuid.UUID) (*payroll.Run, error) {
+	return r.run, nil
+}
+
+func (r *failingResults) GetResult(context.Context, uuid.UUID, uuid.UUID) (*payroll.Result, error) {
+	return nil, apperror.New(apperror.TypeNotFound, "test", "result not found")
+}
+
+func (r *failingResults) SaveResult(context.Context, *payroll.Result) error {
+	return errStoreDown
+}
+
+func TestDeduct_RollsBackWithThePayrollResult(t *testing.T) {
+	f := newDeductFixture(t)
+	payrollService := payroll.NewService(&failingResults{run: f.run}, f.employees, nil, nil, uow.NewMemory(),
+		&testkit.Audit{}, &testkit.Publisher{}, logger.Nop{}, f.service)
+
+	_, err := payrollService.Calculate(testkit.AdminContext(f.run.TenantID), f.run.ID, payroll.CalculateParams{
+		EmployeeID: f.employeeID(),
+		Currency:   "USD",
+		Earnings:   []payroll.Item{{Code: "SALARY", Kind: payroll.ItemKindEarning, Amount: 1000}},
+	})
+
+	require.ErrorIs(t, err, errStoreDown)
+	for _, id := range []uuid.UUID{f.older, f.newer} {
+		loan := f.repo.get(id)
+		assert.Empty(t, loan.Repayments, "the repayment is undone with the result")
+		assert.Equal(t, money.Amount(100), loan.DueAsOf(start))
+	}
+}
+
+func TestService_DoesNotLeakLoansAcrossTenants(t *testing.T) {
+	f := newDeductFixture(t)
+
+	_, err := f.service.Get(testkit.AdminContext(f.run.TenantID), f.older)
+	require.NoError(t, err)
+
+	other := testkit.AdminContext(uuid.New())
+	_, err = f.service.Get(other, f.older)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	list, err := f.service.ListByEmployeeID(other, f.employeeID())
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = f.service.Payoff(other, f.older, start)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+
+	otherRun := &payroll.Run{TenantID: uuid.New(), PeriodEnd: start}
+	otherRun.Initialize()
+	items, err := f.service.Deduct(other, otherRun, &payroll.Result{EmployeeID: f.employeeID(), Net: 1000})
+	require.NoError(t, err)
+	assert.Empty(t, items, "another tenant's run recovers nothing")
+	for _, id := range []uuid.UUID{f.older, f.newer} {
+		assert.True(t, f.repo.get(id).IsActive())
+		assert.Empty(t, f.repo.get(id).Repayments)
+	}
+}
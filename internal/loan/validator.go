@@ -0,0 +1,59 @@
+package loan
+
+import (
+	"fmt"
+	"payroll/internal/money"
+	"payroll/internal/platform/validation"
+	"time"
+)
+
+const maxInterestRate = 10000
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+func (v *Validator) ValidateKind(kind LoanKind) {
+	if !kind.IsValid() {
+		v.AddError("Kind", "is invalid")
+	}
+}
+
+func (v *Validator) ValidatePrincipal(principal money.Amount) {
+	if !principal.IsPositive() {
+		v.AddError("Principal", "must be greater than zero")
+	}
+}
+
+func (v *Validator) ValidateInterestRate(kind LoanKind, rate int64) {
+	if rate < 0 {
+		v.AddError("InterestRate", "cannot be negative")
+	} else if rate > maxInterestRate {
+		v.AddError("InterestRate", fmt.Sprintf("must be at most %d basis points", maxInterestRate))
+	} else if kind == KindAdvance && rate != 0 {
+		v.AddError("InterestRate", "must be zero for salary advances")
+	}
+}
+
+func (v *Validator) ValidateStartDate(startDate time.Time) {
+	if startDate.IsZero() {
+		v.AddError("StartDate", "is empty")
+	}
+}
+
+func (v *Validator) ValidateInstallments(amount *money.Amount, count *int) {
+	switch {
+	case amount == nil && count == nil:
+		v.AddError("Installments", "either InstallmentAmount or InstallmentCount is required")
+	case amount != nil && count != nil:
+		v.AddError("Installments", "only one of InstallmentAmount or InstallmentCount can be set")
+	case amount != nil && !amount.IsPositive():
+		v.AddError("InstallmentAmount", "must be greater than zero")
+	case count != nil && (*count < 1 || *count > maxInstallments):
+		v.AddError("InstallmentCount", fmt.Sprintf("must be between 1 and %d", maxInstallments))
+	}
+}
@@ -0,0 +1,15 @@
+package money
+
+import "payroll/internal/iso"
+
+// defaultMinorUnits is used for codes missing from the ISO 4217 data.
+const defaultMinorUnits = 2
+
+// MinorUnits returns the number of decimal digits of the minor unit of the
+// currency as defined by ISO 4217, e.g. 2 for USD (cents) and 0 for JPY.
+func MinorUnits(currency string) int {
+	if c, ok := iso.LookupCurrency(currency); ok {
+		return c.MinorUnits
+	}
+	return defaultMinorUnits
+}
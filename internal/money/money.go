@@ -0,0 +1,68 @@
+package money
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Amount is a monetary value expressed in the minor unit of its currency
+// (e.g. cents). Using integers keeps payroll arithmetic exact.
+type Amount int64
+
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+func (a Amount) IsNegative() bool {
+	return a < 0
+}
+
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+// MulDiv returns a*num/den rounded half away from zero.
+func (a Amount) MulDiv(num, den int64) Amount {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+	product := int64(a) * num
+	if (product < 0) != (den < 0) {
+		return Amount((product - den/2) / den)
+	}
+	return Amount((product + den/2) / den)
+}
+
+func Min(a, b Amount) Amount {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func Max(a, b Amount) Amount {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Format renders the amount as a plain decimal with the given number of
+// minor-unit digits, e.g. 123456 with 2 digits is "1234.56".
+func (a Amount) Format(digits int) string {
+	value := int64(a)
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	if digits <= 0 {
+		return sign + strconv.FormatInt(value, 10)
+	}
+
+	s := strconv.FormatInt(value, 10)
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
@@ -0,0 +1,220 @@
+package payroll
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const modelOrigin = "Payroll"
+
+// Events the service publishes. The payload is the Run, or the Result for
+// EventResultCalculated.
+const (
+	EventRunCreated       = "PayrollRunCreated"
+	EventResultCalculated = "PayrollResultCalculated"
+	EventRunFinalized     = "PayrollRunFinalized"
+)
+
+type RunStatus string
+
+const (
+	RunStatusOpen      RunStatus = "OPEN"
+	RunStatusFinalized RunStatus = "FINALIZED"
+)
+
+func (s RunStatus) IsValid() bool {
+	switch s {
+	case RunStatusOpen, RunStatusFinalized:
+		return true
+	}
+	return false
+}
+
+type ItemKind string
+
+// Employer contributions are paid by the employer on top of gross pay; they
+// count towards the cost of the employee but not towards net pay.
+const (
+	ItemKindEarning      ItemKind = "EARNING"
+	ItemKindDeduction    ItemKind = "DEDUCTION"
+	ItemKindContribution ItemKind = "EMPLOYER_CONTRIBUTION"
+)
+
+func (k ItemKind) IsValid() bool {
+	switch k {
+	case ItemKindEarning, ItemKindDeduction, ItemKindContribution:
+		return true
+	}
+	return false
+}
+
+type Run struct {
+	domain.BaseEntity
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Status      RunStatus
+	FinalizedAt *time.Time
+	Variance    *VarianceAnalysis
+	// VarianceWaiver records why the run is finalized without a variance
+	// analysis, e.g. because it is the workspace's first.
+	VarianceWaiver *VarianceWaiver
+}
+
+// snapshot copies the run deeply enough to serve as the before image of an
+// audited change.
+func (r *Run) snapshot() *Run {
+	c := *r
+	if r.Variance != nil {
+		v := *r.Variance
+		v.Warnings = append([]Warning(nil), r.Variance.Warnings...)
+		c.Variance = &v
+	}
+	return &c
+}
+
+type CreateRunParams struct {
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+func NewRun(params CreateRunParams) (*Run, error) {
+	validator := NewValidator()
+
+	if params.TenantID == uuid.Nil {
+		validator.AddError("TenantID", "is empty")
+	}
+	if params.WorkspaceID == uuid.Nil {
+		validator.AddError("WorkspaceID", "is empty")
+	}
+	validator.ValidatePeriod(params.PeriodStart, params.PeriodEnd)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	run := &Run{
+		TenantID:    params.TenantID,
+		WorkspaceID: params.WorkspaceID,
+		PeriodStart: params.PeriodStart,
+		PeriodEnd:   params.PeriodEnd,
+		Status:      RunStatusOpen,
+	}
+	run.Initialize()
+
+	return run, nil
+}
+
+func (r *Run) IsOpen() bool {
+	return r.Status == RunStatusOpen
+}
+
+// Conversion keeps the amount an item was denominated in before it was
+// converted to the pay currency, with the rate used.
+type Conversion struct {
+	Amount   money.Amount
+	Currency string
+	Rate     string
+}
+
+// Item is a single line of a payroll result. SourceID links the item to the
+// record that produced it (a loan, a garnishment order, ...), if any.
+// Currency is only needed on input when it differs from the pay currency.
+type Item struct {
+	Code        string
+	Description string
+	Kind        ItemKind
+	Amount      money.Amount
+	Currency    string
+	Original    *Conversion
+	SourceID    *uuid.UUID
+}
+
+type Result struct {
+	domain.BaseEntity
+	TenantID      uuid.UUID
+	RunID         uuid.UUID
+	EmployeeID    uuid.UUID
+	Currency      string
+	Items         []Item
+	Gross         money.Amount
+	Deductions    money.Amount
+	Net           money.Amount
+	Contributions money.Amount
+}
+
+func newResult(run *Run, employeeID uuid.UUID, currency string) *Result {
+	result := &Result{
+		TenantID:   run.TenantID,
+		RunID:      run.ID,
+		EmployeeID: employeeID,
+		Currency:   currency,
+	}
+	result.Initialize()
+	return result
+}
+
+func (r *Result) AddItem(item Item) {
+	if item.Currency == "" {
+		item.Currency = r.Currency
+	}
+	r.Items = append(r.Items, item)
+	switch item.Kind {
+	case ItemKindEarning:
+		r.Gross += item.Amount
+	case ItemKindDeduction:
+		r.Deductions += item.Amount
+	case ItemKindContribution:
+		r.Contributions += item.Amount
+	}
+	r.Net = r.Gross - r.Deductions
+}
+
+// EmployerCost is what the employee costs the employer for the run.
+func (r *Result) EmployerCost() money.Amount {
+	return r.Gross + r.Contributions
+}
+
+// Deductor contributes deduction items to a result while it is being
+// calculated. Deductors run in the order they were registered and each one
+// sees the net left by the previous ones, so they must never deduct more than
+// result.Net.
+type Deductor interface {
+	Deduct(ctx context.Context, run *Run, result *Result) ([]Item, error)
+}
+
+// Converter converts an amount between currencies at the rate effective on
+// date and returns the rate used.
+type Converter interface {
+	Convert(ctx context.Context, amount money.Amount, from, to string, date time.Time) (money.Amount, string, error)
+}
+
+type Repository interface {
+	CreateRun(ctx context.Context, run *Run) error
+	GetRun(ctx context.Context, id uuid.UUID) (*Run, error)
+	// UpdateRun must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	UpdateRun(ctx context.Context, run *Run) error
+	// SaveResult stores the result, replacing the one of the same run and
+	// employee, which has the same ID.
+	SaveResult(ctx context.Context, result *Result) error
+	GetResult(ctx context.Context, runID uuid.UUID, employeeID uuid.UUID) (*Result, error)
+	ListResultsByRunID(ctx context.Context, runID uuid.UUID) ([]*Result, error)
+	// IterateResultsByRunID calls fn for every result of the run without
+	// loading them all at once. Iteration stops at the first error from fn.
+	IterateResultsByRunID(ctx context.Context, runID uuid.UUID, fn func(*Result) error) error
+	// ListRunsByTenantID returns the runs whose period ends within [from, to].
+	ListRunsByTenantID(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*Run, error)
+	ExistsOpenRunByWorkspaceIDAndTenantID(ctx context.Context, workspaceID uuid.UUID, tenantID uuid.UUID) (bool, error)
+	// ExistsFinalizedRunEndingBefore reports whether the workspace has a
+	// finalized run whose period ends before the given time.
+	ExistsFinalizedRunEndingBefore(ctx context.Context, workspaceID uuid.UUID, tenantID uuid.UUID, before time.Time) (bool, error)
+}
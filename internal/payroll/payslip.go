@@ -0,0 +1,106 @@
+package payroll
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/money"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/tenant"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payslip is an employee's result in a finalized run.
+type Payslip struct {
+	RunID       uuid.UUID
+	WorkspaceID uuid.UUID
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	FinalizedAt *time.Time
+	Result      *Result
+}
+
+// ListPayslips returns the employee's payslips of the finalized runs whose
+// period ends within [from, to], oldest first. Payslips the caller may not
+// read are left out.
+func (s *Service) ListPayslips(ctx context.Context, employeeID uuid.UUID, from, to time.Time) ([]*Payslip, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := s.repo.ListRunsByTenantID(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var payslips []*Payslip
+	for _, run := range runs {
+		if run.Status != RunStatusFinalized {
+			continue
+		}
+		result, err := s.repo.GetResult(ctx, run.ID, employeeID)
+		if apperror.IsType(err, apperror.TypeNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		payslips = append(payslips, &Payslip{
+			RunID:       run.ID,
+			WorkspaceID: run.WorkspaceID,
+			PeriodStart: run.PeriodStart,
+			PeriodEnd:   run.PeriodEnd,
+			FinalizedAt: run.FinalizedAt,
+			Result:      result,
+		})
+	}
+	sort.SliceStable(payslips, func(i, j int) bool {
+		return payslips[i].PeriodEnd.Before(payslips[j].PeriodEnd)
+	})
+
+	return auth.Filter(ctx, auth.PermPayrollRead, payslips, func(p *Payslip) auth.Resource {
+		return auth.Resource{WorkspaceID: p.WorkspaceID, EmployeeID: employeeID}
+	})
+}
+
+// YearToDate totals an employee's payslips of one calendar year in one pay
+// currency.
+type YearToDate struct {
+	Year          int
+	Currency      string
+	Payslips      int
+	Gross         money.Amount
+	Deductions    money.Amount
+	Net           money.Amount
+	Contributions money.Amount
+}
+
+// YearToDate totals the employee's payslips from the start of asOf's year
+// (in UTC) up to asOf, one total per pay currency.
+func (s *Service) YearToDate(ctx context.Context, employeeID uuid.UUID, asOf time.Time) ([]*YearToDate, error) {
+	asOf = asOf.UTC()
+	start := time.Date(asOf.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	payslips, err := s.ListPayslips(ctx, employeeID, start, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	var totals []*YearToDate
+	byCurrency := make(map[string]*YearToDate)
+	for _, p := range payslips {
+		total, ok := byCurrency[p.Result.Currency]
+		if !ok {
+			total = &YearToDate{Year: asOf.Year(), Currency: p.Result.Currency}
+			byCurrency[p.Result.Currency] = total
+			totals = append(totals, total)
+		}
+		total.Payslips++
+		total.Gross += p.Result.Gross
+		total.Deductions += p.Result.Deductions
+		total.Net += p.Result.Net
+		total.Contributions += p.Result.Contributions
+	}
+	return totals, nil
+}
@@ -0,0 +1,397 @@
+package payroll
+
+import (
+	"context"
+	"fmt"
+	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/employee"
+	"payroll/internal/event"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/tenant"
+	"payroll/internal/platform/uow"
+	"payroll/internal/workspace"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	serviceOrigin = "PayrollService"
+	auditRun      = "PayrollRun"
+	auditResult   = "PayrollResult"
+)
+
+type Service struct {
+	repo          Repository
+	employeeRepo  employee.Repository
+	workspaceRepo workspace.Repository
+	converter     Converter
+	deductors     []Deductor
+	uow           uow.UnitOfWork
+	audit         audit.Recorder
+	events        event.Publisher
+	logger        logger.Logger
+}
+
+func NewService(r Repository, er employee.Repository, wr workspace.Repository, c Converter, u uow.UnitOfWork, a audit.Recorder, p event.Publisher, l logger.Logger, deductors ...Deductor) *Service {
+	return &Service{
+		repo:          r,
+		employeeRepo:  er,
+		workspaceRepo: wr,
+		converter:     c,
+		deductors:     deductors,
+		uow:           u,
+		audit:         a,
+		events:        p,
+		logger:        l,
+	}
+}
+
+// saveRun updates the run, records the change in the audit log and
+// publishes events, in one unit of work.
+func (s *Service) saveRun(ctx context.Context, before, run *Run, events ...event.Event) error {
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdateRun(ctx, run); err != nil {
+			return err
+		}
+		err := s.audit.Record(ctx, audit.Event{
+			TenantID: run.TenantID, EntityType: auditRun, EntityID: run.ID,
+			Action: audit.ActionUpdate, Before: before, After: run,
+		})
+		if err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, events...)
+	})
+}
+
+// CalculateParams describes one employee's pay. Currency is the currency the
+// employee is paid in; earnings denominated in another currency are converted
+// at the rate effective at the end of the run period.
+type CalculateParams struct {
+	EmployeeID uuid.UUID
+	Currency   string
+	Earnings   []Item
+}
+
+func (s *Service) CreateRun(ctx context.Context, params CreateRunParams) (*Run, error) {
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	params.TenantID = tenantID
+	if err := auth.Authorize(ctx, auth.PermPayrollRun, auth.Resource{WorkspaceID: params.WorkspaceID}, serviceOrigin); err != nil {
+		return nil, err
+	}
+
+	run, err := NewRun(params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := s.checkWorkspaceActive(ctx, run); err != nil {
+			return err
+		}
+		if err := s.repo.CreateRun(ctx, run); err != nil {
+			return err
+		}
+		err := s.audit.Record(ctx, audit.Event{
+			TenantID: run.TenantID, EntityType: auditRun, EntityID: run.ID,
+			Action: audit.ActionCreate, After: run,
+		})
+		if err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, event.Event{Type: EventRunCreated, TenantID: run.TenantID, EntityID: run.ID, Payload: run})
+	})
+	if err != nil {
+		s.logger.Error(err, "Failed to save payroll run")
+		return nil, err
+	}
+
+	s.logger.Info("Payroll run created", "run_id", run.ID, "workspace_id", run.WorkspaceID)
+	return run, nil
+}
+
+// checkWorkspaceActive fails unless the run's workspace is ACTIVE. CreateRun
+// calls it in the unit of work that creates the run; see
+// workspace.OpenRunChecker.
+func (s *Service) checkWorkspaceActive(ctx context.Context, run *Run) error {
+	ws, err := s.workspaceRepo.Get(ctx, run.WorkspaceID)
+	if err != nil && !apperror.IsType(err, apperror.TypeNotFound) {
+		return err
+	}
+	if err != nil || ws.IsDeleted() || ws.TenantID != run.TenantID {
+		return apperror.New(apperror.TypeInvalid, serviceOrigin, "Invalid WorkspaceID")
+	}
+	if ws.Status != workspace.WorkspaceStatusActive {
+		return apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll can only run in an ACTIVE workspace")
+	}
+	return nil
+}
+
+func (s *Service) GetRun(ctx context.Context, id uuid.UUID) (*Run, error) {
+	run, err := s.getRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, auth.PermPayrollRead, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// getRun returns the run unless it belongs to another tenant.
+func (s *Service) getRun(ctx context.Context, id uuid.UUID) (*Run, error) {
+	run, err := s.repo.GetRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, run.TenantID, serviceOrigin, "Payroll run not found"); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// authorize checks that the caller holds perm on the run's workspace.
+func authorize(ctx context.Context, perm auth.Permission, run *Run) error {
+	return auth.Authorize(ctx, perm, auth.Resource{WorkspaceID: run.WorkspaceID}, serviceOrigin)
+}
+
+// HasOpenRun implements workspace.OpenRunChecker. It reports whether the
+// workspace has a run of the caller's tenant that is not finalized.
+func (s *Service) HasOpenRun(ctx context.Context, workspaceID uuid.UUID) (bool, error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	return s.repo.ExistsOpenRunByWorkspaceIDAndTenantID(ctx, workspaceID, tenantID)
+}
+
+// ListResults returns the results of the run the caller may read: all of
+// them for payroll staff, and only their own for self-service users.
+func (s *Service) ListResults(ctx context.Context, runID uuid.UUID) ([]*Result, error) {
+	run, err := s.getRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.repo.ListResultsByRunID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	return auth.Filter(ctx, auth.PermPayrollRead, results, func(r *Result) auth.Resource {
+		return auth.Resource{WorkspaceID: run.WorkspaceID, EmployeeID: r.EmployeeID}
+	})
+}
+
+// Calculate computes (or recomputes) the result of one employee in an open
+// run: earnings first, then every registered deductor in order. The employee
+// must be a live employee of the run's workspace. The result and whatever the
+// deductors write, such as loan balances, are saved in one unit of work.
+func (s *Service) Calculate(ctx context.Context, runID uuid.UUID, params CalculateParams) (*Result, error) {
+	validator := NewValidator()
+	if params.EmployeeID == uuid.Nil {
+		validator.AddError("EmployeeID", "is empty")
+	}
+	validator.ValidateCurrency(params.Currency)
+	validator.ValidateEarnings(params.Earnings)
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	var result *Result
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.calculate(ctx, runID, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Service) calculate(ctx context.Context, runID uuid.UUID, params CalculateParams) (*Result, error) {
+	run, err := s.getRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, auth.PermPayrollRun, run); err != nil {
+		return nil, err
+	}
+	if !run.IsOpen() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run is not open")
+	}
+	if err := s.checkEmployee(ctx, run, params.EmployeeID); err != nil {
+		return nil, err
+	}
+
+	// A recalculation replaces the employee's result under the same ID, so
+	// that the deductors and the audit log see one result per employee.
+	result := newResult(run, params.EmployeeID, params.Currency)
+	previous, err := s.repo.GetResult(ctx, run.ID, params.EmployeeID)
+	switch {
+	case err == nil:
+		result.ID, result.CreatedAt, result.Version = previous.ID, previous.CreatedAt, previous.Version
+	case !apperror.IsType(err, apperror.TypeNotFound):
+		return nil, err
+	}
+
+	for _, item := range params.Earnings {
+		item, err := s.toPayCurrency(ctx, run, params.Currency, item)
+		if err != nil {
+			return nil, err
+		}
+		result.AddItem(item)
+	}
+
+	for _, deductor := range s.deductors {
+		items, err := deductor.Deduct(ctx, run, result)
+		if err != nil {
+			s.logger.Error(err, "Deductor failed", "run_id", run.ID, "employee_id", params.EmployeeID)
+			return nil, err
+		}
+		for _, item := range items {
+			result.AddItem(item)
+		}
+	}
+
+	if result.Net.IsNegative() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Deductions exceed gross pay")
+	}
+
+	ev := audit.Event{
+		TenantID: run.TenantID, EntityType: auditResult, EntityID: result.ID,
+		Action: audit.ActionCreate, After: result,
+	}
+	if previous != nil {
+		ev.Action, ev.Before = audit.ActionUpdate, previous
+	}
+
+	if err := s.repo.SaveResult(ctx, result); err != nil {
+		s.logger.Error(err, "Failed to save payroll result", "run_id", run.ID, "employee_id", params.EmployeeID)
+		return nil, err
+	}
+	if err := s.audit.Record(ctx, ev); err != nil {
+		return nil, err
+	}
+	err = s.events.Publish(ctx, event.Event{Type: EventResultCalculated, TenantID: run.TenantID, EntityID: result.ID, Payload: result})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkEmployee fails with TypeNotFound unless the employee exists, is not
+// deleted and works in the run's workspace.
+func (s *Service) checkEmployee(ctx context.Context, run *Run, employeeID uuid.UUID) error {
+	e, err := s.employeeRepo.GetByID(ctx, employeeID)
+	if err != nil && !apperror.IsType(err, apperror.TypeNotFound) {
+		return err
+	}
+	if err != nil || e.IsDeleted() || e.TenantID != run.TenantID || e.WorkspaceID != run.WorkspaceID {
+		return apperror.New(apperror.TypeNotFound, serviceOrigin, "Employee not found")
+	}
+	return nil
+}
+
+func (s *Service) toPayCurrency(ctx context.Context, run *Run, currency string, item Item) (Item, error) {
+	if item.Currency == "" || item.Currency == currency {
+		item.Currency = currency
+		return item, nil
+	}
+	if s.converter == nil {
+		return Item{}, apperror.New(apperror.TypeInvalid, serviceOrigin, "Currency conversion is not configured")
+	}
+
+	converted, rate, err := s.converter.Convert(ctx, item.Amount, item.Currency, currency, run.PeriodEnd)
+	if err != nil {
+		s.logger.Error(err, "Failed to convert payroll item", "run_id", run.ID, "from", item.Currency, "to", currency)
+		return Item{}, err
+	}
+
+	item.Original = &Conversion{Amount: item.Amount, Currency: item.Currency, Rate: rate}
+	item.Amount = converted
+	item.Currency = currency
+	return item, nil
+}
+
+// FinalizeRun approves an open run. The run needs a variance analysis, or a
+// waiver recorded with WaiveVariance. The analysis is refreshed first, since
+// results may have been recalculated since, and the run is only finalized
+// once every warning has been acknowledged.
+func (s *Service) FinalizeRun(ctx context.Context, id uuid.UUID) (*Run, error) {
+	var run *Run
+	var pending int
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		run, pending, err = s.finalize(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pending > 0 {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin,
+			fmt.Sprintf("Payroll run has %d unacknowledged variance warnings", pending))
+	}
+
+	s.logger.Info("Payroll run finalized", "run_id", id)
+	return run, nil
+}
+
+// finalize checks and finalizes the run inside FinalizeRun's unit of work.
+// While warnings are unacknowledged it only saves the refreshed analysis and
+// returns how many are pending.
+func (s *Service) finalize(ctx context.Context, id uuid.UUID) (*Run, int, error) {
+	run, err := s.getRun(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := authorize(ctx, auth.PermPayrollApprove, run); err != nil {
+		return nil, 0, err
+	}
+	if !run.IsOpen() {
+		return nil, 0, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run is not open")
+	}
+	if run.Variance == nil && run.VarianceWaiver == nil {
+		return nil, 0, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run has no variance analysis or waiver")
+	}
+	before := run.snapshot()
+
+	if run.Variance != nil {
+		analysis := &VarianceAnalysis{
+			PreviousRunID:      run.Variance.PreviousRunID,
+			NetChangeThreshold: run.Variance.NetChangeThreshold,
+		}
+		if err := s.analyze(ctx, run, analysis); err != nil {
+			return nil, 0, err
+		}
+		run.Variance = analysis
+
+		if pending := analysis.Unacknowledged(); pending > 0 {
+			run.Touch()
+			if err := s.saveRun(ctx, before, run); err != nil {
+				s.logger.Error(err, "Failed to save variance analysis", "run_id", run.ID)
+				return nil, 0, err
+			}
+			return run, pending, nil
+		}
+	}
+
+	now := time.Now().UTC()
+	run.Status = RunStatusFinalized
+	run.FinalizedAt = &now
+	run.Touch()
+
+	finalized := event.Event{Type: EventRunFinalized, TenantID: run.TenantID, EntityID: run.ID, Payload: run}
+	if err := s.saveRun(ctx, before, run, finalized); err != nil {
+		s.logger.Error(err, "Failed to finalize payroll run", "run_id", id)
+		return nil, 0, err
+	}
+	return run, 0, nil
+}
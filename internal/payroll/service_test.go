@@ -0,0 +1,284 @@
+package payroll
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/money"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/uow"
+	"payroll/internal/testkit"
+	"payroll/internal/workspace"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// balanceDeductor deducts a fixed amount and records it as a write, the way
+// loan recovery updates a loan balance.
+type balanceDeductor struct {
+	amount    money.Amount
+	recovered money.Amount
+}
+
+func (d *balanceDeductor) Deduct(ctx context.Context, _ *Run, _ *Result) ([]Item, error) {
+	d.recovered += d.amount
+	uow.OnRollback(ctx, func() { d.recovered -= d.amount })
+	return []Item{{Code: "LOAN", Kind: ItemKindDeduction, Amount: d.amount}}, nil
+}
+
+// stubEmployees holds the employees payroll may calculate for.
+type stubEmployees struct {
+	employee.Repository
+	employees map[uuid.UUID]*employee.Employee
+}
+
+func newStubEmployees() *stubEmployees {
+	return &stubEmployees{employees: make(map[uuid.UUID]*employee.Employee)}
+}
+
+func (r *stubEmployees) GetByID(_ context.Context, id uuid.UUID) (*employee.Employee, error) {
+	e, ok := r.employees[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "employee not found")
+	}
+	return e, nil
+}
+
+// hire adds an employee of the run's workspace and returns its ID.
+func (r *stubEmployees) hire(run *Run) uuid.UUID {
+	e := &employee.Employee{TenantID: run.TenantID, WorkspaceID: run.WorkspaceID}
+	e.Initialize()
+	r.employees[e.ID] = e
+	return e.ID
+}
+
+func TestCalculate_RollsBackDeductorWritesWhenRejected(t *testing.T) {
+	repo := newMemoryRepo()
+	employees := newStubEmployees()
+	deductor := &balanceDeductor{amount: 5000}
+	svc := NewService(repo, employees, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{}, deductor)
+
+	run := &Run{TenantID: tenantID, WorkspaceID: uuid.New(), Status: RunStatusOpen, PeriodEnd: time.Now()}
+	run.Initialize()
+	repo.runs[run.ID] = run
+
+	_, err := svc.Calculate(ctx, run.ID, CalculateParams{
+		EmployeeID: employees.hire(run),
+		Currency:   "USD",
+		Earnings:   []Item{{Code: "SALARY", Kind: ItemKindEarning, Amount: 1000}},
+	})
+
+	require.True(t, apperror.IsType(err, apperror.TypeInvalid))
+	assert.True(t, deductor.recovered.IsZero())
+}
+
+func TestCalculate_RequiresALiveEmployeeOfTheRunsWorkspace(t *testing.T) {
+	repo := newMemoryRepo()
+	employees := newStubEmployees()
+	svc := NewService(repo, employees, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	run := &Run{TenantID: tenantID, WorkspaceID: uuid.New(), Status: RunStatusOpen, PeriodEnd: time.Now()}
+	run.Initialize()
+	repo.runs[run.ID] = run
+
+	deleted := employees.hire(run)
+	employees.employees[deleted].SoftDelete()
+	otherWorkspace := employees.hire(&Run{TenantID: tenantID, WorkspaceID: uuid.New()})
+	otherTenant := employees.hire(&Run{TenantID: uuid.New(), WorkspaceID: run.WorkspaceID})
+
+	for name, employeeID := range map[string]uuid.UUID{
+		"unknown":         uuid.New(),
+		"deleted":         deleted,
+		"other workspace": otherWorkspace,
+		"other tenant":    otherTenant,
+	} {
+		_, err := svc.Calculate(ctx, run.ID, CalculateParams{
+			EmployeeID: employeeID,
+			Currency:   "USD",
+			Earnings:   []Item{{Code: "SALARY", Kind: ItemKindEarning, Amount: 1000}},
+		})
+		assert.True(t, apperror.IsType(err, apperror.TypeNotFound), name)
+	}
+	assert.Empty(t, repo.results)
+}
+
+func TestService_DoesNotLeakRunsAcrossTenants(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	run := &Run{TenantID: tenantID, WorkspaceID: uuid.New(), Status: RunStatusOpen, PeriodEnd: time.Now()}
+	run.Initialize()
+	repo.runs[run.ID] = run
+
+	open, err := svc.HasOpenRun(ctx, run.WorkspaceID)
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	other := testkit.AdminContext(uuid.New())
+	open, err = svc.HasOpenRun(other, run.WorkspaceID)
+	require.NoError(t, err)
+	assert.False(t, open)
+	_, err = svc.GetRun(other, run.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	_, err = svc.ListResults(other, run.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	_, err = svc.Calculate(other, run.ID, CalculateParams{
+		EmployeeID: uuid.New(),
+		Currency:   "USD",
+		Earnings:   []Item{{Code: "SALARY", Kind: ItemKindEarning, Amount: 1000}},
+	})
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	_, err = svc.FinalizeRun(other, run.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	assert.Equal(t, RunStatusOpen, run.Status)
+}
+
+func TestService_AuthorizesByRole(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	run := &Run{TenantID: tenantID, WorkspaceID: uuid.New(), Status: RunStatusOpen, PeriodEnd: time.Now()}
+	run.Initialize()
+	repo.runs[run.ID] = run
+	ada, grace := netResult(uuid.New(), 1000), netResult(uuid.New(), 2000)
+	repo.results[run.ID] = []*Result{ada, grace}
+
+	manager := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "bob", TenantID: tenantID, Roles: []auth.Role{auth.RolePayrollManager},
+		WorkspaceIDs: []uuid.UUID{run.WorkspaceID},
+	})
+	_, err := svc.FinalizeRun(manager, run.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	self := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "ada", TenantID: tenantID, Roles: []auth.Role{auth.RoleSelfService}, EmployeeID: ada.EmployeeID,
+	})
+	results, err := svc.ListResults(self, run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []*Result{ada}, results)
+
+	approver := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "carol", TenantID: tenantID, Roles: []auth.Role{auth.RoleApprover},
+	})
+	_, err = svc.WaiveVariance(manager, run.ID, WaiveVarianceParams{Reason: "First run"})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = svc.WaiveVariance(approver, run.ID, WaiveVarianceParams{Reason: "First run"})
+	require.NoError(t, err)
+	run, err = svc.FinalizeRun(approver, run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RunStatusFinalized, run.Status)
+	assert.Equal(t, "carol", run.VarianceWaiver.By)
+}
+
+// stubWorkspaces returns a workspace of the given tenant and status for any
+// ID.
+type stubWorkspaces struct {
+	workspace.Repository
+	tenantID uuid.UUID
+	status   workspace.WorkspaceStatus
+}
+
+func (r stubWorkspaces) Get(_ context.Context, id uuid.UUID) (*workspace.Workspace, error) {
+	ws := &workspace.Workspace{TenantID: r.tenantID, Status: r.status}
+	ws.ID = id
+	return ws, nil
+}
+
+func TestCreateRun_RequiresAnActiveWorkspaceOfTheTenant(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	params := CreateRunParams{WorkspaceID: uuid.New(), PeriodStart: start, PeriodEnd: start.AddDate(0, 1, -1)}
+
+	for name, workspaces := range map[string]stubWorkspaces{
+		"pending":      {tenantID: tenantID, status: workspace.WorkspaceStatusPending},
+		"inactive":     {tenantID: tenantID, status: workspace.WorkspaceStatusInactive},
+		"other tenant": {tenantID: uuid.New(), status: workspace.WorkspaceStatusActive},
+	} {
+		t.Run(name, func(t *testing.T) {
+			repo := newMemoryRepo()
+			svc := NewService(repo, nil, workspaces, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+
+			_, err := svc.CreateRun(ctx, params)
+
+			assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+			assert.Empty(t, repo.runs)
+		})
+	}
+}
+
+func TestService_AuditsAndPublishesRunAndResults(t *testing.T) {
+	repo := newMemoryRepo()
+	audits := &testkit.Audit{}
+	events := &testkit.Publisher{}
+	employees := newStubEmployees()
+	workspaces := stubWorkspaces{tenantID: tenantID, status: workspace.WorkspaceStatusActive}
+	svc := NewService(repo, employees, workspaces, nil, uow.NewMemory(), audits, events, logger.Nop{})
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	run, err := svc.CreateRun(ctx, CreateRunParams{WorkspaceID: uuid.New(), PeriodStart: start, PeriodEnd: start.AddDate(0, 1, -1)})
+	require.NoError(t, err)
+	employeeID := employees.hire(run)
+	var results []*Result
+	for _, amount := range []money.Amount{1000, 1200} {
+		result, err := svc.Calculate(ctx, run.ID, CalculateParams{
+			EmployeeID: employeeID,
+			Currency:   "USD",
+			Earnings:   []Item{{Code: "SALARY", Kind: ItemKindEarning, Amount: amount}},
+		})
+		require.NoError(t, err)
+		results = append(results, result)
+	}
+	assert.Equal(t, results[0].ID, results[1].ID, "a recalculation keeps the result's ID")
+	_, err = svc.WaiveVariance(ctx, run.ID, WaiveVarianceParams{Reason: "First run"})
+	require.NoError(t, err)
+	_, err = svc.FinalizeRun(ctx, run.ID)
+	require.NoError(t, err)
+
+	var got []string
+	for _, ev := range audits.Events {
+		got = append(got, ev.EntityType+" "+string(ev.Action))
+	}
+	assert.Equal(t, []string{
+		"PayrollRun CREATE", "PayrollResult CREATE", "PayrollResult UPDATE", "PayrollRun UPDATE", "PayrollRun UPDATE",
+	}, got)
+	assert.Equal(t, RunStatusOpen, audits.Events[4].Before.(*Run).Status)
+	assert.Equal(t, audits.Events[1].EntityID, audits.Events[2].EntityID)
+
+	got = nil
+	for _, ev := range events.Events {
+		got = append(got, ev.Type)
+	}
+	assert.Equal(t, []string{EventRunCreated, EventResultCalculated, EventResultCalculated, EventRunFinalized}, got)
+}
+
+func TestYearToDate_TotalsOwnFinalizedPayslips(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	ada, grace := uuid.New(), uuid.New()
+	for i, status := range []RunStatus{RunStatusFinalized, RunStatusFinalized, RunStatusOpen} {
+		run := &Run{TenantID: tenantID, WorkspaceID: uuid.New(), Status: status,
+			PeriodEnd: time.Date(2026, time.Month(i+1), 28, 0, 0, 0, 0, time.UTC)}
+		run.Initialize()
+		repo.runs[run.ID] = run
+		repo.results[run.ID] = []*Result{netResult(ada, 1000), netResult(grace, 2000)}
+	}
+	lastYear := &Run{TenantID: tenantID, Status: RunStatusFinalized, PeriodEnd: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)}
+	lastYear.Initialize()
+	repo.runs[lastYear.ID] = lastYear
+	repo.results[lastYear.ID] = []*Result{netResult(ada, 1000)}
+
+	self := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "ada", TenantID: tenantID, Roles: []auth.Role{auth.RoleSelfService}, EmployeeID: ada,
+	})
+	totals, err := svc.YearToDate(self, ada, time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, totals, 1)
+	assert.Equal(t, 2, totals[0].Payslips)
+	assert.EqualValues(t, 2000, totals[0].Net)
+
+	payslips, err := svc.ListPayslips(self, grace, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, payslips, "self-service users only see their own payslips")
+}
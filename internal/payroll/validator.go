@@ -0,0 +1,63 @@
+package payroll
+
+import (
+	"fmt"
+	"payroll/internal/platform/validation"
+	"time"
+)
+
+const (
+	maxItemCodeLength  = 20
+	currencyCodeLength = 3
+)
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+func (v *Validator) ValidatePeriod(start, end time.Time) {
+	if start.IsZero() {
+		v.AddError("PeriodStart", "is empty")
+	}
+	if end.IsZero() {
+		v.AddError("PeriodEnd", "is empty")
+	} else if !start.IsZero() && end.Before(start) {
+		v.AddError("PeriodEnd", "cannot be before PeriodStart")
+	}
+}
+
+func (v *Validator) ValidateCurrency(currency string) {
+	if currency == "" {
+		v.AddError("Currency", "is empty")
+	} else if len(currency) != currencyCodeLength {
+		v.AddError("Currency", "must be a 3-letter currency code")
+	}
+}
+
+func (v *Validator) ValidateThreshold(threshold int64) {
+	if threshold < 0 {
+		v.AddError("NetChangeThreshold", "cannot be negative")
+	} else if threshold > MaxNetChangeThreshold {
+		v.AddError("NetChangeThreshold", fmt.Sprintf("cannot exceed %d", MaxNetChangeThreshold))
+	}
+}
+
+func (v *Validator) ValidateEarnings(items []Item) {
+	for _, item := range items {
+		if item.Kind != ItemKindEarning {
+			v.AddError("Earnings", "must only contain earning items")
+		}
+		if item.Code == "" {
+			v.AddError("Earnings", "contains an item with an empty code")
+		} else if len(item.Code) > maxItemCodeLength {
+			v.AddError("Earnings", fmt.Sprintf("contains an item code longer than %d characters", maxItemCodeLength))
+		}
+		if item.Amount.IsNegative() {
+			v.AddError("Earnings", "cannot contain negative amounts")
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package payroll
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/money"
+	"payroll/internal/platform/auth"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultNetChangeThreshold flags net pay changes above 10%.
+	DefaultNetChangeThreshold int64 = 1000
+	// MaxNetChangeThreshold is the highest threshold an analysis may use,
+	// 50%, so that a threshold cannot silence the net change warnings.
+	MaxNetChangeThreshold int64 = 5000
+)
+
+type WarningType string
+
+const (
+	WarningNetChange       WarningType = "NET_CHANGE"
+	WarningCurrencyChanged WarningType = "CURRENCY_CHANGED"
+	WarningNewHire         WarningType = "NEW_HIRE"
+	WarningLeaver          WarningType = "LEAVER"
+	WarningZeroNet         WarningType = "ZERO_NET"
+	WarningNegativeNet     WarningType = "NEGATIVE_NET"
+)
+
+// Warning is a difference between two runs that a reviewer has to look at
+// before the run can be finalized. Previous and Current are the net pay of
+// the employee in each run, when the employee is present in it.
+type Warning struct {
+	ID         uuid.UUID
+	Type       WarningType
+	EmployeeID uuid.UUID
+	Previous   *money.Amount
+	Current    *money.Amount
+	// Change in basis points of the previous net; only set for NET_CHANGE.
+	Change         *int64
+	AcknowledgedBy *string
+	AcknowledgedAt *time.Time
+}
+
+func (w *Warning) IsAcknowledged() bool {
+	return w.AcknowledgedAt != nil
+}
+
+// sameFinding reports whether two warnings describe the same situation, so an
+// acknowledgement survives re-analysis as long as nothing changed.
+func (w *Warning) sameFinding(other *Warning) bool {
+	return w.Type == other.Type && w.EmployeeID == other.EmployeeID &&
+		equalAmounts(w.Previous, other.Previous) && equalAmounts(w.Current, other.Current)
+}
+
+func equalAmounts(a, b *money.Amount) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// VarianceAnalysis is the comparison of a run against a finalized run of the
+// same workspace whose period ended before the run's started.
+type VarianceAnalysis struct {
+	PreviousRunID uuid.UUID
+	// NetChangeThreshold in basis points of the previous net pay.
+	NetChangeThreshold int64
+	AnalyzedAt         time.Time
+	Warnings           []Warning
+}
+
+func (v *VarianceAnalysis) Unacknowledged() int {
+	count := 0
+	for i := range v.Warnings {
+		if !v.Warnings[i].IsAcknowledged() {
+			count++
+		}
+	}
+	return count
+}
+
+// VarianceWaiver is the decision to finalize a run without comparing it to a
+// previous one, which is only possible when there is none.
+type VarianceWaiver struct {
+	By     string
+	Reason string
+	At     time.Time
+}
+
+type AnalyzeVarianceParams struct {
+	PreviousRunID uuid.UUID
+	// Defaults to DefaultNetChangeThreshold.
+	NetChangeThreshold *int64
+}
+
+type WaiveVarianceParams struct {
+	Reason string
+}
+
+type AcknowledgeParams struct {
+	WarningIDs []uuid.UUID
+}
+
+// compareResults builds the warnings of current against previous.
+func compareResults(previous, current []*Result, threshold int64) []Warning {
+	before := make(map[uuid.UUID]*Result, len(previous))
+	for _, result := range previous {
+		before[result.EmployeeID] = result
+	}
+
+	var warnings []Warning
+	add := func(t WarningType, employeeID uuid.UUID, prev, cur *Result, change *int64) {
+		warning := Warning{ID: uuid.New(), Type: t, EmployeeID: employeeID, Change: change}
+		if prev != nil {
+			net := prev.Net
+			warning.Previous = &net
+		}
+		if cur != nil {
+			net := cur.Net
+			warning.Current = &net
+		}
+		warnings = append(warnings, warning)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(current))
+	for _, cur := range current {
+		seen[cur.EmployeeID] = true
+		switch {
+		case cur.Net.IsNegative():
+			add(WarningNegativeNet, cur.EmployeeID, before[cur.EmployeeID], cur, nil)
+		case cur.Net.IsZero():
+			add(WarningZeroNet, cur.EmployeeID, before[cur.EmployeeID], cur, nil)
+		}
+
+		prev, ok := before[cur.EmployeeID]
+		if !ok {
+			add(WarningNewHire, cur.EmployeeID, nil, cur, nil)
+			continue
+		}
+		if prev.Currency != cur.Currency {
+			add(WarningCurrencyChanged, cur.EmployeeID, prev, cur, nil)
+			continue
+		}
+		if change, exceeded := netChange(prev.Net, cur.Net, threshold); exceeded {
+			add(WarningNetChange, cur.EmployeeID, prev, cur, change)
+		}
+	}
+
+	for _, prev := range previous {
+		if !seen[prev.EmployeeID] {
+			add(WarningLeaver, prev.EmployeeID, prev, nil, nil)
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Type != warnings[j].Type {
+			return warnings[i].Type < warnings[j].Type
+		}
+		return warnings[i].EmployeeID.String() < warnings[j].EmployeeID.String()
+	})
+	return warnings
+}
+
+// netChange returns the change from prev to cur in basis points of prev and
+// whether it exceeds threshold. Any change from a zero net exceeds it.
+func netChange(prev, cur money.Amount, threshold int64) (*int64, bool) {
+	if prev == cur {
+		return nil, false
+	}
+	if prev.IsZero() {
+		return nil, true
+	}
+	diff := cur - prev
+	base := int64(prev)
+	if base < 0 {
+		base = -base
+	}
+	change := int64(diff.MulDiv(10000, base))
+	magnitude := change
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+	return &change, magnitude > threshold
+}
+
+// AnalyzeVariance compares an open run with a previous run of the same
+// workspace and stores the resulting warnings on the run. Warnings already
+// acknowledged stay acknowledged if the finding did not change. The run
+// cannot be finalized until every warning is acknowledged.
+func (s *Service) AnalyzeVariance(ctx context.Context, runID uuid.UUID, params AnalyzeVarianceParams) (*VarianceAnalysis, error) {
+	run, err := s.getRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, auth.PermPayrollRun, run); err != nil {
+		return nil, err
+	}
+	if !run.IsOpen() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run is not open")
+	}
+
+	threshold := DefaultNetChangeThreshold
+	if params.NetChangeThreshold != nil {
+		threshold = *params.NetChangeThreshold
+	}
+	validator := NewValidator()
+	validator.ValidateThreshold(threshold)
+	if params.PreviousRunID == uuid.Nil {
+		validator.AddError("PreviousRunID", "is empty")
+	} else if params.PreviousRunID == run.ID {
+		validator.AddError("PreviousRunID", "must differ from the analysed run")
+	}
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	analysis := &VarianceAnalysis{PreviousRunID: params.PreviousRunID, NetChangeThreshold: threshold}
+	if err := s.analyze(ctx, run, analysis); err != nil {
+		return nil, err
+	}
+
+	before := run.snapshot()
+	run.Variance = analysis
+	run.Touch()
+	if err := s.saveRun(ctx, before, run); err != nil {
+		s.logger.Error(err, "Failed to save variance analysis", "run_id", run.ID)
+		return nil, err
+	}
+
+	s.logger.Info("Variance analysis completed", "run_id", run.ID, "warnings", len(analysis.Warnings))
+	return analysis, nil
+}
+
+// analyze fills analysis.Warnings. Findings already present in the run's
+// current analysis keep their ID and acknowledgement.
+func (s *Service) analyze(ctx context.Context, run *Run, analysis *VarianceAnalysis) error {
+	previousRun, err := s.getRun(ctx, analysis.PreviousRunID)
+	if err != nil {
+		return err
+	}
+	if previousRun.WorkspaceID != run.WorkspaceID {
+		return apperror.New(apperror.TypeInvalid, serviceOrigin, "Runs belong to different workspaces")
+	}
+	if previousRun.Status != RunStatusFinalized {
+		return apperror.New(apperror.TypeInvalid, serviceOrigin, "Previous payroll run is not finalized")
+	}
+	if !previousRun.PeriodEnd.Before(run.PeriodStart) {
+		return apperror.New(apperror.TypeInvalid, serviceOrigin, "Previous payroll run does not end before the run starts")
+	}
+
+	previous, err := s.repo.ListResultsByRunID(ctx, previousRun.ID)
+	if err != nil {
+		return err
+	}
+	current, err := s.repo.ListResultsByRunID(ctx, run.ID)
+	if err != nil {
+		return err
+	}
+
+	analysis.Warnings = compareResults(previous, current, analysis.NetChangeThreshold)
+	analysis.AnalyzedAt = time.Now().UTC()
+
+	if run.Variance != nil {
+		for i := range analysis.Warnings {
+			for _, old := range run.Variance.Warnings {
+				if analysis.Warnings[i].sameFinding(&old) {
+					analysis.Warnings[i].ID = old.ID
+					analysis.Warnings[i].AcknowledgedBy = old.AcknowledgedBy
+					analysis.Warnings[i].AcknowledgedAt = old.AcknowledgedAt
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AcknowledgeWarnings records that the caller reviewed the given warnings.
+func (s *Service) AcknowledgeWarnings(ctx context.Context, runID uuid.UUID, params AcknowledgeParams) (*VarianceAnalysis, error) {
+	run, err := s.getRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, auth.PermPayrollApprove, run); err != nil {
+		return nil, err
+	}
+	if !run.IsOpen() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run is not open")
+	}
+	if run.Variance == nil {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run has no variance analysis")
+	}
+
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	validator := NewValidator()
+	if len(params.WarningIDs) == 0 {
+		validator.AddError("WarningIDs", "is empty")
+	}
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	before := run.snapshot()
+	now := time.Now().UTC()
+	by := p.Subject
+	for _, id := range params.WarningIDs {
+		found := false
+		for i := range run.Variance.Warnings {
+			warning := &run.Variance.Warnings[i]
+			if warning.ID == id {
+				warning.AcknowledgedBy = &by
+				warning.AcknowledgedAt = &now
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, apperror.New(apperror.TypeNotFound, serviceOrigin, "Warning "+id.String()+" not found")
+		}
+	}
+
+	run.Touch()
+	if err := s.saveRun(ctx, before, run); err != nil {
+		s.logger.Error(err, "Failed to save acknowledged warnings", "run_id", run.ID)
+		return nil, err
+	}
+
+	return run.Variance, nil
+}
+
+// WaiveVariance records that the caller decided to finalize the run without a
+// variance analysis because there is no previous run to compare it with. A
+// run that has been analysed, or whose workspace has a finalized run that
+// ended before it started, cannot be waived; it has to be analysed and its
+// warnings acknowledged instead.
+func (s *Service) WaiveVariance(ctx context.Context, runID uuid.UUID, params WaiveVarianceParams) (*Run, error) {
+	run, err := s.getRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, auth.PermPayrollApprove, run); err != nil {
+		return nil, err
+	}
+	if !run.IsOpen() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run is not open")
+	}
+	if run.Variance != nil {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run has a variance analysis")
+	}
+	previous, err := s.repo.ExistsFinalizedRunEndingBefore(ctx, run.WorkspaceID, run.TenantID, run.PeriodStart)
+	if err != nil {
+		return nil, err
+	}
+	if previous {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll run has a previous run to compare with and must be analysed")
+	}
+
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+	validator := NewValidator()
+	if params.Reason == "" {
+		validator.AddError("Reason", "is empty")
+	}
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	before := run.snapshot()
+	run.VarianceWaiver = &VarianceWaiver{By: p.Subject, Reason: params.Reason, At: time.Now().UTC()}
+	run.Touch()
+	if err := s.saveRun(ctx, before, run); err != nil {
+		s.logger.Error(err, "Failed to save variance waiver", "run_id", run.ID)
+		return nil, err
+	}
+
+	s.logger.Info("Variance analysis waived", "run_id", run.ID, "waived_by", p.Subject)
+	return run, nil
+}
@@ -0,0 +1,265 @@
+package payroll
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/money"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/uow"
+	"payroll/internal/testkit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	tenantID = uuid.New()
+	ctx      = testkit.AdminContext(tenantID)
+)
+
+type memoryRepo struct {
+	Repository
+	runs    map[uuid.UUID]*Run
+	results map[uuid.UUID][]*Result
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{runs: map[uuid.UUID]*Run{}, results: map[uuid.UUID][]*Result{}}
+}
+
+func (r *memoryRepo) GetRun(_ context.Context, id uuid.UUID) (*Run, error) {
+	run, ok := r.runs[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "run not found")
+	}
+	return run, nil
+}
+
+func (r *memoryRepo) UpdateRun(_ context.Context, run *Run) error {
+	r.runs[run.ID] = run
+	return nil
+}
+
+func (r *memoryRepo) CreateRun(_ context.Context, run *Run) error {
+	r.runs[run.ID] = run
+	return nil
+}
+
+func (r *memoryRepo) GetResult(_ context.Context, runID, employeeID uuid.UUID) (*Result, error) {
+	for _, result := range r.results[runID] {
+		if result.EmployeeID == employeeID {
+			return result, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "result not found")
+}
+
+func (r *memoryRepo) SaveResult(_ context.Context, result *Result) error {
+	results := r.results[result.RunID]
+	for i, existing := range results {
+		if existing.EmployeeID == result.EmployeeID {
+			results[i] = result
+			return nil
+		}
+	}
+	r.results[result.RunID] = append(results, result)
+	return nil
+}
+
+func (r *memoryRepo) ListRunsByTenantID(_ context.Context, tenantID uuid.UUID, from, to time.Time) ([]*Run, error) {
+	var runs []*Run
+	for _, run := range r.runs {
+		if run.TenantID == tenantID && !run.PeriodEnd.Before(from) && !run.PeriodEnd.After(to) {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+func (r *memoryRepo) ExistsOpenRunByWorkspaceIDAndTenantID(_ context.Context, workspaceID, tenantID uuid.UUID) (bool, error) {
+	for _, run := range r.runs {
+		if run.WorkspaceID == workspaceID && run.TenantID == tenantID && run.Status != RunStatusFinalized {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepo) ExistsFinalizedRunEndingBefore(_ context.Context, workspaceID, tenantID uuid.UUID, before time.Time) (bool, error) {
+	for _, run := range r.runs {
+		if run.WorkspaceID == workspaceID && run.TenantID == tenantID && run.Status == RunStatusFinalized && run.PeriodEnd.Before(before) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepo) ListResultsByRunID(_ context.Context, runID uuid.UUID) ([]*Result, error) {
+	return r.results[runID], nil
+}
+
+// consecutiveRuns returns a finalized run of the workspace for March and an
+// open one for April.
+func consecutiveRuns(workspaceID uuid.UUID) (previous, current *Run) {
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	previous = &Run{TenantID: tenantID, WorkspaceID: workspaceID, Status: RunStatusFinalized, PeriodStart: march, PeriodEnd: march.AddDate(0, 1, -1)}
+	previous.Initialize()
+	current = &Run{TenantID: tenantID, WorkspaceID: workspaceID, Status: RunStatusOpen, PeriodStart: march.AddDate(0, 1, 0), PeriodEnd: march.AddDate(0, 2, -1)}
+	current.Initialize()
+	return previous, current
+}
+
+func netResult(employeeID uuid.UUID, net money.Amount) *Result {
+	result := &Result{EmployeeID: employeeID, Currency: "USD"}
+	result.AddItem(Item{Code: "SALARY", Kind: ItemKindEarning, Amount: net})
+	return result
+}
+
+func TestCompareResults(t *testing.T) {
+	stable, raised, zeroed, hired, left := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	previous := []*Result{
+		netResult(stable, 1000), netResult(raised, 1000), netResult(zeroed, 1000), netResult(left, 1000),
+	}
+	current := []*Result{
+		netResult(stable, 1050), netResult(raised, 1200), netResult(zeroed, 0), netResult(hired, 900),
+	}
+
+	warnings := compareResults(previous, current, DefaultNetChangeThreshold)
+
+	types := map[WarningType][]uuid.UUID{}
+	for _, w := range warnings {
+		types[w.Type] = append(types[w.Type], w.EmployeeID)
+	}
+	assert.ElementsMatch(t, []uuid.UUID{raised, zeroed}, types[WarningNetChange])
+	assert.Equal(t, []uuid.UUID{zeroed}, types[WarningZeroNet])
+	assert.Equal(t, []uuid.UUID{hired}, types[WarningNewHire])
+	assert.Equal(t, []uuid.UUID{left}, types[WarningLeaver])
+
+	for _, w := range warnings {
+		if w.Type == WarningNetChange && w.EmployeeID == raised {
+			assert.EqualValues(t, 2000, *w.Change)
+		}
+	}
+}
+
+func TestFinalizeRun_BlockedUntilWarningsAcknowledged(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	employeeID := uuid.New()
+
+	previous, current := consecutiveRuns(uuid.New())
+	repo.runs[previous.ID] = previous
+	repo.runs[current.ID] = current
+	repo.results[previous.ID] = []*Result{netResult(employeeID, 1000)}
+	repo.results[current.ID] = []*Result{netResult(employeeID, 2000)}
+
+	analysis, err := svc.AnalyzeVariance(ctx, current.ID, AnalyzeVarianceParams{PreviousRunID: previous.ID})
+	require.NoError(t, err)
+	require.Len(t, analysis.Warnings, 1)
+
+	_, err = svc.FinalizeRun(ctx, current.ID)
+	require.True(t, apperror.IsType(err, apperror.TypeInvalid))
+
+	acknowledged, err := svc.AcknowledgeWarnings(ctx, current.ID, AcknowledgeParams{
+		WarningIDs: []uuid.UUID{analysis.Warnings[0].ID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", *acknowledged.Warnings[0].AcknowledgedBy)
+
+	run, err := svc.FinalizeRun(ctx, current.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RunStatusFinalized, run.Status)
+}
+
+func TestFinalizeRun_RecalculatedResultNeedsNewAcknowledgement(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	employeeID := uuid.New()
+
+	previous, current := consecutiveRuns(uuid.New())
+	repo.runs[previous.ID] = previous
+	repo.runs[current.ID] = current
+	repo.results[previous.ID] = []*Result{netResult(employeeID, 1000)}
+	repo.results[current.ID] = []*Result{netResult(employeeID, 2000)}
+
+	analysis, err := svc.AnalyzeVariance(ctx, current.ID, AnalyzeVarianceParams{PreviousRunID: previous.ID})
+	require.NoError(t, err)
+	_, err = svc.AcknowledgeWarnings(ctx, current.ID, AcknowledgeParams{
+		WarningIDs: []uuid.UUID{analysis.Warnings[0].ID},
+	})
+	require.NoError(t, err)
+
+	repo.results[current.ID] = []*Result{netResult(employeeID, 3000)}
+
+	_, err = svc.FinalizeRun(ctx, current.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+}
+
+func TestFinalizeRun_RequiresAnalysisOrWaiver(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	run := &Run{TenantID: tenantID, Status: RunStatusOpen, PeriodEnd: time.Now()}
+	run.Initialize()
+	repo.runs[run.ID] = run
+
+	_, err := svc.FinalizeRun(ctx, run.ID)
+	require.True(t, apperror.IsType(err, apperror.TypeInvalid))
+	assert.Equal(t, RunStatusOpen, run.Status)
+
+	_, err = svc.WaiveVariance(ctx, run.ID, WaiveVarianceParams{})
+	require.True(t, apperror.IsType(err, apperror.TypeInvalid), "a waiver needs a reason")
+
+	_, err = svc.WaiveVariance(ctx, run.ID, WaiveVarianceParams{Reason: "First run of the workspace"})
+	require.NoError(t, err)
+	finalized, err := svc.FinalizeRun(ctx, run.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RunStatusFinalized, finalized.Status)
+	assert.Equal(t, "admin", finalized.VarianceWaiver.By, "the waiver is recorded for the caller")
+}
+
+func TestAnalyzeVariance_RequiresAnEarlierFinalizedRunAndABoundedThreshold(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	previous, current := consecutiveRuns(uuid.New())
+	repo.runs[previous.ID] = previous
+	repo.runs[current.ID] = current
+
+	threshold := MaxNetChangeThreshold + 1
+	_, err := svc.AnalyzeVariance(ctx, current.ID, AnalyzeVarianceParams{PreviousRunID: previous.ID, NetChangeThreshold: &threshold})
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Contains(t, domainErr.Details, "NetChangeThreshold")
+
+	previous.Status = RunStatusOpen
+	_, err = svc.AnalyzeVariance(ctx, current.ID, AnalyzeVarianceParams{PreviousRunID: previous.ID})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid), "an open run cannot serve as the previous run")
+
+	previous.Status = RunStatusFinalized
+	_, err = svc.AnalyzeVariance(ctx, previous.ID, AnalyzeVarianceParams{PreviousRunID: current.ID})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+
+	later := &Run{TenantID: tenantID, WorkspaceID: current.WorkspaceID, Status: RunStatusFinalized, PeriodStart: current.PeriodStart, PeriodEnd: current.PeriodEnd}
+	later.Initialize()
+	repo.runs[later.ID] = later
+	_, err = svc.AnalyzeVariance(ctx, current.ID, AnalyzeVarianceParams{PreviousRunID: later.ID})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid), "a run of the same period is not a previous run")
+
+	_, err = svc.AnalyzeVariance(ctx, current.ID, AnalyzeVarianceParams{PreviousRunID: previous.ID})
+	assert.NoError(t, err)
+}
+
+func TestWaiveVariance_RejectedWhenThereIsAPreviousRun(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, nil, nil, uow.NewMemory(), &testkit.Audit{}, &testkit.Publisher{}, logger.Nop{})
+	previous, current := consecutiveRuns(uuid.New())
+	repo.runs[previous.ID] = previous
+	repo.runs[current.ID] = current
+
+	_, err := svc.WaiveVariance(ctx, current.ID, WaiveVarianceParams{Reason: "No time"})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+	assert.Nil(t, current.VarianceWaiver)
+}
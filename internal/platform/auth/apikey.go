@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"payroll/internal/apperror"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key, so that leaked keys are easy to spot.
+const APIKeyPrefix = "pk_"
+
+// GenerateAPIKey returns a new random API key. Only its hash (see
+// HashAPIKey) should be stored; the key itself is shown to its owner once.
+func GenerateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the value API keys are stored and looked up by.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyResolver returns the principal an API key authenticates as, or a
+// TypeUnauthenticated error if the key is unknown, expired or revoked.
+type APIKeyResolver interface {
+	ResolveAPIKey(ctx context.Context, key string) (*Principal, error)
+}
+
+// Authenticator authenticates the credentials of an Authorization header:
+// "Bearer <JWT>" for users and "ApiKey <key>" for integrations.
+type Authenticator struct {
+	tokens  *JWTVerifier
+	apiKeys APIKeyResolver
+}
+
+func NewAuthenticator(v *JWTVerifier, r APIKeyResolver) *Authenticator {
+	return &Authenticator{tokens: v, apiKeys: r}
+}
+
+// Authenticate returns a copy of ctx that acts as the caller identified by
+// authorization.
+func (a *Authenticator) Authenticate(ctx context.Context, authorization string) (context.Context, error) {
+	scheme, credentials, _ := strings.Cut(strings.TrimSpace(authorization), " ")
+	credentials = strings.TrimSpace(credentials)
+	if credentials == "" {
+		return nil, apperror.New(apperror.TypeUnauthenticated, origin, "Missing credentials")
+	}
+
+	var (
+		p   *Principal
+		err error
+	)
+	switch {
+	case strings.EqualFold(scheme, "Bearer") && a.tokens != nil:
+		p, err = a.tokens.Verify(credentials)
+	case strings.EqualFold(scheme, "ApiKey") && a.apiKeys != nil:
+		p, err = a.apiKeys.ResolveAPIKey(ctx, credentials)
+	default:
+		return nil, apperror.New(apperror.TypeUnauthenticated, origin, "Unsupported authorization scheme")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return WithPrincipal(ctx, p), nil
+}
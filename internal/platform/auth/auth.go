@@ -0,0 +1,218 @@
+// Package auth identifies the caller of a request and decides what it may
+// do.
+//
+// The transport authenticates the caller (see Authenticator) and stores the
+// resulting Principal in the request context with WithPrincipal. Services
+// then call Authorize before every operation, so permissions hold whatever
+// the transport.
+package auth
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/platform/tenant"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+const origin = "Auth"
+
+type Role string
+
+const (
+	// RolePlatformAdmin operates the platform: it manages tenants and the
+	// reference data shared by all of them, but not tenant data, which it
+	// may only purge once tenants have deleted it.
+	RolePlatformAdmin Role = "PLATFORM_ADMIN"
+	// RoleTenantAdmin may do anything within its tenant.
+	RoleTenantAdmin Role = "TENANT_ADMIN"
+	// RolePayrollManager runs payroll for the workspaces it is assigned to.
+	RolePayrollManager Role = "PAYROLL_MANAGER"
+	// RoleApprover reviews and approves payroll runs across the tenant.
+	RoleApprover Role = "APPROVER"
+	// RoleAuditor may read everything in its tenant and change nothing.
+	RoleAuditor Role = "AUDITOR"
+	// RoleSelfService may read its own employee record and pay results.
+	RoleSelfService Role = "SELF_SERVICE"
+)
+
+func (r Role) IsValid() bool {
+	_, ok := grants[r]
+	return ok
+}
+
+type Permission string
+
+const (
+	PermTenantManage        Permission = "tenant:manage"
+	PermReferenceDataManage Permission = "reference-data:manage"
+	PermAPIKeyManage        Permission = "api-key:manage"
+	PermWorkspaceRead       Permission = "workspace:read"
+	PermWorkspaceWrite      Permission = "workspace:write"
+	PermEmployeeRead        Permission = "employee:read"
+	PermEmployeeWrite       Permission = "employee:write"
+	PermPayrollRead         Permission = "payroll:read"
+	PermPayrollRun          Permission = "payroll:run"
+	PermPayrollApprove      Permission = "payroll:approve"
+	PermReportRead          Permission = "report:read"
+	PermAuditRead           Permission = "audit:read"
+	PermWebhookManage       Permission = "webhook:manage"
+	PermProfileRequest      Permission = "profile:request-change"
+	PermPrivacyManage       Permission = "privacy:manage"
+	// PermDataPurge allows removing soft-deleted data of every tenant
+	// permanently, which the retention job does.
+	PermDataPurge Permission = "data:purge"
+)
+
+func (p Permission) IsValid() bool {
+	for _, g := range grants {
+		if slices.Contains(g.permissions, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// scope is the part of a tenant a role's permissions extend to.
+type scope int
+
+const (
+	scopeTenant scope = iota
+	scopeWorkspace
+	scopeEmployee
+)
+
+type grant struct {
+	scope       scope
+	permissions []Permission
+}
+
+var grants = map[Role]grant{
+	RolePlatformAdmin: {scopeTenant, []Permission{PermTenantManage, PermReferenceDataManage, PermAuditRead, PermDataPurge}},
+	RoleTenantAdmin: {scopeTenant, []Permission{
+		PermAPIKeyManage, PermAuditRead, PermWebhookManage, PermWorkspaceRead, PermWorkspaceWrite, PermEmployeeRead, PermEmployeeWrite,
+		PermPayrollRead, PermPayrollRun, PermPayrollApprove, PermReportRead, PermPrivacyManage,
+	}},
+	RolePayrollManager: {scopeWorkspace, []Permission{
+		PermWorkspaceRead, PermEmployeeRead, PermEmployeeWrite, PermPayrollRead, PermPayrollRun, PermReportRead,
+	}},
+	RoleApprover: {scopeTenant, []Permission{
+		PermWorkspaceRead, PermEmployeeRead, PermPayrollRead, PermPayrollApprove, PermReportRead,
+	}},
+	RoleAuditor: {scopeTenant, []Permission{
+		PermWorkspaceRead, PermEmployeeRead, PermPayrollRead, PermReportRead, PermAuditRead,
+	}},
+	RoleSelfService: {scopeEmployee, []Permission{PermEmployeeRead, PermPayrollRead, PermProfileRequest}},
+}
+
+// Resource is what a permission is exercised on. Operations on the tenant as
+// a whole leave it empty; operations on a workspace's data set WorkspaceID,
+// and operations on one employee's data set both.
+type Resource struct {
+	WorkspaceID uuid.UUID
+	EmployeeID  uuid.UUID
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject  string
+	TenantID uuid.UUID
+	Roles    []Role
+	// WorkspaceIDs are the workspaces a payroll manager is assigned to.
+	WorkspaceIDs []uuid.UUID
+	// EmployeeID links a self-service user to its employee record.
+	EmployeeID uuid.UUID
+	// Scopes, when not empty, narrow the permissions granted by Roles, e.g.
+	// for an API key that only reads reports.
+	Scopes []Permission
+}
+
+// Can reports whether p holds perm on res.
+func (p *Principal) Can(perm Permission, res Resource) bool {
+	if len(p.Scopes) > 0 && !slices.Contains(p.Scopes, perm) {
+		return false
+	}
+	for _, role := range p.Roles {
+		g, ok := grants[role]
+		if !ok || !slices.Contains(g.permissions, perm) {
+			continue
+		}
+		switch g.scope {
+		case scopeTenant:
+			return true
+		case scopeWorkspace:
+			if res.WorkspaceID != uuid.Nil && slices.Contains(p.WorkspaceIDs, res.WorkspaceID) {
+				return true
+			}
+		case scopeEmployee:
+			if res.EmployeeID != uuid.Nil && res.EmployeeID == p.EmployeeID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Workspaces returns the workspaces p holds perm on. all is true when p
+// holds it on the whole tenant, in which case ids is nil.
+func (p *Principal) Workspaces(perm Permission) (ids []uuid.UUID, all bool) {
+	if p.Can(perm, Resource{}) {
+		return nil, true
+	}
+	for _, id := range p.WorkspaceIDs {
+		if p.Can(perm, Resource{WorkspaceID: id}) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, false
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx that acts as p, and for p's tenant if it
+// belongs to one.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	if p.TenantID != uuid.Nil {
+		ctx = tenant.WithID(ctx, p.TenantID)
+	}
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal ctx acts as, or a TypeUnauthenticated
+// error when there is none.
+func FromContext(ctx context.Context) (*Principal, error) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	if !ok || p == nil {
+		return nil, apperror.New(apperror.TypeUnauthenticated, origin, "No principal in request context")
+	}
+	return p, nil
+}
+
+// Authorize returns nil if the principal ctx acts as holds perm on res, and a
+// TypeForbidden error otherwise.
+func Authorize(ctx context.Context, perm Permission, res Resource, errOrigin string) error {
+	p, err := FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !p.Can(perm, res) {
+		return apperror.New(apperror.TypeForbidden, errOrigin, "Not allowed to "+string(perm))
+	}
+	return nil
+}
+
+// Filter returns the items of items the principal ctx acts as holds perm on.
+func Filter[T any](ctx context.Context, perm Permission, items []T, resource func(T) Resource) ([]T, error) {
+	p, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var allowed []T
+	for _, item := range items {
+		if p.Can(perm, resource(item)) {
+			allowed = append(allowed, item)
+		}
+	}
+	return allowed, nil
+}
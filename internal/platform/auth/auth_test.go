@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"payroll/internal/apperror"
+	"payroll/internal/platform/tenant"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrincipal_CanRespectsRoleScope(t *testing.T) {
+	assigned, other := uuid.New(), uuid.New()
+	self := uuid.New()
+
+	manager := &Principal{Roles: []Role{RolePayrollManager}, WorkspaceIDs: []uuid.UUID{assigned}}
+	assert.True(t, manager.Can(PermPayrollRun, Resource{WorkspaceID: assigned}))
+	assert.True(t, manager.Can(PermEmployeeRead, Resource{WorkspaceID: assigned, EmployeeID: uuid.New()}))
+	assert.False(t, manager.Can(PermPayrollRun, Resource{WorkspaceID: other}))
+	assert.False(t, manager.Can(PermPayrollRun, Resource{}))
+	assert.False(t, manager.Can(PermPayrollApprove, Resource{WorkspaceID: assigned}))
+
+	employee := &Principal{Roles: []Role{RoleSelfService}, EmployeeID: self}
+	assert.True(t, employee.Can(PermPayrollRead, Resource{WorkspaceID: assigned, EmployeeID: self}))
+	assert.False(t, employee.Can(PermPayrollRead, Resource{WorkspaceID: assigned, EmployeeID: uuid.New()}))
+	assert.False(t, employee.Can(PermEmployeeWrite, Resource{WorkspaceID: assigned, EmployeeID: self}))
+
+	auditor := &Principal{Roles: []Role{RoleAuditor}}
+	assert.True(t, auditor.Can(PermReportRead, Resource{}))
+	assert.False(t, auditor.Can(PermEmployeeWrite, Resource{WorkspaceID: assigned}))
+
+	integration := &Principal{Roles: []Role{RoleTenantAdmin}, Scopes: []Permission{PermReportRead}}
+	assert.True(t, integration.Can(PermReportRead, Resource{}))
+	assert.False(t, integration.Can(PermEmployeeWrite, Resource{}))
+
+	ids, all := manager.Workspaces(PermWorkspaceRead)
+	assert.False(t, all)
+	assert.Equal(t, []uuid.UUID{assigned}, ids)
+	_, all = auditor.Workspaces(PermWorkspaceRead)
+	assert.True(t, all)
+}
+
+func TestAuthorize(t *testing.T) {
+	tenantID := uuid.New()
+
+	err := Authorize(context.Background(), PermReportRead, Resource{}, "test")
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+
+	ctx := WithPrincipal(context.Background(), &Principal{Subject: "u1", TenantID: tenantID, Roles: []Role{RoleAuditor}})
+	id, ok := tenant.ID(ctx)
+	require.True(t, ok)
+	assert.Equal(t, tenantID, id)
+
+	assert.NoError(t, Authorize(ctx, PermReportRead, Resource{}, "test"))
+	assert.True(t, apperror.IsType(Authorize(ctx, PermPayrollRun, Resource{}, "test"), apperror.TypeForbidden))
+}
+
+func TestAuthenticator_APIKey(t *testing.T) {
+	key, err := GenerateAPIKey()
+	require.NoError(t, err)
+	resolver := stubResolver{HashAPIKey(key): {Subject: "api-key:erp", TenantID: uuid.New(), Roles: []Role{RoleAuditor}}}
+	a := NewAuthenticator(nil, resolver)
+
+	ctx, err := a.Authenticate(context.Background(), "ApiKey "+key)
+	require.NoError(t, err)
+	p, err := FromContext(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "api-key:erp", p.Subject)
+
+	_, err = a.Authenticate(context.Background(), "ApiKey "+APIKeyPrefix+"forged")
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+	_, err = a.Authenticate(context.Background(), "Bearer token")
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+	_, err = a.Authenticate(context.Background(), "")
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+}
+
+type stubResolver map[string]*Principal
+
+func (r stubResolver) ResolveAPIKey(_ context.Context, key string) (*Principal, error) {
+	p, ok := r[HashAPIKey(key)]
+	if !ok {
+		return nil, apperror.New(apperror.TypeUnauthenticated, "test", "unknown API key")
+	}
+	return p, nil
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"payroll/internal/apperror"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// Key verifies the signature of tokens whose header names its ID and
+// algorithm. HS256 keys use Secret; RS256 and ES256 keys use PublicKey, an
+// *rsa.PublicKey or an *ecdsa.PublicKey respectively.
+type Key struct {
+	ID        string
+	Algorithm string
+	Secret    []byte
+	PublicKey crypto.PublicKey
+}
+
+type JWTConfig struct {
+	// Keys must not be empty. A token without a "kid" header is only
+	// accepted when there is exactly one key.
+	Keys     []Key
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+}
+
+// JWTVerifier authenticates signed JWT bearer tokens against locally
+// configured keys. Besides the registered claims it reads
+//
+//	tid    the tenant ID
+//	roles  an array of Role
+//	wsp    an array of workspace IDs, for payroll managers
+//	emp    the employee ID, for self-service users
+//	scope  space-separated permissions narrowing the roles
+type JWTVerifier struct {
+	keys   map[string]Key
+	config JWTConfig
+	now    func() time.Time
+}
+
+func NewJWTVerifier(config JWTConfig) (*JWTVerifier, error) {
+	if len(config.Keys) == 0 {
+		return nil, fmt.Errorf("auth: no JWT keys configured")
+	}
+	keys := make(map[string]Key, len(config.Keys))
+	for _, k := range config.Keys {
+		switch k.Algorithm {
+		case AlgHS256:
+			if len(k.Secret) < 32 {
+				return nil, fmt.Errorf("auth: key %q: HS256 secret must be at least 32 bytes", k.ID)
+			}
+		case AlgRS256:
+			if _, ok := k.PublicKey.(*rsa.PublicKey); !ok {
+				return nil, fmt.Errorf("auth: key %q: RS256 requires an RSA public key", k.ID)
+			}
+		case AlgES256:
+			if _, ok := k.PublicKey.(*ecdsa.PublicKey); !ok {
+				return nil, fmt.Errorf("auth: key %q: ES256 requires an ECDSA public key", k.ID)
+			}
+		default:
+			return nil, fmt.Errorf("auth: key %q: unsupported algorithm %q", k.ID, k.Algorithm)
+		}
+		if _, dup := keys[k.ID]; dup {
+			return nil, fmt.Errorf("auth: duplicate key ID %q", k.ID)
+		}
+		keys[k.ID] = k
+	}
+	return &JWTVerifier{keys: keys, config: config, now: time.Now}, nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Issuer     string      `json:"iss"`
+	Subject    string      `json:"sub"`
+	Audience   audience    `json:"aud"`
+	ExpiresAt  int64       `json:"exp"`
+	NotBefore  int64       `json:"nbf"`
+	TenantID   uuid.UUID   `json:"tid"`
+	Roles      []Role      `json:"roles"`
+	Workspaces []uuid.UUID `json:"wsp"`
+	EmployeeID uuid.UUID   `json:"emp"`
+	Scope      string      `json:"scope"`
+}
+
+// audience accepts both forms of the "aud" claim: a string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Verify checks the token's signature, issuer, audience and validity period
+// and returns the principal it identifies. Every failure is reported as a
+// TypeUnauthenticated error.
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	key, err := v.key(header)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+	if !verifySignature(key, parts[0]+"."+parts[1], signature) {
+		return nil, invalidToken("invalid signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	p := &Principal{
+		Subject:      claims.Subject,
+		TenantID:     claims.TenantID,
+		Roles:        claims.Roles,
+		WorkspaceIDs: claims.Workspaces,
+		EmployeeID:   claims.EmployeeID,
+	}
+	for _, s := range strings.Fields(claims.Scope) {
+		p.Scopes = append(p.Scopes, Permission(s))
+	}
+	return p, nil
+}
+
+// key returns the key the token names. The algorithm is taken from the key,
+// never from the token, so a token cannot choose how it is verified.
+func (v *JWTVerifier) key(header jwtHeader) (Key, error) {
+	var key Key
+	if header.KeyID == "" && len(v.keys) == 1 {
+		for _, k := range v.keys {
+			key = k
+		}
+	} else {
+		k, ok := v.keys[header.KeyID]
+		if !ok {
+			return Key{}, invalidToken("unknown key")
+		}
+		key = k
+	}
+	if header.Algorithm != key.Algorithm {
+		return Key{}, invalidToken("unexpected algorithm")
+	}
+	return key, nil
+}
+
+func (v *JWTVerifier) checkClaims(c jwtClaims) error {
+	now := v.now()
+	if c.Subject == "" {
+		return invalidToken("missing subject")
+	}
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(v.config.Leeway)) {
+		return invalidToken("token expired")
+	}
+	if c.NotBefore != 0 && now.Add(v.config.Leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return invalidToken("token not yet valid")
+	}
+	if v.config.Issuer != "" && c.Issuer != v.config.Issuer {
+		return invalidToken("unexpected issuer")
+	}
+	if v.config.Audience != "" && !slices.Contains(c.Audience, v.config.Audience) {
+		return invalidToken("unexpected audience")
+	}
+	for _, r := range c.Roles {
+		if !r.IsValid() {
+			return invalidToken("unknown role")
+		}
+	}
+	return nil
+}
+
+func verifySignature(key Key, signed string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+	switch key.Algorithm {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write([]byte(signed))
+		return hmac.Equal(mac.Sum(nil), signature)
+	case AlgRS256:
+		return rsa.VerifyPKCS1v15(key.PublicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	case AlgES256:
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key.PublicKey.(*ecdsa.PublicKey), digest[:], r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func invalidToken(reason string) error {
+	return apperror.New(apperror.TypeUnauthenticated, origin, "Invalid token: "+reason)
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+	testNow    = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func signToken(t *testing.T, header, claims map[string]any, sign func(signed string) []byte) string {
+	t.Helper()
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(signed))
+}
+
+func hs256(secret []byte) func(string) []byte {
+	return func(signed string) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	}
+}
+
+func validClaims(tenantID uuid.UUID) map[string]any {
+	return map[string]any{
+		"iss":   "payroll-idp",
+		"aud":   []string{"payroll-api"},
+		"sub":   "user-1",
+		"exp":   testNow.Add(time.Hour).Unix(),
+		"tid":   tenantID,
+		"roles": []Role{RoleApprover},
+		"scope": "payroll:read payroll:approve",
+	}
+}
+
+func newTestVerifier(t *testing.T, keys ...Key) *JWTVerifier {
+	t.Helper()
+	v, err := NewJWTVerifier(JWTConfig{Keys: keys, Issuer: "payroll-idp", Audience: "payroll-api"})
+	require.NoError(t, err)
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestJWTVerifier_HS256(t *testing.T) {
+	v := newTestVerifier(t, Key{ID: "k1", Algorithm: AlgHS256, Secret: testSecret})
+	tenantID := uuid.New()
+
+	token := signToken(t, map[string]any{"alg": AlgHS256, "kid": "k1"}, validClaims(tenantID), hs256(testSecret))
+	p, err := v.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", p.Subject)
+	assert.Equal(t, tenantID, p.TenantID)
+	assert.Equal(t, []Role{RoleApprover}, p.Roles)
+	assert.Equal(t, []Permission{PermPayrollRead, PermPayrollApprove}, p.Scopes)
+
+	forged := signToken(t, map[string]any{"alg": AlgHS256, "kid": "k1"}, validClaims(tenantID), hs256([]byte("another secret of at least 32 bytes")))
+	expiredClaims := validClaims(tenantID)
+	expiredClaims["exp"] = testNow.Add(-time.Minute).Unix()
+	expired := signToken(t, map[string]any{"alg": AlgHS256, "kid": "k1"}, expiredClaims, hs256(testSecret))
+	otherAudience := validClaims(tenantID)
+	otherAudience["aud"] = "another-api"
+	wrongAudience := signToken(t, map[string]any{"alg": AlgHS256, "kid": "k1"}, otherAudience, hs256(testSecret))
+	unsigned := signToken(t, map[string]any{"alg": "none", "kid": "k1"}, validClaims(tenantID), func(string) []byte { return nil })
+
+	for name, token := range map[string]string{
+		"forged":         forged,
+		"expired":        expired,
+		"wrong audience": wrongAudience,
+		"unsigned":       unsigned,
+		"malformed":      "not-a-token",
+	} {
+		_, err := v.Verify(token)
+		assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated), name)
+	}
+}
+
+func TestJWTVerifier_ES256(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	v := newTestVerifier(t,
+		Key{ID: "hmac", Algorithm: AlgHS256, Secret: testSecret},
+		Key{ID: "ec", Algorithm: AlgES256, PublicKey: priv.Public()},
+	)
+
+	es256 := func(signed string) []byte {
+		digest := sha256.Sum256([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		require.NoError(t, err)
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	}
+
+	token := signToken(t, map[string]any{"alg": AlgES256, "kid": "ec"}, validClaims(uuid.New()), es256)
+	_, err = v.Verify(token)
+	assert.NoError(t, err)
+
+	// A token may not pick a different algorithm than its key's.
+	confused := signToken(t, map[string]any{"alg": AlgHS256, "kid": "ec"}, validClaims(uuid.New()), hs256(testSecret))
+	_, err = v.Verify(confused)
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+
+	// With several keys the token must name one.
+	anonymous := signToken(t, map[string]any{"alg": AlgES256}, validClaims(uuid.New()), es256)
+	_, err = v.Verify(anonymous)
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+}
+
+func TestNewJWTVerifier_RejectsWeakKeys(t *testing.T) {
+	_, err := NewJWTVerifier(JWTConfig{Keys: []Key{{ID: "k", Algorithm: AlgHS256, Secret: []byte("short")}}})
+	assert.Error(t, err)
+	_, err = NewJWTVerifier(JWTConfig{Keys: []Key{{ID: "k", Algorithm: AlgRS256, PublicKey: crypto.PublicKey(nil)}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+// Package etag converts entity versions to and from HTTP entity tags, so that
+// update endpoints can return an ETag header and honour If-Match.
+package etag
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid entity tag")
+
+// Format returns the strong entity tag of a version, e.g. "3".
+func Format(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseIfMatch returns the version named by an If-Match header. An empty
+// header or "*" imposes no precondition and yields nil. Weak tags are
+// rejected because If-Match requires strong comparison.
+func ParseIfMatch(header string) (*int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return nil, ErrInvalid
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version < 1 {
+		return nil, ErrInvalid
+	}
+	return &version, nil
+}
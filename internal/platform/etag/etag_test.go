@@ -0,0 +1,26 @@
+package etag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	version, err := ParseIfMatch(Format(3))
+	require.NoError(t, err)
+	require.NotNil(t, version)
+	assert.Equal(t, int64(3), *version)
+
+	for _, header := range []string{"", "*"} {
+		version, err := ParseIfMatch(header)
+		assert.NoError(t, err)
+		assert.Nil(t, version)
+	}
+
+	for _, header := range []string{`W/"3"`, `3`, `"abc"`, `"0"`, `"3", "4"`} {
+		_, err := ParseIfMatch(header)
+		assert.ErrorIs(t, err, ErrInvalid, header)
+	}
+}
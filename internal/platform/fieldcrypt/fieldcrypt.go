@@ -0,0 +1,236 @@
+// Package fieldcrypt encrypts individual fields of stored records with
+// envelope encryption and computes blind indexes for looking them up.
+//
+// Every value is encrypted with a fresh data key (AES-256-GCM), and the data
+// key is wrapped with a key-encryption key from a KeyProvider. A sealed value
+// names the key-encryption key it was wrapped with, so keys can be rotated:
+// new values use the provider's current key, old ones still open with the
+// key they name, and Rewrap moves them to the current key without touching
+// the encrypted data.
+//
+// A blind index is an HMAC of the normalised value under a separate index
+// key. Equal values have equal indexes, so a store can check uniqueness and
+// look values up without decrypting them. The index key cannot be rotated
+// without recomputing every index.
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of every key in bytes (AES-256).
+const KeySize = 32
+
+const version = "v1"
+
+var (
+	ErrMalformed  = errors.New("fieldcrypt: malformed sealed value")
+	ErrUnknownKey = errors.New("fieldcrypt: unknown key")
+)
+
+// Key is a key-encryption key. IDs must not contain ".".
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// KeyProvider supplies key-encryption keys and the blind index key, e.g.
+// from a KMS or, for development, LocalKeyProvider.
+type KeyProvider interface {
+	// CurrentKey returns the key new values are wrapped with.
+	CurrentKey(ctx context.Context) (Key, error)
+	// Key returns the key with the given ID, or ErrUnknownKey.
+	Key(ctx context.Context, id string) (Key, error)
+	// IndexKey returns the key blind indexes are computed with.
+	IndexKey(ctx context.Context) ([]byte, error)
+}
+
+type Encrypter struct {
+	keys KeyProvider
+}
+
+func New(keys KeyProvider) *Encrypter {
+	return &Encrypter{keys: keys}
+}
+
+// Seal encrypts plaintext and returns it as
+//
+//	v1.<key ID>.<wrapped data key>.<encrypted value>
+//
+// with both binary parts in unpadded base64url. The additional data (e.g. the
+// record's ID and the field's name) is authenticated but not stored; Open must
+// be given the same, so a value cannot be moved to another record or field.
+func (e *Encrypter) Seal(ctx context.Context, plaintext, additionalData []byte) (string, error) {
+	kek, err := e.keys.CurrentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := encrypt(kek.Secret, dataKey, []byte(kek.ID))
+	if err != nil {
+		return "", err
+	}
+	value, err := encrypt(dataKey, plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{version, kek.ID, encode(wrapped), encode(value)}, "."), nil
+}
+
+// Open decrypts a value returned by Seal.
+func (e *Encrypter) Open(ctx context.Context, sealed string, additionalData []byte) ([]byte, error) {
+	s, err := parse(sealed)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := e.unwrap(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := decrypt(dataKey, s.value, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// CurrentKeyID returns the ID of the key Seal and Rewrap use.
+func (e *Encrypter) CurrentKeyID(ctx context.Context) (string, error) {
+	kek, err := e.keys.CurrentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	return kek.ID, nil
+}
+
+// KeyID returns the ID of the key sealed was wrapped with.
+func KeyID(sealed string) (string, error) {
+	s, err := parse(sealed)
+	if err != nil {
+		return "", err
+	}
+	return s.keyID, nil
+}
+
+// Rewrap returns sealed with its data key wrapped by the current key. The
+// encrypted value, and so the additional data it needs, is unchanged.
+func (e *Encrypter) Rewrap(ctx context.Context, sealed string) (string, error) {
+	s, err := parse(sealed)
+	if err != nil {
+		return "", err
+	}
+	kek, err := e.keys.CurrentKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	if s.keyID == kek.ID {
+		return sealed, nil
+	}
+	dataKey, err := e.unwrap(ctx, s)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := encrypt(kek.Secret, dataKey, []byte(kek.ID))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{version, kek.ID, encode(wrapped), encode(s.value)}, "."), nil
+}
+
+// BlindIndex returns the hex-encoded index of value. The purpose (e.g.
+// "employee.email") keeps the indexes of different fields apart; normalise
+// value first so that values considered equal index equally.
+func (e *Encrypter) BlindIndex(ctx context.Context, purpose, value string) (string, error) {
+	key, err := e.keys.IndexKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+type sealedValue struct {
+	keyID   string
+	wrapped []byte
+	value   []byte
+}
+
+func parse(sealed string) (sealedValue, error) {
+	parts := strings.Split(sealed, ".")
+	if len(parts) != 4 || parts[0] != version || parts[1] == "" {
+		return sealedValue{}, ErrMalformed
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return sealedValue{}, ErrMalformed
+	}
+	value, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return sealedValue{}, ErrMalformed
+	}
+	return sealedValue{keyID: parts[1], wrapped: wrapped, value: value}, nil
+}
+
+func (e *Encrypter) unwrap(ctx context.Context, s sealedValue) ([]byte, error) {
+	kek, err := e.keys.Key(ctx, s.keyID)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := decrypt(kek.Secret, s.wrapped, []byte(kek.ID))
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: unwrap data key with key %q: %w", kek.ID, err)
+	}
+	return dataKey, nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// encrypt returns nonce || AES-GCM ciphertext.
+func encrypt(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func decrypt(key, data, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,103 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func key(id string, b byte) Key {
+	return Key{ID: id, Secret: bytes.Repeat([]byte{b}, KeySize)}
+}
+
+func provider(t *testing.T, current string, keys ...Key) *LocalKeyProvider {
+	p, err := NewLocalKeyProvider(current, keys, bytes.Repeat([]byte{9}, KeySize))
+	require.NoError(t, err)
+	return p
+}
+
+func TestSeal_OpensOnlyWithSameAdditionalData(t *testing.T) {
+	ctx := context.Background()
+	e := New(provider(t, "k1", key("k1", 1)))
+
+	sealed, err := e.Seal(ctx, []byte("DE89 3704 0044"), []byte("emp-1/BankAccount"))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "DE89")
+
+	plaintext, err := e.Open(ctx, sealed, []byte("emp-1/BankAccount"))
+	require.NoError(t, err)
+	assert.Equal(t, "DE89 3704 0044", string(plaintext))
+
+	_, err = e.Open(ctx, sealed, []byte("emp-2/BankAccount"))
+	assert.Error(t, err, "a value copied to another record does not open")
+
+	again, err := e.Seal(ctx, []byte("DE89 3704 0044"), []byte("emp-1/BankAccount"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every value has its own data key and nonce")
+
+	_, err = e.Open(ctx, "v1.k1.not-base64!.x", nil)
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestRewrap_MovesValuesToTheCurrentKey(t *testing.T) {
+	ctx := context.Background()
+	old := New(provider(t, "k1", key("k1", 1)))
+	sealed, err := old.Seal(ctx, []byte("1001"), []byte("ad"))
+	require.NoError(t, err)
+
+	rotated := New(provider(t, "k2", key("k1", 1), key("k2", 2)))
+	plaintext, err := rotated.Open(ctx, sealed, []byte("ad"))
+	require.NoError(t, err, "old values open while their key is still listed")
+	assert.Equal(t, "1001", string(plaintext))
+
+	rewrapped, err := rotated.Rewrap(ctx, sealed)
+	require.NoError(t, err)
+	id, err := KeyID(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", id)
+
+	retired := New(provider(t, "k2", key("k2", 2)))
+	plaintext, err = retired.Open(ctx, rewrapped, []byte("ad"))
+	require.NoError(t, err)
+	assert.Equal(t, "1001", string(plaintext))
+	_, err = retired.Open(ctx, sealed, []byte("ad"))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestBlindIndex_IsDeterministicPerPurpose(t *testing.T) {
+	ctx := context.Background()
+	e := New(provider(t, "k1", key("k1", 1)))
+	rotated := New(provider(t, "k2", key("k2", 2)))
+
+	a, err := e.BlindIndex(ctx, "employee.email", "ada@example.com")
+	require.NoError(t, err)
+	b, err := rotated.BlindIndex(ctx, "employee.email", "ada@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, a, b, "rotating the encryption key keeps indexes")
+
+	c, err := e.BlindIndex(ctx, "employee.doc_number", "ada@example.com")
+	require.NoError(t, err)
+	assert.NotEqual(t, a, c)
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	k := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize))
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"current": "dev", "keys": {"dev": "`+k+`"}, "index_key": "`+k+`"}`), 0o600))
+
+	p, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	current, err := p.CurrentKey(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "dev", current.ID)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"current": "prod", "keys": {"dev": "`+k+`"}, "index_key": "`+k+`"}`), 0o600))
+	_, err = LoadKeyFile(path)
+	assert.ErrorContains(t, err, `current key "prod" is not listed`)
+}
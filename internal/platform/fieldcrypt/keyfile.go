@@ -0,0 +1,94 @@
+package fieldcrypt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LocalKeyProvider holds its keys in memory. It is meant for development and
+// tests; production keys belong in a KMS.
+type LocalKeyProvider struct {
+	current  string
+	keys     map[string]Key
+	indexKey []byte
+}
+
+// NewLocalKeyProvider returns a provider whose current key is the one with
+// ID current. Older keys stay listed so that their values can still be
+// opened until they have been rewrapped.
+func NewLocalKeyProvider(current string, keys []Key, indexKey []byte) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{current: current, keys: make(map[string]Key, len(keys)), indexKey: indexKey}
+	for _, k := range keys {
+		if k.ID == "" || strings.Contains(k.ID, ".") {
+			return nil, fmt.Errorf("fieldcrypt: invalid key ID %q", k.ID)
+		}
+		if len(k.Secret) != KeySize {
+			return nil, fmt.Errorf("fieldcrypt: key %q must be %d bytes", k.ID, KeySize)
+		}
+		if _, dup := p.keys[k.ID]; dup {
+			return nil, fmt.Errorf("fieldcrypt: duplicate key ID %q", k.ID)
+		}
+		p.keys[k.ID] = k
+	}
+	if _, ok := p.keys[current]; !ok {
+		return nil, fmt.Errorf("fieldcrypt: current key %q is not listed", current)
+	}
+	if len(indexKey) != KeySize {
+		return nil, fmt.Errorf("fieldcrypt: index key must be %d bytes", KeySize)
+	}
+	return p, nil
+}
+
+// keyFile is the format LoadKeyFile reads, with keys in standard base64:
+//
+//	{"current": "2026-01", "keys": {"2026-01": "..."}, "index_key": "..."}
+type keyFile struct {
+	Current  string            `json:"current"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"index_key"`
+}
+
+// LoadKeyFile reads a LocalKeyProvider from a JSON key file.
+func LoadKeyFile(path string) (*LocalKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("fieldcrypt: parse key file: %w", err)
+	}
+	var keys []Key
+	for id, secret := range f.Keys {
+		b, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: key %q: %w", id, err)
+		}
+		keys = append(keys, Key{ID: id, Secret: b})
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(f.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("fieldcrypt: index key: %w", err)
+	}
+	return NewLocalKeyProvider(f.Current, keys, indexKey)
+}
+
+func (p *LocalKeyProvider) CurrentKey(context.Context) (Key, error) {
+	return p.keys[p.current], nil
+}
+
+func (p *LocalKeyProvider) Key(_ context.Context, id string) (Key, error) {
+	k, ok := p.keys[id]
+	if !ok {
+		return Key{}, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return k, nil
+}
+
+func (p *LocalKeyProvider) IndexKey(context.Context) ([]byte, error) {
+	return p.indexKey, nil
+}
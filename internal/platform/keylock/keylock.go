@@ -0,0 +1,46 @@
+package keylock
+
+import "sync"
+
+// KeyLock is a set of mutexes identified by key. It serializes work on the
+// same key (e.g. a tenant) while letting different keys proceed in parallel.
+// Entries are released when no goroutine holds or waits for them.
+type KeyLock struct {
+	mu    sync.Mutex
+	locks map[string]*entry
+}
+
+type entry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func New() *KeyLock {
+	return &KeyLock{locks: make(map[string]*entry)}
+}
+
+// Lock blocks until key is available and returns the function that releases
+// it.
+func (k *KeyLock) Lock(key string) func() {
+	k.mu.Lock()
+	e, ok := k.locks[key]
+	if !ok {
+		e = &entry{}
+		k.locks[key] = e
+	}
+	e.refs++
+	k.mu.Unlock()
+
+	e.mu.Lock()
+
+	return func() {
+		e.mu.Unlock()
+
+		k.mu.Lock()
+		e.refs--
+		if e.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,9 @@
+package logger
+
+// Nop discards everything it is given, e.g. in tests.
+type Nop struct{}
+
+func (Nop) Info(string, ...any)         {}
+func (Nop) Debug(string, ...any)        {}
+func (Nop) Warn(string, ...any)         {}
+func (Nop) Error(error, string, ...any) {}
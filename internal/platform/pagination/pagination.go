@@ -0,0 +1,103 @@
+// Package pagination implements cursor-based paging over entities whose IDs
+// are UUIDv7, and therefore sort in creation order.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Params is what a caller asks for: an opaque cursor returned by a previous
+// page (empty for the first page) and a page size (zero for the default).
+type Params struct {
+	Cursor string
+	Limit  int
+}
+
+// Query is the resolved form of Params handed to repositories: items that
+// sort strictly after (AfterKey, After), at most Limit of them. AfterKey is
+// empty unless the listing is sorted by something other than ID. After is
+// uuid.Nil on the first page.
+type Query struct {
+	After    uuid.UUID
+	AfterKey string
+	Limit    int
+}
+
+type Page[T any] struct {
+	Items []T
+	// NextCursor is empty when there are no more items.
+	NextCursor string
+}
+
+func EncodeCursor(id uuid.UUID) string {
+	return EncodeKeyCursor("", id)
+}
+
+// EncodeKeyCursor encodes the position of an item in a listing sorted by key
+// and then by ID.
+func EncodeKeyCursor(key string, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(append(id[:], key...))
+}
+
+// DecodeCursor returns the ID and sort key encoded in cursor. An empty cursor
+// decodes to uuid.Nil.
+func DecodeCursor(cursor string) (uuid.UUID, string, error) {
+	if cursor == "" {
+		return uuid.Nil, "", nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(b) < len(uuid.Nil) {
+		return uuid.Nil, "", ErrInvalidCursor
+	}
+	id, err := uuid.FromBytes(b[:len(uuid.Nil)])
+	if err != nil || id == uuid.Nil {
+		return uuid.Nil, "", ErrInvalidCursor
+	}
+	return id, string(b[len(uuid.Nil):]), nil
+}
+
+// Validate returns the field errors of p, keyed by field name.
+func (p Params) Validate() map[string]string {
+	errs := make(map[string]string)
+	if _, _, err := DecodeCursor(p.Cursor); err != nil {
+		errs["Cursor"] = "is invalid"
+	}
+	if p.Limit < 0 || p.Limit > MaxLimit {
+		errs["Limit"] = fmt.Sprintf("must be between 0 and %d", MaxLimit)
+	}
+	return errs
+}
+
+// Query resolves p, which must already be valid. The returned Limit is one
+// more than the page size so that NewPage can tell whether another page
+// exists without a separate count.
+func (p Params) Query() Query {
+	after, key, _ := DecodeCursor(p.Cursor)
+	limit := p.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	return Query{After: after, AfterKey: key, Limit: limit + 1}
+}
+
+// NewPage trims the extra item fetched by a Query and derives the cursor of
+// the next page from the last item kept.
+func NewPage[T any](items []T, q Query, cursor func(T) string) *Page[T] {
+	size := q.Limit - 1
+	if len(items) <= size {
+		return &Page[T]{Items: items}
+	}
+	items = items[:size]
+	return &Page[T]{Items: items, NextCursor: cursor(items[size-1])}
+}
@@ -0,0 +1,38 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.Must(uuid.NewV7())
+	decoded, key, err := DecodeCursor(EncodeCursor(id))
+	require.NoError(t, err)
+	assert.Equal(t, id, decoded)
+	assert.Empty(t, key)
+
+	decoded, key, err = DecodeCursor(EncodeKeyCursor("lovelace", id))
+	require.NoError(t, err)
+	assert.Equal(t, id, decoded)
+	assert.Equal(t, "lovelace", key)
+
+	_, _, err = DecodeCursor("not-a-cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestNewPage(t *testing.T) {
+	ids := []uuid.UUID{uuid.Must(uuid.NewV7()), uuid.Must(uuid.NewV7()), uuid.Must(uuid.NewV7())}
+	q := Params{Limit: 2}.Query()
+	assert.Equal(t, 3, q.Limit)
+
+	page := NewPage(ids, q, EncodeCursor)
+	assert.Equal(t, ids[:2], page.Items)
+	assert.Equal(t, EncodeCursor(ids[1]), page.NextCursor)
+
+	page = NewPage(ids[:2], q, EncodeCursor)
+	assert.Empty(t, page.NextCursor)
+}
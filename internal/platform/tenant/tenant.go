@@ -0,0 +1,84 @@
+// Package tenant carries the identity of the tenant a request acts for in
+// its context.Context.
+//
+// Services of tenant-owned data require it on every call: they only create
+// data for that tenant, and report entities of any other tenant as not
+// found, so that callers cannot even learn that they exist. Repositories
+// must additionally scope their queries to ID(ctx).
+package tenant
+
+import (
+	"context"
+	"payroll/internal/apperror"
+
+	"github.com/google/uuid"
+)
+
+const origin = "Tenant"
+
+type contextKey struct{}
+
+// WithID returns a copy of ctx that acts for the given tenant.
+func WithID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// ID returns the tenant ctx acts for, if any.
+func ID(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(contextKey{}).(uuid.UUID)
+	return id, ok && id != uuid.Nil
+}
+
+// FromContext returns the tenant ctx acts for, or a TypeUnauthenticated
+// error when there is none.
+func FromContext(ctx context.Context) (uuid.UUID, error) {
+	id, ok := ID(ctx)
+	if !ok {
+		return uuid.Nil, apperror.New(apperror.TypeUnauthenticated, origin, "No tenant in request context")
+	}
+	return id, nil
+}
+
+// Resolve returns the tenant ctx acts for. claimed is a TenantID supplied by
+// the caller; it may be empty, but otherwise it must name the same tenant.
+func Resolve(ctx context.Context, claimed uuid.UUID) (uuid.UUID, error) {
+	id, err := FromContext(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if claimed != uuid.Nil && claimed != id {
+		return uuid.Nil, apperror.NewValidationError(origin, map[string]string{
+			"TenantID": "does not match the tenant of the request",
+		})
+	}
+	return id, nil
+}
+
+// Check returns nil if ctx acts for ownerID. Otherwise it returns the same
+// TypeNotFound error the caller would get for a missing entity, described by
+// what (e.g. "Employee not found").
+func Check(ctx context.Context, ownerID uuid.UUID, errOrigin, what string) error {
+	id, err := FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if ownerID != id {
+		return apperror.New(apperror.TypeNotFound, errOrigin, what)
+	}
+	return nil
+}
+
+// Filter returns the items of items owned by the tenant ctx acts for.
+func Filter[T any](ctx context.Context, items []T, owner func(T) uuid.UUID) ([]T, error) {
+	id, err := FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var owned []T
+	for _, item := range items {
+		if owner(item) == id {
+			owned = append(owned, item)
+		}
+	}
+	return owned, nil
+}
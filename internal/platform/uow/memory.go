@@ -0,0 +1,40 @@
+package uow
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryKey struct{}
+
+// Memory is the unit of work of in-memory repositories. Units of work run
+// one at a time, so none of them sees another's uncommitted writes.
+type Memory struct {
+	mu sync.Mutex
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(memoryKey{}).(*undoLog); ok {
+		return fn(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &undoLog{}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.rollback()
+		}
+	}()
+
+	return fn(context.WithValue(ctx, memoryKey{}, tx))
+}
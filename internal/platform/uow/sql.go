@@ -0,0 +1,77 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type sqlKey struct{}
+
+// sqlTx is the state of a SQL unit of work.
+type sqlTx struct {
+	tx *sql.Tx
+	undoLog
+}
+
+// Conn is what SQL repositories run statements on. Both *sql.DB and *sql.Tx
+// implement it.
+type Conn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SQL is the unit of work of repositories backed by database/sql.
+type SQL struct {
+	db   *sql.DB
+	opts *sql.TxOptions
+}
+
+// NewSQL returns a unit of work that runs transactions on db with opts,
+// which may be nil for the driver's defaults.
+func NewSQL(db *sql.DB, opts *sql.TxOptions) *SQL {
+	return &SQL{db: db, opts: opts}
+}
+
+func (u *SQL) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(sqlKey{}).(*sqlTx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := u.db.BeginTx(ctx, u.opts)
+	if err != nil {
+		return err
+	}
+	state := &sqlTx{tx: tx}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			state.rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, sqlKey{}, state)); err != nil {
+		rbErr := tx.Rollback()
+		state.rollback()
+		if rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		state.rollback()
+		return err
+	}
+	return nil
+}
+
+// GetConn returns the transaction of the unit of work in ctx, or db when
+// there is none.
+func GetConn(ctx context.Context, db *sql.DB) Conn {
+	if state, ok := ctx.Value(sqlKey{}).(*sqlTx); ok {
+		return state.tx
+	}
+	return db
+}
@@ -0,0 +1,47 @@
+// Package uow provides units of work: a function whose writes, across any
+// number of repositories, are committed together or not at all.
+//
+// The unit of work travels in the context handed to the function, so
+// repositories take part without being told: a SQL repository runs its
+// statements on GetConn(ctx, db), and an in-memory repository registers how to
+// undo each write with OnRollback. Repositories must use the context they
+// are given for every read and write.
+package uow
+
+import "context"
+
+// undoLog holds the undo steps registered with OnRollback.
+type undoLog struct {
+	undo []func()
+}
+
+func (l *undoLog) rollback() {
+	for i := len(l.undo) - 1; i >= 0; i-- {
+		l.undo[i]()
+	}
+}
+
+// OnRollback registers how to undo a write when the unit of work in ctx
+// rolls back. In-memory repositories undo their writes with it; any
+// repository may also restore what it changed on the caller's entity, such
+// as an incremented Version. Outside a unit of work the write is final and
+// undo is dropped.
+func OnRollback(ctx context.Context, undo func()) {
+	for _, key := range []any{memoryKey{}, sqlKey{}} {
+		switch tx := ctx.Value(key).(type) {
+		case *undoLog:
+			tx.undo = append(tx.undo, undo)
+			return
+		case *sqlTx:
+			tx.undo = append(tx.undo, undo)
+			return
+		}
+	}
+}
+
+type UnitOfWork interface {
+	// Do runs fn in a unit of work and commits it if fn returns nil. Any
+	// error or panic rolls it back. Called from inside fn, Do joins the
+	// enclosing unit of work instead of starting another.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
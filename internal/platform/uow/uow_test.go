@@ -0,0 +1,98 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory_RollsBackOnErrorAndPanic(t *testing.T) {
+	u := NewMemory()
+	store := map[string]int{}
+	write := func(ctx context.Context, key string) {
+		store[key] = 1
+		OnRollback(ctx, func() { delete(store, key) })
+	}
+
+	err := u.Do(context.Background(), func(ctx context.Context) error {
+		write(ctx, "a")
+		return u.Do(ctx, func(ctx context.Context) error {
+			write(ctx, "b")
+			return errors.New("boom")
+		})
+	})
+	assert.EqualError(t, err, "boom")
+	assert.Empty(t, store)
+
+	assert.Panics(t, func() {
+		_ = u.Do(context.Background(), func(ctx context.Context) error {
+			write(ctx, "c")
+			panic("boom")
+		})
+	})
+	assert.Empty(t, store)
+
+	require.NoError(t, u.Do(context.Background(), func(ctx context.Context) error {
+		write(ctx, "d")
+		return nil
+	}))
+	assert.Equal(t, map[string]int{"d": 1}, store)
+}
+
+// recordingDriver is a database/sql driver that only records transaction
+// boundaries.
+type recordingDriver struct{ events *[]string }
+
+func (d recordingDriver) Open(string) (driver.Conn, error) { return recordingConn(d), nil }
+
+type recordingConn recordingDriver
+
+func (c recordingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c recordingConn) Close() error                        { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) {
+	*c.events = append(*c.events, "begin")
+	return recordingTx(c), nil
+}
+
+type recordingTx recordingConn
+
+func (tx recordingTx) Commit() error {
+	*tx.events = append(*tx.events, "commit")
+	return nil
+}
+
+func (tx recordingTx) Rollback() error {
+	*tx.events = append(*tx.events, "rollback")
+	return nil
+}
+
+func TestSQL_CommitsOrRollsBackOnce(t *testing.T) {
+	var events []string
+	sql.Register("uow-recording", recordingDriver{events: &events})
+	db, err := sql.Open("uow-recording", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	u := NewSQL(db, nil)
+	assert.Equal(t, db, GetConn(context.Background(), db))
+
+	err = u.Do(context.Background(), func(ctx context.Context) error {
+		_, isTx := GetConn(ctx, db).(*sql.Tx)
+		assert.True(t, isTx)
+		return u.Do(ctx, func(context.Context) error { return nil })
+	})
+	require.NoError(t, err)
+
+	err = u.Do(context.Background(), func(ctx context.Context) error {
+		OnRollback(ctx, func() { events = append(events, "undo") })
+		return errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+
+	assert.Equal(t, []string{"begin", "commit", "begin", "rollback", "undo"}, events)
+}
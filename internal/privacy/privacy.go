@@ -0,0 +1,108 @@
+// Package privacy answers data subject requests about employees: an export
+// of everything held about an employee, and erasure of their personal data.
+//
+// Erasure pseudonymises the employee (see employee.Employee.Pseudonymise) and
+// the personal data in their change requests, and scrubs the payloads of the
+// outbox entries and webhook deliveries about them, which earlier releases
+// filled with the employee's personal data. Contracts, loans, garnishment
+// orders and payroll results refer to the employee by ID only and are kept
+// for their statutory retention period, so payroll totals stay intact.
+// Audit entries are kept as well, as the log is append-only, hash-chained
+// and retained as a legal obligation; they hold no personal data, as
+// audit.Diff records changes to it as REDACTED. Copies that webhook
+// subscribers have already received are outside this service: subscribers
+// are sent employee.EventErased and must erase their own copies.
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"payroll/internal/audit"
+	"payroll/internal/contract"
+	"payroll/internal/employee"
+	"payroll/internal/garnishment"
+	"payroll/internal/loan"
+	"payroll/internal/payroll"
+	"payroll/internal/selfservice"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Export is everything held about one employee.
+type Export struct {
+	TenantID       uuid.UUID
+	EmployeeID     uuid.UUID
+	GeneratedAt    time.Time
+	GeneratedBy    string
+	Employee       *employee.Employee
+	Contracts      []*contract.Contract
+	Loans          []*loan.Loan
+	Garnishments   []*garnishment.Order
+	Payslips       []*payroll.Payslip
+	ChangeRequests []*selfservice.ChangeRequest
+	AuditEntries   []*audit.Entry
+}
+
+// ManifestFile names the archive entry that describes the others.
+const ManifestFile = "manifest.json"
+
+// Manifest describes an archive and lists the files it holds.
+type Manifest struct {
+	TenantID    uuid.UUID
+	EmployeeID  uuid.UUID
+	GeneratedAt time.Time
+	GeneratedBy string
+	Files       []ManifestEntry
+}
+
+type ManifestEntry struct {
+	Name string
+	// Records is the number of items in the file: 1 for the employee.
+	Records int
+}
+
+// WriteArchive writes the export to w as a zip archive of JSON files, one per
+// kind of data, and a manifest.
+func (e *Export) WriteArchive(w io.Writer) error {
+	files := []struct {
+		name    string
+		records int
+		data    any
+	}{
+		{"employee.json", 1, e.Employee},
+		{"contracts.json", len(e.Contracts), e.Contracts},
+		{"loans.json", len(e.Loans), e.Loans},
+		{"garnishments.json", len(e.Garnishments), e.Garnishments},
+		{"payslips.json", len(e.Payslips), e.Payslips},
+		{"change-requests.json", len(e.ChangeRequests), e.ChangeRequests},
+		{"audit-entries.json", len(e.AuditEntries), e.AuditEntries},
+	}
+
+	manifest := Manifest{TenantID: e.TenantID, EmployeeID: e.EmployeeID, GeneratedAt: e.GeneratedAt, GeneratedBy: e.GeneratedBy}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, ManifestEntry{Name: f.name, Records: f.records})
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeJSON(zw, ManifestFile, e.GeneratedAt, manifest); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := writeJSON(zw, f.name, e.GeneratedAt, f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeJSON(zw *zip.Writer, name string, modified time.Time, v any) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
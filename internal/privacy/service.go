@@ -0,0 +1,207 @@
+package privacy
+
+import (
+	"context"
+	"payroll/internal/audit"
+	"payroll/internal/contract"
+	"payroll/internal/employee"
+	"payroll/internal/event"
+	"payroll/internal/garnishment"
+	"payroll/internal/loan"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/tenant"
+	"payroll/internal/selfservice"
+	"payroll/internal/webhook"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const serviceOrigin = "PrivacyService"
+
+// Eraser pseudonymises employees, e.g. employee.Service.
+type Eraser interface {
+	Erase(ctx context.Context, id uuid.UUID) (*employee.Employee, error)
+}
+
+// Payslips reads finalized payroll results, e.g. payroll.Service.
+type Payslips interface {
+	ListPayslips(ctx context.Context, employeeID uuid.UUID, from, to time.Time) ([]*payroll.Payslip, error)
+}
+
+type Service struct {
+	employeeRepo    employee.Repository
+	eraser          Eraser
+	contractRepo    contract.Repository
+	loanRepo        loan.Repository
+	garnishmentRepo garnishment.Repository
+	payslips        Payslips
+	requestRepo     selfservice.Repository
+	auditRepo       audit.Repository
+	outbox          event.Store
+	deliveryRepo    webhook.DeliveryRepository
+	logger          logger.Logger
+	now             func() time.Time
+}
+
+func NewService(er employee.Repository, e Eraser, cr contract.Repository, lr loan.Repository, gr garnishment.Repository, p Payslips, rr selfservice.Repository, ar audit.Repository, es event.Store, dr webhook.DeliveryRepository, l logger.Logger) *Service {
+	return &Service{
+		employeeRepo:    er,
+		eraser:          e,
+		contractRepo:    cr,
+		loanRepo:        lr,
+		garnishmentRepo: gr,
+		payslips:        p,
+		requestRepo:     rr,
+		auditRepo:       ar,
+		outbox:          es,
+		deliveryRepo:    dr,
+		logger:          l,
+		now:             time.Now,
+	}
+}
+
+// Export collects everything held about the employee, including soft-deleted
+// and erased employees. Write it out with Export.WriteArchive.
+func (s *Service) Export(ctx context.Context, employeeID uuid.UUID) (*Export, error) {
+	emp, err := s.get(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{
+		TenantID:    emp.TenantID,
+		EmployeeID:  emp.ID,
+		GeneratedAt: s.now().UTC(),
+		GeneratedBy: p.Subject,
+		Employee:    emp,
+	}
+	if export.Contracts, err = s.contractRepo.ListByEmployeeID(ctx, emp.ID); err != nil {
+		return nil, err
+	}
+	if export.Loans, err = s.loanRepo.ListByEmployeeID(ctx, emp.ID); err != nil {
+		return nil, err
+	}
+	if export.Garnishments, err = s.garnishmentRepo.ListByEmployeeID(ctx, emp.ID); err != nil {
+		return nil, err
+	}
+	if export.Payslips, err = s.payslips.ListPayslips(ctx, emp.ID, time.Time{}, export.GeneratedAt); err != nil {
+		return nil, err
+	}
+	if export.ChangeRequests, err = s.requestRepo.ListByEmployeeID(ctx, emp.ID); err != nil {
+		return nil, err
+	}
+
+	// The audit entries of the employee and of every record about them.
+	ids := []uuid.UUID{emp.ID}
+	for _, c := range export.Contracts {
+		ids = append(ids, c.ID)
+	}
+	for _, l := range export.Loans {
+		ids = append(ids, l.ID)
+	}
+	for _, o := range export.Garnishments {
+		ids = append(ids, o.ID)
+	}
+	for _, slip := range export.Payslips {
+		ids = append(ids, slip.Result.ID)
+	}
+	for _, id := range ids {
+		entries, err := s.auditEntries(ctx, emp.TenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		export.AuditEntries = append(export.AuditEntries, entries...)
+	}
+	sort.Slice(export.AuditEntries, func(i, j int) bool {
+		return export.AuditEntries[i].Sequence < export.AuditEntries[j].Sequence
+	})
+
+	s.logger.Info("Personal data exported", "employee_id", emp.ID, "exported_by", p.Subject)
+	return export, nil
+}
+
+// auditEntries returns every entry of the tenant about the entity.
+func (s *Service) auditEntries(ctx context.Context, tenantID, entityID uuid.UUID) ([]*audit.Entry, error) {
+	var all []*audit.Entry
+	query := pagination.Query{Limit: pagination.MaxLimit}
+	for {
+		entries, err := s.auditRepo.List(ctx, audit.Filter{TenantID: tenantID, EntityID: &entityID, Query: query})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, entries...)
+		if len(entries) < query.Limit {
+			return all, nil
+		}
+		query.After = entries[len(entries)-1].ID
+	}
+}
+
+// Erase pseudonymises the employee and removes the personal data from their
+// change requests and from the payloads of the events and webhook deliveries
+// about them. What is kept, and why, is described in the package
+// documentation. Erasing an employee again only repeats the clean-up.
+func (s *Service) Erase(ctx context.Context, employeeID uuid.UUID) (*employee.Employee, error) {
+	if _, err := s.get(ctx, employeeID); err != nil {
+		return nil, err
+	}
+
+	// The employee service takes a tenant lock that must not be acquired
+	// inside a unit of work, so the other copies are cleaned up
+	// afterwards. If that fails, erasing again finishes the job.
+	emp, err := s.eraser.Erase(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.requestRepo.ListByEmployeeID(ctx, emp.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range requests {
+		r.Erase()
+		if err := s.requestRepo.Update(ctx, r); err != nil {
+			s.logger.Error(err, "Failed to erase change request", "request_id", r.ID, "employee_id", emp.ID)
+			return nil, err
+		}
+	}
+
+	events, err := s.outbox.ScrubByEntityID(ctx, emp.TenantID, emp.ID)
+	if err != nil {
+		s.logger.Error(err, "Failed to erase outbox entries", "employee_id", emp.ID)
+		return nil, err
+	}
+	deliveries, err := s.deliveryRepo.ScrubByEntityID(ctx, emp.TenantID, emp.ID)
+	if err != nil {
+		s.logger.Error(err, "Failed to erase webhook deliveries", "employee_id", emp.ID)
+		return nil, err
+	}
+
+	s.logger.Info("Personal data erased", "employee_id", emp.ID, "change_requests", len(requests), "events", events, "webhook_deliveries", deliveries)
+	return emp, nil
+}
+
+// get returns the employee in any state, provided the caller may handle
+// their data subject requests.
+func (s *Service) get(ctx context.Context, id uuid.UUID) (*employee.Employee, error) {
+	emp, err := s.employeeRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, emp.TenantID, serviceOrigin, "Employee not found"); err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermPrivacyManage, auth.Resource{WorkspaceID: emp.WorkspaceID, EmployeeID: emp.ID}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	return emp, nil
+}
@@ -0,0 +1,306 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/contract"
+	"payroll/internal/employee"
+	"payroll/internal/event"
+	"payroll/internal/garnishment"
+	"payroll/internal/loan"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/selfservice"
+	"payroll/internal/webhook"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubEmployeeRepo struct {
+	employee.Repository
+	emp *employee.Employee
+}
+
+func (r *stubEmployeeRepo) GetByID(_ context.Context, id uuid.UUID) (*employee.Employee, error) {
+	if r.emp.ID != id {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "employee not found")
+	}
+	return r.emp, nil
+}
+
+// eraserFunc stands in for employee.Service.Erase.
+type eraserFunc func(ctx context.Context, id uuid.UUID) (*employee.Employee, error)
+
+func (f eraserFunc) Erase(ctx context.Context, id uuid.UUID) (*employee.Employee, error) {
+	return f(ctx, id)
+}
+
+type stubContractRepo struct {
+	contract.Repository
+	contracts []*contract.Contract
+}
+
+func (r *stubContractRepo) ListByEmployeeID(context.Context, uuid.UUID) ([]*contract.Contract, error) {
+	return r.contracts, nil
+}
+
+type stubLoanRepo struct{ loan.Repository }
+
+func (stubLoanRepo) ListByEmployeeID(context.Context, uuid.UUID) ([]*loan.Loan, error) {
+	return nil, nil
+}
+
+type stubGarnishmentRepo struct{ garnishment.Repository }
+
+func (stubGarnishmentRepo) ListByEmployeeID(context.Context, uuid.UUID) ([]*garnishment.Order, error) {
+	return nil, nil
+}
+
+type stubPayslips struct {
+	payslips []*payroll.Payslip
+}
+
+func (s stubPayslips) ListPayslips(context.Context, uuid.UUID, time.Time, time.Time) ([]*payroll.Payslip, error) {
+	return s.payslips, nil
+}
+
+type memoryRequestRepo struct {
+	selfservice.Repository
+	requests []*selfservice.ChangeRequest
+	updated  int
+}
+
+func (r *memoryRequestRepo) ListByEmployeeID(context.Context, uuid.UUID) ([]*selfservice.ChangeRequest, error) {
+	return r.requests, nil
+}
+
+func (r *memoryRequestRepo) Update(context.Context, *selfservice.ChangeRequest) error {
+	r.updated++
+	return nil
+}
+
+// memoryAuditRepo honours the entity filter and the page size.
+type memoryAuditRepo struct {
+	audit.Repository
+	entries []*audit.Entry
+}
+
+func (r *memoryAuditRepo) List(_ context.Context, filter audit.Filter) ([]*audit.Entry, error) {
+	var out []*audit.Entry
+	for _, e := range r.entries {
+		if e.TenantID != filter.TenantID || e.EntityID != *filter.EntityID {
+			continue
+		}
+		if filter.After != uuid.Nil && e.ID.String() <= filter.After.String() {
+			continue
+		}
+		if len(out) == filter.Limit {
+			break
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+type memoryOutbox struct {
+	event.Store
+	entries []*event.Entry
+}
+
+func (s *memoryOutbox) ScrubByEntityID(_ context.Context, tenantID, entityID uuid.UUID) (int, error) {
+	n := 0
+	for _, e := range s.entries {
+		if e.TenantID == tenantID && e.EntityID == entityID {
+			e.Payload = json.RawMessage("null")
+			n++
+		}
+	}
+	return n, nil
+}
+
+type memoryDeliveryRepo struct {
+	webhook.DeliveryRepository
+	deliveries []*webhook.Delivery
+}
+
+func (r *memoryDeliveryRepo) ScrubByEntityID(_ context.Context, tenantID, entityID uuid.UUID) (int, error) {
+	n := 0
+	for _, d := range r.deliveries {
+		if d.TenantID != tenantID || d.EntityID != entityID {
+			continue
+		}
+		b, err := webhook.ScrubBody(d.Body)
+		if err != nil {
+			return n, err
+		}
+		d.Body = b
+		n++
+	}
+	return n, nil
+}
+
+type fixture struct {
+	service    *Service
+	ada        *employee.Employee
+	requests   *memoryRequestRepo
+	audits     *memoryAuditRepo
+	outbox     *memoryOutbox
+	deliveries *memoryDeliveryRepo
+	admin      context.Context
+}
+
+func newFixture() *fixture {
+	ada := &employee.Employee{TenantID: uuid.New(), WorkspaceID: uuid.New(), FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Status: employee.StatusTerminated}
+	ada.Initialize()
+
+	c := &contract.Contract{TenantID: ada.TenantID, EmployeeID: ada.ID, Salary: 5000}
+	c.Initialize()
+	result := &payroll.Result{TenantID: ada.TenantID, EmployeeID: ada.ID, Net: 4000}
+	result.Initialize()
+
+	auditRepo := &memoryAuditRepo{}
+	for i, id := range []uuid.UUID{ada.ID, uuid.New(), c.ID, result.ID, ada.ID} {
+		auditRepo.entries = append(auditRepo.entries, &audit.Entry{
+			ID: uuid.Must(uuid.NewV7()), TenantID: ada.TenantID, Sequence: int64(i + 1), EntityID: id,
+		})
+	}
+
+	phone := "555-0100"
+	pending := &selfservice.ChangeRequest{EmployeeID: ada.ID, Status: selfservice.RequestStatusPending, Changes: selfservice.ProfileChanges{Phone: &phone}}
+	pending.Initialize()
+	requests := &memoryRequestRepo{requests: []*selfservice.ChangeRequest{pending}}
+
+	eraser := eraserFunc(func(_ context.Context, id uuid.UUID) (*employee.Employee, error) {
+		ada.Pseudonymise(time.Now())
+		return ada, nil
+	})
+
+	outbox := &memoryOutbox{}
+	deliveries := &memoryDeliveryRepo{}
+	return &fixture{
+		service: NewService(&stubEmployeeRepo{emp: ada}, eraser, &stubContractRepo{contracts: []*contract.Contract{c}},
+			stubLoanRepo{}, stubGarnishmentRepo{}, stubPayslips{payslips: []*payroll.Payslip{{RunID: uuid.New(), Result: result}}},
+			requests, auditRepo, outbox, deliveries, logger.Nop{}),
+		ada:        ada,
+		requests:   requests,
+		audits:     auditRepo,
+		outbox:     outbox,
+		deliveries: deliveries,
+		admin: auth.WithPrincipal(context.Background(), &auth.Principal{
+			Subject: "admin", TenantID: ada.TenantID, Roles: []auth.Role{auth.RoleTenantAdmin},
+		}),
+	}
+}
+
+func TestExport_WritesEverythingHeldAsJSONArchive(t *testing.T) {
+	f := newFixture()
+
+	export, err := f.service.Export(f.admin, f.ada.ID)
+	require.NoError(t, err)
+	require.Len(t, export.AuditEntries, 4, "entries about the employee, their contract and their payroll result")
+	assert.Equal(t, []int64{1, 3, 4, 5}, []int64{
+		export.AuditEntries[0].Sequence, export.AuditEntries[1].Sequence, export.AuditEntries[2].Sequence, export.AuditEntries[3].Sequence,
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, export.WriteArchive(&buf))
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		require.NoError(t, err)
+		files[zf.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal(files[ManifestFile], &manifest))
+	assert.Equal(t, f.ada.ID, manifest.EmployeeID)
+	assert.Equal(t, "admin", manifest.GeneratedBy)
+	for _, entry := range manifest.Files {
+		assert.Contains(t, files, entry.Name)
+	}
+	assert.Contains(t, manifest.Files, ManifestEntry{Name: "contracts.json", Records: 1})
+
+	var emp employee.Employee
+	require.NoError(t, json.Unmarshal(files["employee.json"], &emp))
+	assert.Equal(t, "ada@example.com", emp.Email)
+}
+
+func TestErase_PseudonymisesEmployeeAndChangeRequests(t *testing.T) {
+	f := newFixture()
+
+	emp, err := f.service.Erase(f.admin, f.ada.ID)
+	require.NoError(t, err)
+	assert.True(t, emp.IsErased())
+
+	r := f.requests.requests[0]
+	assert.Equal(t, selfservice.RequestStatusCancelled, r.Status)
+	assert.True(t, r.Changes.IsEmpty())
+	assert.Equal(t, 1, f.requests.updated)
+}
+
+func TestErase_LeavesNoPersonalDataInEventsDeliveriesOrAudit(t *testing.T) {
+	f := newFixture()
+	original := *f.ada
+	changed := original
+	changed.Email = "lovelace@example.com"
+	changes, err := audit.Diff(&original, &changed)
+	require.NoError(t, err)
+	f.audits.entries[0].Changes = changes
+
+	// Earlier releases published the employee as it was.
+	payload, err := json.Marshal(&changed)
+	require.NoError(t, err)
+	m := event.Message{ID: uuid.New(), Type: employee.EventUpdated, TenantID: f.ada.TenantID, EntityID: f.ada.ID, Payload: payload}
+	f.outbox.entries = append(f.outbox.entries, &event.Entry{Message: m})
+	body, err := json.Marshal(map[string]any{"id": m.ID, "type": m.Type, "tenant_id": m.TenantID, "entity_id": m.EntityID, "data": m.Payload})
+	require.NoError(t, err)
+	f.deliveries.deliveries = append(f.deliveries.deliveries, &webhook.Delivery{
+		TenantID: m.TenantID, MessageID: m.ID, EventType: m.Type, EntityID: m.EntityID, Body: body,
+	})
+
+	_, err = f.service.Erase(f.admin, f.ada.ID)
+	require.NoError(t, err)
+
+	stores, err := json.Marshal([]any{f.outbox.entries, f.deliveries.deliveries, f.audits.entries})
+	require.NoError(t, err)
+	for _, value := range []string{"Ada", "Lovelace", "ada@example.com", "lovelace@example.com"} {
+		assert.NotContains(t, string(stores), value)
+	}
+	var scrubbed map[string]any
+	require.NoError(t, json.Unmarshal(f.deliveries.deliveries[0].Body, &scrubbed))
+	assert.Equal(t, m.ID.String(), scrubbed["id"], "the delivery is still sent")
+}
+
+func TestPrivacy_RequiresPrivacyPermission(t *testing.T) {
+	f := newFixture()
+	manager := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "bob", TenantID: f.ada.TenantID, Roles: []auth.Role{auth.RolePayrollManager}, WorkspaceIDs: []uuid.UUID{f.ada.WorkspaceID},
+	})
+
+	_, err := f.service.Export(manager, f.ada.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = f.service.Erase(manager, f.ada.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	assert.False(t, f.ada.IsErased())
+}
+
+var (
+	_ Eraser   = (*employee.Service)(nil)
+	_ Payslips = (*payroll.Service)(nil)
+)
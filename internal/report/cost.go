@@ -0,0 +1,147 @@
+package report
+
+import (
+	"context"
+	"payroll/internal/money"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/tenant"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CostSummary is the employer cost of a workspace for one calendar month, in
+// one pay currency.
+type CostSummary struct {
+	WorkspaceID   uuid.UUID
+	WorkspaceCode string
+	Month         time.Time
+	Currency      string
+	Employees     int
+	Gross         money.Amount
+	Contributions money.Amount
+	Net           money.Amount
+}
+
+func (c *CostSummary) EmployerCost() money.Amount {
+	return c.Gross + c.Contributions
+}
+
+type costKey struct {
+	workspaceID uuid.UUID
+	month       time.Time
+	currency    string
+}
+
+// CostSummaries aggregates the finalized runs of a tenant whose period ends
+// within [from, to] into one summary per workspace, month and currency. Only
+// the workspaces whose reports the caller may read are included.
+func (s *Service) CostSummaries(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*CostSummary, error) {
+	tenantID, err := tenant.Resolve(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := s.payrollRepo.ListRunsByTenantID(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	runs, err = auth.Filter(ctx, auth.PermReportRead, runs, func(run *payroll.Run) auth.Resource {
+		return auth.Resource{WorkspaceID: run.WorkspaceID}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[costKey]*CostSummary)
+	codes := make(map[uuid.UUID]string)
+	for _, run := range runs {
+		if run.Status != payroll.RunStatusFinalized {
+			continue
+		}
+		if _, ok := codes[run.WorkspaceID]; !ok {
+			ws, err := s.workspaceRepo.Get(ctx, run.WorkspaceID)
+			if err != nil {
+				return nil, err
+			}
+			codes[run.WorkspaceID] = ws.Code
+		}
+
+		month := time.Date(run.PeriodEnd.Year(), run.PeriodEnd.Month(), 1, 0, 0, 0, 0, time.UTC)
+		err := s.payrollRepo.IterateResultsByRunID(ctx, run.ID, func(result *payroll.Result) error {
+			key := costKey{workspaceID: run.WorkspaceID, month: month, currency: result.Currency}
+			summary, ok := summaries[key]
+			if !ok {
+				summary = &CostSummary{
+					WorkspaceID:   run.WorkspaceID,
+					WorkspaceCode: codes[run.WorkspaceID],
+					Month:         month,
+					Currency:      result.Currency,
+				}
+				summaries[key] = summary
+			}
+			summary.Employees++
+			summary.Gross += result.Gross
+			summary.Contributions += result.Contributions
+			summary.Net += result.Net
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	list := make([]*CostSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		list = append(list, summary)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.WorkspaceCode != b.WorkspaceCode {
+			return a.WorkspaceCode < b.WorkspaceCode
+		}
+		if !a.Month.Equal(b.Month) {
+			return a.Month.Before(b.Month)
+		}
+		return a.Currency < b.Currency
+	})
+	return list, nil
+}
+
+// WriteCostSummaries writes the result of CostSummaries as a table and closes
+// the writer.
+func (s *Service) WriteCostSummaries(ctx context.Context, tenantID uuid.UUID, from, to time.Time, w TableWriter) error {
+	summaries, err := s.CostSummaries(ctx, tenantID, from, to)
+	if err != nil {
+		return err
+	}
+
+	header := []Cell{
+		Text("Workspace"), Text("Month"), Text("Currency"), Text("Employees"),
+		Text("Gross"), Text("Employer Contributions"), Text("Employer Cost"), Text("Net"),
+	}
+	if err := w.WriteRow(header); err != nil {
+		return err
+	}
+
+	for _, summary := range summaries {
+		digits := money.MinorUnits(summary.Currency)
+		row := []Cell{
+			Text(summary.WorkspaceCode),
+			Text(summary.Month.Format("2006-01")),
+			Text(summary.Currency),
+			Number(strconv.Itoa(summary.Employees)),
+			Number(summary.Gross.Format(digits)),
+			Number(summary.Contributions.Format(digits)),
+			Number(summary.EmployerCost().Format(digits)),
+			Number(summary.Net.Format(digits)),
+		}
+		if err := w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
@@ -0,0 +1,275 @@
+package report
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/money"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/tenant"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+const (
+	totalDepartmentLabel = "TOTAL DEPARTMENT"
+	totalWorkspaceLabel  = "TOTAL WORKSPACE"
+	// registerBatchSize is how many results are written per lookup of their
+	// employees.
+	registerBatchSize = 500
+)
+
+type column struct {
+	kind payroll.ItemKind
+	code string
+}
+
+// registerLayout holds the pay item columns of a register, grouped by kind
+// and sorted by code.
+type registerLayout struct {
+	earnings      []column
+	deductions    []column
+	contributions []column
+	index         map[column]int
+}
+
+func newRegisterLayout(seen map[column]bool) *registerLayout {
+	layout := &registerLayout{index: make(map[column]int)}
+	for col := range seen {
+		switch col.kind {
+		case payroll.ItemKindEarning:
+			layout.earnings = append(layout.earnings, col)
+		case payroll.ItemKindDeduction:
+			layout.deductions = append(layout.deductions, col)
+		case payroll.ItemKindContribution:
+			layout.contributions = append(layout.contributions, col)
+		}
+	}
+	for _, cols := range [][]column{layout.earnings, layout.deductions, layout.contributions} {
+		sort.Slice(cols, func(i, j int) bool { return cols[i].code < cols[j].code })
+	}
+
+	i := 0
+	for _, cols := range [][]column{layout.earnings, layout.deductions, layout.contributions} {
+		for _, col := range cols {
+			layout.index[col] = i
+			i++
+		}
+	}
+	return layout
+}
+
+func (l *registerLayout) header() []Cell {
+	cells := []Cell{
+		Text("Employee ID"), Text("Doc Number"), Text("Last Name"), Text("First Name"),
+		Text("Department"), Text("Currency"),
+	}
+	for _, col := range l.earnings {
+		cells = append(cells, Text(col.code))
+	}
+	cells = append(cells, Text("Gross"))
+	for _, col := range l.deductions {
+		cells = append(cells, Text(col.code))
+	}
+	cells = append(cells, Text("Deductions"), Text("Net"))
+	for _, col := range l.contributions {
+		cells = append(cells, Text(col.code))
+	}
+	return append(cells, Text("Employer Cost"))
+}
+
+// registerLine is the amounts of one register row: one value per pay item
+// column followed by the totals.
+type registerLine struct {
+	items         []money.Amount
+	gross         money.Amount
+	deductions    money.Amount
+	net           money.Amount
+	contributions money.Amount
+}
+
+func (l *registerLayout) newLine() *registerLine {
+	return &registerLine{items: make([]money.Amount, len(l.index))}
+}
+
+func (l *registerLayout) lineFor(result *payroll.Result) *registerLine {
+	line := l.newLine()
+	for _, item := range result.Items {
+		line.items[l.index[column{item.Kind, item.Code}]] += item.Amount
+	}
+	line.gross = result.Gross
+	line.deductions = result.Deductions
+	line.net = result.Net
+	line.contributions = result.Contributions
+	return line
+}
+
+func (line *registerLine) add(other *registerLine) {
+	for i, amount := range other.items {
+		line.items[i] += amount
+	}
+	line.gross += other.gross
+	line.deductions += other.deductions
+	line.net += other.net
+	line.contributions += other.contributions
+}
+
+func (l *registerLayout) amounts(line *registerLine, currency string) []Cell {
+	digits := money.MinorUnits(currency)
+	format := func(a money.Amount) Cell { return Number(a.Format(digits)) }
+
+	var cells []Cell
+	i := 0
+	for range l.earnings {
+		cells = append(cells, format(line.items[i]))
+		i++
+	}
+	cells = append(cells, format(line.gross))
+	for range l.deductions {
+		cells = append(cells, format(line.items[i]))
+		i++
+	}
+	cells = append(cells, format(line.deductions), format(line.net))
+	for range l.contributions {
+		cells = append(cells, format(line.items[i]))
+		i++
+	}
+	return append(cells, format(line.gross+line.contributions))
+}
+
+type totalKey struct {
+	department string
+	currency   string
+}
+
+// WriteRegister writes the payroll register of a finalized run: one row per
+// employee and one column per pay item, followed by totals per department and
+// for the whole workspace (per currency when employees are paid in more than
+// one). Results are streamed from the repository twice, first to discover the
+// pay item columns and then to write the rows, and their employees are read
+// in batches as the rows are written, so memory use does not grow with the
+// number of employees. The writer is closed once the register is complete.
+func (s *Service) WriteRegister(ctx context.Context, runID uuid.UUID, w TableWriter) error {
+	run, err := s.payrollRepo.GetRun(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if err := tenant.Check(ctx, run.TenantID, serviceOrigin, "Payroll run not found"); err != nil {
+		return err
+	}
+	if err := auth.Authorize(ctx, auth.PermReportRead, auth.Resource{WorkspaceID: run.WorkspaceID}, serviceOrigin); err != nil {
+		return err
+	}
+	if run.Status != payroll.RunStatusFinalized {
+		return apperror.New(apperror.TypeInvalid, serviceOrigin, "Payroll register is only available for finalized runs")
+	}
+
+	seen := make(map[column]bool)
+	err = s.payrollRepo.IterateResultsByRunID(ctx, run.ID, func(result *payroll.Result) error {
+		for _, item := range result.Items {
+			seen[column{item.Kind, item.Code}] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	layout := newRegisterLayout(seen)
+
+	if err := w.WriteRow(layout.header()); err != nil {
+		return err
+	}
+
+	departmentTotals := make(map[totalKey]*registerLine)
+	workspaceTotals := make(map[string]*registerLine)
+	batch := make([]*payroll.Result, 0, registerBatchSize)
+	writeBatch := func() error {
+		employees, err := s.employeesOf(ctx, run, batch)
+		if err != nil {
+			return err
+		}
+		for _, result := range batch {
+			emp := employees[result.EmployeeID]
+			line := layout.lineFor(result)
+
+			key := totalKey{department: department(emp), currency: result.Currency}
+			if departmentTotals[key] == nil {
+				departmentTotals[key] = layout.newLine()
+			}
+			departmentTotals[key].add(line)
+			if workspaceTotals[result.Currency] == nil {
+				workspaceTotals[result.Currency] = layout.newLine()
+			}
+			workspaceTotals[result.Currency].add(line)
+
+			if err := w.WriteRow(append(employeeCells(result.EmployeeID, emp, result.Currency), layout.amounts(line, result.Currency)...)); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+	err = s.payrollRepo.IterateResultsByRunID(ctx, run.ID, func(result *payroll.Result) error {
+		batch = append(batch, result)
+		if len(batch) < registerBatchSize {
+			return nil
+		}
+		return writeBatch()
+	})
+	if err == nil {
+		err = writeBatch()
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := make([]totalKey, 0, len(departmentTotals))
+	for key := range departmentTotals {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].department != keys[j].department {
+			return keys[i].department < keys[j].department
+		}
+		return keys[i].currency < keys[j].currency
+	})
+	for _, key := range keys {
+		cells := []Cell{Text(totalDepartmentLabel), Text(""), Text(""), Text(""), Text(key.department), Text(key.currency)}
+		if err := w.WriteRow(append(cells, layout.amounts(departmentTotals[key], key.currency)...)); err != nil {
+			return err
+		}
+	}
+
+	currencies := make([]string, 0, len(workspaceTotals))
+	for currency := range workspaceTotals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		cells := []Cell{Text(totalWorkspaceLabel), Text(""), Text(""), Text(""), Text(""), Text(currency)}
+		if err := w.WriteRow(append(cells, layout.amounts(workspaceTotals[currency], currency)...)); err != nil {
+			return err
+		}
+	}
+
+	return w.Close()
+}
+
+func department(emp *employee.Employee) string {
+	if emp == nil || emp.Department == nil {
+		return ""
+	}
+	return *emp.Department
+}
+
+func employeeCells(id uuid.UUID, emp *employee.Employee, currency string) []Cell {
+	if emp == nil {
+		return []Cell{Text(id.String()), Text(""), Text(""), Text(""), Text(""), Text(currency)}
+	}
+	return []Cell{
+		Text(id.String()), Text(emp.DocNumber), Text(emp.LastName), Text(emp.FirstName),
+		Text(department(emp)), Text(currency),
+	}
+}
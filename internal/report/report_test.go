@@ -0,0 +1,264 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/payroll"
+	"payroll/internal/testkit"
+	"payroll/internal/workspace"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePayrollRepo struct {
+	payroll.Repository
+	runs    []*payroll.Run
+	results map[uuid.UUID][]*payroll.Result
+}
+
+func (r *fakePayrollRepo) GetRun(_ context.Context, id uuid.UUID) (*payroll.Run, error) {
+	for _, run := range r.runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakePayrollRepo) IterateResultsByRunID(_ context.Context, runID uuid.UUID, fn func(*payroll.Result) error) error {
+	for _, result := range r.results[runID] {
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakePayrollRepo) ListRunsByTenantID(_ context.Context, tenantID uuid.UUID, _, _ time.Time) ([]*payroll.Run, error) {
+	var runs []*payroll.Run
+	for _, run := range r.runs {
+		if run.TenantID == tenantID {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+type fakeEmployeeRepo struct {
+	employee.Repository
+	employees []*employee.Employee
+	lookups   []int
+}
+
+// ListByIDs records the size of every lookup in lookups.
+func (r *fakeEmployeeRepo) ListByIDs(_ context.Context, ids []uuid.UUID) ([]*employee.Employee, error) {
+	r.lookups = append(r.lookups, len(ids))
+	var list []*employee.Employee
+	for _, emp := range r.employees {
+		if slices.Contains(ids, emp.ID) {
+			list = append(list, emp)
+		}
+	}
+	return list, nil
+}
+
+type fakeWorkspaceRepo struct {
+	workspace.Repository
+}
+
+func (fakeWorkspaceRepo) Get(_ context.Context, id uuid.UUID) (*workspace.Workspace, error) {
+	return &workspace.Workspace{Code: "WS1"}, nil
+}
+
+var (
+	tenantID = uuid.New()
+	ctx      = testkit.AdminContext(tenantID)
+)
+
+func newFixture(status payroll.RunStatus) (*Service, *payroll.Run) {
+	run := &payroll.Run{TenantID: tenantID, Status: status, PeriodEnd: time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)}
+	run.Initialize()
+
+	sales, ops := "Sales", "Ops"
+	alice := &employee.Employee{TenantID: tenantID, FirstName: "Alice", LastName: "Smith", DocNumber: "1", Department: &sales}
+	alice.Initialize()
+	bob := &employee.Employee{TenantID: tenantID, FirstName: "Bob", LastName: "Jones", DocNumber: "2", Department: &ops}
+	bob.Initialize()
+
+	newResult := func(emp *employee.Employee, items ...payroll.Item) *payroll.Result {
+		result := &payroll.Result{EmployeeID: emp.ID, Currency: "USD"}
+		for _, item := range items {
+			result.AddItem(item)
+		}
+		return result
+	}
+	results := []*payroll.Result{
+		newResult(alice,
+			payroll.Item{Code: "SALARY", Kind: payroll.ItemKindEarning, Amount: 300000},
+			payroll.Item{Code: "LOAN", Kind: payroll.ItemKindDeduction, Amount: 10000},
+			payroll.Item{Code: "LOAN", Kind: payroll.ItemKindDeduction, Amount: 5000},
+			payroll.Item{Code: "PENSION", Kind: payroll.ItemKindContribution, Amount: 24000},
+		),
+		newResult(bob,
+			payroll.Item{Code: "SALARY", Kind: payroll.ItemKindEarning, Amount: 200000},
+			payroll.Item{Code: "BONUS", Kind: payroll.ItemKindEarning, Amount: 5050},
+		),
+	}
+
+	svc := NewService(
+		&fakePayrollRepo{runs: []*payroll.Run{run}, results: map[uuid.UUID][]*payroll.Result{run.ID: results}},
+		&fakeEmployeeRepo{employees: []*employee.Employee{alice, bob}},
+		fakeWorkspaceRepo{},
+		nil,
+	)
+	return svc, run
+}
+
+func TestWriteRegister_CSV(t *testing.T) {
+	svc, run := newFixture(payroll.RunStatusFinalized)
+	var buf bytes.Buffer
+
+	require.NoError(t, svc.WriteRegister(ctx, run.ID, NewCSVWriter(&buf)))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, "Employee ID,Doc Number,Last Name,First Name,Department,Currency,BONUS,SALARY,Gross,LOAN,Deductions,Net,PENSION,Employer Cost", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ",1,Smith,Alice,Sales,USD,0.00,3000.00,3000.00,150.00,150.00,2850.00,240.00,3240.00"))
+	assert.Equal(t, "TOTAL DEPARTMENT,,,,Ops,USD,50.50,2000.00,2050.50,0.00,0.00,2050.50,0.00,2050.50", lines[3])
+	assert.Equal(t, "TOTAL WORKSPACE,,,,,USD,50.50,5000.00,5050.50,150.00,150.00,4900.50,240.00,5290.50", lines[5])
+}
+
+func TestWriteRegister_IncludesEmployeesDeletedSinceTheRun(t *testing.T) {
+	svc, run := newFixture(payroll.RunStatusFinalized)
+	bob := svc.employeeRepo.(*fakeEmployeeRepo).employees[1]
+	bob.SoftDelete()
+	var buf bytes.Buffer
+
+	require.NoError(t, svc.WriteRegister(ctx, run.ID, NewCSVWriter(&buf)))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.True(t, strings.HasPrefix(lines[2], bob.ID.String()+",2,Jones,Bob,Ops,USD,"))
+	assert.Equal(t, "TOTAL DEPARTMENT,,,,Ops,USD,50.50,2000.00,2050.50,0.00,0.00,2050.50,0.00,2050.50", lines[3])
+}
+
+func TestWriteRegister_ReadsEmployeesInBatches(t *testing.T) {
+	svc, run := newFixture(payroll.RunStatusFinalized)
+	repo := svc.payrollRepo.(*fakePayrollRepo)
+	for len(repo.results[run.ID]) <= 2*registerBatchSize {
+		repo.results[run.ID] = append(repo.results[run.ID], &payroll.Result{EmployeeID: uuid.New(), Currency: "USD"})
+	}
+	var buf bytes.Buffer
+
+	require.NoError(t, svc.WriteRegister(ctx, run.ID, NewCSVWriter(&buf)))
+
+	assert.Equal(t, []int{registerBatchSize, registerBatchSize, 1}, svc.employeeRepo.(*fakeEmployeeRepo).lookups)
+	assert.Contains(t, buf.String(), "\n"+repo.results[run.ID][0].EmployeeID.String()+",1,Smith,Alice,Sales,USD,")
+}
+
+func TestWriteRegister_IgnoresEmployeesOfOtherTenants(t *testing.T) {
+	svc, run := newFixture(payroll.RunStatusFinalized)
+	svc.employeeRepo.(*fakeEmployeeRepo).employees[0].TenantID = uuid.New()
+	var buf bytes.Buffer
+
+	require.NoError(t, svc.WriteRegister(ctx, run.ID, NewCSVWriter(&buf)))
+
+	assert.NotContains(t, buf.String(), "Smith")
+}
+
+func TestCSVWriter_EscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+
+	require.NoError(t, w.WriteRow([]Cell{
+		Text("=HYPERLINK(\"http://x\")"), Text("+1"), Text("-2"), Text("@SUM(A1)"), Text("\tTab"), Text("Smith"), Text(""), Number("-12.50"),
+	}))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "\"'=HYPERLINK(\"\"http://x\"\")\",'+1,'-2,'@SUM(A1),'\tTab,Smith,,-12.50\n", buf.String())
+}
+
+func TestWriteRegister_RequiresFinalizedRun(t *testing.T) {
+	svc, run := newFixture(payroll.RunStatusOpen)
+
+	err := svc.WriteRegister(ctx, run.ID, NewCSVWriter(io.Discard))
+	assert.Error(t, err)
+}
+
+func TestWriteRegister_XLSX(t *testing.T) {
+	svc, run := newFixture(payroll.RunStatusFinalized)
+	var buf bytes.Buffer
+	w, err := NewXLSXWriter(&buf, "Register")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.WriteRegister(ctx, run.ID, w))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	var sheet string
+	for _, f := range archive.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			sheet = string(content)
+		}
+	}
+	assert.Contains(t, sheet, `<c r="N6"><v>5290.50</v></c>`)
+	assert.Contains(t, sheet, `<c r="C2" t="inlineStr"><is><t xml:space="preserve">Smith</t></is></c>`)
+}
+
+func TestCostSummaries(t *testing.T) {
+	svc, _ := newFixture(payroll.RunStatusFinalized)
+
+	summaries, err := svc.CostSummaries(ctx, uuid.Nil, time.Time{}, time.Now())
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "WS1", summaries[0].WorkspaceCode)
+	assert.Equal(t, 2, summaries[0].Employees)
+	assert.EqualValues(t, 529050, summaries[0].EmployerCost())
+}
+
+func TestService_DoesNotLeakReportsAcrossTenants(t *testing.T) {
+	svc, run := newFixture(payroll.RunStatusFinalized)
+	other := testkit.AdminContext(uuid.New())
+
+	var buf bytes.Buffer
+	err := svc.WriteRegister(other, run.ID, NewCSVWriter(&buf))
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	assert.Empty(t, buf.String())
+
+	summaries, err := svc.CostSummaries(other, uuid.Nil, time.Time{}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, summaries)
+	_, err = svc.CostSummaries(other, tenantID, time.Time{}, time.Now())
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid), "another tenant cannot be claimed")
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+	assert.Equal(t, "AZ", columnName(51))
+}
+
+func TestSanitizeSheetName(t *testing.T) {
+	assert.Equal(t, "Q1_2026 _payroll_", sanitizeSheetName("Q1/2026 [payroll]"))
+	assert.Equal(t, "Sheet1", sanitizeSheetName("''"))
+
+	long := sanitizeSheetName(strings.Repeat("ü", 40))
+	assert.Equal(t, maxSheetNameLength, utf8.RuneCountInString(long))
+	assert.True(t, utf8.ValidString(long), "multi-byte characters are not cut in half")
+}
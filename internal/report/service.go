@@ -0,0 +1,50 @@
+package report
+
+import (
+	"context"
+	"payroll/internal/employee"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/logger"
+	"payroll/internal/workspace"
+
+	"github.com/google/uuid"
+)
+
+const serviceOrigin = "ReportService"
+
+type Service struct {
+	payrollRepo   payroll.Repository
+	employeeRepo  employee.Repository
+	workspaceRepo workspace.Repository
+	logger        logger.Logger
+}
+
+func NewService(pr payroll.Repository, er employee.Repository, wr workspace.Repository, l logger.Logger) *Service {
+	return &Service{
+		payrollRepo:   pr,
+		employeeRepo:  er,
+		workspaceRepo: wr,
+		logger:        l,
+	}
+}
+
+// employeesOf returns the employees of the results by ID, including those
+// soft-deleted since the run. Employees that no longer exist, or that are
+// not of the run's tenant, are missing.
+func (s *Service) employeesOf(ctx context.Context, run *payroll.Run, results []*payroll.Result) (map[uuid.UUID]*employee.Employee, error) {
+	ids := make([]uuid.UUID, len(results))
+	for i, result := range results {
+		ids[i] = result.EmployeeID
+	}
+	employees, err := s.employeeRepo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*employee.Employee, len(employees))
+	for _, emp := range employees {
+		if emp.TenantID == run.TenantID {
+			byID[emp.ID] = emp
+		}
+	}
+	return byID, nil
+}
@@ -0,0 +1,59 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// Cell is a single value of a report row. Numeric cells hold plain decimals
+// so spreadsheet formats can store them as numbers.
+type Cell struct {
+	Value   string
+	Numeric bool
+}
+
+func Text(value string) Cell {
+	return Cell{Value: value}
+}
+
+func Number(value string) Cell {
+	return Cell{Value: value, Numeric: true}
+}
+
+// TableWriter receives report rows one at a time so reports can be streamed
+// without holding the whole table in memory.
+type TableWriter interface {
+	WriteRow(cells []Cell) error
+	Close() error
+}
+
+// CSVWriter writes rows as CSV. Text cells that a spreadsheet would read as
+// a formula are prefixed with an apostrophe, so that names and codes cannot
+// inject formulas.
+type CSVWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *CSVWriter) WriteRow(cells []Cell) error {
+	c.record = c.record[:0]
+	for _, cell := range cells {
+		value := cell.Value
+		if !cell.Numeric && value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			value = "'" + value
+		}
+		c.record = append(c.record, value)
+	}
+	return c.w.Write(c.record)
+}
+
+// Close flushes buffered rows. It does not close the underlying writer.
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
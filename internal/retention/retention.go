@@ -0,0 +1,59 @@
+// Package retention permanently removes soft-deleted entities once they have
+// been kept for the configured retention period.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"payroll/internal/platform/logger"
+	"time"
+)
+
+// PurgeFunc permanently removes the entities soft-deleted before the given
+// time and returns how many were removed, e.g. employee.Service.Purge.
+type PurgeFunc func(ctx context.Context, before time.Time) (int, error)
+
+// Target names one kind of entity to purge.
+type Target struct {
+	Name  string
+	Purge PurgeFunc
+}
+
+type Job struct {
+	retention time.Duration
+	targets   []Target
+	logger    logger.Logger
+	now       func() time.Time
+}
+
+// NewJob returns a job that purges the targets in the given order. Order
+// matters when one entity refers to another: list dependants (employees)
+// before what they depend on (workspaces, countries).
+func NewJob(retention time.Duration, l logger.Logger, targets ...Target) *Job {
+	return &Job{
+		retention: retention,
+		targets:   targets,
+		logger:    l,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run purges every target once. ctx must act as a platform administrator,
+// since purging requires auth.PermDataPurge. A failing target does not stop
+// the others; their errors are returned together.
+func (j *Job) Run(ctx context.Context) error {
+	before := j.now().Add(-j.retention)
+
+	var errs []error
+	for _, target := range j.targets {
+		n, err := target.Purge(ctx, before)
+		if err != nil {
+			j.logger.Error(err, "Failed to purge deleted entities", "target", target.Name)
+			errs = append(errs, fmt.Errorf("purge %s: %w", target.Name, err))
+			continue
+		}
+		j.logger.Info("Purged deleted entities", "target", target.Name, "count", n, "deleted_before", before)
+	}
+	return errors.Join(errs...)
+}
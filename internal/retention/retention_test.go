@@ -0,0 +1,39 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"payroll/internal/platform/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob_PurgesEveryTargetWithCutoff(t *testing.T) {
+	now := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	var calls []string
+	var cutoffs []time.Time
+	purge := func(name string, err error) PurgeFunc {
+		return func(_ context.Context, before time.Time) (int, error) {
+			calls = append(calls, name)
+			cutoffs = append(cutoffs, before)
+			return 1, err
+		}
+	}
+
+	job := NewJob(30*24*time.Hour, logger.Nop{},
+		Target{Name: "employees", Purge: purge("employees", errors.New("boom"))},
+		Target{Name: "workspaces", Purge: purge("workspaces", nil)},
+	)
+	job.now = func() time.Time { return now }
+
+	err := job.Run(context.Background())
+
+	assert.ErrorContains(t, err, "purge employees: boom")
+	assert.Equal(t, []string{"employees", "workspaces"}, calls)
+	for _, cutoff := range cutoffs {
+		assert.Equal(t, time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), cutoff)
+	}
+}
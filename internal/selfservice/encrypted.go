@@ -0,0 +1,251 @@
+package selfservice
+
+import (
+	"context"
+	"payroll/internal/platform/fieldcrypt"
+
+	"github.com/google/uuid"
+)
+
+// SealedChangeRequest is a change request as EncryptedRepository hands it to
+// storage. Request.Changes, Request.Current and Request.ReviewNote are
+// cleared and held encrypted in the fields of the same name; a field of
+// Changes or Current is nil where the request's is, and ReviewNote is ""
+// when unset.
+type SealedChangeRequest struct {
+	Request    ChangeRequest
+	Changes    ProfileChanges
+	Current    ProfileChanges
+	ReviewNote string
+	// KeyID names the key-encryption key the fields are wrapped with.
+	KeyID string
+}
+
+// SealedRepository stores sealed change requests for EncryptedRepository,
+// with the semantics of Repository.
+type SealedRepository interface {
+	Create(ctx context.Context, r *SealedChangeRequest) error
+	Get(ctx context.Context, id uuid.UUID) (*SealedChangeRequest, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, r *SealedChangeRequest) error
+	ExistsPendingByEmployeeID(ctx context.Context, employeeID uuid.UUID) (bool, error)
+	ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*SealedChangeRequest, error)
+	List(ctx context.Context, filter Filter) ([]*SealedChangeRequest, error)
+	// ListNotSealedWith returns up to limit requests whose KeyID is not
+	// keyID.
+	ListNotSealedWith(ctx context.Context, keyID string, limit int) ([]*SealedChangeRequest, error)
+	// UpdateSealing stores the sealed fields and KeyID of r, unless the
+	// stored request's version is no longer r.Request.Version, and reports
+	// whether it did. Nothing else is changed.
+	UpdateSealing(ctx context.Context, r *SealedChangeRequest) (bool, error)
+}
+
+// EncryptedRepository is a Repository that encrypts the personal data of
+// change requests, the requested and current values and the review note,
+// with the Encrypter employee.EncryptedRepository uses.
+type EncryptedRepository struct {
+	store SealedRepository
+	crypt *fieldcrypt.Encrypter
+}
+
+var _ Repository = (*EncryptedRepository)(nil)
+
+func NewEncryptedRepository(store SealedRepository, e *fieldcrypt.Encrypter) *EncryptedRepository {
+	return &EncryptedRepository{store: store, crypt: e}
+}
+
+func (r *EncryptedRepository) Create(ctx context.Context, req *ChangeRequest) error {
+	sealed, err := r.seal(ctx, req)
+	if err != nil {
+		return err
+	}
+	return r.store.Create(ctx, sealed)
+}
+
+func (r *EncryptedRepository) Get(ctx context.Context, id uuid.UUID) (*ChangeRequest, error) {
+	sealed, err := r.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.open(ctx, sealed)
+}
+
+func (r *EncryptedRepository) Update(ctx context.Context, req *ChangeRequest) error {
+	sealed, err := r.seal(ctx, req)
+	if err != nil {
+		return err
+	}
+	if err := r.store.Update(ctx, sealed); err != nil {
+		return err
+	}
+	// The store may increment the version of the copy it was given.
+	req.Version = sealed.Request.Version
+	return nil
+}
+
+func (r *EncryptedRepository) ExistsPendingByEmployeeID(ctx context.Context, employeeID uuid.UUID) (bool, error) {
+	return r.store.ExistsPendingByEmployeeID(ctx, employeeID)
+}
+
+func (r *EncryptedRepository) ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*ChangeRequest, error) {
+	sealed, err := r.store.ListByEmployeeID(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, sealed)
+}
+
+func (r *EncryptedRepository) List(ctx context.Context, filter Filter) ([]*ChangeRequest, error) {
+	sealed, err := r.store.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, sealed)
+}
+
+// RotateKeys rewraps the fields of every request sealed with an older key
+// with the provider's current key, like employee.EncryptedRepository's
+// RotateKeys, and returns how many were rewrapped. Run both before retiring
+// a key.
+func (r *EncryptedRepository) RotateKeys(ctx context.Context, batchSize int) (int, error) {
+	rotated := 0
+	for {
+		keyID, err := r.crypt.CurrentKeyID(ctx)
+		if err != nil {
+			return rotated, err
+		}
+		batch, err := r.store.ListNotSealedWith(ctx, keyID, batchSize)
+		if err != nil || len(batch) == 0 {
+			return rotated, err
+		}
+		progress := false
+		for _, sealed := range batch {
+			if err := r.rewrap(ctx, sealed, keyID); err != nil {
+				return rotated, err
+			}
+			ok, err := r.store.UpdateSealing(ctx, sealed)
+			if err != nil {
+				return rotated, err
+			}
+			if ok {
+				rotated++
+				progress = true
+			}
+		}
+		if !progress {
+			return rotated, nil
+		}
+	}
+}
+
+// field is a sensitive field of a change request, with the name its
+// additional data uses.
+type field struct {
+	name  string
+	value **string
+}
+
+func fields(changes, current *ProfileChanges) []field {
+	return []field{
+		{"Changes.Phone", &changes.Phone},
+		{"Changes.Address", &changes.Address},
+		{"Changes.BankAccount", &changes.BankAccount},
+		{"Current.Phone", &current.Phone},
+		{"Current.Address", &current.Address},
+		{"Current.BankAccount", &current.BankAccount},
+	}
+}
+
+func (r *EncryptedRepository) rewrap(ctx context.Context, sealed *SealedChangeRequest, keyID string) error {
+	for _, f := range fields(&sealed.Changes, &sealed.Current) {
+		if *f.value == nil {
+			continue
+		}
+		rewrapped, err := r.crypt.Rewrap(ctx, **f.value)
+		if err != nil {
+			return err
+		}
+		*f.value = &rewrapped
+	}
+	if sealed.ReviewNote != "" {
+		rewrapped, err := r.crypt.Rewrap(ctx, sealed.ReviewNote)
+		if err != nil {
+			return err
+		}
+		sealed.ReviewNote = rewrapped
+	}
+	sealed.KeyID = keyID
+	return nil
+}
+
+func (r *EncryptedRepository) seal(ctx context.Context, req *ChangeRequest) (*SealedChangeRequest, error) {
+	sealed := &SealedChangeRequest{Request: *req}
+	sealed.Request.Changes = ProfileChanges{}
+	sealed.Request.Current = ProfileChanges{}
+	sealed.Request.ReviewNote = ""
+
+	var err error
+	if sealed.KeyID, err = r.crypt.CurrentKeyID(ctx); err != nil {
+		return nil, err
+	}
+	plain := fields(&req.Changes, &req.Current)
+	for i, f := range fields(&sealed.Changes, &sealed.Current) {
+		if *plain[i].value == nil {
+			continue
+		}
+		value, err := r.crypt.Seal(ctx, []byte(**plain[i].value), additionalData(req.ID, f.name))
+		if err != nil {
+			return nil, err
+		}
+		*f.value = &value
+	}
+	if req.ReviewNote != "" {
+		if sealed.ReviewNote, err = r.crypt.Seal(ctx, []byte(req.ReviewNote), additionalData(req.ID, "ReviewNote")); err != nil {
+			return nil, err
+		}
+	}
+	return sealed, nil
+}
+
+func (r *EncryptedRepository) open(ctx context.Context, sealed *SealedChangeRequest) (*ChangeRequest, error) {
+	req := sealed.Request
+	opened := fields(&req.Changes, &req.Current)
+	for i, f := range fields(&sealed.Changes, &sealed.Current) {
+		if *f.value == nil {
+			continue
+		}
+		plaintext, err := r.crypt.Open(ctx, **f.value, additionalData(req.ID, f.name))
+		if err != nil {
+			return nil, err
+		}
+		value := string(plaintext)
+		*opened[i].value = &value
+	}
+	if sealed.ReviewNote != "" {
+		plaintext, err := r.crypt.Open(ctx, sealed.ReviewNote, additionalData(req.ID, "ReviewNote"))
+		if err != nil {
+			return nil, err
+		}
+		req.ReviewNote = string(plaintext)
+	}
+	return &req, nil
+}
+
+func (r *EncryptedRepository) openAll(ctx context.Context, sealed []*SealedChangeRequest) ([]*ChangeRequest, error) {
+	requests := make([]*ChangeRequest, 0, len(sealed))
+	for _, s := range sealed {
+		req, err := r.open(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// additionalData binds a sealed value to its request and field, so that it
+// cannot be copied to another.
+func additionalData(id uuid.UUID, field string) []byte {
+	return []byte(id.String() + "/" + field)
+}
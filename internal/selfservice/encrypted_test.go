@@ -0,0 +1,181 @@
+package selfservice
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"payroll/internal/apperror"
+	"payroll/internal/platform/fieldcrypt"
+	"payroll/internal/platform/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySealedRepo keeps sealed requests in the order they were created.
+type memorySealedRepo struct {
+	sealed []SealedChangeRequest
+}
+
+func (r *memorySealedRepo) Create(_ context.Context, s *SealedChangeRequest) error {
+	r.sealed = append(r.sealed, *s)
+	return nil
+}
+
+func (r *memorySealedRepo) Get(_ context.Context, id uuid.UUID) (*SealedChangeRequest, error) {
+	for _, s := range r.sealed {
+		if s.Request.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "request not found")
+}
+
+func (r *memorySealedRepo) Update(_ context.Context, s *SealedChangeRequest) error {
+	for i := range r.sealed {
+		if r.sealed[i].Request.ID == s.Request.ID {
+			r.sealed[i] = *s
+			return nil
+		}
+	}
+	return apperror.New(apperror.TypeNotFound, "test", "request not found")
+}
+
+func (r *memorySealedRepo) ExistsPendingByEmployeeID(_ context.Context, employeeID uuid.UUID) (bool, error) {
+	for _, s := range r.sealed {
+		if s.Request.EmployeeID == employeeID && s.Request.IsPending() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memorySealedRepo) ListByEmployeeID(_ context.Context, employeeID uuid.UUID) ([]*SealedChangeRequest, error) {
+	var out []*SealedChangeRequest
+	for i := len(r.sealed) - 1; i >= 0; i-- {
+		if s := r.sealed[i]; s.Request.EmployeeID == employeeID {
+			out = append(out, &s)
+		}
+	}
+	return out, nil
+}
+
+func (r *memorySealedRepo) List(_ context.Context, filter Filter) ([]*SealedChangeRequest, error) {
+	var out []*SealedChangeRequest
+	for _, s := range r.sealed {
+		if s.Request.TenantID == filter.TenantID && (filter.Status == nil || s.Request.Status == *filter.Status) {
+			out = append(out, &s)
+		}
+	}
+	return out, nil
+}
+
+func (r *memorySealedRepo) ListNotSealedWith(_ context.Context, keyID string, limit int) ([]*SealedChangeRequest, error) {
+	var out []*SealedChangeRequest
+	for _, s := range r.sealed {
+		if s.KeyID != keyID && len(out) < limit {
+			out = append(out, &s)
+		}
+	}
+	return out, nil
+}
+
+func (r *memorySealedRepo) UpdateSealing(_ context.Context, s *SealedChangeRequest) (bool, error) {
+	for i := range r.sealed {
+		stored := &r.sealed[i]
+		if stored.Request.ID != s.Request.ID {
+			continue
+		}
+		if stored.Request.Version != s.Request.Version {
+			return false, nil
+		}
+		stored.Changes, stored.Current, stored.ReviewNote, stored.KeyID = s.Changes, s.Current, s.ReviewNote, s.KeyID
+		return true, nil
+	}
+	return false, nil
+}
+
+func testKeys(t *testing.T, current string, ids ...string) *fieldcrypt.Encrypter {
+	var keys []fieldcrypt.Key
+	for _, id := range ids {
+		keys = append(keys, fieldcrypt.Key{ID: id, Secret: bytes.Repeat([]byte(id[len(id)-1:]), fieldcrypt.KeySize)})
+	}
+	p, err := fieldcrypt.NewLocalKeyProvider(current, keys, bytes.Repeat([]byte{9}, fieldcrypt.KeySize))
+	require.NoError(t, err)
+	return fieldcrypt.New(p)
+}
+
+// sealedValues returns every sealed field of s.
+func sealedValues(s SealedChangeRequest) []string {
+	values := []string{s.ReviewNote}
+	for _, f := range fields(&s.Changes, &s.Current) {
+		if *f.value != nil {
+			values = append(values, **f.value)
+		}
+	}
+	return values
+}
+
+func TestEncryptedRepository_StoresRequestsEncrypted(t *testing.T) {
+	f := newFixture()
+	store := &memorySealedRepo{}
+	f.service = NewService(NewEncryptedRepository(store, testKeys(t, "k1", "k1")), f.employees, stubPayslips{}, nil, activeTenants{}, logger.Nop{})
+
+	r, err := f.service.RequestChange(f.self, RequestChangeParams{ProfileChanges{Phone: strPtr(""), BankAccount: strPtr("DE89 3704 0044")}})
+	require.NoError(t, err)
+	_, err = f.service.Approve(f.manager, r.ID, ReviewParams{Note: "verified by phone on 555-0100"})
+	require.NoError(t, err)
+
+	require.Len(t, store.sealed, 1)
+	stored := store.sealed[0]
+	assert.True(t, stored.Request.Changes.IsEmpty())
+	assert.True(t, stored.Request.Current.IsEmpty())
+	assert.Empty(t, stored.Request.ReviewNote)
+	assert.Nil(t, stored.Changes.Address, "fields left unchanged stay unset")
+	for _, sealed := range sealedValues(stored) {
+		assert.NotEmpty(t, sealed)
+		assert.NotContains(t, sealed, "555-0100")
+		assert.NotContains(t, sealed, "DE89")
+	}
+
+	mine, err := f.service.ListMyRequests(f.self)
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	got := mine[0]
+	assert.Equal(t, RequestStatusApproved, got.Status)
+	assert.Equal(t, "", *got.Changes.Phone, "an empty change still clears the field")
+	assert.Equal(t, "DE89 3704 0044", *got.Changes.BankAccount)
+	assert.Equal(t, "555-0100", *got.Current.Phone)
+	assert.Nil(t, got.Changes.Address)
+	assert.Equal(t, "verified by phone on 555-0100", got.ReviewNote)
+}
+
+func TestEncryptedRepository_RotateKeysRewrapsOldValues(t *testing.T) {
+	ctx := context.Background()
+	store := &memorySealedRepo{}
+	f := newFixture()
+	r, err := NewChangeRequest(f.ada, "ada", RequestChangeParams{ProfileChanges{Address: strPtr("1 Main St")}})
+	require.NoError(t, err)
+	require.NoError(t, NewEncryptedRepository(store, testKeys(t, "k1", "k1")).Create(ctx, r))
+
+	repo := NewEncryptedRepository(store, testKeys(t, "k2", "k1", "k2"))
+	n, err := repo.RotateKeys(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "k2", store.sealed[0].KeyID)
+	for _, sealed := range sealedValues(store.sealed[0]) {
+		if sealed == "" {
+			continue
+		}
+		keyID, err := fieldcrypt.KeyID(sealed)
+		require.NoError(t, err)
+		assert.Equal(t, "k2", keyID)
+	}
+
+	got, err := NewEncryptedRepository(store, testKeys(t, "k2", "k2")).Get(ctx, r.ID)
+	require.NoError(t, err, "the retired key is no longer needed")
+	assert.Equal(t, "1 Main St", *got.Changes.Address)
+	assert.Equal(t, "12 St James's Square", *got.Current.Address)
+}
@@ -0,0 +1,183 @@
+// Package selfservice is what employees use about themselves: their
+// profile, payslips, year-to-date totals and leave balances, and requests to
+// change their personal data.
+//
+// The employee is always the one the caller's principal is mapped to
+// (auth.Principal.EmployeeID), never one named in the request. Changes are
+// not applied directly: they wait in an approval queue until someone who
+// may write the employee approves them.
+package selfservice
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/employee"
+	"payroll/internal/platform/pagination"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const modelOrigin = "ChangeRequest"
+
+type RequestStatus string
+
+const (
+	RequestStatusPending   RequestStatus = "PENDING"
+	RequestStatusApproved  RequestStatus = "APPROVED"
+	RequestStatusRejected  RequestStatus = "REJECTED"
+	RequestStatusCancelled RequestStatus = "CANCELLED"
+)
+
+func (s RequestStatus) IsValid() bool {
+	switch s {
+	case RequestStatusPending, RequestStatusApproved, RequestStatusRejected, RequestStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ProfileChanges are the personal data employees may change. A nil field is
+// left as it is; an empty string clears it (except Address).
+type ProfileChanges struct {
+	Phone       *string
+	Address     *string
+	BankAccount *string
+}
+
+func (c ProfileChanges) IsEmpty() bool {
+	return c.Phone == nil && c.Address == nil && c.BankAccount == nil
+}
+
+// ChangeRequest is an employee's request to change their personal data.
+type ChangeRequest struct {
+	domain.BaseEntity
+	TenantID    uuid.UUID
+	WorkspaceID uuid.UUID
+	EmployeeID  uuid.UUID
+	Changes     ProfileChanges
+	// Current holds the values the changes replace, as they were when the
+	// request was made, for the approver to compare.
+	Current     ProfileChanges
+	Status      RequestStatus
+	RequestedBy string
+	ReviewedBy  string
+	ReviewedAt  *time.Time
+	ReviewNote  string
+}
+
+type RequestChangeParams struct {
+	ProfileChanges
+}
+
+type ReviewParams struct {
+	Note string
+	// Version, when set, must equal the request's current version or the
+	// review fails with a conflict.
+	Version *int64
+}
+
+func NewChangeRequest(emp *employee.Employee, requestedBy string, params RequestChangeParams) (*ChangeRequest, error) {
+	changes := params.ProfileChanges
+	for _, field := range []*string{changes.Phone, changes.Address, changes.BankAccount} {
+		if field != nil {
+			*field = strings.TrimSpace(*field)
+		}
+	}
+
+	validator := NewValidator()
+	validator.ValidateChanges(changes)
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	r := &ChangeRequest{
+		TenantID:    emp.TenantID,
+		WorkspaceID: emp.WorkspaceID,
+		EmployeeID:  emp.ID,
+		Changes:     changes,
+		Status:      RequestStatusPending,
+		RequestedBy: requestedBy,
+	}
+	if changes.Phone != nil {
+		r.Current.Phone = valueOf(emp.Phone)
+	}
+	if changes.Address != nil {
+		r.Current.Address = &emp.Address
+	}
+	if changes.BankAccount != nil {
+		r.Current.BankAccount = valueOf(emp.BankAccount)
+	}
+	r.Initialize()
+
+	return r, nil
+}
+
+// valueOf returns a pointer to a copy of *s, or to "" if s is nil.
+func valueOf(s *string) *string {
+	v := ""
+	if s != nil {
+		v = *s
+	}
+	return &v
+}
+
+func (r *ChangeRequest) IsPending() bool {
+	return r.Status == RequestStatusPending
+}
+
+// Erase removes the personal data from the request, for the employee's
+// erasure request, and cancels it if it is still pending.
+func (r *ChangeRequest) Erase() {
+	r.Changes = ProfileChanges{}
+	r.Current = ProfileChanges{}
+	r.ReviewNote = ""
+	if r.IsPending() {
+		r.Status = RequestStatusCancelled
+	}
+	r.Touch()
+}
+
+// LeaveBalance is how much of one type of leave an employee has.
+type LeaveBalance struct {
+	Type string
+	// Unit is what the amounts are counted in, e.g. "DAYS" or "HOURS".
+	Unit      string
+	Entitled  float64
+	Taken     float64
+	Available float64
+	AsOf      time.Time
+}
+
+// LeaveBalances reports employees' leave balances. Leave is not tracked by
+// payroll, so balances come from the system that does, e.g. the HR system.
+type LeaveBalances interface {
+	ListByEmployeeID(ctx context.Context, tenantID, employeeID uuid.UUID) ([]*LeaveBalance, error)
+}
+
+type Filter struct {
+	TenantID uuid.UUID
+	Status   *RequestStatus
+	// WorkspaceIDs, when not nil, restricts the results to these workspaces.
+	WorkspaceIDs []uuid.UUID
+	pagination.Query
+}
+
+// Repository persists change requests. An employee has at most one pending
+// request: the service checks before creating one, and implementations
+// backed by a shared store should also enforce it (e.g. with a partial
+// unique index) and report a violation as an apperror TypeConflict.
+type Repository interface {
+	Create(ctx context.Context, r *ChangeRequest) error
+	Get(ctx context.Context, id uuid.UUID) (*ChangeRequest, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, r *ChangeRequest) error
+	ExistsPendingByEmployeeID(ctx context.Context, employeeID uuid.UUID) (bool, error)
+	// ListByEmployeeID returns the employee's requests, newest first.
+	ListByEmployeeID(ctx context.Context, employeeID uuid.UUID) ([]*ChangeRequest, error)
+	// List returns requests oldest first, the order they should be reviewed in.
+	List(ctx context.Context, filter Filter) ([]*ChangeRequest, error)
+}
@@ -0,0 +1,318 @@
+package selfservice
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/tenant"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const serviceOrigin = "SelfService"
+
+// Employees reads and updates employees, e.g. employee.Service.
+type Employees interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*employee.Employee, error)
+	Update(ctx context.Context, id uuid.UUID, params employee.UpdateEmployeeParams) (*employee.Employee, error)
+}
+
+// Payslips reads finalized payroll results, e.g. payroll.Service.
+type Payslips interface {
+	ListPayslips(ctx context.Context, employeeID uuid.UUID, from, to time.Time) ([]*payroll.Payslip, error)
+	YearToDate(ctx context.Context, employeeID uuid.UUID, asOf time.Time) ([]*payroll.YearToDate, error)
+}
+
+// TenantPolicy enforces the tenant's status.
+type TenantPolicy interface {
+	// CheckActive fails if the tenant is suspended.
+	CheckActive(ctx context.Context, tenantID uuid.UUID) error
+}
+
+type Service struct {
+	repo      Repository
+	employees Employees
+	payslips  Payslips
+	leave     LeaveBalances
+	tenants   TenantPolicy
+	logger    logger.Logger
+	now       func() time.Time
+}
+
+func NewService(r Repository, e Employees, p Payslips, lb LeaveBalances, tp TenantPolicy, l logger.Logger) *Service {
+	return &Service{repo: r, employees: e, payslips: p, leave: lb, tenants: tp, logger: l, now: time.Now}
+}
+
+// self returns the principal of ctx, which must be mapped to an employee.
+func self(ctx context.Context) (*auth.Principal, error) {
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.EmployeeID == uuid.Nil {
+		return nil, apperror.New(apperror.TypeForbidden, serviceOrigin, "Caller is not mapped to an employee")
+	}
+	return p, nil
+}
+
+// GetProfile returns the caller's own employee record.
+func (s *Service) GetProfile(ctx context.Context) (*employee.Employee, error) {
+	p, err := self(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.employees.GetByID(ctx, p.EmployeeID)
+}
+
+// ListPayslips returns the caller's payslips of the given calendar year.
+func (s *Service) ListPayslips(ctx context.Context, year int) ([]*payroll.Payslip, error) {
+	p, err := self(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0).Add(-time.Nanosecond)
+	return s.payslips.ListPayslips(ctx, p.EmployeeID, from, to)
+}
+
+// YearToDate returns the caller's totals of the current year, one per pay
+// currency.
+func (s *Service) YearToDate(ctx context.Context) ([]*payroll.YearToDate, error) {
+	p, err := self(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.payslips.YearToDate(ctx, p.EmployeeID, s.now())
+}
+
+func (s *Service) LeaveBalances(ctx context.Context) ([]*LeaveBalance, error) {
+	emp, err := s.GetProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.leave.ListByEmployeeID(ctx, emp.TenantID, emp.ID)
+}
+
+// RequestChange queues a change to the caller's personal data for approval.
+// An employee can have one pending request at a time.
+func (s *Service) RequestChange(ctx context.Context, params RequestChangeParams) (*ChangeRequest, error) {
+	emp, err := s.GetProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, _ := auth.FromContext(ctx)
+	if err := auth.Authorize(ctx, auth.PermProfileRequest, resource(emp.WorkspaceID, emp.ID), serviceOrigin); err != nil {
+		return nil, err
+	}
+	if err := s.tenants.CheckActive(ctx, emp.TenantID); err != nil {
+		return nil, err
+	}
+
+	r, err := NewChangeRequest(emp, p.Subject, params)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.repo.ExistsPendingByEmployeeID(ctx, emp.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, apperror.New(apperror.TypeConflict, serviceOrigin, "A change request is already pending")
+	}
+
+	if err := s.repo.Create(ctx, r); err != nil {
+		s.logger.Error(err, "Failed to save change request", "employee_id", emp.ID)
+		return nil, err
+	}
+	s.logger.Info("Change request submitted", "request_id", r.ID, "employee_id", emp.ID)
+	return r, nil
+}
+
+// ListMyRequests returns the caller's change requests, newest first.
+func (s *Service) ListMyRequests(ctx context.Context) ([]*ChangeRequest, error) {
+	p, err := self(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListByEmployeeID(ctx, p.EmployeeID)
+}
+
+// CancelRequest withdraws one of the caller's pending requests.
+func (s *Service) CancelRequest(ctx context.Context, id uuid.UUID) (*ChangeRequest, error) {
+	p, err := self(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.EmployeeID != p.EmployeeID {
+		return nil, apperror.New(apperror.TypeNotFound, serviceOrigin, "Change request not found")
+	}
+	if !r.IsPending() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Change request is not pending")
+	}
+
+	r.Status = RequestStatusCancelled
+	r.Touch()
+	if err := s.repo.Update(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type ListRequestsParams struct {
+	// Status defaults to PENDING.
+	Status *RequestStatus
+	pagination.Params
+}
+
+// ListRequests is the approval queue: the requests of the employees the
+// caller may write, oldest first.
+func (s *Service) ListRequests(ctx context.Context, params ListRequestsParams) (*pagination.Page[*ChangeRequest], error) {
+	tenantID, err := tenant.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if params.Status == nil {
+		pending := RequestStatusPending
+		params.Status = &pending
+	}
+
+	validator := NewValidator()
+	if !params.Status.IsValid() {
+		validator.AddError("Status", "is invalid")
+	}
+	validator.ValidatePage(params.Params)
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	query := params.Params.Query()
+	filter := Filter{TenantID: tenantID, Status: params.Status, Query: query}
+	if ids, all := p.Workspaces(auth.PermEmployeeWrite); !all {
+		if len(ids) == 0 {
+			return nil, apperror.New(apperror.TypeForbidden, serviceOrigin, "Not allowed to review change requests")
+		}
+		filter.WorkspaceIDs = ids
+	}
+
+	requests, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return pagination.NewPage(requests, query, func(r *ChangeRequest) string {
+		return pagination.EncodeCursor(r.ID)
+	}), nil
+}
+
+// Approve applies a pending request to the employee through
+// employee.Service.Update, so the change is validated, audited and
+// published like any other, and marks the request approved.
+func (s *Service) Approve(ctx context.Context, id uuid.UUID, params ReviewParams) (*ChangeRequest, error) {
+	r, p, err := s.review(ctx, id, params, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The update runs in its own unit of work: the employee service takes
+	// a tenant lock that must not be acquired inside one. If saving the
+	// request fails afterwards, approving it again reapplies the same
+	// values.
+	_, err = s.employees.Update(ctx, r.EmployeeID, employee.UpdateEmployeeParams{
+		Phone:       r.Changes.Phone,
+		Address:     r.Changes.Address,
+		BankAccount: r.Changes.BankAccount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.finishReview(ctx, r, p, RequestStatusApproved, params.Note)
+}
+
+// Reject closes a pending request without applying it. A note telling the
+// employee why is required.
+func (s *Service) Reject(ctx context.Context, id uuid.UUID, params ReviewParams) (*ChangeRequest, error) {
+	r, p, err := s.review(ctx, id, params, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.finishReview(ctx, r, p, RequestStatusRejected, params.Note)
+}
+
+// review loads a pending request the caller may review.
+func (s *Service) review(ctx context.Context, id uuid.UUID, params ReviewParams, noteRequired bool) (*ChangeRequest, *auth.Principal, error) {
+	params.Note = strings.TrimSpace(params.Note)
+	validator := NewValidator()
+	validator.ValidateNote(params.Note, noteRequired)
+	if validator.HasErrors() {
+		return nil, nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	r, err := s.get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermEmployeeWrite, resource(r.WorkspaceID, r.EmployeeID), serviceOrigin); err != nil {
+		return nil, nil, err
+	}
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.EmployeeID == r.EmployeeID || p.Subject == r.RequestedBy {
+		return nil, nil, apperror.New(apperror.TypeForbidden, serviceOrigin, "Cannot review your own change request")
+	}
+	if !r.MatchesVersion(params.Version) {
+		return nil, nil, apperror.NewConflictError(serviceOrigin, "Change request was modified since it was read")
+	}
+	if !r.IsPending() {
+		return nil, nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Change request is not pending")
+	}
+	return r, p, nil
+}
+
+func (s *Service) finishReview(ctx context.Context, r *ChangeRequest, p *auth.Principal, status RequestStatus, note string) (*ChangeRequest, error) {
+	now := s.now().UTC()
+	r.Status = status
+	r.ReviewedBy = p.Subject
+	r.ReviewedAt = &now
+	r.ReviewNote = strings.TrimSpace(note)
+	r.Touch()
+
+	if err := s.repo.Update(ctx, r); err != nil {
+		s.logger.Error(err, "Failed to save reviewed change request", "request_id", r.ID)
+		return nil, err
+	}
+	s.logger.Info("Change request reviewed", "request_id", r.ID, "status", status, "reviewed_by", p.Subject)
+	return r, nil
+}
+
+// get returns the request unless it belongs to another tenant.
+func (s *Service) get(ctx context.Context, id uuid.UUID) (*ChangeRequest, error) {
+	r, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, r.TenantID, serviceOrigin, "Change request not found"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func resource(workspaceID, employeeID uuid.UUID) auth.Resource {
+	return auth.Resource{WorkspaceID: workspaceID, EmployeeID: employeeID}
+}
@@ -0,0 +1,274 @@
+package selfservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRepo struct {
+	requests []*ChangeRequest
+}
+
+func (r *memoryRepo) Create(_ context.Context, req *ChangeRequest) error {
+	r.requests = append(r.requests, req)
+	return nil
+}
+
+func (r *memoryRepo) Get(_ context.Context, id uuid.UUID) (*ChangeRequest, error) {
+	for _, req := range r.requests {
+		if req.ID == id {
+			return req, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "request not found")
+}
+
+func (r *memoryRepo) Update(context.Context, *ChangeRequest) error {
+	return nil
+}
+
+func (r *memoryRepo) ExistsPendingByEmployeeID(_ context.Context, employeeID uuid.UUID) (bool, error) {
+	for _, req := range r.requests {
+		if req.EmployeeID == employeeID && req.IsPending() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepo) ListByEmployeeID(_ context.Context, employeeID uuid.UUID) ([]*ChangeRequest, error) {
+	var out []*ChangeRequest
+	for i := len(r.requests) - 1; i >= 0; i-- {
+		if r.requests[i].EmployeeID == employeeID {
+			out = append(out, r.requests[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryRepo) List(_ context.Context, filter Filter) ([]*ChangeRequest, error) {
+	var out []*ChangeRequest
+	for _, req := range r.requests {
+		if req.TenantID != filter.TenantID || (filter.Status != nil && req.Status != *filter.Status) {
+			continue
+		}
+		if filter.WorkspaceIDs != nil && !containsID(filter.WorkspaceIDs, req.WorkspaceID) {
+			continue
+		}
+		out = append(out, req)
+	}
+	return out, nil
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+// stubEmployees stands in for employee.Service, including its check that
+// the caller may read or write the employee.
+type stubEmployees struct {
+	employees map[uuid.UUID]*employee.Employee
+	updates   []employee.UpdateEmployeeParams
+}
+
+func (s *stubEmployees) GetByID(ctx context.Context, id uuid.UUID) (*employee.Employee, error) {
+	emp, ok := s.employees[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "employee not found")
+	}
+	return emp, auth.Authorize(ctx, auth.PermEmployeeRead, resource(emp.WorkspaceID, emp.ID), "test")
+}
+
+func (s *stubEmployees) Update(ctx context.Context, id uuid.UUID, params employee.UpdateEmployeeParams) (*employee.Employee, error) {
+	emp := s.employees[id]
+	if err := auth.Authorize(ctx, auth.PermEmployeeWrite, resource(emp.WorkspaceID, emp.ID), "test"); err != nil {
+		return nil, err
+	}
+	s.updates = append(s.updates, params)
+	return emp, nil
+}
+
+type stubPayslips struct {
+	Payslips
+}
+
+type activeTenants struct{}
+
+func (activeTenants) CheckActive(context.Context, uuid.UUID) error { return nil }
+
+type fixture struct {
+	service   *Service
+	employees *stubEmployees
+	ada       *employee.Employee
+	self      context.Context
+	manager   context.Context
+}
+
+func newFixture() *fixture {
+	phone := "555-0100"
+	ada := &employee.Employee{TenantID: uuid.New(), WorkspaceID: uuid.New(), FirstName: "Ada", Address: "12 St James's Square", Phone: &phone}
+	ada.Initialize()
+	employees := &stubEmployees{employees: map[uuid.UUID]*employee.Employee{ada.ID: ada}}
+	return &fixture{
+		service:   NewService(&memoryRepo{}, employees, stubPayslips{}, nil, activeTenants{}, logger.Nop{}),
+		employees: employees,
+		ada:       ada,
+		self: auth.WithPrincipal(context.Background(), &auth.Principal{
+			Subject: "ada", TenantID: ada.TenantID, Roles: []auth.Role{auth.RoleSelfService}, EmployeeID: ada.ID,
+		}),
+		manager: auth.WithPrincipal(context.Background(), &auth.Principal{
+			Subject: "bob", TenantID: ada.TenantID, Roles: []auth.Role{auth.RolePayrollManager}, WorkspaceIDs: []uuid.UUID{ada.WorkspaceID},
+		}),
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestRequestChange_WaitsForApproval(t *testing.T) {
+	f := newFixture()
+
+	r, err := f.service.RequestChange(f.self, RequestChangeParams{ProfileChanges{Phone: strPtr(" 555-0199 "), BankAccount: strPtr("DE89 3704 0044")}})
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusPending, r.Status)
+	assert.Equal(t, "555-0199", *r.Changes.Phone)
+	assert.Equal(t, "555-0100", *r.Current.Phone)
+	assert.Equal(t, "", *r.Current.BankAccount)
+	assert.Nil(t, r.Changes.Address)
+	assert.Empty(t, f.employees.updates, "nothing is applied before approval")
+
+	_, err = f.service.RequestChange(f.self, RequestChangeParams{ProfileChanges{Address: strPtr("1 Main St")}})
+	assert.True(t, apperror.IsType(err, apperror.TypeConflict))
+
+	_, err = f.service.Approve(f.self, r.ID, ReviewParams{})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden), "employees cannot approve their own requests")
+
+	queue, err := f.service.ListRequests(f.manager, ListRequestsParams{})
+	require.NoError(t, err)
+	assert.Equal(t, []*ChangeRequest{r}, queue.Items)
+
+	other := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "eve", TenantID: f.ada.TenantID, Roles: []auth.Role{auth.RolePayrollManager}, WorkspaceIDs: []uuid.UUID{uuid.New()},
+	})
+	queue, err = f.service.ListRequests(other, ListRequestsParams{})
+	require.NoError(t, err)
+	assert.Empty(t, queue.Items)
+	_, err = f.service.Approve(other, r.ID, ReviewParams{})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	r, err = f.service.Approve(f.manager, r.ID, ReviewParams{Note: "verified by phone"})
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusApproved, r.Status)
+	assert.Equal(t, "bob", r.ReviewedBy)
+	require.Len(t, f.employees.updates, 1)
+	assert.Equal(t, "DE89 3704 0044", *f.employees.updates[0].BankAccount)
+	assert.Nil(t, f.employees.updates[0].Address)
+
+	_, err = f.service.Approve(f.manager, r.ID, ReviewParams{})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+}
+
+func TestReview_RejectsTheRequesterUnderAnotherRole(t *testing.T) {
+	f := newFixture()
+	r, err := f.service.RequestChange(f.self, RequestChangeParams{ProfileChanges{BankAccount: strPtr("DE89 3704 0044")}})
+	require.NoError(t, err)
+
+	// The requester signs in as a payroll manager, without an employee identity.
+	asManager := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "ada", TenantID: f.ada.TenantID, Roles: []auth.Role{auth.RolePayrollManager}, WorkspaceIDs: []uuid.UUID{f.ada.WorkspaceID},
+	})
+	_, err = f.service.Approve(asManager, r.ID, ReviewParams{})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = f.service.Reject(asManager, r.ID, ReviewParams{Note: "never mind"})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	assert.Empty(t, f.employees.updates)
+}
+
+func TestReject_RequiresNoteAndLeavesEmployeeUnchanged(t *testing.T) {
+	f := newFixture()
+	r, err := f.service.RequestChange(f.self, RequestChangeParams{ProfileChanges{Address: strPtr("1 Main St")}})
+	require.NoError(t, err)
+
+	_, err = f.service.Reject(f.manager, r.ID, ReviewParams{Note: "  "})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+
+	r, err = f.service.Reject(f.manager, r.ID, ReviewParams{Note: "Please attach proof of address"})
+	require.NoError(t, err)
+	assert.Equal(t, RequestStatusRejected, r.Status)
+	assert.Empty(t, f.employees.updates)
+
+	mine, err := f.service.ListMyRequests(f.self)
+	require.NoError(t, err)
+	assert.Equal(t, []*ChangeRequest{r}, mine)
+}
+
+func TestRequestChange_Validates(t *testing.T) {
+	f := newFixture()
+
+	_, err := f.service.RequestChange(f.self, RequestChangeParams{})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+
+	_, err = f.service.RequestChange(f.self, RequestChangeParams{ProfileChanges{Phone: strPtr("0123456789 0123456789 0123456789")}})
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Contains(t, domainErr.Details, "Phone")
+}
+
+func TestSelfService_RequiresEmployeeIdentity(t *testing.T) {
+	f := newFixture()
+
+	_, err := f.service.GetProfile(f.manager)
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = f.service.RequestChange(f.manager, RequestChangeParams{ProfileChanges{Address: strPtr("1 Main St")}})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	emp, err := f.service.GetProfile(f.self)
+	require.NoError(t, err)
+	assert.Equal(t, f.ada.ID, emp.ID)
+}
+
+var (
+	_ Employees = (*employee.Service)(nil)
+	_ Payslips  = (*payroll.Service)(nil)
+)
+
+func TestListPayslips_CoversTheCalendarYear(t *testing.T) {
+	f := newFixture()
+	var gotFrom, gotTo time.Time
+	f.service.payslips = payslipsFunc(func(_ context.Context, employeeID uuid.UUID, from, to time.Time) ([]*payroll.Payslip, error) {
+		assert.Equal(t, f.ada.ID, employeeID)
+		gotFrom, gotTo = from, to
+		return nil, nil
+	})
+
+	_, err := f.service.ListPayslips(f.self, 2026)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), gotFrom)
+	assert.Equal(t, 2026, gotTo.Year())
+	assert.Equal(t, 2027, gotTo.Add(time.Nanosecond).Year())
+}
+
+type payslipsFunc func(ctx context.Context, employeeID uuid.UUID, from, to time.Time) ([]*payroll.Payslip, error)
+
+func (f payslipsFunc) ListPayslips(ctx context.Context, employeeID uuid.UUID, from, to time.Time) ([]*payroll.Payslip, error) {
+	return f(ctx, employeeID, from, to)
+}
+
+func (f payslipsFunc) YearToDate(context.Context, uuid.UUID, time.Time) ([]*payroll.YearToDate, error) {
+	return nil, nil
+}
@@ -0,0 +1,51 @@
+package selfservice
+
+import (
+	"fmt"
+	"payroll/internal/employee"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/validation"
+)
+
+const maxNoteLength = 500
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+// ValidateChanges applies the employee's own rules to the changed fields.
+func (v *Validator) ValidateChanges(changes ProfileChanges) {
+	if changes.IsEmpty() {
+		v.AddError("Changes", "is empty")
+		return
+	}
+	if changes.Address != nil && *changes.Address == "" {
+		v.AddError("Address", "is empty")
+	}
+
+	ev := employee.NewValidator()
+	ev.ValidatePhone(changes.Phone)
+	ev.ValidateAddress(changes.Address)
+	ev.ValidateBankAccount(changes.BankAccount)
+	for field, msg := range ev.Errors() {
+		v.AddError(field, msg)
+	}
+}
+
+func (v *Validator) ValidateNote(note string, required bool) {
+	if required && note == "" {
+		v.AddError("Note", "is empty")
+	} else if len(note) > maxNoteLength {
+		v.AddError("Note", fmt.Sprintf("must be less than %d characters", maxNoteLength))
+	}
+}
+
+func (v *Validator) ValidatePage(params pagination.Params) {
+	for field, msg := range params.Validate() {
+		v.AddError(field, msg)
+	}
+}
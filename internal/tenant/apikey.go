@@ -0,0 +1,184 @@
+package tenant
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/platform/auth"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey lets an integration call the API on behalf of a tenant. Only the
+// key's hash is stored; the key itself is returned once, on creation.
+type APIKey struct {
+	domain.BaseEntity
+	TenantID uuid.UUID
+	Name     string
+	// Hint is the start of the key, to help owners tell their keys apart.
+	Hint         string
+	Hash         string
+	Roles        []auth.Role
+	WorkspaceIDs []uuid.UUID
+	Scopes       []auth.Permission
+	ExpiresAt    *time.Time
+	RevokedAt    *time.Time
+}
+
+type CreateAPIKeyParams struct {
+	Name         string
+	Roles        []auth.Role
+	WorkspaceIDs []uuid.UUID
+	Scopes       []auth.Permission
+	ExpiresAt    *time.Time
+}
+
+const apiKeyHintLength = 10
+
+func NewAPIKey(tenantID uuid.UUID, key string, params CreateAPIKeyParams) (*APIKey, error) {
+	validator := NewValidator()
+	validator.ValidateName(params.Name)
+	validator.ValidateRoles(params.Roles, params.WorkspaceIDs)
+	validator.ValidateScopes(params.Scopes)
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		validator.AddError("ExpiresAt", "must be in the future")
+	}
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	k := &APIKey{
+		TenantID:     tenantID,
+		Name:         params.Name,
+		Hint:         key[:apiKeyHintLength],
+		Hash:         auth.HashAPIKey(key),
+		Roles:        params.Roles,
+		WorkspaceIDs: params.WorkspaceIDs,
+		Scopes:       params.Scopes,
+		ExpiresAt:    params.ExpiresAt,
+	}
+	k.Initialize()
+
+	return k, nil
+}
+
+// IsValid reports whether the key can still be used at the given time.
+func (k *APIKey) IsValid(at time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || at.Before(*k.ExpiresAt))
+}
+
+// Principal returns the caller the key authenticates as.
+func (k *APIKey) Principal() *auth.Principal {
+	return &auth.Principal{
+		Subject:      "api-key:" + k.ID.String(),
+		TenantID:     k.TenantID,
+		Roles:        k.Roles,
+		WorkspaceIDs: k.WorkspaceIDs,
+		Scopes:       k.Scopes,
+	}
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, k *APIKey) error
+	Get(ctx context.Context, id uuid.UUID) (*APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*APIKey, error)
+	ListByTenantID(ctx context.Context, tenantID uuid.UUID) ([]*APIKey, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, k *APIKey) error
+}
+
+// CreateAPIKey creates an API key for the caller's tenant and returns it
+// along with the key itself, which cannot be retrieved again.
+func (s *Service) CreateAPIKey(ctx context.Context, params CreateAPIKeyParams) (*APIKey, string, error) {
+	p, err := s.authorizeAPIKeys(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.CheckActive(ctx, p.TenantID); err != nil {
+		return nil, "", err
+	}
+	params.Name = strings.TrimSpace(params.Name)
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		s.logger.Error(err, "Failed to generate API key")
+		return nil, "", err
+	}
+	k, err := NewAPIKey(p.TenantID, key, params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.apiKeyRepo.Create(ctx, k); err != nil {
+		s.logger.Error(err, "Failed to save API key", "tenant_id", p.TenantID)
+		return nil, "", err
+	}
+
+	s.logger.Info("API key created", "tenant_id", p.TenantID, "api_key_id", k.ID, "created_by", p.Subject)
+	return k, key, nil
+}
+
+func (s *Service) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	p, err := s.authorizeAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.apiKeyRepo.ListByTenantID(ctx, p.TenantID)
+}
+
+// RevokeAPIKey stops the key from authenticating. Revoking is permanent.
+func (s *Service) RevokeAPIKey(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	p, err := s.authorizeAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := s.apiKeyRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if k.TenantID != p.TenantID {
+		return nil, apperror.New(apperror.TypeNotFound, serviceOrigin, "API key not found")
+	}
+	if k.RevokedAt != nil {
+		return k, nil
+	}
+
+	now := time.Now().UTC()
+	k.RevokedAt = &now
+	k.Touch()
+
+	if err := s.apiKeyRepo.Update(ctx, k); err != nil {
+		s.logger.Error(err, "Failed to revoke API key", "api_key_id", id)
+		return nil, err
+	}
+
+	s.logger.Info("API key revoked", "tenant_id", p.TenantID, "api_key_id", id, "revoked_by", p.Subject)
+	return k, nil
+}
+
+// ResolveAPIKey implements auth.APIKeyResolver.
+func (s *Service) ResolveAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	k, err := s.apiKeyRepo.GetByHash(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		if apperror.IsType(err, apperror.TypeNotFound) {
+			return nil, apperror.New(apperror.TypeUnauthenticated, serviceOrigin, "Invalid API key")
+		}
+		return nil, err
+	}
+	if !k.IsValid(time.Now()) {
+		return nil, apperror.New(apperror.TypeUnauthenticated, serviceOrigin, "Invalid API key")
+	}
+	return k.Principal(), nil
+}
+
+func (s *Service) authorizeAPIKeys(ctx context.Context) (*auth.Principal, error) {
+	if err := auth.Authorize(ctx, auth.PermAPIKeyManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	return auth.FromContext(ctx)
+}
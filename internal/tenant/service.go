@@ -0,0 +1,287 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"payroll/internal/apperror"
+	"payroll/internal/country"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const serviceOrigin = "TenantService"
+
+// Service administers tenants. Managing tenants is reserved to platform
+// admins; the policy methods (CheckActive, CheckWorkspaceLimit, ...) are
+// called by other services on behalf of their callers and are not
+// authorized themselves.
+type Service struct {
+	repo        Repository
+	apiKeyRepo  APIKeyRepository
+	countryRepo country.Repository
+	logger      logger.Logger
+}
+
+func NewService(r Repository, kr APIKeyRepository, cr country.Repository, l logger.Logger) *Service {
+	return &Service{
+		repo:        r,
+		apiKeyRepo:  kr,
+		countryRepo: cr,
+		logger:      l,
+	}
+}
+
+type SuspendParams struct {
+	Reason string
+}
+
+func (s *Service) Create(ctx context.Context, params CreateTenantParams) (*Tenant, error) {
+	if err := auth.Authorize(ctx, auth.PermTenantManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+
+	t, err := NewTenant(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCountry(ctx, t.DefaultCountryID); err != nil {
+		return nil, err
+	}
+	if err := s.checkName(ctx, t.Name); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, t); err != nil {
+		s.logger.Error(err, "Failed to save tenant")
+		return nil, err
+	}
+
+	s.logger.Info("Tenant created", "tenant_id", t.ID)
+	return t, nil
+}
+
+// Get returns a tenant to a platform admin, or to any member of the tenant.
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Tenant, error) {
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.TenantID != id {
+		if err := auth.Authorize(ctx, auth.PermTenantManage, auth.Resource{}, serviceOrigin); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.Get(ctx, id)
+}
+
+func (s *Service) List(ctx context.Context) ([]*Tenant, error) {
+	if err := auth.Authorize(ctx, auth.PermTenantManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx)
+}
+
+func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateTenantParams) (*Tenant, error) {
+	if err := auth.Authorize(ctx, auth.PermTenantManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+
+	t, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !t.MatchesVersion(params.Version) {
+		return nil, apperror.NewConflictError(serviceOrigin, "Tenant was modified since it was read")
+	}
+
+	validator := NewValidator()
+	nameChanged := false
+
+	if params.Name != nil {
+		name := strings.TrimSpace(*params.Name)
+		validator.ValidateName(name)
+		nameChanged = name != t.Name
+		t.Name = name
+	}
+	if params.Limits != nil {
+		validator.ValidateLimits(*params.Limits)
+		t.Limits = *params.Limits
+	}
+	if params.DefaultCountryID != nil {
+		if *params.DefaultCountryID == uuid.Nil {
+			t.DefaultCountryID = nil
+		} else {
+			t.DefaultCountryID = params.DefaultCountryID
+		}
+	}
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	if params.DefaultCountryID != nil {
+		if err := s.checkCountry(ctx, t.DefaultCountryID); err != nil {
+			return nil, err
+		}
+	}
+	if nameChanged {
+		if err := s.checkName(ctx, t.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	t.Touch()
+
+	if err := s.repo.Update(ctx, t); err != nil {
+		s.logger.Error(err, "Failed to save tenant", "tenant_id", id)
+		return nil, err
+	}
+	return t, nil
+}
+
+// Suspend stops the tenant from changing its data until it is reactivated.
+func (s *Service) Suspend(ctx context.Context, id uuid.UUID, params SuspendParams) (*Tenant, error) {
+	if err := auth.Authorize(ctx, auth.PermTenantManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+
+	validator := NewValidator()
+	validator.ValidateReason(params.Reason)
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	t, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !t.IsActive() {
+		return nil, apperror.New(apperror.TypeInvalid, serviceOrigin, "Tenant is already suspended")
+	}
+
+	now := time.Now().UTC()
+	t.Status = StatusSuspended
+	t.SuspendedAt = &now
+	t.SuspendReason = params.Reason
+	t.Touch()
+
+	if err := s.repo.Update(ctx, t); err != nil {
+		s.logger.Error(err, "Failed to suspend tenant", "tenant_id", id)
+		return nil, err
+	}
+
+	s.logger.Info("Tenant suspended", "tenant_id", id, "reason", params.Reason)
+	return t, nil
+}
+
+func (s *Service) Reactivate(ctx context.Context, id uuid.UUID) (*Tenant, error) {
+	if err := auth.Authorize(ctx, auth.PermTenantManage, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	t, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.IsActive() {
+		return t, nil
+	}
+
+	t.Status = StatusActive
+	t.SuspendedAt = nil
+	t.SuspendReason = ""
+	t.Touch()
+
+	if err := s.repo.Update(ctx, t); err != nil {
+		s.logger.Error(err, "Failed to reactivate tenant", "tenant_id", id)
+		return nil, err
+	}
+
+	s.logger.Info("Tenant reactivated", "tenant_id", id)
+	return t, nil
+}
+
+// CheckActive returns a TypeForbidden error if the tenant is suspended.
+func (s *Service) CheckActive(ctx context.Context, tenantID uuid.UUID) error {
+	_, err := s.getActive(ctx, tenantID)
+	return err
+}
+
+// CheckWorkspaceLimit returns a TypeForbidden error unless the tenant is
+// active and its plan allows it to have count workspaces.
+func (s *Service) CheckWorkspaceLimit(ctx context.Context, tenantID uuid.UUID, count int) error {
+	t, err := s.getActive(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	return checkLimit("MaxWorkspaces", t.Limits.MaxWorkspaces, count)
+}
+
+// CheckEmployeeLimit returns a TypeForbidden error unless the tenant is
+// active and its plan allows it to have count employees.
+func (s *Service) CheckEmployeeLimit(ctx context.Context, tenantID uuid.UUID, count int) error {
+	t, err := s.getActive(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	return checkLimit("MaxEmployees", t.Limits.MaxEmployees, count)
+}
+
+// DefaultCountryID returns the tenant's default country, or uuid.Nil if it
+// has none.
+func (s *Service) DefaultCountryID(ctx context.Context, tenantID uuid.UUID) (uuid.UUID, error) {
+	t, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if t.DefaultCountryID == nil {
+		return uuid.Nil, nil
+	}
+	return *t.DefaultCountryID, nil
+}
+
+func (s *Service) getActive(ctx context.Context, tenantID uuid.UUID) (*Tenant, error) {
+	t, err := s.repo.Get(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !t.IsActive() {
+		return nil, apperror.New(apperror.TypeForbidden, serviceOrigin, "Tenant is suspended")
+	}
+	return t, nil
+}
+
+func checkLimit(field string, limit, count int) error {
+	if limit > 0 && count > limit {
+		return apperror.NewWithDetails(apperror.TypeForbidden, serviceOrigin, "Plan limit exceeded",
+			map[string]string{field: fmt.Sprintf("limit of %d reached", limit)})
+	}
+	return nil
+}
+
+func (s *Service) checkCountry(ctx context.Context, countryID *uuid.UUID) error {
+	if countryID == nil {
+		return nil
+	}
+	c, err := s.countryRepo.GetByID(ctx, *countryID)
+	if err != nil || c.IsDeleted() {
+		return apperror.New(apperror.TypeInvalid, serviceOrigin, "Invalid DefaultCountryID")
+	}
+	return nil
+}
+
+func (s *Service) checkName(ctx context.Context, name string) error {
+	exists, err := s.repo.ExistsByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apperror.NewDuplicateError(serviceOrigin, map[string]string{"Name": "already exists"})
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package tenant
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"payroll/internal/apperror"
+	"payroll/internal/country"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRepo struct {
+	Repository
+	tenants map[uuid.UUID]*Tenant
+}
+
+func (r *memoryRepo) Create(_ context.Context, t *Tenant) error {
+	r.tenants[t.ID] = t
+	return nil
+}
+
+func (r *memoryRepo) Get(_ context.Context, id uuid.UUID) (*Tenant, error) {
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "tenant not found")
+	}
+	return t, nil
+}
+
+func (r *memoryRepo) Update(_ context.Context, t *Tenant) error {
+	r.tenants[t.ID] = t
+	return nil
+}
+
+func (r *memoryRepo) ExistsByName(_ context.Context, name string) (bool, error) {
+	for _, t := range r.tenants {
+		if t.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryAPIKeyRepo struct {
+	APIKeyRepository
+	keys map[uuid.UUID]*APIKey
+}
+
+func (r *memoryAPIKeyRepo) Create(_ context.Context, k *APIKey) error {
+	r.keys[k.ID] = k
+	return nil
+}
+
+func (r *memoryAPIKeyRepo) Get(_ context.Context, id uuid.UUID) (*APIKey, error) {
+	k, ok := r.keys[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "API key not found")
+	}
+	return k, nil
+}
+
+func (r *memoryAPIKeyRepo) GetByHash(_ context.Context, hash string) (*APIKey, error) {
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			return k, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "API key not found")
+}
+
+func (r *memoryAPIKeyRepo) Update(_ context.Context, k *APIKey) error {
+	r.keys[k.ID] = k
+	return nil
+}
+
+type stubCountryRepo struct {
+	country.Repository
+	country *country.Country
+}
+
+func (r *stubCountryRepo) GetByID(_ context.Context, id uuid.UUID) (*country.Country, error) {
+	if r.country == nil || r.country.ID != id {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "country not found")
+	}
+	return r.country, nil
+}
+
+func newTestService() (*Service, *stubCountryRepo) {
+	c := &country.Country{Name: "Argentina"}
+	c.Initialize()
+	countries := &stubCountryRepo{country: c}
+	keys := &memoryAPIKeyRepo{keys: make(map[uuid.UUID]*APIKey)}
+	return NewService(&memoryRepo{tenants: make(map[uuid.UUID]*Tenant)}, keys, countries, logger.Nop{}), countries
+}
+
+var platformAdmin = auth.WithPrincipal(context.Background(), &auth.Principal{
+	Subject: "ops", Roles: []auth.Role{auth.RolePlatformAdmin},
+})
+
+func TestCreate_ValidatesNameAndDefaultCountry(t *testing.T) {
+	s, countries := newTestService()
+	ctx := platformAdmin
+
+	unknown := uuid.New()
+	_, err := s.Create(ctx, CreateTenantParams{Name: "Acme", DefaultCountryID: &unknown})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+
+	acme, err := s.Create(ctx, CreateTenantParams{Name: "Acme", DefaultCountryID: &countries.country.ID})
+	require.NoError(t, err)
+	assert.Equal(t, StatusActive, acme.Status)
+
+	_, err = s.Create(ctx, CreateTenantParams{Name: " Acme "})
+	assert.True(t, apperror.IsType(err, apperror.TypeDuplicate))
+
+	id, err := s.DefaultCountryID(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Equal(t, countries.country.ID, id)
+}
+
+func TestSuspend_BlocksWritesUntilReactivated(t *testing.T) {
+	s, _ := newTestService()
+	ctx := platformAdmin
+	acme, err := s.Create(ctx, CreateTenantParams{Name: "Acme", Limits: Limits{MaxEmployees: 2}})
+	require.NoError(t, err)
+
+	assert.NoError(t, s.CheckEmployeeLimit(ctx, acme.ID, 2))
+	assert.True(t, apperror.IsType(s.CheckEmployeeLimit(ctx, acme.ID, 3), apperror.TypeForbidden))
+	assert.NoError(t, s.CheckWorkspaceLimit(ctx, acme.ID, 1000))
+
+	_, err = s.Suspend(ctx, acme.ID, SuspendParams{})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+
+	acme, err = s.Suspend(ctx, acme.ID, SuspendParams{Reason: "unpaid invoices"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusSuspended, acme.Status)
+	assert.NotNil(t, acme.SuspendedAt)
+	assert.True(t, apperror.IsType(s.CheckActive(ctx, acme.ID), apperror.TypeForbidden))
+	assert.True(t, apperror.IsType(s.CheckEmployeeLimit(ctx, acme.ID, 1), apperror.TypeForbidden))
+
+	acme, err = s.Reactivate(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Nil(t, acme.SuspendedAt)
+	assert.Empty(t, acme.SuspendReason)
+	assert.NoError(t, s.CheckActive(ctx, acme.ID))
+}
+
+func TestService_RequiresPlatformAdmin(t *testing.T) {
+	s, _ := newTestService()
+	acme, err := s.Create(platformAdmin, CreateTenantParams{Name: "Acme"})
+	require.NoError(t, err)
+
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "alice", TenantID: acme.ID, Roles: []auth.Role{auth.RoleTenantAdmin},
+	})
+	_, err = s.Create(admin, CreateTenantParams{Name: "Other"})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = s.Suspend(admin, acme.ID, SuspendParams{Reason: "self-inflicted"})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = s.Get(admin, acme.ID)
+	assert.NoError(t, err)
+	_, err = s.Create(context.Background(), CreateTenantParams{Name: "Other"})
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+}
+
+func TestAPIKeys_AuthenticateUntilRevoked(t *testing.T) {
+	s, _ := newTestService()
+	acme, err := s.Create(platformAdmin, CreateTenantParams{Name: "Acme"})
+	require.NoError(t, err)
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "alice", TenantID: acme.ID, Roles: []auth.Role{auth.RoleTenantAdmin},
+	})
+
+	_, _, err = s.CreateAPIKey(admin, CreateAPIKeyParams{Name: "ERP", Roles: []auth.Role{auth.RolePlatformAdmin}})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+
+	k, key, err := s.CreateAPIKey(admin, CreateAPIKeyParams{
+		Name:   "ERP",
+		Roles:  []auth.Role{auth.RoleAuditor},
+		Scopes: []auth.Permission{auth.PermReportRead},
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, k.Hash, key)
+	assert.True(t, strings.HasPrefix(key, k.Hint))
+
+	p, err := s.ResolveAPIKey(context.Background(), key)
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, p.TenantID)
+	assert.True(t, p.Can(auth.PermReportRead, auth.Resource{}))
+	assert.False(t, p.Can(auth.PermEmployeeRead, auth.Resource{}))
+
+	_, err = s.RevokeAPIKey(admin, k.ID)
+	require.NoError(t, err)
+	_, err = s.ResolveAPIKey(context.Background(), key)
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+}
@@ -0,0 +1,100 @@
+package tenant
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const modelOrigin = "Tenant"
+
+type Status string
+
+const (
+	StatusActive    Status = "ACTIVE"
+	StatusSuspended Status = "SUSPENDED"
+)
+
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusActive, StatusSuspended:
+		return true
+	}
+	return false
+}
+
+// Limits caps what a tenant's plan allows. Zero means unlimited.
+type Limits struct {
+	MaxWorkspaces int
+	MaxEmployees  int
+}
+
+// Tenant is a customer of the platform. Suspended tenants can still read
+// their data but cannot change it.
+type Tenant struct {
+	domain.BaseEntity
+	Name   string
+	Status Status
+	Limits Limits
+	// DefaultCountryID is used for new workspaces that do not name a country.
+	DefaultCountryID *uuid.UUID
+	SuspendedAt      *time.Time
+	SuspendReason    string
+}
+
+type CreateTenantParams struct {
+	Name             string
+	Limits           Limits
+	DefaultCountryID *uuid.UUID
+}
+
+type UpdateTenantParams struct {
+	Name   *string
+	Limits *Limits
+	// A pointer to uuid.Nil clears the default country.
+	DefaultCountryID *uuid.UUID
+	// Version, when set, must equal the current version or the update fails
+	// with a conflict.
+	Version *int64
+}
+
+func NewTenant(params CreateTenantParams) (*Tenant, error) {
+	validator := NewValidator()
+
+	params.Name = strings.TrimSpace(params.Name)
+
+	validator.ValidateName(params.Name)
+	validator.ValidateLimits(params.Limits)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	t := &Tenant{
+		Name:             params.Name,
+		Status:           StatusActive,
+		Limits:           params.Limits,
+		DefaultCountryID: params.DefaultCountryID,
+	}
+	t.Initialize()
+
+	return t, nil
+}
+
+func (t *Tenant) IsActive() bool {
+	return t.Status == StatusActive
+}
+
+type Repository interface {
+	Create(ctx context.Context, t *Tenant) error
+	Get(ctx context.Context, id uuid.UUID) (*Tenant, error)
+	List(ctx context.Context) ([]*Tenant, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, t *Tenant) error
+	ExistsByName(ctx context.Context, name string) (bool, error)
+}
@@ -0,0 +1,75 @@
+package tenant
+
+import (
+	"fmt"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/validation"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxNameLength   = 100
+	maxReasonLength = 500
+)
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+func (v *Validator) ValidateName(name string) {
+	if name == "" {
+		v.AddError("Name", "is empty")
+	} else if len(name) > maxNameLength {
+		v.AddError("Name", fmt.Sprintf("must be less than %d characters", maxNameLength))
+	}
+}
+
+func (v *Validator) ValidateLimits(limits Limits) {
+	if limits.MaxWorkspaces < 0 {
+		v.AddError("MaxWorkspaces", "cannot be negative")
+	}
+	if limits.MaxEmployees < 0 {
+		v.AddError("MaxEmployees", "cannot be negative")
+	}
+}
+
+func (v *Validator) ValidateReason(reason string) {
+	if reason == "" {
+		v.AddError("Reason", "is empty")
+	} else if len(reason) > maxReasonLength {
+		v.AddError("Reason", fmt.Sprintf("must be less than %d characters", maxReasonLength))
+	}
+}
+
+// ValidateRoles checks the roles of an API key. Keys act for integrations,
+// so they cannot hold the platform admin or self-service roles, and a
+// payroll manager key must be assigned to workspaces.
+func (v *Validator) ValidateRoles(roles []auth.Role, workspaceIDs []uuid.UUID) {
+	if len(roles) == 0 {
+		v.AddError("Roles", "is empty")
+		return
+	}
+	for _, r := range roles {
+		switch {
+		case !r.IsValid():
+			v.AddError("Roles", fmt.Sprintf("unknown role %q", r))
+		case r == auth.RolePlatformAdmin || r == auth.RoleSelfService:
+			v.AddError("Roles", fmt.Sprintf("role %s cannot be given to an API key", r))
+		case r == auth.RolePayrollManager && len(workspaceIDs) == 0:
+			v.AddError("WorkspaceIDs", "is empty")
+		}
+	}
+}
+
+func (v *Validator) ValidateScopes(scopes []auth.Permission) {
+	for _, s := range scopes {
+		if !s.IsValid() {
+			v.AddError("Scopes", fmt.Sprintf("unknown scope %q", s))
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package testkit holds the fakes and helpers shared by the services' tests.
+package testkit
+
+import (
+	"context"
+	"payroll/internal/audit"
+	"payroll/internal/event"
+	"payroll/internal/platform/auth"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// AdminContext returns a context acting as an administrator of the tenant.
+func AdminContext(tenantID uuid.UUID) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "admin", TenantID: tenantID, Roles: []auth.Role{auth.RoleTenantAdmin},
+	})
+}
+
+// PlatformAdminContext returns a context acting as a platform
+// administrator, who belongs to no tenant.
+func PlatformAdminContext() context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "operator", Roles: []auth.Role{auth.RolePlatformAdmin},
+	})
+}
+
+// Audit is an audit.Recorder that keeps the events it records.
+type Audit struct {
+	mu     sync.Mutex
+	Events []audit.Event
+}
+
+var _ audit.Recorder = (*Audit)(nil)
+
+func (r *Audit) Record(_ context.Context, ev audit.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Events = append(r.Events, ev)
+	return nil
+}
+
+// Publisher is an event.Publisher that keeps the events it publishes.
+type Publisher struct {
+	mu     sync.Mutex
+	Events []event.Event
+}
+
+var _ event.Publisher = (*Publisher)(nil)
+
+func (r *Publisher) Publish(_ context.Context, events ...event.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Events = append(r.Events, events...)
+	return nil
+}
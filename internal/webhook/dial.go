@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// guard returns a copy of client that does not follow redirects and only
+// connects to public addresses, or to those in allowed, so that a
+// subscription cannot reach the internal network. The address is checked
+// after it is resolved, so a host name cannot be pointed at an internal
+// address once the subscription's URL has been validated. The client's
+// Transport must be nil or an *http.Transport; its proxy is not used, as
+// the proxy would connect on the client's behalf.
+func guard(client *http.Client, allowed []netip.Prefix) *http.Client {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		panic(fmt.Sprintf("webhook: client transport %T is not an *http.Transport", t))
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			return checkAddress(address, allowed)
+		},
+	}
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	transport.DialTLSContext = nil
+
+	guarded := *client
+	guarded.Transport = transport
+	guarded.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &guarded
+}
+
+// sharedNetworks are not public although net/netip does not classify them
+// as private: carrier-grade NAT space, and the NAT64 prefix, which maps to
+// any IPv4 address including private ones.
+var sharedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// checkAddress fails unless the resolved address (host:port) is public or
+// in allowed.
+func checkAddress(address string, allowed []netip.Prefix) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	ip := addrPort.Addr().Unmap()
+	for _, prefix := range allowed {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("refusing to connect to non-public address %s", ip)
+	}
+	for _, prefix := range sharedNetworks {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("refusing to connect to non-public address %s", ip)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,444 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"payroll/internal/apperror"
+	"payroll/internal/event"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/tenant"
+	"payroll/internal/platform/uow"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const serviceOrigin = "WebhookService"
+
+// maxResponseBody is how much of a response is read before the connection
+// is released. The body itself is not kept.
+const maxResponseBody = 64 << 10
+
+// TenantPolicy enforces the tenant's status.
+type TenantPolicy interface {
+	// CheckActive fails if the tenant is suspended.
+	CheckActive(ctx context.Context, tenantID uuid.UUID) error
+}
+
+type Config struct {
+	// BatchSize is how many deliveries one DeliverDue call sends at most.
+	// Defaults to 50.
+	BatchSize int
+	// MaxAttempts is how many times a delivery is tried before it is
+	// dead-lettered. Defaults to 8.
+	MaxAttempts int
+	// Backoff returns the delay after the given failed attempt (1-based).
+	// Defaults to event.ExponentialBackoff(1m, 6h).
+	Backoff func(attempt int) time.Duration
+	// AllowedNetworks are non-public networks deliveries may still be sent
+	// to, e.g. that of an internal relay. By default only public addresses
+	// are.
+	AllowedNetworks []netip.Prefix
+}
+
+type Service struct {
+	subscriptions SubscriptionRepository
+	deliveries    DeliveryRepository
+	tenants       TenantPolicy
+	uow           uow.UnitOfWork
+	// client sends the deliveries; see guard.
+	client *http.Client
+	config Config
+	logger logger.Logger
+	now    func() time.Time
+}
+
+// NewService returns a Service that sends deliveries with a copy of client,
+// which should have a timeout. Redirects are not followed, and connections
+// to addresses that are not public, or in config.AllowedNetworks, are
+// refused.
+func NewService(sr SubscriptionRepository, dr DeliveryRepository, tp TenantPolicy, u uow.UnitOfWork, client *http.Client, config Config, l logger.Logger) *Service {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.Backoff == nil {
+		config.Backoff = event.ExponentialBackoff(time.Minute, 6*time.Hour)
+	}
+	return &Service{
+		subscriptions: sr,
+		deliveries:    dr,
+		tenants:       tp,
+		uow:           u,
+		client:        guard(client, config.AllowedNetworks),
+		config:        config,
+		logger:        l,
+		now:           time.Now,
+	}
+}
+
+// authorize returns the tenant of a caller allowed to manage webhooks.
+func authorize(ctx context.Context) (uuid.UUID, error) {
+	if err := auth.Authorize(ctx, auth.PermWebhookManage, auth.Resource{}, serviceOrigin); err != nil {
+		return uuid.Nil, err
+	}
+	return tenant.FromContext(ctx)
+}
+
+// CreateSubscription registers an endpoint and returns it with its signing
+// secret, which is not returned again.
+func (s *Service) CreateSubscription(ctx context.Context, params CreateSubscriptionParams) (*Subscription, string, error) {
+	tenantID, err := authorize(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.tenants.CheckActive(ctx, tenantID); err != nil {
+		return nil, "", err
+	}
+
+	sub, err := NewSubscription(tenantID, params)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.subscriptions.Create(ctx, sub); err != nil {
+		s.logger.Error(err, "Failed to save webhook subscription", "tenant_id", tenantID)
+		return nil, "", err
+	}
+
+	s.logger.Info("Webhook subscription created", "tenant_id", tenantID, "subscription_id", sub.ID)
+	return sub, sub.Secret, nil
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	tenantID, err := authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.subscriptions.ListByTenantID(ctx, tenantID)
+}
+
+// UpdateSubscription checks params.Version and saves the subscription in
+// one unit of work, so that no other write can come in between.
+func (s *Service) UpdateSubscription(ctx context.Context, id uuid.UUID, params UpdateSubscriptionParams) (*Subscription, error) {
+	var sub *Subscription
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		sub, err = s.getSubscription(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !sub.MatchesVersion(params.Version) {
+			return apperror.NewConflictError(serviceOrigin, "webhook subscription was modified since it was read")
+		}
+		if err := s.tenants.CheckActive(ctx, sub.TenantID); err != nil {
+			return err
+		}
+
+		validator := NewValidator()
+		if params.URL != nil {
+			url := strings.TrimSpace(*params.URL)
+			validator.ValidateURL(url)
+			sub.URL = url
+		}
+		if params.EventTypes != nil {
+			validator.ValidateEventTypes(params.EventTypes)
+			sub.EventTypes = params.EventTypes
+		}
+		if params.Description != nil {
+			description := strings.TrimSpace(*params.Description)
+			validator.ValidateDescription(description)
+			sub.Description = description
+		}
+		if params.Active != nil {
+			sub.Active = *params.Active
+		}
+		if validator.HasErrors() {
+			return apperror.NewValidationError(serviceOrigin, validator.Errors())
+		}
+
+		sub.Touch()
+		return s.subscriptions.Update(ctx, sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// DeleteSubscription stops deliveries to the endpoint. Pending deliveries
+// are dead-lettered when they come due.
+func (s *Service) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	sub, err := s.getSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	sub.SoftDelete()
+	return s.subscriptions.Update(ctx, sub)
+}
+
+// getSubscription authorizes the caller and returns the subscription unless
+// it is deleted or belongs to another tenant.
+func (s *Service) getSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	if _, err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	sub, err := s.subscriptions.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, sub.TenantID, serviceOrigin, "Webhook subscription not found"); err != nil {
+		return nil, err
+	}
+	if sub.IsDeleted() {
+		return nil, apperror.New(apperror.TypeNotFound, serviceOrigin, "Webhook subscription not found")
+	}
+	return sub, nil
+}
+
+// Handle implements event.Handler: it queues a delivery of the message for
+// every active subscription of its tenant that wants it. Register it with
+// the event dispatcher.
+func (s *Service) Handle(ctx context.Context, m *event.Message) error {
+	if m.TenantID == uuid.Nil {
+		return nil
+	}
+	subs, err := s.subscriptions.ListByTenantID(ctx, m.TenantID)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []*Delivery
+	var template *Delivery
+	for _, sub := range subs {
+		if !sub.Wants(m.Type) {
+			continue
+		}
+		if template == nil {
+			if template, err = s.newDelivery(m); err != nil {
+				return err
+			}
+		}
+		d := *template
+		d.ID = uuid.Must(uuid.NewV7())
+		d.SubscriptionID = sub.ID
+		deliveries = append(deliveries, &d)
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		// The dispatcher delivers at least once; deliveries of a message
+		// are created together, so any of them means this is a repeat.
+		exists, err := s.deliveries.ExistsByMessageID(ctx, m.ID)
+		if err != nil || exists {
+			return err
+		}
+		for _, d := range deliveries {
+			if err := s.deliveries.Create(ctx, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// newDelivery returns what the deliveries of m have in common.
+func (s *Service) newDelivery(m *event.Message) (*Delivery, error) {
+	b, err := json.Marshal(body{
+		ID:         m.ID,
+		Type:       m.Type,
+		TenantID:   m.TenantID,
+		EntityID:   m.EntityID,
+		OccurredAt: m.OccurredAt,
+		Data:       m.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	return &Delivery{
+		TenantID:      m.TenantID,
+		MessageID:     m.ID,
+		EventType:     m.Type,
+		EntityID:      m.EntityID,
+		Body:          b,
+		Status:        DeliveryStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+// DeliverDue sends one batch of due deliveries and returns how many
+// succeeded. It is a background job that runs across tenants.
+func (s *Service) DeliverDue(ctx context.Context) (int, error) {
+	due, err := s.deliveries.Due(ctx, s.now().UTC(), s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for _, d := range due {
+		s.deliver(ctx, d)
+		if err := s.deliveries.Update(ctx, d); err != nil {
+			s.logger.Error(err, "Failed to save webhook delivery", "delivery_id", d.ID)
+			return succeeded, err
+		}
+		if d.Status == DeliveryStatusSucceeded {
+			succeeded++
+		}
+	}
+	return succeeded, nil
+}
+
+// deliver makes one attempt at d and updates its status.
+func (s *Service) deliver(ctx context.Context, d *Delivery) {
+	sub, err := s.subscriptions.Get(ctx, d.SubscriptionID)
+	if err != nil && !apperror.IsType(err, apperror.TypeNotFound) {
+		s.logger.Error(err, "Failed to load webhook subscription", "delivery_id", d.ID)
+		return
+	}
+
+	attempt := Attempt{At: s.now().UTC()}
+	if sub == nil || sub.IsDeleted() || !sub.Active {
+		attempt.Error = "subscription is inactive or deleted"
+		d.Attempts = append(d.Attempts, attempt)
+		d.Status = DeliveryStatusDead
+		return
+	}
+
+	attempt.StatusCode, err = s.send(ctx, sub, d, attempt.At)
+	attempt.Duration = s.now().UTC().Sub(attempt.At)
+	if err == nil && (attempt.StatusCode < 200 || attempt.StatusCode > 299) {
+		err = fmt.Errorf("unexpected status %d", attempt.StatusCode)
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	d.Attempts = append(d.Attempts, attempt)
+
+	switch {
+	case err == nil:
+		d.Status = DeliveryStatusSucceeded
+		d.DeliveredAt = &attempt.At
+	case len(d.Attempts) >= s.config.MaxAttempts:
+		d.Status = DeliveryStatusDead
+		s.logger.Warn("Webhook delivery dead-lettered", "delivery_id", d.ID, "subscription_id", sub.ID, "error", attempt.Error)
+	default:
+		d.NextAttemptAt = attempt.At.Add(s.config.Backoff(len(d.Attempts)))
+	}
+}
+
+func (s *Service) send(ctx context.Context, sub *Subscription, d *Delivery, at time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, d.MessageID.String())
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderTimestamp, fmt.Sprint(at.Unix()))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, at, d.Body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, nil
+}
+
+type ListDeliveriesParams struct {
+	Status *DeliveryStatus
+	pagination.Params
+}
+
+// ListDeliveries returns a page of the subscription's delivery log, newest
+// first.
+func (s *Service) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, params ListDeliveriesParams) (*pagination.Page[*Delivery], error) {
+	sub, err := s.getSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	validator := NewValidator()
+	if params.Status != nil && !params.Status.IsValid() {
+		validator.AddError("Status", "is invalid")
+	}
+	validator.ValidatePage(params.Params)
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	query := params.Params.Query()
+	deliveries, err := s.deliveries.List(ctx, DeliveryFilter{
+		TenantID:       sub.TenantID,
+		SubscriptionID: sub.ID,
+		Status:         params.Status,
+		Query:          query,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.NewPage(deliveries, query, func(d *Delivery) string {
+		return pagination.EncodeCursor(d.ID)
+	}), nil
+}
+
+// Replay queues the delivery's body to be sent again as a new delivery, e.g.
+// after a dead-lettered delivery's endpoint has been fixed. The receiver
+// sees the same message ID.
+func (s *Service) Replay(ctx context.Context, deliveryID uuid.UUID) (*Delivery, error) {
+	tenantID, err := authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tenants.CheckActive(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	original, err := s.deliveries.Get(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, original.TenantID, serviceOrigin, "Webhook delivery not found"); err != nil {
+		return nil, err
+	}
+	if original.Status == DeliveryStatusPending {
+		return nil, apperror.New(apperror.TypeConflict, serviceOrigin, "Webhook delivery is still pending")
+	}
+	if _, err := s.getSubscription(ctx, original.SubscriptionID); err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	replay := &Delivery{
+		ID:             uuid.Must(uuid.NewV7()),
+		TenantID:       original.TenantID,
+		SubscriptionID: original.SubscriptionID,
+		MessageID:      original.MessageID,
+		EventType:      original.EventType,
+		EntityID:       original.EntityID,
+		Body:           original.Body,
+		Status:         DeliveryStatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		ReplayOf:       &original.ID,
+	}
+	if err := s.deliveries.Create(ctx, replay); err != nil {
+		s.logger.Error(err, "Failed to save webhook replay", "delivery_id", deliveryID)
+		return nil, err
+	}
+	return replay, nil
+}
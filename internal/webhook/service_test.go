@@ -0,0 +1,384 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/employee"
+	"payroll/internal/event"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/logger"
+	"payroll/internal/platform/uow"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySubscriptionRepo struct {
+	SubscriptionRepository
+	subs map[uuid.UUID]*Subscription
+}
+
+func (r *memorySubscriptionRepo) Create(_ context.Context, s *Subscription) error {
+	r.subs[s.ID] = s
+	return nil
+}
+
+func (r *memorySubscriptionRepo) Get(_ context.Context, id uuid.UUID) (*Subscription, error) {
+	s, ok := r.subs[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "subscription not found")
+	}
+	return s, nil
+}
+
+func (r *memorySubscriptionRepo) ListByTenantID(_ context.Context, tenantID uuid.UUID) ([]*Subscription, error) {
+	var out []*Subscription
+	for _, s := range r.subs {
+		if s.TenantID == tenantID && !s.IsDeleted() {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r *memorySubscriptionRepo) Update(_ context.Context, s *Subscription) error {
+	r.subs[s.ID] = s
+	return nil
+}
+
+type memoryDeliveryRepo struct {
+	deliveries []*Delivery
+}
+
+func (r *memoryDeliveryRepo) Create(ctx context.Context, d *Delivery) error {
+	n := len(r.deliveries)
+	r.deliveries = append(r.deliveries, d)
+	uow.OnRollback(ctx, func() { r.deliveries = r.deliveries[:n] })
+	return nil
+}
+
+func (r *memoryDeliveryRepo) Get(_ context.Context, id uuid.UUID) (*Delivery, error) {
+	for _, d := range r.deliveries {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, apperror.New(apperror.TypeNotFound, "test", "delivery not found")
+}
+
+func (r *memoryDeliveryRepo) Update(context.Context, *Delivery) error {
+	return nil
+}
+
+func (r *memoryDeliveryRepo) ExistsByMessageID(_ context.Context, messageID uuid.UUID) (bool, error) {
+	for _, d := range r.deliveries {
+		if d.MessageID == messageID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryDeliveryRepo) Due(_ context.Context, now time.Time, limit int) ([]*Delivery, error) {
+	var due []*Delivery
+	for _, d := range r.deliveries {
+		if d.Status == DeliveryStatusPending && !d.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryDeliveryRepo) List(_ context.Context, filter DeliveryFilter) ([]*Delivery, error) {
+	var out []*Delivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		d := r.deliveries[i]
+		if d.TenantID == filter.TenantID && d.SubscriptionID == filter.SubscriptionID &&
+			(filter.Status == nil || d.Status == *filter.Status) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryDeliveryRepo) ScrubByEntityID(_ context.Context, tenantID, entityID uuid.UUID) (int, error) {
+	n := 0
+	for _, d := range r.deliveries {
+		if d.TenantID != tenantID || d.EntityID != entityID {
+			continue
+		}
+		b, err := ScrubBody(d.Body)
+		if err != nil {
+			return n, err
+		}
+		d.Body = b
+		n++
+	}
+	return n, nil
+}
+
+type activeTenants struct{}
+
+func (activeTenants) CheckActive(context.Context, uuid.UUID) error { return nil }
+
+// receiver is an HTTPS endpoint that checks signatures and answers with
+// the next queued status.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	secret   string
+	statuses []int
+	received []http.Header
+	bodies   [][]byte
+	verified []error
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.received = append(r.received, req.Header)
+		r.bodies = append(r.bodies, b)
+		r.verified = append(r.verified, VerifySignature(r.secret, req.Header, b, 5*time.Minute, time.Now()))
+		status := http.StatusNoContent
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		if status >= 300 && status < 400 {
+			w.Header().Set("Location", "/moved")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+type fixture struct {
+	ctx        context.Context
+	tenantID   uuid.UUID
+	service    *Service
+	deliveries *memoryDeliveryRepo
+	receiver   *receiver
+	now        time.Time
+}
+
+// loopback lets the fixture's service reach the receiver.
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+func newFixture(t *testing.T) *fixture {
+	return newFixtureAllowing(t, loopback)
+}
+
+// newFixtureAllowing returns a fixture whose service may send deliveries to
+// the given non-public networks.
+func newFixtureAllowing(t *testing.T, allowed []netip.Prefix) *fixture {
+	f := &fixture{tenantID: uuid.New(), deliveries: &memoryDeliveryRepo{}, receiver: newReceiver(t), now: time.Now()}
+	f.ctx = auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "admin", TenantID: f.tenantID, Roles: []auth.Role{auth.RoleTenantAdmin},
+	})
+	config := Config{MaxAttempts: 3, Backoff: func(int) time.Duration { return time.Minute }, AllowedNetworks: allowed}
+	f.service = NewService(&memorySubscriptionRepo{subs: map[uuid.UUID]*Subscription{}}, f.deliveries, activeTenants{}, uow.NewMemory(),
+		f.receiver.Client(), config, logger.Nop{})
+	f.service.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *fixture) subscribe(t *testing.T, types ...string) *Subscription {
+	t.Helper()
+	sub, secret, err := f.service.CreateSubscription(f.ctx, CreateSubscriptionParams{URL: f.receiver.URL, EventTypes: types})
+	require.NoError(t, err)
+	f.receiver.secret = secret
+	return sub
+}
+
+func (f *fixture) publish(t *testing.T, eventType string) *event.Message {
+	t.Helper()
+	m := &event.Message{
+		ID: uuid.New(), Type: eventType, TenantID: f.tenantID, EntityID: uuid.New(),
+		OccurredAt: f.now, Payload: json.RawMessage(`{"FirstName":"Ada"}`),
+	}
+	require.NoError(t, f.service.Handle(context.Background(), m))
+	return m
+}
+
+func TestCreateSubscription_Validates(t *testing.T) {
+	f := newFixture(t)
+
+	_, _, err := f.service.CreateSubscription(f.ctx, CreateSubscriptionParams{URL: "http://hr.example.com/hook", EventTypes: []string{"Nope"}})
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, "must use https", domainErr.Details["URL"])
+	assert.Contains(t, domainErr.Details, "EventTypes")
+
+	approver := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "carol", TenantID: f.tenantID, Roles: []auth.Role{auth.RoleApprover},
+	})
+	_, _, err = f.service.CreateSubscription(approver, CreateSubscriptionParams{URL: f.receiver.URL, EventTypes: []string{employee.EventCreated}})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+}
+
+func TestDeliverDue_SendsSignedDeliveriesToMatchingSubscriptions(t *testing.T) {
+	f := newFixture(t)
+	sub := f.subscribe(t, employee.EventCreated)
+
+	m := f.publish(t, employee.EventCreated)
+	f.publish(t, payroll.EventRunFinalized)
+	require.NoError(t, f.service.Handle(context.Background(), m), "a redelivered message is not queued twice")
+	require.Len(t, f.deliveries.deliveries, 1)
+
+	n, err := f.service.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.Len(t, f.receiver.received, 1)
+	assert.NoError(t, f.receiver.verified[0])
+	assert.Equal(t, m.ID.String(), f.receiver.received[0].Get(HeaderID))
+	assert.Equal(t, employee.EventCreated, f.receiver.received[0].Get(HeaderEvent))
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(f.receiver.bodies[0], &got))
+	assert.Equal(t, "Ada", got["data"].(map[string]any)["FirstName"])
+
+	d := f.deliveries.deliveries[0]
+	assert.Equal(t, sub.ID, d.SubscriptionID)
+	assert.Equal(t, DeliveryStatusSucceeded, d.Status)
+	require.Len(t, d.Attempts, 1)
+	assert.Equal(t, http.StatusNoContent, d.Attempts[0].StatusCode)
+}
+
+func TestDeliverDue_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	f := newFixture(t)
+	sub := f.subscribe(t, employee.EventUpdated)
+	f.receiver.statuses = []int{500, 500, 500, 200}
+	f.publish(t, employee.EventUpdated)
+	d := f.deliveries.deliveries[0]
+
+	for i := range 3 {
+		_, err := f.service.DeliverDue(context.Background())
+		require.NoError(t, err)
+		require.Len(t, d.Attempts, i+1)
+		_, err = f.service.DeliverDue(context.Background())
+		require.NoError(t, err)
+		require.Len(t, d.Attempts, i+1, "not retried before the backoff")
+		f.now = f.now.Add(time.Minute)
+	}
+	assert.Equal(t, DeliveryStatusDead, d.Status)
+	assert.Equal(t, "unexpected status 500", d.Attempts[2].Error)
+
+	dead := DeliveryStatusDead
+	page, err := f.service.ListDeliveries(f.ctx, sub.ID, ListDeliveriesParams{Status: &dead})
+	require.NoError(t, err)
+	assert.Equal(t, []*Delivery{d}, page.Items)
+
+	replay, err := f.service.Replay(f.ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &d.ID, replay.ReplayOf)
+	n, err := f.service.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, DeliveryStatusSucceeded, replay.Status)
+	assert.Equal(t, f.receiver.received[0].Get(HeaderID), f.receiver.received[3].Get(HeaderID))
+
+	other := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "eve", TenantID: uuid.New(), Roles: []auth.Role{auth.RoleTenantAdmin},
+	})
+	_, err = f.service.Replay(other, d.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+}
+
+func TestDeliverDue_DeadLettersNonPublicAddresses(t *testing.T) {
+	f := newFixtureAllowing(t, nil)
+	f.subscribe(t, employee.EventCreated)
+	f.publish(t, employee.EventCreated)
+	d := f.deliveries.deliveries[0]
+
+	for range 3 {
+		n, err := f.service.DeliverDue(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		f.now = f.now.Add(time.Minute)
+	}
+
+	assert.Equal(t, DeliveryStatusDead, d.Status)
+	require.Len(t, d.Attempts, 3)
+	assert.Contains(t, d.Attempts[0].Error, "refusing to connect to non-public address 127.0.0.1")
+	assert.Empty(t, f.receiver.received, "the loopback receiver is never reached")
+}
+
+func TestDeliverDue_DoesNotFollowRedirects(t *testing.T) {
+	f := newFixture(t)
+	f.subscribe(t, employee.EventCreated)
+	f.receiver.statuses = []int{http.StatusTemporaryRedirect}
+	f.publish(t, employee.EventCreated)
+
+	n, err := f.service.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	d := f.deliveries.deliveries[0]
+	require.Len(t, d.Attempts, 1)
+	assert.Equal(t, "unexpected status 307", d.Attempts[0].Error)
+	assert.Len(t, f.receiver.received, 1, "the redirect is not followed")
+}
+
+func TestCheckAddress(t *testing.T) {
+	for _, address := range []string{
+		"127.0.0.1:443", "[::1]:443", "10.1.2.3:443", "172.16.0.1:443", "192.168.1.1:443",
+		"169.254.169.254:80", "[fe80::1]:443", "0.0.0.0:443", "[::]:443", "[::ffff:127.0.0.1]:443", "[fd00::1]:443",
+		"100.64.0.1:443", "100.127.255.254:443", "[64:ff9b::a01:203]:443",
+	} {
+		assert.Error(t, checkAddress(address, nil), address)
+	}
+	assert.NoError(t, checkAddress("93.184.216.34:443", nil))
+	assert.NoError(t, checkAddress("[2606:2800:220:1::1]:443", nil))
+	assert.NoError(t, checkAddress("10.1.2.3:443", []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}))
+	assert.NoError(t, checkAddress("100.128.0.1:443", nil))
+}
+
+func TestScrubByEntityID_KeepsTheEnvelope(t *testing.T) {
+	f := newFixture(t)
+	f.subscribe(t, employee.EventCreated)
+	m := f.publish(t, employee.EventCreated)
+	other := f.publish(t, employee.EventCreated)
+
+	n, err := f.deliveries.ScrubByEntityID(context.Background(), f.tenantID, m.EntityID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(f.deliveries.deliveries[0].Body, &got))
+	assert.Nil(t, got["data"])
+	assert.Equal(t, m.ID.String(), got["id"])
+	assert.Equal(t, m.EntityID.String(), got["entity_id"])
+	assert.Equal(t, other.EntityID, f.deliveries.deliveries[1].EntityID)
+	assert.Contains(t, string(f.deliveries.deliveries[1].Body), "Ada")
+}
+
+func TestVerifySignature_RejectsTamperingAndStaleRequests(t *testing.T) {
+	at := time.Now()
+	body := []byte(`{"id":"1"}`)
+	header := http.Header{}
+	header.Set(HeaderTimestamp, "0")
+	header.Set(HeaderSignature, Sign("secret", at, body))
+
+	assert.Error(t, VerifySignature("secret", header, body, time.Minute, at), "timestamp is not the signed one")
+
+	header.Set(HeaderTimestamp, fmt.Sprint(at.Unix()))
+	assert.NoError(t, VerifySignature("secret", header, body, time.Minute, at))
+	assert.Error(t, VerifySignature("other", header, body, time.Minute, at))
+	assert.Error(t, VerifySignature("secret", header, []byte(`{"id":"2"}`), time.Minute, at))
+	assert.Error(t, VerifySignature("secret", header, body, time.Minute, at.Add(time.Hour)))
+}
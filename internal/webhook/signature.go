@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderEvent     = "Webhook-Event"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+)
+
+const signatureVersion = "v1="
+
+// Sign returns the signature of body sent at the given time: "v1=" followed
+// by the hex HMAC-SHA256, keyed with the secret, of "<unix seconds>.<body>".
+// Covering the timestamp lets receivers reject replayed requests.
+func Sign(secret string, at time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(at.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the signature headers of a received delivery, and
+// that it was sent within tolerance of now. Receivers written in Go can use
+// it as is.
+func VerifySignature(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return errors.New("webhook: missing or invalid timestamp")
+	}
+	at := time.Unix(unix, 0)
+	if now.Sub(at) > tolerance || at.Sub(now) > tolerance {
+		return errors.New("webhook: timestamp outside tolerance")
+	}
+	signature := header.Get(HeaderSignature)
+	if !strings.HasPrefix(signature, signatureVersion) ||
+		!hmac.Equal([]byte(signature), []byte(Sign(secret, at, body))) {
+		return errors.New("webhook: signature mismatch")
+	}
+	return nil
+}
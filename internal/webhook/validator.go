@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/validation"
+	"slices"
+)
+
+const (
+	maxURLLength         = 2048
+	maxDescriptionLength = 500
+)
+
+type Validator struct {
+	validation.Validator
+}
+
+func NewValidator() *Validator {
+	return &Validator{*validation.New()}
+}
+
+// ValidateURL accepts absolute https URLs without credentials.
+func (v *Validator) ValidateURL(raw string) {
+	if raw == "" {
+		v.AddError("URL", "is empty")
+		return
+	}
+	if len(raw) > maxURLLength {
+		v.AddError("URL", fmt.Sprintf("must be less than %d characters", maxURLLength))
+		return
+	}
+	u, err := url.Parse(raw)
+	switch {
+	case err != nil || u.Host == "":
+		v.AddError("URL", "is not a valid URL")
+	case u.Scheme != "https":
+		v.AddError("URL", "must use https")
+	case u.User != nil:
+		v.AddError("URL", "must not contain credentials")
+	}
+}
+
+func (v *Validator) ValidateEventTypes(types []string) {
+	if len(types) == 0 {
+		v.AddError("EventTypes", "is empty")
+		return
+	}
+	for _, t := range types {
+		if !slices.Contains(EventTypes, t) {
+			v.AddError("EventTypes", fmt.Sprintf("unknown event type %q", t))
+		}
+	}
+}
+
+func (v *Validator) ValidateDescription(description string) {
+	if len(description) > maxDescriptionLength {
+		v.AddError("Description", fmt.Sprintf("must be less than %d characters", maxDescriptionLength))
+	}
+}
+
+func (v *Validator) ValidatePage(params pagination.Params) {
+	for field, msg := range params.Validate() {
+		v.AddError(field, msg)
+	}
+}
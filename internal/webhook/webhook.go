@@ -0,0 +1,225 @@
+// Package webhook delivers domain events to endpoints that tenants register.
+//
+// The Service subscribes to the event outbox and turns each message into
+// one Delivery per matching subscription. Deliveries are then sent by
+// DeliverDue. Each one is signed with the subscription's secret and retried
+// with backoff until it succeeds or is dead-lettered. Every attempt is
+// kept on the delivery as its log.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/employee"
+	"payroll/internal/payroll"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/workspace"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const modelOrigin = "Webhook"
+
+// EventTypes are the events tenants can subscribe to.
+var EventTypes = []string{
+	employee.EventCreated, employee.EventUpdated, employee.EventDeleted, employee.EventRestored, employee.EventErased,
+	workspace.EventCreated, workspace.EventUpdated, workspace.EventDeleted, workspace.EventRestored,
+	workspace.EventStatusChanged,
+	payroll.EventRunCreated, payroll.EventResultCalculated, payroll.EventRunFinalized,
+}
+
+// SecretPrefix starts every signing secret.
+const SecretPrefix = "whsec_"
+
+// Subscription is an endpoint of a tenant and the events it receives.
+type Subscription struct {
+	domain.BaseEntity
+	TenantID    uuid.UUID
+	URL         string
+	EventTypes  []string
+	Description string
+	Active      bool
+	// Secret signs the deliveries. It is only returned when the
+	// subscription is created.
+	Secret string `json:"-" audit:"-"`
+}
+
+type CreateSubscriptionParams struct {
+	URL         string
+	EventTypes  []string
+	Description string
+}
+
+type UpdateSubscriptionParams struct {
+	URL         *string
+	EventTypes  []string
+	Description *string
+	Active      *bool
+	// Version, when set, must equal the current version or the update fails
+	// with a conflict.
+	Version *int64
+}
+
+func NewSubscription(tenantID uuid.UUID, params CreateSubscriptionParams) (*Subscription, error) {
+	validator := NewValidator()
+
+	params.URL = strings.TrimSpace(params.URL)
+	params.Description = strings.TrimSpace(params.Description)
+
+	validator.ValidateURL(params.URL)
+	validator.ValidateEventTypes(params.EventTypes)
+	validator.ValidateDescription(params.Description)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Subscription{
+		TenantID:    tenantID,
+		URL:         params.URL,
+		EventTypes:  params.EventTypes,
+		Description: params.Description,
+		Active:      true,
+		Secret:      secret,
+	}
+	s.Initialize()
+
+	return s, nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Wants reports whether the subscription receives events of the given type.
+func (s *Subscription) Wants(eventType string) bool {
+	if !s.Active || s.IsDeleted() {
+		return false
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "PENDING"
+	DeliveryStatusSucceeded DeliveryStatus = "SUCCEEDED"
+	// DeliveryStatusDead is a delivery that is not tried again. It can be
+	// replayed.
+	DeliveryStatusDead DeliveryStatus = "DEAD"
+)
+
+func (s DeliveryStatus) IsValid() bool {
+	switch s {
+	case DeliveryStatusPending, DeliveryStatusSucceeded, DeliveryStatusDead:
+		return true
+	}
+	return false
+}
+
+// Attempt is one try at sending a delivery. StatusCode is 0 when no
+// response was received.
+type Attempt struct {
+	At         time.Time
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+}
+
+// Delivery is one message on its way to one subscription.
+type Delivery struct {
+	ID             uuid.UUID
+	TenantID       uuid.UUID
+	SubscriptionID uuid.UUID
+	MessageID      uuid.UUID
+	EventType      string
+	// EntityID identifies what the message is about, e.g. the employee.
+	EntityID uuid.UUID
+	// Body is the JSON document that is sent, fixed when the delivery is
+	// created but for its data, which is scrubbed when the entity is erased.
+	Body          json.RawMessage
+	Status        DeliveryStatus
+	Attempts      []Attempt
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+	// ReplayOf is the delivery this one replays.
+	ReplayOf *uuid.UUID
+}
+
+// body is what receivers get. ID is the message ID, so it stays the same
+// across retries and replays and receivers can use it to drop duplicates.
+type body struct {
+	ID         uuid.UUID       `json:"id"`
+	Type       string          `json:"type"`
+	TenantID   uuid.UUID       `json:"tenant_id"`
+	EntityID   uuid.UUID       `json:"entity_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type SubscriptionRepository interface {
+	Create(ctx context.Context, s *Subscription) error
+	Get(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	// ListByTenantID returns the tenant's subscriptions that are not deleted.
+	ListByTenantID(ctx context.Context, tenantID uuid.UUID) ([]*Subscription, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, s *Subscription) error
+}
+
+type DeliveryFilter struct {
+	TenantID       uuid.UUID
+	SubscriptionID uuid.UUID
+	Status         *DeliveryStatus
+	pagination.Query
+}
+
+type DeliveryRepository interface {
+	Create(ctx context.Context, d *Delivery) error
+	Get(ctx context.Context, id uuid.UUID) (*Delivery, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, d *Delivery) error
+	ExistsByMessageID(ctx context.Context, messageID uuid.UUID) (bool, error)
+	// Due returns up to limit pending deliveries whose NextAttemptAt is not
+	// after now, oldest first.
+	Due(ctx context.Context, now time.Time, limit int) ([]*Delivery, error)
+	// List returns deliveries newest first.
+	List(ctx context.Context, filter DeliveryFilter) ([]*Delivery, error)
+	// ScrubByEntityID replaces the data in the bodies of the tenant's
+	// deliveries about the entity with JSON null, e.g. when its personal data
+	// is erased, and returns how many there were. Use ScrubBody.
+	ScrubByEntityID(ctx context.Context, tenantID, entityID uuid.UUID) (int, error)
+}
+
+// ScrubBody returns a delivery body with its data replaced by JSON null, for
+// implementations of DeliveryRepository.ScrubByEntityID.
+func ScrubBody(b json.RawMessage) (json.RawMessage, error) {
+	var scrubbed body
+	if err := json.Unmarshal(b, &scrubbed); err != nil {
+		return nil, err
+	}
+	scrubbed.Data = json.RawMessage("null")
+	return json.Marshal(scrubbed)
+}
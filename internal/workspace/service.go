@@ -3,26 +3,105 @@ package workspace
 import (
 	"context"
 	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/event"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/keylock"
+	"payroll/internal/platform/pagination"
+	"payroll/internal/platform/tenant"
+	"payroll/internal/platform/uow"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-const serviceOrigin = "Workspace Service"
+const (
+	serviceOrigin = "Workspace Service"
+	auditEntity   = "Workspace"
+)
+
+// TenantPolicy enforces the tenant's status and plan. Reads are allowed for
+// suspended tenants; every write checks the policy first.
+type TenantPolicy interface {
+	// CheckActive fails if the tenant is suspended.
+	CheckActive(ctx context.Context, tenantID uuid.UUID) error
+	// CheckWorkspaceLimit fails if the tenant is suspended or its plan does
+	// not allow count workspaces.
+	CheckWorkspaceLimit(ctx context.Context, tenantID uuid.UUID, count int) error
+	// DefaultCountryID returns uuid.Nil if the tenant has no default country.
+	DefaultCountryID(ctx context.Context, tenantID uuid.UUID) (uuid.UUID, error)
+}
 
 type Service struct {
-	repo Repository
+	repo      Repository
+	readiness ReadinessChecker
+	runs      OpenRunChecker
+	tenants   TenantPolicy
+	uow       uow.UnitOfWork
+	audit     audit.Recorder
+	events    event.Publisher
+	// tenantLocks serializes the workspace limit and code checks with the
+	// writes they guard, per tenant.
+	tenantLocks *keylock.KeyLock
+}
+
+func NewService(r Repository, rc ReadinessChecker, orc OpenRunChecker, tp TenantPolicy, u uow.UnitOfWork, a audit.Recorder, p event.Publisher) *Service {
+	return &Service{repo: r, readiness: rc, runs: orc, tenants: tp, uow: u, audit: a, events: p, tenantLocks: keylock.New()}
+}
+
+// eventTypes maps audited actions to the events published for them.
+var eventTypes = map[audit.Action]string{
+	audit.ActionCreate:  EventCreated,
+	audit.ActionUpdate:  EventUpdated,
+	audit.ActionDelete:  EventDeleted,
+	audit.ActionRestore: EventRestored,
 }
 
-func NewService(r Repository) *Service {
-	return &Service{repo: r}
+// save runs write, records the change of ws in the audit log and publishes
+// its event, in one unit of work. before is nil for creations.
+func (s *Service) save(ctx context.Context, action audit.Action, before, ws *Workspace, write func(ctx context.Context) error) error {
+	ev := audit.Event{TenantID: ws.TenantID, EntityType: auditEntity, EntityID: ws.ID, Action: action, Before: before, After: ws}
+	return s.uow.Do(ctx, func(ctx context.Context) error {
+		if err := write(ctx); err != nil {
+			return err
+		}
+		if err := s.audit.Record(ctx, ev); err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, event.Event{Type: eventTypes[action], TenantID: ws.TenantID, EntityID: ws.ID, Payload: ws})
+	})
 }
 
 func (s *Service) Create(ctx context.Context, params CreateWorkspaceParams) (*Workspace, error) {
+	if err := auth.Authorize(ctx, auth.PermWorkspaceWrite, auth.Resource{}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	params.TenantID = tenantID
+
+	if params.CountryID == uuid.Nil {
+		params.CountryID, err = s.tenants.DefaultCountryID(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	workspace, err := NewWorkspace(params)
 	if err != nil {
 		return nil, err
 	}
 
+	unlock := s.tenantLocks.Lock(workspace.TenantID.String())
+	defer unlock()
+
+	if err := s.checkLimit(ctx, workspace.TenantID); err != nil {
+		return nil, err
+	}
+
 	exists, err := s.repo.ExistsByTenantIDAndCode(ctx, workspace.TenantID, workspace.Code)
 	if err != nil {
 		return nil, err
@@ -32,7 +111,10 @@ func (s *Service) Create(ctx context.Context, params CreateWorkspaceParams) (*Wo
 			"a workspace with this code already exists for the given tenant")
 	}
 
-	if err := s.repo.Create(ctx, workspace); err != nil {
+	err = s.save(ctx, audit.ActionCreate, nil, workspace, func(ctx context.Context) error {
+		return s.repo.Create(ctx, workspace)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -40,46 +122,228 @@ func (s *Service) Create(ctx context.Context, params CreateWorkspaceParams) (*Wo
 }
 
 func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Workspace, error) {
-	return s.repo.Get(ctx, id)
+	if err := auth.Authorize(ctx, auth.PermWorkspaceRead, auth.Resource{WorkspaceID: id}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	return s.get(ctx, id)
 }
 
-func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateWorkspaceParams) (*Workspace, error) {
-	ws, err := s.repo.Get(ctx, id)
+// get returns the workspace unless it has been soft-deleted or belongs to
+// another tenant.
+func (s *Service) get(ctx context.Context, id uuid.UUID) (*Workspace, error) {
+	ws, err := s.getAnyState(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if ws.IsDeleted() {
+		return nil, apperror.New(apperror.TypeNotFound, serviceOrigin, "workspace not found")
+	}
+	return ws, nil
+}
 
-	validator := NewValidator()
+// getAnyState is get without the soft-delete check.
+func (s *Service) getAnyState(ctx context.Context, id uuid.UUID) (*Workspace, error) {
+	ws, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Check(ctx, ws.TenantID, serviceOrigin, "workspace not found"); err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
 
-	if params.Code != nil {
-		validator.ValidateCode(*params.Code)
-		ws.Code = *params.Code
+// List returns a page of the tenant's workspaces in creation order, limited
+// to those the caller may read.
+func (s *Service) List(ctx context.Context, params ListWorkspacesParams) (*pagination.Page[*Workspace], error) {
+	tenantID, err := tenant.Resolve(ctx, params.TenantID)
+	if err != nil {
+		return nil, err
 	}
-	if params.Name != nil {
-		validator.ValidateName(*params.Name)
-		ws.Name = *params.Name
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if params.Status != nil {
-		validator.ValidateStatus(params.Status)
-		ws.Status = *params.Status
+	params.TenantID = tenantID
+	params.Search = strings.TrimSpace(params.Search)
+
+	validator := NewValidator()
+	validator.ValidateStatus(params.Status)
+	if params.CountryID != nil && *params.CountryID == uuid.Nil {
+		validator.AddError("CountryID", "is empty")
 	}
+	validator.ValidateSearch(params.Search)
+	validator.ValidatePage(params.Params)
 
 	if validator.HasErrors() {
 		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
 	}
 
-	ws.Touch()
+	query := params.Params.Query()
+	filter := Filter{
+		TenantID:  params.TenantID,
+		Status:    params.Status,
+		CountryID: params.CountryID,
+		Search:    params.Search,
+		Query:     query,
+	}
+	if ids, all := p.Workspaces(auth.PermWorkspaceRead); !all {
+		if len(ids) == 0 {
+			return &pagination.Page[*Workspace]{}, nil
+		}
+		filter.IDs = ids
+	}
 
-	if err := s.repo.Update(ctx, ws); err != nil {
+	workspaces, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.NewPage(workspaces, query, func(ws *Workspace) string {
+		return pagination.EncodeCursor(ws.ID)
+	}), nil
+}
+
+// Update checks params.Version and saves the workspace in one unit of work,
+// so that no other write can come in between.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateWorkspaceParams) (*Workspace, error) {
+	if err := auth.Authorize(ctx, auth.PermWorkspaceWrite, auth.Resource{WorkspaceID: id}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	var ws *Workspace
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		ws, err = s.get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !ws.MatchesVersion(params.Version) {
+			return apperror.NewConflictError(serviceOrigin, "workspace was modified since it was read")
+		}
+		if err := s.tenants.CheckActive(ctx, ws.TenantID); err != nil {
+			return err
+		}
+		before := *ws
+
+		validator := NewValidator()
+
+		if params.Code != nil {
+			validator.ValidateCode(*params.Code)
+			ws.Code = *params.Code
+		}
+		if params.Name != nil {
+			validator.ValidateName(*params.Name)
+			ws.Name = *params.Name
+		}
+
+		if validator.HasErrors() {
+			return apperror.NewValidationError(serviceOrigin, validator.Errors())
+		}
+
+		ws.Touch()
+
+		return s.save(ctx, audit.ActionUpdate, &before, ws, func(ctx context.Context) error {
+			return s.repo.Update(ctx, ws)
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return ws, nil
 }
 
+// Delete soft-deletes the workspace. It can be restored until it is purged.
 func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
-	if _, err := s.repo.Get(ctx, id); err != nil {
+	if err := auth.Authorize(ctx, auth.PermWorkspaceWrite, auth.Resource{WorkspaceID: id}, serviceOrigin); err != nil {
+		return err
+	}
+	ws, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.tenants.CheckActive(ctx, ws.TenantID); err != nil {
+		return err
+	}
+	before := *ws
+	ws.SoftDelete()
+	return s.save(ctx, audit.ActionDelete, &before, ws, func(ctx context.Context) error {
+		return s.repo.Update(ctx, ws)
+	})
+}
+
+// Restore undoes Delete, provided no other workspace of the tenant has taken
+// the code in the meantime.
+func (s *Service) Restore(ctx context.Context, id uuid.UUID) (*Workspace, error) {
+	if err := auth.Authorize(ctx, auth.PermWorkspaceWrite, auth.Resource{WorkspaceID: id}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	ws, err := s.getAnyState(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ws.IsDeleted() {
+		return ws, nil
+	}
+
+	unlock := s.tenantLocks.Lock(ws.TenantID.String())
+	defer unlock()
+
+	if err := s.checkLimit(ctx, ws.TenantID); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.ExistsByTenantIDAndCode(ctx, ws.TenantID, ws.Code)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, apperror.NewDuplicateError(serviceOrigin, map[string]string{"Code": "already exists"})
+	}
+
+	before := *ws
+	ws.Restore()
+	err = s.save(ctx, audit.ActionRestore, &before, ws, func(ctx context.Context) error {
+		return s.repo.Update(ctx, ws)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+// checkLimit fails unless the tenant can have one more workspace.
+func (s *Service) checkLimit(ctx context.Context, tenantID uuid.UUID) error {
+	count, err := s.repo.CountByTenantID(ctx, tenantID)
+	if err != nil {
 		return err
 	}
-	return s.repo.Delete(ctx, id)
+	return s.tenants.CheckWorkspaceLimit(ctx, tenantID, count+1)
+}
+
+// Purge permanently removes the workspaces soft-deleted before the given time
+// and records each removal in its tenant's audit log. It is a maintenance job
+// that runs across tenants, so it requires auth.PermDataPurge.
+func (s *Service) Purge(ctx context.Context, before time.Time) (int, error) {
+	if err := auth.Authorize(ctx, auth.PermDataPurge, auth.Resource{}, serviceOrigin); err != nil {
+		return 0, err
+	}
+	var purged []*Workspace
+	err := s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if purged, err = s.repo.PurgeDeleted(ctx, before); err != nil {
+			return err
+		}
+		for _, ws := range purged {
+			err := s.audit.Record(ctx, audit.Event{TenantID: ws.TenantID, EntityType: auditEntity, EntityID: ws.ID, Action: audit.ActionPurge})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(purged), nil
 }
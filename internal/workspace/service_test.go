@@ -1 +1,509 @@
 package workspace
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/platform/auth"
+	"payroll/internal/platform/uow"
+	"payroll/internal/testkit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRepo struct {
+	Repository
+	mu         sync.Mutex
+	workspaces map[uuid.UUID]*Workspace
+	changes    []*StatusChange
+	// checkDelay slows down the checks callers make before writing, to
+	// widen any window between them and the write.
+	checkDelay time.Duration
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{workspaces: make(map[uuid.UUID]*Workspace)}
+}
+
+func (r *memoryRepo) Create(_ context.Context, ws *Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workspaces[ws.ID] = ws
+	return nil
+}
+
+func (r *memoryRepo) Get(_ context.Context, id uuid.UUID) (*Workspace, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ws, ok := r.workspaces[id]
+	if !ok {
+		return nil, apperror.New(apperror.TypeNotFound, "test", "workspace not found")
+	}
+	return ws, nil
+}
+
+func (r *memoryRepo) List(_ context.Context, filter Filter) ([]*Workspace, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*Workspace
+	for _, ws := range r.workspaces {
+		if ws.IsDeleted() || ws.TenantID != filter.TenantID || bytes.Compare(ws.ID[:], filter.After[:]) <= 0 {
+			continue
+		}
+		if filter.IDs != nil && !slices.Contains(filter.IDs, ws.ID) {
+			continue
+		}
+		if filter.Status != nil && ws.Status != *filter.Status {
+			continue
+		}
+		if filter.CountryID != nil && ws.CountryID != *filter.CountryID {
+			continue
+		}
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(ws.Code), search) && !strings.Contains(strings.ToLower(ws.Name), search) {
+			continue
+		}
+		list = append(list, ws)
+	}
+	slices.SortFunc(list, func(a, b *Workspace) int { return bytes.Compare(a.ID[:], b.ID[:]) })
+	if len(list) > filter.Limit {
+		list = list[:filter.Limit]
+	}
+	return list, nil
+}
+
+func (r *memoryRepo) Update(_ context.Context, ws *Workspace) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workspaces[ws.ID] = ws
+	return nil
+}
+
+func (r *memoryRepo) ExistsByTenantIDAndCode(_ context.Context, tenantID uuid.UUID, code string) (bool, error) {
+	time.Sleep(r.checkDelay)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ws := range r.workspaces {
+		if !ws.IsDeleted() && ws.TenantID == tenantID && ws.Code == code {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepo) CountByTenantID(_ context.Context, tenantID uuid.UUID) (int, error) {
+	time.Sleep(r.checkDelay)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, ws := range r.workspaces {
+		if !ws.IsDeleted() && ws.TenantID == tenantID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryRepo) PurgeDeleted(_ context.Context, before time.Time) ([]*Workspace, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var purged []*Workspace
+	for id, ws := range r.workspaces {
+		if ws.IsDeleted() && ws.DeletedAt.Before(before) {
+			delete(r.workspaces, id)
+			purged = append(purged, ws)
+		}
+	}
+	return purged, nil
+}
+
+func (r *memoryRepo) AddStatusChange(_ context.Context, change *StatusChange) error {
+	r.changes = append(r.changes, change)
+	return nil
+}
+
+type stubReadiness struct {
+	calendar   bool
+	glMappings int
+}
+
+func (s *stubReadiness) HasPayCalendar(context.Context, uuid.UUID) (bool, error) {
+	return s.calendar, nil
+}
+
+func (s *stubReadiness) CountGLMappings(context.Context, uuid.UUID) (int, error) {
+	return s.glMappings, nil
+}
+
+type stubRuns struct {
+	open bool
+	// checked, when set, is called after each check.
+	checked func()
+}
+
+func (s *stubRuns) HasOpenRun(context.Context, uuid.UUID) (bool, error) {
+	open := s.open
+	if s.checked != nil {
+		s.checked()
+	}
+	return open, nil
+}
+
+type stubTenants struct {
+	suspended      bool
+	maxWorkspaces  int
+	defaultCountry uuid.UUID
+}
+
+func (s *stubTenants) CheckActive(context.Context, uuid.UUID) error {
+	if s.suspended {
+		return apperror.New(apperror.TypeForbidden, "test", "tenant is suspended")
+	}
+	return nil
+}
+
+func (s *stubTenants) CheckWorkspaceLimit(ctx context.Context, tenantID uuid.UUID, count int) error {
+	if err := s.CheckActive(ctx, tenantID); err != nil {
+		return err
+	}
+	if s.maxWorkspaces > 0 && count > s.maxWorkspaces {
+		return apperror.New(apperror.TypeForbidden, "test", "plan limit exceeded")
+	}
+	return nil
+}
+
+func (s *stubTenants) DefaultCountryID(context.Context, uuid.UUID) (uuid.UUID, error) {
+	return s.defaultCountry, nil
+}
+
+type statusFixture struct {
+	ctx       context.Context
+	tenantID  uuid.UUID
+	service   *Service
+	repo      *memoryRepo
+	readiness *stubReadiness
+	runs      *stubRuns
+	tenants   *stubTenants
+	uow       *uow.Memory
+	audit     *testkit.Audit
+	events    *testkit.Publisher
+}
+
+func newStatusFixture() *statusFixture {
+	f := &statusFixture{tenantID: uuid.New(), repo: newMemoryRepo(), readiness: &stubReadiness{}, runs: &stubRuns{}, tenants: &stubTenants{}, uow: uow.NewMemory(), audit: &testkit.Audit{}, events: &testkit.Publisher{}}
+	f.ctx = testkit.AdminContext(f.tenantID)
+	f.service = NewService(f.repo, f.readiness, f.runs, f.tenants, f.uow, f.audit, f.events)
+	return f
+}
+
+func (f *statusFixture) create(t *testing.T) *Workspace {
+	t.Helper()
+	ws, err := f.service.Create(f.ctx, CreateWorkspaceParams{
+		CountryID: uuid.New(),
+		Code:      "HQ",
+		Name:      "Headquarters",
+	})
+	require.NoError(t, err)
+	require.Equal(t, WorkspaceStatusPending, ws.Status)
+	return ws
+}
+
+func TestChangeStatus_ActivationRequiresReadiness(t *testing.T) {
+	f := newStatusFixture()
+	ws := f.create(t)
+
+	_, err := f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusActive})
+
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, apperror.TypeInvalid, domainErr.Type)
+	assert.Contains(t, domainErr.Details, "PayCalendar")
+	assert.Contains(t, domainErr.Details, "GLMappings")
+	assert.Equal(t, WorkspaceStatusPending, ws.Status)
+
+	f.readiness.calendar, f.readiness.glMappings = true, 2
+	ws, err = f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusActive, Reason: "go live"})
+	require.NoError(t, err)
+	assert.Equal(t, WorkspaceStatusActive, ws.Status)
+
+	require.Len(t, f.repo.changes, 1)
+	change := f.repo.changes[0]
+	assert.Equal(t, WorkspaceStatusPending, change.From)
+	assert.Equal(t, WorkspaceStatusActive, change.To)
+	assert.Equal(t, "admin", change.Actor)
+	assert.Equal(t, "go live", change.Reason)
+
+	require.Len(t, f.audit.Events, 2)
+	ev := f.audit.Events[1]
+	assert.Equal(t, audit.ActionUpdate, ev.Action)
+	assert.Equal(t, ws.ID, ev.EntityID)
+	assert.Equal(t, WorkspaceStatusPending, ev.Before.(*Workspace).Status)
+
+	var types []string
+	for _, ev := range f.events.Events {
+		types = append(types, ev.Type)
+	}
+	assert.Equal(t, []string{EventCreated, EventStatusChanged, EventUpdated}, types)
+	assert.Equal(t, change, f.events.Events[1].Payload)
+}
+
+func TestChangeStatus_DeactivationBlockedByOpenRun(t *testing.T) {
+	f := newStatusFixture()
+	f.readiness.calendar, f.readiness.glMappings = true, 1
+	ws := f.create(t)
+	_, err := f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusActive})
+	require.NoError(t, err)
+
+	f.runs.open = true
+	_, err = f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusInactive})
+
+	var domainErr *apperror.DomainError
+	require.ErrorAs(t, err, &domainErr)
+	assert.Contains(t, domainErr.Details, "PayrollRun")
+
+	f.runs.open = false
+	ws, err = f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusInactive})
+	require.NoError(t, err)
+	assert.Equal(t, WorkspaceStatusInactive, ws.Status)
+}
+
+func TestChangeStatus_RunCannotOpenBetweenCheckAndDeactivation(t *testing.T) {
+	f := newStatusFixture()
+	f.readiness.calendar, f.readiness.glMappings = true, 1
+	ws := f.create(t)
+	_, err := f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusActive})
+	require.NoError(t, err)
+
+	// A run is created in a unit of work of its own, as payroll does, right
+	// after the check.
+	created := make(chan struct{})
+	f.runs.checked = func() {
+		f.runs.checked = nil
+		go func() {
+			_ = f.uow.Do(context.Background(), func(context.Context) error {
+				f.runs.open = true
+				return nil
+			})
+			close(created)
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	ws, err = f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusInactive})
+	require.NoError(t, err)
+	assert.Equal(t, WorkspaceStatusInactive, ws.Status)
+	select {
+	case <-created:
+		t.Fatal("the run was created before the workspace was deactivated")
+	default:
+	}
+	<-created
+}
+
+func TestChangeStatus_RejectsInvalidTransition(t *testing.T) {
+	f := newStatusFixture()
+	ws := f.create(t)
+	_, err := f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusInactive})
+	require.NoError(t, err)
+
+	_, err = f.service.ChangeStatus(f.ctx, ws.ID, ChangeStatusParams{Status: WorkspaceStatusPending})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+	assert.Len(t, f.repo.changes, 1)
+}
+
+func TestPurge_RequiresPurgePermissionAndAudits(t *testing.T) {
+	f := newStatusFixture()
+	ws := f.create(t)
+	require.NoError(t, f.service.Delete(f.ctx, ws.ID))
+
+	_, err := f.service.Purge(f.ctx, time.Now().Add(time.Minute))
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden), "tenant admins cannot purge")
+
+	purged, err := f.service.Purge(testkit.PlatformAdminContext(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Empty(t, f.repo.workspaces)
+	last := f.audit.Events[len(f.audit.Events)-1]
+	assert.Equal(t, audit.ActionPurge, last.Action)
+	assert.Equal(t, ws.ID, last.EntityID)
+	assert.Equal(t, f.tenantID, last.TenantID)
+}
+
+func TestList_FiltersAndPaginatesByTenant(t *testing.T) {
+	f := newStatusFixture()
+	for i := 0; i < 5; i++ {
+		_, err := f.service.Create(f.ctx, CreateWorkspaceParams{
+			CountryID: uuid.New(),
+			Code:      fmt.Sprintf("BR-%d", i),
+			Name:      fmt.Sprintf("Branch %d", i),
+		})
+		require.NoError(t, err)
+	}
+	other := testkit.AdminContext(uuid.New())
+	_, err := f.service.Create(other, CreateWorkspaceParams{
+		CountryID: uuid.New(), Code: "BR-X", Name: "Branch of another tenant",
+	})
+	require.NoError(t, err)
+
+	params := ListWorkspacesParams{Search: "branch"}
+	params.Limit = 2
+
+	var codes []string
+	for {
+		page, err := f.service.List(f.ctx, params)
+		require.NoError(t, err)
+		for _, ws := range page.Items {
+			codes = append(codes, ws.Code)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		params.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"BR-0", "BR-1", "BR-2", "BR-3", "BR-4"}, codes)
+
+	active := WorkspaceStatusActive
+	page, err := f.service.List(f.ctx, ListWorkspacesParams{Status: &active})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	params.Cursor = "%%%"
+	_, err = f.service.List(f.ctx, params)
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+}
+
+func TestService_DoesNotLeakAcrossTenants(t *testing.T) {
+	f := newStatusFixture()
+	ws := f.create(t)
+	other := testkit.AdminContext(uuid.New())
+	name := "Hijacked"
+
+	_, err := f.service.Get(other, ws.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	_, err = f.service.Update(other, ws.ID, UpdateWorkspaceParams{Name: &name})
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	_, err = f.service.ChangeStatus(other, ws.ID, ChangeStatusParams{Status: WorkspaceStatusInactive})
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+	assert.True(t, apperror.IsType(f.service.Delete(other, ws.ID), apperror.TypeNotFound))
+	_, err = f.service.ListStatusChanges(other, ws.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeNotFound))
+
+	_, err = f.service.List(other, ListWorkspacesParams{TenantID: f.tenantID})
+	assert.True(t, apperror.IsType(err, apperror.TypeInvalid))
+	page, err := f.service.List(other, ListWorkspacesParams{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	assert.Equal(t, "Headquarters", ws.Name)
+	assert.Equal(t, WorkspaceStatusPending, ws.Status)
+}
+
+func TestCreate_EnforcesTenantPolicy(t *testing.T) {
+	f := newStatusFixture()
+	f.tenants.maxWorkspaces = 1
+	f.tenants.defaultCountry = uuid.New()
+
+	ws, err := f.service.Create(f.ctx, CreateWorkspaceParams{Code: "HQ", Name: "Headquarters"})
+	require.NoError(t, err)
+	assert.Equal(t, f.tenants.defaultCountry, ws.CountryID)
+
+	_, err = f.service.Create(f.ctx, CreateWorkspaceParams{Code: "BR", Name: "Branch"})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	require.NoError(t, f.service.Delete(f.ctx, ws.ID))
+	other, err := f.service.Create(f.ctx, CreateWorkspaceParams{Code: "BR", Name: "Branch"})
+	require.NoError(t, err)
+	_, err = f.service.Restore(f.ctx, ws.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	f.tenants.suspended = true
+	name := "Renamed"
+	_, err = f.service.Update(f.ctx, other.ID, UpdateWorkspaceParams{Name: &name})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = f.service.Get(f.ctx, other.ID)
+	assert.NoError(t, err)
+}
+
+func TestCreate_SerializesChecksPerTenant(t *testing.T) {
+	// createAll creates a workspace per code concurrently and returns the
+	// errors.
+	createAll := func(f *statusFixture, codes ...string) []error {
+		errs := make([]error, len(codes))
+		var start, done sync.WaitGroup
+		start.Add(1)
+		for i, code := range codes {
+			done.Add(1)
+			go func() {
+				defer done.Done()
+				start.Wait()
+				_, errs[i] = f.service.Create(f.ctx, CreateWorkspaceParams{CountryID: uuid.New(), Code: code, Name: "Workspace " + code})
+			}()
+		}
+		start.Done()
+		done.Wait()
+		return errs
+	}
+	succeeded := func(errs []error, rejectedAs apperror.Type) int {
+		n := 0
+		for _, err := range errs {
+			if err == nil {
+				n++
+			} else {
+				assert.True(t, apperror.IsType(err, rejectedAs), "unexpected error: %v", err)
+			}
+		}
+		return n
+	}
+
+	f := newStatusFixture()
+	f.repo.checkDelay = time.Millisecond
+	errs := createAll(f, "HQ", "HQ", "HQ", "HQ", "HQ", "HQ", "HQ", "HQ")
+	assert.Equal(t, 1, succeeded(errs, apperror.TypeDuplicate), "the code is taken once")
+	assert.Len(t, f.repo.workspaces, 1)
+
+	f = newStatusFixture()
+	f.repo.checkDelay = time.Millisecond
+	f.tenants.maxWorkspaces = 1
+	errs = createAll(f, "A", "B", "C", "D", "E", "F", "G", "H")
+	assert.Equal(t, 1, succeeded(errs, apperror.TypeForbidden), "the plan limit holds")
+	assert.Len(t, f.repo.workspaces, 1)
+}
+
+func TestService_AuthorizesByRoleAndWorkspace(t *testing.T) {
+	f := newStatusFixture()
+	hq := f.create(t)
+	branch, err := f.service.Create(f.ctx, CreateWorkspaceParams{CountryID: uuid.New(), Code: "BR", Name: "Branch"})
+	require.NoError(t, err)
+
+	manager := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "bob", TenantID: f.tenantID, Roles: []auth.Role{auth.RolePayrollManager},
+		WorkspaceIDs: []uuid.UUID{branch.ID},
+	})
+	_, err = f.service.Get(manager, branch.ID)
+	assert.NoError(t, err)
+	_, err = f.service.Get(manager, hq.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	page, err := f.service.List(manager, ListWorkspacesParams{})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, branch.ID, page.Items[0].ID)
+
+	name := "Renamed"
+	_, err = f.service.Update(manager, branch.ID, UpdateWorkspaceParams{Name: &name})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+	_, err = f.service.Create(manager, CreateWorkspaceParams{CountryID: uuid.New(), Code: "NEW", Name: "New"})
+	assert.True(t, apperror.IsType(err, apperror.TypeForbidden))
+
+	_, err = f.service.Get(context.Background(), hq.ID)
+	assert.True(t, apperror.IsType(err, apperror.TypeUnauthenticated))
+}
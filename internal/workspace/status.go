@@ -0,0 +1,199 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"payroll/internal/apperror"
+	"payroll/internal/audit"
+	"payroll/internal/event"
+	"payroll/internal/platform/auth"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxReasonLength = 500
+
+// transitions lists the allowed status changes. A workspace never goes back
+// to PENDING once it has left it.
+var transitions = map[WorkspaceStatus][]WorkspaceStatus{
+	WorkspaceStatusPending:  {WorkspaceStatusActive, WorkspaceStatusInactive},
+	WorkspaceStatusActive:   {WorkspaceStatusInactive},
+	WorkspaceStatusInactive: {WorkspaceStatusActive},
+}
+
+func (s WorkspaceStatus) CanTransitionTo(to WorkspaceStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusChange records a transition of a workspace's status.
+type StatusChange struct {
+	ID          uuid.UUID
+	WorkspaceID uuid.UUID
+	From        WorkspaceStatus
+	To          WorkspaceStatus
+	Actor       string
+	Reason      string
+	OccurredAt  time.Time
+}
+
+type ChangeStatusParams struct {
+	Status WorkspaceStatus
+	Reason string
+	// Version, when set, must equal the current version or the change fails
+	// with a conflict.
+	Version *int64
+}
+
+// ReadinessChecker reports whether a workspace has the setup it needs before
+// it can run payroll.
+type ReadinessChecker interface {
+	HasPayCalendar(ctx context.Context, workspaceID uuid.UUID) (bool, error)
+	CountGLMappings(ctx context.Context, workspaceID uuid.UUID) (int, error)
+}
+
+// OpenRunChecker reports whether a workspace has a payroll run in progress.
+// ChangeStatus calls it in the unit of work that deactivates the workspace,
+// and payroll creates runs only for ACTIVE workspaces in its own, so with
+// serializable units of work a run cannot open in a workspace being
+// deactivated.
+type OpenRunChecker interface {
+	HasOpenRun(ctx context.Context, workspaceID uuid.UUID) (bool, error)
+}
+
+// ChangeStatus moves a workspace to a new status if the transition is
+// allowed and its preconditions hold:
+//
+//   - becoming ACTIVE requires a pay calendar and at least one GL mapping;
+//   - an ACTIVE workspace cannot become INACTIVE while a payroll run is open.
+//
+// The change is recorded with its reason, and with the subject of the
+// caller's principal as its actor.
+func (s *Service) ChangeStatus(ctx context.Context, id uuid.UUID, params ChangeStatusParams) (*Workspace, error) {
+	if err := auth.Authorize(ctx, auth.PermWorkspaceWrite, auth.Resource{WorkspaceID: id}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	p, err := auth.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params.Reason = strings.TrimSpace(params.Reason)
+
+	validator := NewValidator()
+	validator.ValidateStatus(&params.Status)
+	validator.ValidateReason(params.Reason)
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(serviceOrigin, validator.Errors())
+	}
+
+	// The preconditions are checked in the unit of work that saves the
+	// change, so that no payroll run can be created in between.
+	var ws *Workspace
+	err = s.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		ws, err = s.get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !ws.MatchesVersion(params.Version) {
+			return apperror.NewConflictError(serviceOrigin, "workspace was modified since it was read")
+		}
+		if err := s.tenants.CheckActive(ctx, ws.TenantID); err != nil {
+			return err
+		}
+
+		from := ws.Status
+		if from == params.Status {
+			return nil
+		}
+		if !from.CanTransitionTo(params.Status) {
+			return apperror.New(apperror.TypeInvalid, serviceOrigin,
+				fmt.Sprintf("workspace status cannot change from %s to %s", from, params.Status))
+		}
+		if err := s.checkPreconditions(ctx, ws, params.Status); err != nil {
+			return err
+		}
+
+		before := *ws
+		ws.Status = params.Status
+		ws.Touch()
+
+		change := &StatusChange{
+			ID:          uuid.Must(uuid.NewV7()),
+			WorkspaceID: ws.ID,
+			From:        from,
+			To:          params.Status,
+			Actor:       p.Subject,
+			Reason:      params.Reason,
+			OccurredAt:  ws.UpdatedAt,
+		}
+		return s.save(ctx, audit.ActionUpdate, &before, ws, func(ctx context.Context) error {
+			if err := s.repo.Update(ctx, ws); err != nil {
+				return err
+			}
+			if err := s.repo.AddStatusChange(ctx, change); err != nil {
+				return err
+			}
+			return s.events.Publish(ctx, event.Event{Type: EventStatusChanged, TenantID: ws.TenantID, EntityID: ws.ID, Payload: change})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+func (s *Service) checkPreconditions(ctx context.Context, ws *Workspace, to WorkspaceStatus) error {
+	unmet := make(map[string]string)
+
+	switch to {
+	case WorkspaceStatusActive:
+		hasCalendar, err := s.readiness.HasPayCalendar(ctx, ws.ID)
+		if err != nil {
+			return err
+		}
+		if !hasCalendar {
+			unmet["PayCalendar"] = "is not configured"
+		}
+		mappings, err := s.readiness.CountGLMappings(ctx, ws.ID)
+		if err != nil {
+			return err
+		}
+		if mappings == 0 {
+			unmet["GLMappings"] = "at least one is required"
+		}
+	case WorkspaceStatusInactive:
+		if ws.Status == WorkspaceStatusActive {
+			open, err := s.runs.HasOpenRun(ctx, ws.ID)
+			if err != nil {
+				return err
+			}
+			if open {
+				unmet["PayrollRun"] = "a payroll run is still open"
+			}
+		}
+	}
+
+	if len(unmet) > 0 {
+		return apperror.NewWithDetails(apperror.TypeInvalid, serviceOrigin,
+			fmt.Sprintf("workspace cannot change from %s to %s", ws.Status, to), unmet)
+	}
+	return nil
+}
+
+func (s *Service) ListStatusChanges(ctx context.Context, id uuid.UUID) ([]*StatusChange, error) {
+	if err := auth.Authorize(ctx, auth.PermWorkspaceRead, auth.Resource{WorkspaceID: id}, serviceOrigin); err != nil {
+		return nil, err
+	}
+	if _, err := s.get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStatusChanges(ctx, id)
+}
@@ -2,6 +2,7 @@ package workspace
 
 import (
 	"fmt"
+	"payroll/internal/platform/pagination"
 	"payroll/internal/platform/validation"
 
 	"github.com/google/uuid"
@@ -47,3 +48,21 @@ func (v *Validator) ValidateCountryID(countryID uuid.UUID) {
 		v.AddError("CountryID", "is empty")
 	}
 }
+
+func (v *Validator) ValidateReason(reason string) {
+	if len(reason) > maxReasonLength {
+		v.AddError("Reason", fmt.Sprintf("must be less than %d characters", maxReasonLength))
+	}
+}
+
+func (v *Validator) ValidateSearch(search string) {
+	if len(search) > maxNameLength {
+		v.AddError("Search", fmt.Sprintf("must be less than %d characters", maxNameLength))
+	}
+}
+
+func (v *Validator) ValidatePage(params pagination.Params) {
+	for field, msg := range params.Validate() {
+		v.AddError(field, msg)
+	}
+}
@@ -1,93 +1,136 @@
-package workspace
-
-import (
-	"context"
-	"payroll/internal/apperror"
-	"payroll/internal/domain"
-
-	"github.com/google/uuid"
-)
-
-type WorkspaceStatus string
-
-const modelOrigin = "Workspace"
-
-const (
-	WorkspaceStatusActive   WorkspaceStatus = "ACTIVE"
-	WorkspaceStatusInactive WorkspaceStatus = "INACTIVE"
-	WorkspaceStatusPending  WorkspaceStatus = "PENDING"
-)
-
-func (s WorkspaceStatus) IsValid() bool {
-	switch s {
-	case WorkspaceStatusActive, WorkspaceStatusInactive, WorkspaceStatusPending:
-		return true
-	}
-	return false
-}
-
-type Workspace struct {
-	domain.BaseEntity
-	TenantID  uuid.UUID
-	Code      string
-	Name      string
-	Status    WorkspaceStatus
-	CountryID uuid.UUID
-}
-
-type CreateWorkspaceParams struct {
-	TenantID  uuid.UUID
-	CountryID uuid.UUID
-	Code      string
-	Name      string
-	Status    *WorkspaceStatus
-}
-
-type UpdateWorkspaceParams struct {
-	Code   *string
-	Name   *string
-	Status *WorkspaceStatus
-}
-
-func NewWorkspace(params CreateWorkspaceParams) (*Workspace, error) {
-	validator := NewValidator()
-
-	if params.TenantID == uuid.Nil {
-		validator.AddError("TenantID", "is empty")
-	}
-
-	validator.ValidateCode(params.Code)
-	validator.ValidateName(params.Name)
-	validator.ValidateCountryID(params.CountryID)
-	validator.ValidateStatus(params.Status)
-
-	var status WorkspaceStatus
-	if params.Status == nil {
-		status = WorkspaceStatusPending
-	} else {
-		status = *params.Status
-	}
-
-	if validator.HasErrors() {
-		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
-	}
-
-	ws := &Workspace{
-		TenantID:  params.TenantID,
-		Code:      params.Code,
-		Name:      params.Name,
-		CountryID: params.CountryID,
-		Status:    status,
-	}
-	ws.Initialize()
-
-	return ws, nil
-}
-
-type Repository interface {
-	Create(ctx context.Context, ws *Workspace) error
-	Get(ctx context.Context, id uuid.UUID) (*Workspace, error)
-	Update(ctx context.Context, ws *Workspace) error
-	Delete(ctx context.Context, id uuid.UUID) error
-	ExistsByTenantIDAndCode(ctx context.Context, tenantID uuid.UUID, code string) (bool, error)
-}
+package workspace
+
+import (
+	"context"
+	"payroll/internal/apperror"
+	"payroll/internal/domain"
+	"payroll/internal/platform/pagination"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WorkspaceStatus string
+
+const modelOrigin = "Workspace"
+
+// Events the service publishes. The payload is the Workspace, except for
+// EventStatusChanged whose payload is the StatusChange.
+const (
+	EventCreated       = "WorkspaceCreated"
+	EventUpdated       = "WorkspaceUpdated"
+	EventDeleted       = "WorkspaceDeleted"
+	EventRestored      = "WorkspaceRestored"
+	EventStatusChanged = "WorkspaceStatusChanged"
+)
+
+const (
+	WorkspaceStatusActive   WorkspaceStatus = "ACTIVE"
+	WorkspaceStatusInactive WorkspaceStatus = "INACTIVE"
+	WorkspaceStatusPending  WorkspaceStatus = "PENDING"
+)
+
+func (s WorkspaceStatus) IsValid() bool {
+	switch s {
+	case WorkspaceStatusActive, WorkspaceStatusInactive, WorkspaceStatusPending:
+		return true
+	}
+	return false
+}
+
+type Workspace struct {
+	domain.BaseEntity
+	TenantID  uuid.UUID
+	Code      string
+	Name      string
+	Status    WorkspaceStatus
+	CountryID uuid.UUID
+}
+
+// New workspaces always start as PENDING; use Service.ChangeStatus to move
+// them through their lifecycle.
+type CreateWorkspaceParams struct {
+	TenantID  uuid.UUID
+	CountryID uuid.UUID
+	Code      string
+	Name      string
+}
+
+type UpdateWorkspaceParams struct {
+	Code *string
+	Name *string
+	// Version, when set, must equal the current version (e.g. from an
+	// If-Match header) or the update fails with a conflict.
+	Version *int64
+}
+
+func NewWorkspace(params CreateWorkspaceParams) (*Workspace, error) {
+	validator := NewValidator()
+
+	if params.TenantID == uuid.Nil {
+		validator.AddError("TenantID", "is empty")
+	}
+
+	validator.ValidateCode(params.Code)
+	validator.ValidateName(params.Name)
+	validator.ValidateCountryID(params.CountryID)
+
+	if validator.HasErrors() {
+		return nil, apperror.NewValidationError(modelOrigin, validator.Errors())
+	}
+
+	ws := &Workspace{
+		TenantID:  params.TenantID,
+		Code:      params.Code,
+		Name:      params.Name,
+		CountryID: params.CountryID,
+		Status:    WorkspaceStatusPending,
+	}
+	ws.Initialize()
+
+	return ws, nil
+}
+
+// ListWorkspacesParams selects a page of a tenant's workspaces. Search
+// matches a substring of the code or name, ignoring case.
+type ListWorkspacesParams struct {
+	TenantID  uuid.UUID
+	Status    *WorkspaceStatus
+	CountryID *uuid.UUID
+	Search    string
+	pagination.Params
+}
+
+// Filter is what the repository receives from Service.List. Results must be
+// ordered by ID and start after Query.After.
+type Filter struct {
+	TenantID uuid.UUID
+	// IDs, when not nil, restricts the results to these workspaces.
+	IDs       []uuid.UUID
+	Status    *WorkspaceStatus
+	CountryID *uuid.UUID
+	Search    string
+	pagination.Query
+}
+
+// Repository persists workspaces. Get also returns soft-deleted workspaces so
+// that they can be restored; List, ExistsByTenantIDAndCode and
+// CountByTenantID exclude them.
+type Repository interface {
+	Create(ctx context.Context, ws *Workspace) error
+	Get(ctx context.Context, id uuid.UUID) (*Workspace, error)
+	List(ctx context.Context, filter Filter) ([]*Workspace, error)
+	// Update must compare and swap on Version, as domain.BaseEntity
+	// describes.
+	Update(ctx context.Context, ws *Workspace) error
+	// Delete removes a workspace permanently.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// PurgeDeleted permanently removes the workspaces soft-deleted before
+	// the given time and returns them.
+	PurgeDeleted(ctx context.Context, before time.Time) ([]*Workspace, error)
+	ExistsByTenantIDAndCode(ctx context.Context, tenantID uuid.UUID, code string) (bool, error)
+	// CountByTenantID counts the tenant's workspaces that are not deleted.
+	CountByTenantID(ctx context.Context, tenantID uuid.UUID) (int, error)
+	AddStatusChange(ctx context.Context, change *StatusChange) error
+	ListStatusChanges(ctx context.Context, workspaceID uuid.UUID) ([]*StatusChange, error)
+}